@@ -0,0 +1,269 @@
+// Package api is a client for the HTTP endpoints of the etget commands.
+// The endpoints are described by the OpenAPI document openapi.yaml in this
+// directory.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ImportsPath is the path of the imports endpoint of etget status.
+const ImportsPath = "/api/v1/imports"
+
+// Import is the last successful import of a source.
+type Import struct {
+	Source       string    `json:"source"`
+	FinishedAt   time.Time `json:"finished_at"`
+	RowsAffected int64     `json:"rows_affected"`
+}
+
+// PricesPath is the WebSocket endpoint of etget status pushing a
+// PriceUpdate whenever new day-ahead prices are imported.
+const PricesPath = "/ws"
+
+// PriceUpdate is the message pushed on PricesPath after an elspot import.
+type PriceUpdate struct {
+	Import Import `json:"import"`
+
+	// Prices are the Finnish prices from the start of the current hour
+	// on, as far as they are known.
+	Prices []Price `json:"prices"`
+}
+
+// Price is the day-ahead price of the hour starting at Timestamp, in
+// EUR/MWh without VAT.
+type Price struct {
+	Timestamp time.Time `json:"ts"`
+	Price     float64   `json:"price"`
+}
+
+// Paths of etget status compatible with the spot-hinta.fi API, so that
+// scripts written against it can use an etget server as their base URL.
+const (
+	// SpotHintaTodayPath returns the hours of the current day in Finland.
+	SpotHintaTodayPath = "/Today"
+
+	// SpotHintaForwardPath returns the hours of the current and the next
+	// day, as far as they are known.
+	SpotHintaForwardPath = "/TodayAndDayForward"
+
+	// SpotHintaNowPath returns the current hour as a single object.
+	SpotHintaNowPath = "/JustNow"
+)
+
+// SpotHintaPrice is an hour in the layout of the spot-hinta.fi API.
+type SpotHintaPrice struct {
+	// Rank orders the hours of the Finnish day by price, 1 the cheapest.
+	Rank int `json:"Rank"`
+
+	// DateTime is the start of the hour in Finnish time.
+	DateTime time.Time `json:"DateTime"`
+
+	// PriceNoTax and PriceWithTax are in EUR/kWh, without and with VAT.
+	PriceNoTax   float64 `json:"PriceNoTax"`
+	PriceWithTax float64 `json:"PriceWithTax"`
+}
+
+// ConsumptionPath is the endpoint of etget status -serve-consumption
+// returning the hours of a meter.
+const ConsumptionPath = "/api/v1/consumption"
+
+// Hour is the metered energy of an hour with its spot price.
+type Hour struct {
+	Timestamp time.Time `json:"ts"`
+	KWh       float64   `json:"kwh"`
+	SoldKWh   float64   `json:"sold_kwh"`
+
+	// Price is the Finnish spot price in EUR/MWh without VAT.
+	Price float64 `json:"price"`
+}
+
+// Paths of the tables of etget status exchanged by etget sync. The latest
+// rows of a table are at SyncPath followed by the table name, and the rows
+// themselves as CSV at that path followed by SyncRowsSuffix.
+const (
+	SyncPath       = "/api/v1/sync/"
+	SyncRowsSuffix = "/rows"
+)
+
+// SyncState is the latest row of each series of a table.
+type SyncState struct {
+	Table string `json:"table"`
+
+	// Latest is the timestamp of the latest row by the value of the
+	// series column, or by "" in a table of a single series. Series
+	// without rows are left out.
+	Latest map[string]time.Time `json:"latest"`
+}
+
+// SyncResult is the response to rows posted to SyncPath.
+type SyncResult struct {
+	RowsAffected int64 `json:"rows_affected"`
+}
+
+// Client calls an etget server.
+type Client struct {
+	// BaseURL is the server URL, e.g. "https://etget.example.com:8080".
+	BaseURL string
+
+	// Token is sent as a bearer token if set.
+	Token string
+
+	// HTTPClient makes the requests (default http.DefaultClient).
+	HTTPClient *http.Client
+}
+
+// Imports returns the last successful import of each source.
+func (c *Client) Imports(ctx context.Context) ([]Import, error) {
+	var imports []Import
+	if err := c.getJSON(ctx, ImportsPath, &imports); err != nil {
+		return nil, err
+	}
+	return imports, nil
+}
+
+// TodayAndDayForward returns the prices of today and tomorrow in the
+// spot-hinta.fi layout.
+func (c *Client) TodayAndDayForward(ctx context.Context) ([]SpotHintaPrice, error) {
+	var prices []SpotHintaPrice
+	if err := c.getJSON(ctx, SpotHintaForwardPath, &prices); err != nil {
+		return nil, err
+	}
+	return prices, nil
+}
+
+// Consumption returns the hours of meter, a name in the meters of the
+// server's configuration or a meter_id, from the day from to the day
+// before to, both YYYY-MM-DD in Finnish time. Empty days default to the
+// current month, as in etget cost.
+func (c *Client) Consumption(ctx context.Context, meter, from, to string) ([]Hour, error) {
+	q := url.Values{"meter": {meter}}
+	if from != "" {
+		q.Set("from", from)
+	}
+	if to != "" {
+		q.Set("to", to)
+	}
+	var hours []Hour
+	if err := c.getJSON(ctx, ConsumptionPath+"?"+q.Encode(), &hours); err != nil {
+		return nil, err
+	}
+	return hours, nil
+}
+
+// SyncState returns the latest rows of table on the server.
+func (c *Client) SyncState(ctx context.Context, table string) (SyncState, error) {
+	var state SyncState
+	err := c.getJSON(ctx, SyncPath+url.PathEscape(table), &state)
+	return state, err
+}
+
+// SyncRows returns the rows of table after the given time as CSV with a
+// header row, only those of series unless it is empty. The caller must
+// close the returned reader.
+func (c *Client) SyncRows(ctx context.Context, table, series string, after time.Time) (io.ReadCloser, error) {
+	q := url.Values{}
+	if !after.IsZero() {
+		q.Set("after", after.UTC().Format(time.RFC3339))
+	}
+	if series != "" {
+		q.Set("series", series)
+	}
+	path := SyncPath + url.PathEscape(table) + SyncRowsSuffix
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	resp, err := c.do(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// PostSyncRows sends CSV rows of table, with a header row, to be added to
+// the server's table. Rows the server already has are skipped.
+func (c *Client) PostSyncRows(ctx context.Context, table string, rows io.Reader) (SyncResult, error) {
+	var result SyncResult
+	path := SyncPath + url.PathEscape(table) + SyncRowsSuffix
+	resp, err := c.do(ctx, "POST", path, rows)
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return result, fmt.Errorf("api: %s: %s", path, err)
+	}
+	return result, nil
+}
+
+// Metrics returns the Prometheus metrics of etget status.
+func (c *Client) Metrics(ctx context.Context) (string, error) {
+	b, err := c.get(ctx, "/metrics")
+	return string(b), err
+}
+
+// Calendar returns the iCalendar feed of price-calendar.
+func (c *Client) Calendar(ctx context.Context) (string, error) {
+	b, err := c.get(ctx, "/")
+	return string(b), err
+}
+
+func (c *Client) getJSON(ctx context.Context, path string, v interface{}) error {
+	b, err := c.get(ctx, path)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("api: %s: %s", path, err)
+	}
+	return nil
+}
+
+func (c *Client) get(ctx context.Context, path string) ([]byte, error) {
+	resp, err := c.do(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return ioutil.ReadAll(resp.Body)
+}
+
+// do sends a request with body as CSV, if not nil, and returns the response
+// if its status is 200 OK.
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	u, err := url.Parse(strings.TrimSuffix(c.BaseURL, "/") + path)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "text/csv")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	cl := c.HTTPClient
+	if cl == nil {
+		cl = http.DefaultClient
+	}
+	resp, err := cl.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("api: %s: want HTTP status code 200, got %d", path, resp.StatusCode)
+	}
+	return resp, nil
+}
@@ -0,0 +1,97 @@
+package api_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joneskoo/etget/api"
+)
+
+func TestImports(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != api.ImportsPath {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`[{"source":"elspot","finished_at":"2017-07-14T02:40:00Z","rows_affected":24}]`))
+	}))
+	defer srv.Close()
+
+	c := &api.Client{BaseURL: srv.URL, Token: "secret"}
+	got, err := c.Imports(context.Background())
+	if err != nil {
+		t.Fatalf("Imports: %s", err)
+	}
+	want := api.Import{Source: "elspot", FinishedAt: time.Unix(1500000000, 0).UTC(), RowsAffected: 24}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("Imports() = %+v, want [%+v]", got, want)
+	}
+
+	c.Token = "wrong"
+	if _, err := c.Imports(context.Background()); err == nil {
+		t.Error("Imports with wrong token did not return error")
+	}
+}
+
+func TestSyncRows(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != api.SyncPath+"elspot_area"+api.SyncRowsSuffix {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == "POST" {
+			w.Write([]byte(`{"rows_affected":2}`))
+			return
+		}
+		w.Write([]byte(r.URL.RawQuery))
+	}))
+	defer srv.Close()
+
+	c := &api.Client{BaseURL: srv.URL}
+	rows, err := c.SyncRows(context.Background(), "elspot_area", "FI", time.Unix(1500000000, 0))
+	if err != nil {
+		t.Fatalf("SyncRows: %s", err)
+	}
+	b, _ := ioutil.ReadAll(rows)
+	rows.Close()
+	if got, want := string(b), "after=2017-07-14T02%3A40%3A00Z&series=FI"; got != want {
+		t.Errorf("SyncRows query = %q, want %q", got, want)
+	}
+	res, err := c.PostSyncRows(context.Background(), "elspot_area", strings.NewReader("area,ts,price,status\n"))
+	if err != nil || res.RowsAffected != 2 {
+		t.Errorf("PostSyncRows() = %+v, %v; want 2 rows", res, err)
+	}
+	if _, err := c.SyncState(context.Background(), "elspot_area"); err == nil {
+		t.Error("SyncState of a missing path did not return error")
+	}
+}
+
+func TestConsumption(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != api.ConsumptionPath || r.URL.RawQuery != "from=2017-07-14&meter=cottage" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`[{"ts":"2017-07-14T02:40:00Z","kwh":1.5,"sold_kwh":0,"price":30.5}]`))
+	}))
+	defer srv.Close()
+
+	c := &api.Client{BaseURL: srv.URL}
+	got, err := c.Consumption(context.Background(), "cottage", "2017-07-14", "")
+	if err != nil {
+		t.Fatalf("Consumption: %s", err)
+	}
+	want := api.Hour{Timestamp: time.Unix(1500000000, 0).UTC(), KWh: 1.5, Price: 30.5}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("Consumption() = %+v, want [%+v]", got, want)
+	}
+}
@@ -0,0 +1,51 @@
+//go:build js && wasm
+// +build js,wasm
+
+// The elspot-wasm command exposes the elspot parser to JavaScript so that a
+// web page can validate a Nordpool file before it is uploaded.
+//
+// Build with Go 1.15 or later:
+//
+//	GOOS=js GOARCH=wasm go build -o elspot.wasm ./cmd/elspot-wasm
+//
+// and load it with wasm_exec.js from $(go env GOROOT)/misc/wasm. The module
+// defines a global function etgetParseElspot(text) returning
+// {records: [{timestamp, prices, provisional}], error}.
+package main
+
+import (
+	"strings"
+	"syscall/js"
+	"time"
+	_ "time/tzdata" // browsers have no zoneinfo for time.LoadLocation
+
+	"github.com/joneskoo/etget/elspot"
+)
+
+func main() {
+	js.Global().Set("etgetParseElspot", js.FuncOf(parseElspot))
+	select {}
+}
+
+func parseElspot(this js.Value, args []js.Value) interface{} {
+	if len(args) != 1 {
+		return map[string]interface{}{"error": "etgetParseElspot: want 1 argument"}
+	}
+	records, err := elspot.Parse(strings.NewReader(args[0].String()))
+	if err != nil {
+		return map[string]interface{}{"error": err.Error()}
+	}
+	out := make([]interface{}, len(records))
+	for i, r := range records {
+		prices := make(map[string]interface{}, len(r.Prices))
+		for area, p := range r.Prices {
+			prices[area] = p
+		}
+		out[i] = map[string]interface{}{
+			"timestamp":   r.Timestamp.UTC().Format(time.RFC3339),
+			"prices":      prices,
+			"provisional": r.Provisional,
+		}
+	}
+	return map[string]interface{}{"records": out}
+}
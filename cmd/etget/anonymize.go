@@ -0,0 +1,80 @@
+package main
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// anonymizer hides whose and when the rows of an export are, for sharing
+// consumption data to debug parsing: the series, such as metering points,
+// are renamed meter1, meter2, …, the private columns of the table are
+// emptied and all timestamps are moved back by Shift.
+type anonymizer struct {
+	// Shift is whole weeks, so that weekdays and hours keep their shape.
+	Shift time.Duration
+
+	names map[string]string
+}
+
+// newAnonymizer returns an anonymizer moving the rows back by a random
+// 1–10 years.
+func newAnonymizer() (*anonymizer, error) {
+	weeks, err := rand.Int(rand.Reader, big.NewInt(520-52))
+	if err != nil {
+		return nil, err
+	}
+	return &anonymizer{Shift: time.Duration(52+weeks.Int64()) * 7 * 24 * time.Hour}, nil
+}
+
+// apply anonymizes the values scanned from a row of t in place.
+func (a *anonymizer) apply(t table, values []interface{}) {
+	for i, c := range t.Columns {
+		switch v := values[i].(type) {
+		case *pq.NullTime:
+			v.Time = v.Time.Add(-a.Shift)
+		case *sql.NullString:
+			if c.Name == t.Series && v.Valid {
+				v.String = a.pseudonym(v.String)
+			}
+		}
+		for _, name := range t.Private {
+			if c.Name == name {
+				clearValue(values[i])
+			}
+		}
+	}
+}
+
+// pseudonym returns the name of series in order of appearance.
+func (a *anonymizer) pseudonym(series string) string {
+	if a.names == nil {
+		a.names = make(map[string]string)
+	}
+	name, ok := a.names[series]
+	if !ok {
+		name = fmt.Sprintf("meter%d", len(a.names)+1)
+		a.names[series] = name
+	}
+	return name
+}
+
+// clearValue sets a scanned value to NULL.
+func clearValue(v interface{}) {
+	switch v := v.(type) {
+	case *pq.NullTime:
+		v.Valid = false
+	case *sql.NullInt64:
+		v.Valid = false
+	case *sql.NullFloat64:
+		v.Valid = false
+	case *sql.NullBool:
+		v.Valid = false
+	case *sql.NullString:
+		v.Valid = false
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestAnonymizer(t *testing.T) {
+	energiatili, _ := lookupTable("energiatili")
+	a, err := newAnonymizer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Shift < 52*7*24*time.Hour || a.Shift%(7*24*time.Hour) != 0 {
+		t.Fatalf("Shift = %s, want whole weeks of at least a year", a.Shift)
+	}
+	ts := time.Date(2020, 3, 4, 5, 0, 0, 0, time.UTC)
+	row := func(meter string) []interface{} {
+		return []interface{}{
+			&sql.NullString{String: meter, Valid: true},
+			&pq.NullTime{Time: ts, Valid: true},
+			&sql.NullFloat64{Float64: 1.5, Valid: true},
+			&sql.NullFloat64{Float64: -3, Valid: true},
+			&sql.NullFloat64{},
+			&sql.NullBool{Bool: true, Valid: true},
+		}
+	}
+	var got [][]string
+	for _, meter := range []string{"643000000012345678", "643000000087654321", "643000000012345678"} {
+		values := row(meter)
+		a.apply(energiatili, values)
+		record := make([]string, len(values))
+		for i, v := range values {
+			record[i] = formatValue(v)
+		}
+		got = append(got, record)
+	}
+	shifted := ts.Add(-a.Shift)
+	for i, wantMeter := range []string{"meter1", "meter2", "meter1"} {
+		r := got[i]
+		if r[0] != wantMeter || r[1] != shifted.Format(time.RFC3339) || r[2] != "1.5" || r[3] != "" || r[5] != "true" {
+			t.Errorf("row %d = %q, want %s at %s with kwh and no temp", i, r, wantMeter, shifted.Format(time.RFC3339))
+		}
+	}
+	if shifted.Weekday() != ts.Weekday() || shifted.Hour() != ts.Hour() {
+		t.Errorf("shift moved %s to %s, want the same weekday and hour", ts, shifted)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io/ioutil"
+	"os"
+
+	"github.com/joneskoo/etget/internal/archive"
+)
+
+func init() {
+	register("archive", "SHA256", "Write an input file kept by the importers' -archive, to re-import or re-parse it", func(fs *flag.FlagSet) func([]string) error {
+		dir := fs.String("dir", "", "the -archive `directory` of the importer")
+		output := fs.String("o", "", "write the file here instead of standard output")
+		return func(args []string) error {
+			if len(args) != 1 {
+				return errors.New("want the SHA-256 of the file, as in column sha256 of table import_files")
+			}
+			if *dir == "" {
+				return errors.New("-dir is required")
+			}
+			// Reading works whatever the compression of each file.
+			a := &archive.Archive{Dir: *dir}
+			data, err := a.Get(args[0])
+			if os.IsNotExist(err) {
+				return errors.New("no file with that digest in the archive")
+			}
+			if err != nil {
+				return err
+			}
+			if *output != "" {
+				return ioutil.WriteFile(*output, data, 0644)
+			}
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+	})
+}
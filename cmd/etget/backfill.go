@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/joneskoo/etget/entsoe"
+	"github.com/joneskoo/etget/internal/ledger"
+)
+
+// entsoeTokenEnv is the environment variable with the ENTSO-E API token.
+const entsoeTokenEnv = "ENTSOE_TOKEN"
+
+func init() {
+	register("backfill", "", "Load day-ahead prices from ENTSO-E into the elspot table", func(fs *flag.FlagSet) func([]string) error {
+		connstring := fs.String("connstring", "sslmode=disable", connstringUsage)
+		domain := fs.String("domain", "10YFI-1--------U", "EIC code of the bidding zone")
+		from := fs.String("from", "", "first day, YYYY-MM-DD")
+		to := fs.String("to", "", "day after the last day, YYYY-MM-DD (default tomorrow)")
+		chunk := fs.Int("chunk", 30, "days loaded and committed at a time")
+		stateFile := fs.String("state", "etget-backfill.json", "file recording the last completed day, to resume interrupted runs")
+		restart := fs.Bool("restart", false, "ignore the state file and start from -from")
+		allowGaps := fs.Bool("allow-gaps", false, "store the prices of a chunk and go on when ENTSO-E has none for some of its hours, reporting them; without it the run stops at the chunk")
+		notify := fs.Bool("notify", false, "send NOTIFY "+ledger.NotifyChannel+" with the time range of each committed chunk that changed rows")
+		return func(args []string) error {
+			if len(args) != 0 {
+				return errors.New("unexpected arguments")
+			}
+			if *from == "" {
+				return errors.New("-from is required")
+			}
+			if *chunk <= 0 {
+				return errors.New("-chunk must be positive")
+			}
+			token := os.Getenv(entsoeTokenEnv)
+			if token == "" {
+				return fmt.Errorf("no API token; set %s", entsoeTokenEnv)
+			}
+			start, end, err := parsePeriod(*from, *to, time.Now())
+			if err != nil {
+				return err
+			}
+
+			state, err := loadBackfillState(*stateFile)
+			if err != nil {
+				return err
+			}
+			key := ledger.SourceEntsoe + "/" + *domain
+			if !*restart {
+				if start, err = state.resume(key, start); err != nil {
+					return err
+				}
+			}
+			if !start.Before(end) {
+				fmt.Printf("Nothing to do, %s is complete until %s\n", key, state[key])
+				return nil
+			}
+
+			db, err := sql.Open("postgres", *connstring)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			c := &entsoe.Client{Token: token}
+			for _, r := range dayChunks(start, end, *chunk) {
+				points, err := c.DayAheadPrices(context.Background(), *domain, r[0], r[1])
+				missing, gap := err.(*entsoe.MissingError)
+				if gap && !*allowGaps {
+					return fmt.Errorf("%s..%s: %s (run again to retry, or with -allow-gaps to skip them)", r[0].Format("2006-01-02"), r[1].Format("2006-01-02"), err)
+				}
+				if err != nil && !gap {
+					return fmt.Errorf("%s..%s: %s (run again to resume)", r[0].Format("2006-01-02"), r[1].Format("2006-01-02"), err)
+				}
+				n, err := storePrices(db, ledger.SourceEntsoe, points, *notify)
+				if err != nil {
+					return err
+				}
+				state[key] = r[1].AddDate(0, 0, -1).Format("2006-01-02")
+				if err = state.save(*stateFile); err != nil {
+					return err
+				}
+				fmt.Printf("%s..%s: %d prices, %d rows changed\n", r[0].Format("2006-01-02"), state[key], len(points), n)
+				if gap {
+					fmt.Printf("WARNING %s\n", missing)
+				}
+			}
+			return nil
+		}
+	})
+}
+
+// backfillState maps a source to the last day it has been loaded for.
+type backfillState map[string]string
+
+// loadBackfillState reads the state file. A missing file is an empty state.
+func loadBackfillState(name string) (backfillState, error) {
+	state := backfillState{}
+	b, err := ioutil.ReadFile(name)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, &state); err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err)
+	}
+	return state, nil
+}
+
+// save replaces the state file atomically, so that an interrupted run
+// never leaves it truncated.
+func (s backfillState) save(name string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := name + ".tmp"
+	if err = ioutil.WriteFile(tmp, append(b, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+// resume returns the day after the last completed day of key if it is
+// later than start.
+func (s backfillState) resume(key string, start time.Time) (time.Time, error) {
+	last, ok := s[key]
+	if !ok {
+		return start, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", last, start.Location())
+	if err != nil {
+		return start, fmt.Errorf("state of %s: %s", key, err)
+	}
+	if next := t.AddDate(0, 0, 1); next.After(start) {
+		return next, nil
+	}
+	return start, nil
+}
+
+// dayChunks splits [start, end) into ranges of at most days days.
+func dayChunks(start, end time.Time, days int) (chunks [][2]time.Time) {
+	for s := start; s.Before(end); {
+		e := s.AddDate(0, 0, days)
+		if e.After(end) {
+			e = end
+		}
+		chunks = append(chunks, [2]time.Time{s, e})
+		s = e
+	}
+	return chunks
+}
+
+// storePrices upserts the hourly prices into the elspot table in one
+// transaction and records the import from source in the ledger, notifying
+// listeners of the changed hours if notify is set. Prices from ENTSO-E
+// and the other day-ahead sources are final, so they replace provisional
+// ones.
+func storePrices(db *sql.DB, source string, points []entsoe.Point, notify bool) (int64, error) {
+	txn, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer txn.Rollback()
+
+	stmt, err := txn.Prepare(`INSERT INTO elspot AS t (ts, fi, status) VALUES ($1, $2, 'final')
+    ON CONFLICT (ts) DO UPDATE SET fi = EXCLUDED.fi, status = EXCLUDED.status
+    WHERE t.status <> 'final'`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+	var total int64
+	for _, p := range points {
+		res, err := stmt.Exec(p.Start, p.Price)
+		if err != nil {
+			return 0, err
+		}
+		n, _ := res.RowsAffected()
+		total += n
+	}
+	if err = ledger.Record(txn, source, total); err != nil {
+		return 0, err
+	}
+	if notify && total > 0 {
+		n := ledger.Notification{Source: source, From: points[0].Start.UTC(), To: points[len(points)-1].Start.Add(points[len(points)-1].Resolution).UTC(), RowsAffected: total}
+		if err = ledger.Notify(txn, n); err != nil {
+			return 0, err
+		}
+	}
+	return total, txn.Commit()
+}
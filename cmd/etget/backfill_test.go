@@ -0,0 +1,58 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackfillStateResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backfill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "state.json")
+	state, err := loadBackfillState(name)
+	if err != nil {
+		t.Fatalf("loadBackfillState of missing file: %s", err)
+	}
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, helsinki)
+	if got, _ := state.resume("entsoe/FI", start); !got.Equal(start) {
+		t.Errorf("resume with empty state = %s, want %s", got, start)
+	}
+
+	state["entsoe/FI"] = "2023-03-31"
+	if err = state.save(name); err != nil {
+		t.Fatal(err)
+	}
+	if state, err = loadBackfillState(name); err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2023, 4, 1, 0, 0, 0, 0, helsinki)
+	if got, _ := state.resume("entsoe/FI", start); !got.Equal(want) {
+		t.Errorf("resume = %s, want %s", got, want)
+	}
+	later := time.Date(2023, 6, 1, 0, 0, 0, 0, helsinki)
+	if got, _ := state.resume("entsoe/FI", later); !got.Equal(later) {
+		t.Errorf("resume before start = %s, want %s", got, later)
+	}
+}
+
+func TestDayChunks(t *testing.T) {
+	start := time.Date(2023, 3, 1, 0, 0, 0, 0, helsinki)
+	end := time.Date(2023, 4, 5, 0, 0, 0, 0, helsinki)
+	chunks := dayChunks(start, end, 30)
+	if len(chunks) != 2 {
+		t.Fatalf("got %d chunks, want 2", len(chunks))
+	}
+	// The DST change on 2023-03-26 must not shift the day boundaries.
+	if want := time.Date(2023, 3, 31, 0, 0, 0, 0, helsinki); !chunks[0][1].Equal(want) || !chunks[1][0].Equal(want) {
+		t.Errorf("chunk boundary = %s, want %s", chunks[0][1], want)
+	}
+	if !chunks[1][1].Equal(end) {
+		t.Errorf("last chunk ends %s, want %s", chunks[1][1], end)
+	}
+}
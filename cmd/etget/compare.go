@@ -0,0 +1,112 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joneskoo/etget/internal/config"
+)
+
+func init() {
+	register("compare", "", "Compare spot contract cost to a fixed-price contract", func(fs *flag.FlagSet) func([]string) error {
+		connstring := fs.String("connstring", "sslmode=disable", connstringUsage)
+		configFile := fs.String("config", config.DefaultFile, "configuration file with the spot contract")
+		meter := fs.String("meter", "default", "metering point")
+		from := fs.String("from", "", "first day, YYYY-MM-DD (default first day of this month)")
+		to := fs.String("to", "", "day after the last day, YYYY-MM-DD (default tomorrow)")
+		fixed := fs.String("fixed", "", "fixed energy price including VAT, in EUR/kWh or with suffix c for cents/kWh, e.g. 7.5c")
+		fixedMonthly := fs.Float64("fixed-monthly", -1, "monthly fee of the fixed-price contract (default same as spot contract)")
+		return func(args []string) error {
+			if len(args) != 0 {
+				return errors.New("unexpected arguments")
+			}
+			price, err := parseKWhPrice(*fixed)
+			if err != nil {
+				return fmt.Errorf("-fixed: %s", err)
+			}
+			cfg, err := config.Load(*configFile)
+			if err != nil {
+				return err
+			}
+			start, end, err := parsePeriod(*from, *to, time.Now())
+			if err != nil {
+				return err
+			}
+			if *fixedMonthly < 0 {
+				*fixedMonthly = cfg.Contract.Tariff().Month(start, nil).Energy
+			}
+			db, err := sql.Open("postgres", *connstring)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			hours, err := queryHours(db, *meter, start, end)
+			if err != nil {
+				return err
+			}
+			var spotTotal, fixedTotal float64
+			fmt.Printf("%-7s %10s %10s %10s %10s\n", "month", "kWh", "spot EUR", "fixed EUR", "saved EUR")
+			for _, m := range compareMonths(cfg.Contract, hours, price, *fixedMonthly) {
+				fmt.Printf("%-7s %10.1f %10.2f %10.2f %10.2f\n", m.Month.Format("2006-01"), m.KWh, m.Spot, m.Fixed, m.Fixed-m.Spot)
+				spotTotal += m.Spot
+				fixedTotal += m.Fixed
+			}
+			fmt.Printf("%-7s %10s %10.2f %10.2f %10.2f\n", "total", "", spotTotal, fixedTotal, fixedTotal-spotTotal)
+			return nil
+		}
+	})
+}
+
+// parseKWhPrice parses a price in EUR/kWh, or in cents/kWh with suffix c.
+func parseKWhPrice(s string) (float64, error) {
+	if s == "" {
+		return 0, errors.New("price is required")
+	}
+	scale := 1.0
+	if strings.HasSuffix(s, "c") {
+		s = strings.TrimSuffix(s, "c")
+		scale = 0.01
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	return v * scale, nil
+}
+
+// monthComparison is the energy cost of one month on both contracts.
+// Transfer fees are the same for both and left out.
+type monthComparison struct {
+	Month time.Time
+	KWh   float64
+	Spot  float64
+	Fixed float64
+}
+
+// compareMonths prices hours on the spot contract c and on a fixed price
+// per kWh, grouped by calendar month.
+func compareMonths(c config.Contract, hours []hour, fixedPerKWh, fixedMonthly float64) (months []monthComparison) {
+	t := c.Tariff()
+	for _, h := range hours {
+		local := h.Timestamp.In(helsinki)
+		month := time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, helsinki)
+		if len(months) == 0 || !months[len(months)-1].Month.Equal(month) {
+			months = append(months, monthComparison{
+				Month: month,
+				Spot:  t.Month(month, nil).Energy,
+				Fixed: fixedMonthly,
+			})
+		}
+		m := &months[len(months)-1]
+		m.KWh += h.KWh
+		m.Spot += hourCost(c, h).Energy
+		m.Fixed += h.KWh * fixedPerKWh
+	}
+	return months
+}
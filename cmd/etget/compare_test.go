@@ -0,0 +1,51 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/joneskoo/etget/internal/config"
+)
+
+func TestParseKWhPrice(t *testing.T) {
+	cases := []struct {
+		in   string
+		want float64
+	}{
+		{"7.5c", 0.075},
+		{"0.075", 0.075},
+		{"10c", 0.1},
+	}
+	for _, c := range cases {
+		got, err := parseKWhPrice(c.in)
+		if err != nil || math.Abs(got-c.want) > 1e-12 {
+			t.Errorf("parseKWhPrice(%q) = %v, %v, want %v", c.in, got, err, c.want)
+		}
+	}
+	for _, in := range []string{"", "c", "cheap"} {
+		if _, err := parseKWhPrice(in); err == nil {
+			t.Errorf("parseKWhPrice(%q) did not return error", in)
+		}
+	}
+}
+
+func TestCompareMonths(t *testing.T) {
+	contract := config.Contract{MonthlyFee: 3, VAT: 0}
+	jan := time.Date(2016, 1, 31, 23, 0, 0, 0, helsinki)
+	hours := []hour{
+		{Timestamp: jan, KWh: 2, Spot: 100},
+		{Timestamp: jan.Add(time.Hour), KWh: 1, Spot: 20},
+	}
+	got := compareMonths(contract, hours, 0.05, 2)
+	if len(got) != 2 {
+		t.Fatalf("compareMonths returned %d months, want 2: %+v", len(got), got)
+	}
+	// January: 3 + 2*0.1 spot, 2 + 2*0.05 fixed
+	if math.Abs(got[0].Spot-3.2) > 1e-9 || math.Abs(got[0].Fixed-2.1) > 1e-9 {
+		t.Errorf("January = %+v, want spot 3.2, fixed 2.1", got[0])
+	}
+	if got[1].Month.Month() != time.February || math.Abs(got[1].Spot-3.02) > 1e-9 {
+		t.Errorf("February = %+v, want spot 3.02", got[1])
+	}
+}
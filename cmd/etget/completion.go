@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/joneskoo/etget/internal/config"
+	"github.com/joneskoo/etget/internal/preset"
+	"github.com/joneskoo/etget/internal/zoneinfo"
+)
+
+func init() {
+	register("completion", "bash|zsh|fish", "Print the shell completion script", func(fs *flag.FlagSet) func([]string) error {
+		return func(args []string) error {
+			if len(args) != 1 {
+				return errors.New("want bash, zsh or fish")
+			}
+			script, ok := completionScripts[args[0]]
+			if !ok {
+				return fmt.Errorf("unknown shell %q, want bash, zsh or fish", args[0])
+			}
+			fmt.Print(script)
+			return nil
+		}
+	})
+	register("man", "", "Write man pages of etget and its commands", func(fs *flag.FlagSet) func([]string) error {
+		dir := fs.String("dir", ".", "`directory` to write etget.1 and etget-COMMAND.1 into")
+		return func(args []string) error {
+			if len(args) != 0 {
+				return errors.New("unexpected arguments")
+			}
+			pages := manPages()
+			names := make([]string, 0, len(pages))
+			for name := range pages {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				if err := ioutil.WriteFile(filepath.Join(*dir, name), pages[name], 0644); err != nil {
+					return err
+				}
+			}
+			fmt.Printf("OK! %d man pages written to %s\n", len(pages), *dir)
+			return nil
+		}
+	})
+}
+
+// completeCommand is the hidden command the completion scripts run with
+// the words of the command line after etget, the last being the word
+// completed.
+const completeCommand = "__complete"
+
+// The completion scripts ask etget for the candidates, so that they follow
+// the commands and flags of the binary installed. Where etget has none,
+// the shell completes file names.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for etget; source it or install it in
+# /etc/bash_completion.d/etget
+_etget() {
+	local IFS=$'\n'
+	COMPREPLY=($(etget ` + completeCommand + ` "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _etget etget
+`,
+	"zsh": `#compdef etget
+# zsh completion for etget; install it as _etget in a directory of $fpath
+_etget() {
+	local -a candidates
+	candidates=("${(@f)$(etget ` + completeCommand + ` "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+	if [[ -n ${candidates[1]} ]]; then
+		compadd -a candidates
+	else
+		_files
+	fi
+}
+compdef _etget etget
+`,
+	"fish": `# fish completion for etget; install it as
+# ~/.config/fish/completions/etget.fish
+function __etget_complete
+	set -l words (commandline -opc) (commandline -ct)
+	etget ` + completeCommand + ` $words[2..-1] 2>/dev/null
+end
+complete -c etget -a '(__etget_complete)'
+`,
+}
+
+// flagValues complete the values of flags by name, in any command. cfg is
+// the configuration file of the command line, or an empty one.
+var flagValues = map[string]func(cfg *config.Config) []string{
+	"area":    configuredAreas,
+	"areas":   configuredAreas,
+	"preset":  func(*config.Config) []string { return preset.Names() },
+	"profile": func(cfg *config.Config) []string { return cfg.ProfileNames() },
+	"zone":    func(*config.Config) []string { return zoneinfo.Zones() },
+	"dialect": func(*config.Config) []string {
+		var names []string
+		for name := range dialectTypes {
+			names = append(names, name)
+		}
+		return names
+	},
+	"table": func(*config.Config) []string {
+		names := make([]string, len(tables))
+		for i, t := range tables {
+			names[i] = t.Name
+		}
+		return names
+	},
+}
+
+// configuredAreas returns the area codes of the preset of cfg, or of all
+// presets, with the names the elspot section renames areas to. complete
+// sorts the candidates.
+func configuredAreas(cfg *config.Config) []string {
+	names := preset.Names()
+	if cfg.Preset != "" {
+		names = []string{cfg.Preset}
+	}
+	seen := map[string]bool{"SYS": true}
+	for _, name := range names {
+		if p, err := preset.Lookup(name); err == nil {
+			for _, area := range p.Areas {
+				seen[area] = true
+			}
+		}
+	}
+	for _, to := range cfg.Elspot.Columns {
+		seen[to] = true
+	}
+	areas := make([]string, 0, len(seen))
+	for area := range seen {
+		areas = append(areas, area)
+	}
+	return areas
+}
+
+// complete writes the candidates for the last of words, the command line
+// after etget, one per line.
+func complete(w io.Writer, words []string) {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	cur := words[len(words)-1]
+	var candidates []string
+	if len(words) == 1 {
+		for name := range commands {
+			candidates = append(candidates, name)
+		}
+		candidates = append(candidates, "help")
+	} else if c, ok := commands[words[0]]; ok {
+		candidates = completeArgs(c, words[1:len(words)-1], cur)
+	}
+	sort.Strings(candidates)
+	for _, s := range candidates {
+		if strings.HasPrefix(s, cur) {
+			fmt.Fprintln(w, s)
+		}
+	}
+}
+
+// completeArgs returns the candidates for cur after the arguments before
+// it of command c.
+func completeArgs(c *command, before []string, cur string) []string {
+	if strings.HasPrefix(cur, "-") {
+		var names []string
+		c.Flags.VisitAll(func(f *flag.Flag) { names = append(names, "-"+f.Name) })
+		return names
+	}
+	configFile := config.DefaultFile
+	positional := 0
+	for i := 0; i < len(before); i++ {
+		name := strings.TrimLeft(before[i], "-")
+		if name == before[i] || name == "" {
+			positional++
+			continue
+		}
+		if strings.Contains(name, "=") {
+			continue
+		}
+		if f := c.Flags.Lookup(name); f != nil && !isBoolFlag(f) {
+			if i == len(before)-1 {
+				return completeValue(name, configFile)
+			}
+			if name == "config" {
+				configFile = before[i+1]
+			}
+			i++
+		}
+	}
+	// Alternatives such as create|refresh complete the first argument.
+	if first := strings.Fields(c.Args); positional == 0 && len(first) > 0 && strings.Contains(first[0], "|") {
+		return strings.Split(strings.Trim(first[0], "[]"), "|")
+	}
+	return nil
+}
+
+func completeValue(name, configFile string) []string {
+	values, ok := flagValues[name]
+	if !ok {
+		return nil
+	}
+	cfg, err := config.LoadProfile(configFile, "")
+	if err != nil {
+		cfg = &config.Config{}
+	}
+	return values(cfg)
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// manPages returns the man pages by file name: etget.1 listing the
+// commands and etget-COMMAND.1 with the flags of each.
+func manPages() map[string][]byte {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pages := make(map[string][]byte, len(names)+1)
+	var b bytes.Buffer
+	b.WriteString(".TH ETGET 1 \"\" etget\n.SH NAME\netget \\- query and maintain the imported energy data\n")
+	b.WriteString(".SH SYNOPSIS\n.B etget\n.I COMMAND\n[flags] [args]\n.SH COMMANDS\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, ".TP\n.B %s\n%s.\n", manEscape(name), manEscape(commands[name].Short))
+	}
+	b.WriteString(".SH SEE ALSO\n")
+	for i, name := range names {
+		sep := ",\n"
+		if i == len(names)-1 {
+			sep = "\n"
+		}
+		fmt.Fprintf(&b, ".BR etget\\-%s (1)%s", manEscape(name), sep)
+	}
+	pages["etget.1"] = b.Bytes()
+
+	for _, name := range names {
+		pages["etget-"+name+".1"] = commandManPage(commands[name])
+	}
+	return pages
+}
+
+func commandManPage(c *command) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, ".TH ETGET\\-%s 1 \"\" etget\n", manEscape(strings.ToUpper(c.Name)))
+	fmt.Fprintf(&b, ".SH NAME\netget\\-%s \\- %s\n", manEscape(c.Name), manEscape(c.Short))
+	fmt.Fprintf(&b, ".SH SYNOPSIS\n.B etget %s\n[flags]", manEscape(c.Name))
+	if c.Args != "" {
+		fmt.Fprintf(&b, " %s", manEscape(c.Args))
+	}
+	b.WriteString("\n")
+	first := true
+	c.Flags.VisitAll(func(f *flag.Flag) {
+		if first {
+			b.WriteString(".SH OPTIONS\n")
+			first = false
+		}
+		arg, usage := flag.UnquoteUsage(f)
+		fmt.Fprintf(&b, ".TP\n.B \\-%s", manEscape(f.Name))
+		if arg != "" {
+			fmt.Fprintf(&b, " \\fI%s\\fR", manEscape(arg))
+		}
+		b.WriteString("\n" + manEscape(usage))
+		if f.DefValue != "" && !(isBoolFlag(f) && f.DefValue == "false") {
+			fmt.Fprintf(&b, " (default %s)", manEscape(f.DefValue))
+		}
+		b.WriteString("\n")
+	})
+	b.WriteString(".SH SEE ALSO\n.BR etget (1)\n")
+	return b.Bytes()
+}
+
+// manEscape escapes s for troff text.
+func manEscape(s string) string {
+	s = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(s)
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		if strings.HasPrefix(l, ".") || strings.HasPrefix(l, "'") {
+			lines[i] = `\&` + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
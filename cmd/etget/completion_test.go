@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/joneskoo/etget/internal/config"
+)
+
+func TestComplete(t *testing.T) {
+	cases := []struct {
+		words []string
+		want  string
+	}{
+		{[]string{"vi"}, "views"},
+		{[]string{"views", ""}, "create refresh"},
+		{[]string{"views", "create", ""}, ""},
+		{[]string{"views", "-conc"}, "-concurrently"},
+		{[]string{"views", "-concurrently", ""}, "create refresh"},
+		{[]string{"completion", "z"}, "zsh"},
+		{[]string{"import", "-table", "elspot_a"}, "elspot_area"},
+		{[]string{"fix-history", "-zone", "Europe/H"}, "Europe/Helsinki"},
+		{[]string{"cost", "-config", ""}, ""},
+		{[]string{"nope", ""}, ""},
+	}
+	for _, c := range cases {
+		var b bytes.Buffer
+		complete(&b, c.words)
+		if got := strings.Join(strings.Fields(b.String()), " "); got != c.want {
+			t.Errorf("complete(%q) = %q, want %q", c.words, got, c.want)
+		}
+	}
+}
+
+func TestConfiguredAreas(t *testing.T) {
+	cfg := &config.Config{Preset: "se"}
+	cfg.Elspot.Columns = map[string]string{"FI": "finland"}
+	got := configuredAreas(cfg)
+	sort.Strings(got)
+	if want := "SE1 SE2 SE3 SE4 SYS finland"; strings.Join(got, " ") != want {
+		t.Errorf("configuredAreas = %v, want %s", got, want)
+	}
+}
+
+func TestManPages(t *testing.T) {
+	pages := manPages()
+	if len(pages) != len(commands)+1 {
+		t.Errorf("manPages returned %d pages, want %d", len(pages), len(commands)+1)
+	}
+	page := string(pages["etget-views.1"])
+	for _, want := range []string{".TH ETGET\\-VIEWS 1", ".B \\-connstring \\fIstring\\fR", ".B \\-concurrently\n"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("etget-views.1 does not contain %q:\n%s", want, page)
+		}
+	}
+	if !strings.Contains(string(pages["etget.1"]), ".BR etget\\-views (1)") {
+		t.Errorf("etget.1 does not refer to etget-views(1)")
+	}
+}
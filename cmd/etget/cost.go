@@ -0,0 +1,176 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/joneskoo/etget/internal/config"
+	"github.com/joneskoo/etget/internal/zoneinfo"
+	"github.com/joneskoo/etget/tariff"
+)
+
+func init() {
+	register("cost", "", "Print the billed electricity cost of each hour", func(fs *flag.FlagSet) func([]string) error {
+		connstring := fs.String("connstring", "sslmode=disable", connstringUsage)
+		configFile := fs.String("config", config.DefaultFile, "configuration file with the contract")
+		meter := fs.String("meter", "default", "metering point")
+		from := fs.String("from", "", "first day, YYYY-MM-DD (default first day of this month)")
+		to := fs.String("to", "", "day after the last day, YYYY-MM-DD (default tomorrow)")
+		return func(args []string) error {
+			if len(args) != 0 {
+				return errors.New("unexpected arguments")
+			}
+			cfg, err := config.Load(*configFile)
+			if err != nil {
+				return err
+			}
+			start, end, err := parsePeriod(*from, *to, time.Now())
+			if err != nil {
+				return err
+			}
+			db, err := sql.Open("postgres", *connstring)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			hours, err := queryHours(db, *meter, start, end)
+			if err != nil {
+				return err
+			}
+			var energy, transfer, sold float64
+			for _, h := range hours {
+				c := hourCost(cfg.Contract, h)
+				energy += c.Energy
+				transfer += c.Transfer
+				sold += c.Sold
+				fmt.Printf("%s %8.3f kWh %8.3f kWh sold %8.2f EUR/MWh %8.4f EUR\n", h.Timestamp.In(helsinki).Format("2006-01-02 15:04"), h.KWh, h.SoldKWh, h.Spot, c.Net())
+			}
+			f := periodFees(cfg.Contract, start, end, hours)
+			fees := f.Energy + f.Transfer
+			fmt.Printf("energy %.2f EUR + transfer %.2f EUR + monthly fees %.2f EUR - sold %.2f EUR = %.2f EUR\n",
+				energy, transfer, fees, sold, energy+transfer+fees-sold)
+			return nil
+		}
+	})
+}
+
+var helsinki *time.Location
+
+func init() {
+	var err error
+	helsinki, err = zoneinfo.Load("Europe/Helsinki")
+	if err != nil {
+		panic(err)
+	}
+}
+
+// parsePeriod parses the -from and -to flags as days in Finnish time.
+func parsePeriod(from, to string, now time.Time) (start, end time.Time, err error) {
+	now = now.In(helsinki)
+	start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, helsinki)
+	end = time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, helsinki)
+	if from != "" {
+		if start, err = time.ParseInLocation("2006-01-02", from, helsinki); err != nil {
+			return start, end, fmt.Errorf("-from: %s", err)
+		}
+	}
+	if to != "" {
+		if end, err = time.ParseInLocation("2006-01-02", to, helsinki); err != nil {
+			return start, end, fmt.Errorf("-to: %s", err)
+		}
+	}
+	return start, end, nil
+}
+
+// countMonths returns the number of calendar months [start, end) touches.
+func countMonths(start, end time.Time) int {
+	if !start.Before(end) {
+		return 0
+	}
+	last := end.Add(-time.Nanosecond).In(helsinki)
+	start = start.In(helsinki)
+	return (last.Year()-start.Year())*12 + int(last.Month()-start.Month()) + 1
+}
+
+// hour is the consumption, sold production and spot price of one hour.
+type hour struct {
+	Timestamp time.Time
+	KWh       float64
+	SoldKWh   float64
+	Spot      float64 // EUR/MWh without VAT
+}
+
+// queryHours returns the metered hours of meter in [start, end) with their
+// spot prices. Hours without a price are a missingPricesError rather than
+// left out or priced at zero, which would understate the cost.
+func queryHours(db *sql.DB, meter string, start, end time.Time) (hours []hour, err error) {
+	rows, err := db.Query(`SELECT e.ts, COALESCE(e.kwh, 0), COALESCE(e.produced_kwh, 0), p.fi FROM energiatili e LEFT JOIN elspot p USING (ts)
+    WHERE e.meter_id = $1 AND e.ts >= $2 AND e.ts < $3 ORDER BY e.ts`, meter, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var missing missingPricesError
+	for rows.Next() {
+		var h hour
+		var spot sql.NullFloat64
+		if err = rows.Scan(&h.Timestamp, &h.KWh, &h.SoldKWh, &spot); err != nil {
+			return nil, err
+		}
+		if !spot.Valid {
+			missing = append(missing, h.Timestamp)
+			continue
+		}
+		h.Spot = spot.Float64
+		hours = append(hours, h)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		return nil, missing
+	}
+	return hours, nil
+}
+
+// missingPricesError lists the metered hours without a spot price.
+type missingPricesError []time.Time
+
+func (e missingPricesError) Error() string {
+	first, last := e[0].In(helsinki), e[len(e)-1].In(helsinki)
+	return fmt.Sprintf("no spot price for %d metered hours between %s and %s; import them with import-elspot",
+		len(e), first.Format("2006-01-02 15:04"), last.Format("2006-01-02 15:04"))
+}
+
+// hourCost computes the billed energy and transfer cost of h and the
+// credit for its sold energy.
+func hourCost(c config.Contract, h hour) tariff.Cost {
+	return c.Tariff().Hour(h.usage())
+}
+
+func (h hour) usage() tariff.Usage {
+	return tariff.Usage{Time: h.Timestamp.In(helsinki), KWh: h.KWh, SoldKWh: h.SoldKWh, Spot: h.Spot}
+}
+
+// periodFees returns the monthly fees of the calendar months [start, end)
+// touches, the power-based ones by the peaks of hours.
+func periodFees(c config.Contract, start, end time.Time, hours []hour) tariff.Cost {
+	t := c.Tariff()
+	byMonth := make(map[time.Time][]tariff.Usage)
+	for _, h := range hours {
+		u := h.usage()
+		m := time.Date(u.Time.Year(), u.Time.Month(), 1, 0, 0, 0, 0, helsinki)
+		byMonth[m] = append(byMonth[m], u)
+	}
+	var fees tariff.Cost
+	first := start.In(helsinki)
+	for i := 0; i < countMonths(start, end); i++ {
+		m := time.Date(first.Year(), first.Month()+time.Month(i), 1, 0, 0, 0, 0, helsinki)
+		fees = fees.Add(t.Month(m, byMonth[m]))
+	}
+	return fees
+}
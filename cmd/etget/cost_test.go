@@ -0,0 +1,106 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/joneskoo/etget/internal/config"
+	"github.com/joneskoo/etget/tariff"
+)
+
+func TestHourCost(t *testing.T) {
+	contract := config.Contract{
+		MarginPerKWh: 0.002,
+		VAT:          0.24,
+		Transfer: config.Transfer{
+			DayPerKWh:   0.05,
+			NightPerKWh: 0.03,
+			NightStart:  22,
+			NightEnd:    7,
+		},
+	}
+	cases := []struct {
+		local        string
+		energy, tran float64
+	}{
+		// 2 kWh at 50 EUR/MWh: 2 * (0.05*1.24 + 0.002)
+		{"2016-01-10 12:00", 0.128, 0.10},
+		{"2016-01-10 22:00", 0.128, 0.06},
+		{"2016-01-10 06:00", 0.128, 0.06},
+		{"2016-01-10 07:00", 0.128, 0.10},
+	}
+	for _, c := range cases {
+		ts, err := time.ParseInLocation("2006-01-02 15:04", c.local, helsinki)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := hourCost(contract, hour{Timestamp: ts, KWh: 2, Spot: 50})
+		if math.Abs(got.Energy-c.energy) > 1e-9 || math.Abs(got.Transfer-c.tran) > 1e-9 {
+			t.Errorf("hourCost(%s) = %+v, want energy %v transfer %v", c.local, got, c.energy, c.tran)
+		}
+	}
+}
+
+func TestHourCostSold(t *testing.T) {
+	contract := config.Contract{VAT: 0.24, SaleMarginPerKWh: 0.003}
+	ts := time.Date(2019, 6, 1, 12, 0, 0, 0, helsinki)
+	// 4 kWh sold at 50 EUR/MWh: 4 * (0.05 - 0.003), without VAT
+	got := hourCost(contract, hour{Timestamp: ts, KWh: 1, SoldKWh: 4, Spot: 50})
+	if math.Abs(got.Sold-0.188) > 1e-9 || math.Abs(got.Net()-(0.062-0.188)) > 1e-9 {
+		t.Errorf("hourCost = %+v, net %v, want sold 0.188 net -0.126", got, got.Net())
+	}
+}
+
+func TestMissingPricesError(t *testing.T) {
+	ts := time.Date(2025, 1, 10, 14, 0, 0, 0, helsinki)
+	err := missingPricesError{ts, ts.Add(time.Hour), ts.Add(5 * time.Hour)}
+	want := "no spot price for 3 metered hours between 2025-01-10 14:00 and 2025-01-10 19:00; import them with import-elspot"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestCountMonths(t *testing.T) {
+	day := func(s string) time.Time {
+		ts, err := time.ParseInLocation("2006-01-02", s, helsinki)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+	cases := []struct {
+		start, end string
+		want       int
+	}{
+		{"2016-01-01", "2016-02-01", 1},
+		{"2016-01-15", "2016-02-02", 2},
+		{"2015-12-01", "2016-03-01", 3},
+		{"2016-01-01", "2016-01-01", 0},
+	}
+	for _, c := range cases {
+		if got := countMonths(day(c.start), day(c.end)); got != c.want {
+			t.Errorf("countMonths(%s, %s) = %d, want %d", c.start, c.end, got, c.want)
+		}
+	}
+}
+
+func TestPeriodFees(t *testing.T) {
+	contract := config.Contract{
+		Rules: tariff.Tariff{
+			{Name: "basic", Item: tariff.Transfer, Unit: tariff.PerMonth, Rate: 5},
+			{Name: "power", Item: tariff.Transfer, Unit: tariff.PerKW, Rate: 2},
+		},
+	}
+	start := time.Date(2020, 1, 15, 0, 0, 0, 0, helsinki)
+	end := time.Date(2020, 3, 1, 0, 0, 0, 0, helsinki)
+	hours := []hour{
+		{Timestamp: time.Date(2020, 1, 20, 8, 0, 0, 0, helsinki), KWh: 3},
+		{Timestamp: time.Date(2020, 2, 1, 0, 0, 0, 0, helsinki), KWh: 1},
+		{Timestamp: time.Date(2020, 2, 3, 18, 0, 0, 0, helsinki), KWh: 4},
+	}
+	// Two basic fees and the peaks 3 kW and 4 kW.
+	if got := periodFees(contract, start, end, hours); math.Abs(got.Transfer-24) > 1e-9 || got.Energy != 0 {
+		t.Errorf("periodFees = %+v, want transfer 24", got)
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joneskoo/etget/internal/config"
+	"github.com/joneskoo/etget/internal/zoneinfo"
+	"github.com/joneskoo/etget/keyring"
+)
+
+func init() {
+	register("doctor", "", "Check time zones, database, tables, sources and credentials", func(fs *flag.FlagSet) func([]string) error {
+		connstring := fs.String("connstring", "sslmode=disable", connstringUsage)
+		configFile := fs.String("config", config.DefaultFile, "configuration file whose elspot sources are checked")
+		credfile := fs.String("credfile", "./credentials.json", "energiatili.fi credentials file of import-energiatili")
+		offline := fs.Bool("offline", false, "skip the checks of the data sources over the network")
+		timeout := fs.Duration("timeout", 10*time.Second, "time limit of each database and network check")
+		return func(args []string) error {
+			if len(args) != 0 {
+				return errors.New("unexpected arguments")
+			}
+			var ds []diagnosis
+			now := time.Now()
+			ds = append(ds, checkZones(now)...)
+
+			cfg, err := config.Load(*configFile)
+			if err != nil {
+				ds = append(ds, diagnosis{Check: "config", Err: err, Fix: "fix the JSON of " + *configFile + " or pass -config"})
+				cfg = &config.Config{}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+			defer cancel()
+			ds = append(ds, checkDatabase(ctx, *connstring)...)
+
+			ds = append(ds, checkCredentials(*credfile)...)
+			if !*offline {
+				var sources []source
+				for _, s := range cfg.Elspot.Sources {
+					if u, err := url.Parse(s); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+						sources = append(sources, source{Name: "elspot source", URL: s, WantOK: true})
+					}
+				}
+				sources = append(sources, services...)
+				client := &http.Client{Timeout: *timeout}
+				for _, s := range sources {
+					ds = append(ds, checkSource(client, s))
+				}
+			}
+			if failed := writeDiagnoses(os.Stdout, ds); failed > 0 {
+				return fmt.Errorf("%d of %d checks failed", failed, len(ds))
+			}
+			return nil
+		}
+	})
+}
+
+// diagnosis is the result of a doctor check.
+type diagnosis struct {
+	Check  string
+	Detail string
+
+	// Err is set if the check failed, and Fix then tells how to fix it.
+	Err error
+	Fix string
+}
+
+// writeDiagnoses prints a line per check, with the fix of failed ones,
+// and returns the number failed.
+func writeDiagnoses(w io.Writer, ds []diagnosis) (failed int) {
+	for _, d := range ds {
+		if d.Err == nil {
+			fmt.Fprintf(w, "OK   %-15s %s\n", d.Check, d.Detail)
+			continue
+		}
+		failed++
+		fmt.Fprintf(w, "FAIL %-15s %s\n", d.Check, d.Err)
+		if d.Fix != "" {
+			fmt.Fprintf(w, "     %-15s fix: %s\n", "", d.Fix)
+		}
+	}
+	return failed
+}
+
+// checkZones reports the zones the system database lacks, which are read
+// from the pinned copy, and fails for those in which it disagrees with
+// the copy around now.
+func checkZones(now time.Time) []diagnosis {
+	var missing []string
+	mismatches := zoneinfo.Verify(now.AddDate(-2, 0, 0), now.AddDate(2, 0, 0))
+	d := diagnosis{Check: "tzdata"}
+	for _, m := range mismatches {
+		if m.Err != nil {
+			missing = append(missing, m.Zone)
+			continue
+		}
+		if d.Err == nil {
+			d.Err = errors.New(m.String())
+			d.Fix = "update the system tzdata package, or run etget tzdata -verify for all differences"
+		}
+	}
+	switch {
+	case len(missing) == len(zoneinfo.Zones()):
+		d.Detail = "no system zone database, using pinned tzdata " + zoneinfo.Version
+	case len(missing) > 0:
+		d.Detail = fmt.Sprintf("system zone database, pinned tzdata %s for %s", zoneinfo.Version, strings.Join(missing, ", "))
+	default:
+		d.Detail = "system zone database agrees with pinned tzdata " + zoneinfo.Version
+	}
+	return []diagnosis{d}
+}
+
+// tableImporters are the commands that create and upgrade each table.
+var tableImporters = map[string]string{
+	"elspot":          "import-elspot",
+	"elspot_area":     "import-elspot -per-area",
+	"intraday":        "import-intraday",
+	"elspot_capacity": "import-exchange -kind capacity",
+	"elspot_flow":     "import-exchange -kind flow",
+	"timeseries":      "etget fingrid",
+	"energiatili":     "import-energiatili",
+}
+
+// checkDatabase checks the connection, the privilege to create tables and
+// the columns of the tables that exist.
+func checkDatabase(ctx context.Context, connstring string) []diagnosis {
+	db, err := sql.Open("postgres", connstring)
+	if err == nil {
+		err = db.PingContext(ctx)
+	}
+	if err != nil {
+		return []diagnosis{{Check: "database", Err: err, Fix: "check -connstring, or the connstring of the configuration file, and that the server accepts connections"}}
+	}
+	defer db.Close()
+	var user, schema string
+	var create bool
+	err = db.QueryRowContext(ctx, "SELECT current_user, current_schema(), has_schema_privilege(current_schema(), 'CREATE')").Scan(&user, &schema, &create)
+	if err != nil {
+		return []diagnosis{{Check: "database", Err: err}}
+	}
+	ds := []diagnosis{{Check: "database", Detail: fmt.Sprintf("connected as %s to schema %s", user, schema)}}
+	perm := diagnosis{Check: "permissions", Detail: "may create tables in " + schema}
+	if !create {
+		perm.Err = fmt.Errorf("%s may not create tables in schema %s", user, schema)
+		perm.Fix = fmt.Sprintf("GRANT CREATE ON SCHEMA %s TO %s, or give the importers -ddl-connstring of the table owner", schema, user)
+	}
+	ds = append(ds, perm)
+
+	for _, t := range tables {
+		ds = append(ds, checkTable(ctx, db, t))
+	}
+	return ds
+}
+
+// checkTable checks that t, if it exists, has the columns of this version
+// and may be written.
+func checkTable(ctx context.Context, db *sql.DB, t table) diagnosis {
+	d := diagnosis{Check: "table " + t.Name}
+	var exists, insert bool
+	err := db.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", t.Name).Scan(&exists)
+	if err == nil && exists {
+		err = db.QueryRowContext(ctx, "SELECT has_table_privilege($1, 'INSERT')", t.Name).Scan(&insert)
+	}
+	if err != nil {
+		d.Err = err
+		return d
+	}
+	if !exists {
+		d.Detail = "not created yet; " + tableImporters[t.Name] + " creates it"
+		return d
+	}
+	rows, err := db.QueryContext(ctx, "SELECT column_name FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1", t.Name)
+	if err != nil {
+		d.Err = err
+		return d
+	}
+	defer rows.Close()
+	have := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			d.Err = err
+			return d
+		}
+		have[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		d.Err = err
+		return d
+	}
+	if missing := missingColumns(t, have); len(missing) > 0 {
+		d.Err = fmt.Errorf("missing columns %s of this version", strings.Join(missing, ", "))
+		d.Fix = "run " + tableImporters[t.Name] + " once to upgrade the table"
+		return d
+	}
+	if !insert {
+		d.Err = errors.New("no INSERT privilege")
+		d.Fix = "GRANT INSERT ON " + t.Name + " to the importing role"
+		return d
+	}
+	d.Detail = "up to date"
+	return d
+}
+
+// missingColumns returns the columns of t not in have, sorted.
+func missingColumns(t table, have map[string]bool) []string {
+	var missing []string
+	for _, c := range t.Columns {
+		if !have[c.Name] {
+			missing = append(missing, c.Name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// checkCredentials checks the credentials the importers read: the
+// energiatili.fi file, if any, and the API keys in the environment.
+func checkCredentials(credfile string) []diagnosis {
+	d := diagnosis{Check: "energiatili.fi", Detail: "credentials in " + credfile}
+	err := (&keyring.CredentialStore{File: credfile}).Stored()
+	switch {
+	case os.IsNotExist(err):
+		d.Detail = "no " + credfile + "; import-energiatili asks for the credentials on the first run"
+	case err != nil:
+		d.Err = err
+		d.Fix = "remove " + credfile + " and run import-energiatili to enter the credentials again"
+	}
+	ds := []diagnosis{d}
+	for _, env := range []struct{ name, command string }{{entsoeTokenEnv, "etget backfill"}, {fingridKeyEnv, "etget fingrid"}} {
+		d := diagnosis{Check: env.name, Detail: "set"}
+		if os.Getenv(env.name) == "" {
+			d.Detail = "not set; needed only by " + env.command
+		}
+		ds = append(ds, d)
+	}
+	return ds
+}
+
+// source is a data source checked for reachability.
+type source struct {
+	Name string
+	URL  string
+
+	// WantOK fails the check unless the response is 200 OK; otherwise
+	// any response shows the network path works.
+	WantOK bool
+}
+
+// services are the APIs the commands download from.
+var services = []source{
+	{Name: "energiatili.fi", URL: "https://www.energiatili.fi/"},
+	{Name: "ENTSO-E", URL: "https://web-api.tp.entsoe.eu/api"},
+	{Name: "Fingrid", URL: "https://data.fingrid.fi/api"},
+}
+
+// checkSource checks that s answers a HEAD request. Without credentials
+// the APIs refuse it, which still shows that they are reachable.
+func checkSource(client *http.Client, s source) diagnosis {
+	d := diagnosis{Check: s.Name}
+	resp, err := client.Head(s.URL)
+	if err != nil {
+		d.Err = err
+		d.Fix = "check DNS, proxy (HTTPS_PROXY) and firewall rules for " + s.URL
+		return d
+	}
+	resp.Body.Close()
+	if s.WantOK && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusMethodNotAllowed {
+		d.Err = fmt.Errorf("%s: HTTP status %d", s.URL, resp.StatusCode)
+		d.Fix = "update the elspot sources of the configuration file"
+		return d
+	}
+	d.Detail = fmt.Sprintf("%s reachable (HTTP %d)", s.URL, resp.StatusCode)
+	return d
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteDiagnoses(t *testing.T) {
+	var buf bytes.Buffer
+	failed := writeDiagnoses(&buf, []diagnosis{
+		{Check: "database", Detail: "connected"},
+		{Check: "table elspot", Err: errors.New("missing columns status"), Fix: "run import-elspot"},
+	})
+	if failed != 1 {
+		t.Errorf("writeDiagnoses failed = %d, want 1", failed)
+	}
+	want := "OK   database        connected\nFAIL table elspot    missing columns status\n                     fix: run import-elspot\n"
+	if buf.String() != want {
+		t.Errorf("writeDiagnoses wrote\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestMissingColumns(t *testing.T) {
+	energiatili, _ := lookupTable("energiatili")
+	got := missingColumns(energiatili, map[string]bool{"id": true, "meter_id": true, "ts": true, "kwh": true})
+	if want := []string{"estimated", "produced_kwh", "temp"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("missingColumns = %v, want %v", got, want)
+	}
+	for _, tbl := range tables {
+		if tableImporters[tbl.Name] == "" {
+			t.Errorf("no importer for table %s", tbl.Name)
+		}
+	}
+}
+
+func TestCheckSource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/elspot.xls" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+	tests := []struct {
+		s  source
+		ok bool
+	}{
+		{source{Name: "api", URL: srv.URL + "/api"}, true},
+		{source{Name: "elspot source", URL: srv.URL + "/elspot.xls", WantOK: true}, true},
+		{source{Name: "elspot source", URL: srv.URL + "/gone.xls", WantOK: true}, false},
+		{source{Name: "down", URL: "http://127.0.0.1:1/"}, false},
+	}
+	for _, tt := range tests {
+		if d := checkSource(srv.Client(), tt.s); (d.Err == nil) != tt.ok {
+			t.Errorf("checkSource(%s) = %+v, want ok %v", tt.s.URL, d, tt.ok)
+		}
+	}
+}
+
+func TestCheckCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "doctor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "credentials.json")
+	if d := checkCredentials(name)[0]; d.Err != nil || !strings.Contains(d.Detail, "asks for the credentials") {
+		t.Errorf("missing file: %+v", d)
+	}
+	ioutil.WriteFile(name, []byte(`{"username": "u"}`), 0600)
+	if d := checkCredentials(name)[0]; d.Err == nil {
+		t.Errorf("no password: %+v, want error", d)
+	}
+	ioutil.WriteFile(name, []byte(`{"username": "u", "password": "p"}`), 0600)
+	if d := checkCredentials(name)[0]; d.Err != nil {
+		t.Errorf("stored credentials: %+v", d)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joneskoo/etget/fingrid"
+	"github.com/joneskoo/etget/internal/ledger"
+)
+
+// fingridKeyEnv is the environment variable with the Fingrid API key.
+const fingridKeyEnv = "FINGRID_API_KEY"
+
+// createTimeseriesSQL creates the table of external time series. series
+// is the dataset name, e.g. "consumption".
+const createTimeseriesSQL = `CREATE TABLE IF NOT EXISTS timeseries (
+    source  TEXT NOT NULL,
+    series  TEXT NOT NULL,
+    ts      TIMESTAMPTZ NOT NULL,
+    value   DOUBLE PRECISION,
+    PRIMARY KEY (source, series, ts)
+    );`
+
+func init() {
+	register("fingrid", "", "Load Finnish power system data from Fingrid into the timeseries table", func(fs *flag.FlagSet) func([]string) error {
+		connstring := fs.String("connstring", "sslmode=disable", connstringUsage)
+		series := fs.String("series", "consumption,production,wind", "comma-separated datasets: "+datasetNames()+" or numeric dataset IDs")
+		from := fs.String("from", "", "first day, YYYY-MM-DD (default first day of this month)")
+		to := fs.String("to", "", "day after the last day, YYYY-MM-DD (default tomorrow)")
+		return func(args []string) error {
+			if len(args) != 0 {
+				return errors.New("unexpected arguments")
+			}
+			key := os.Getenv(fingridKeyEnv)
+			if key == "" {
+				return fmt.Errorf("no API key; set %s", fingridKeyEnv)
+			}
+			datasets, err := parseDatasets(*series)
+			if err != nil {
+				return fmt.Errorf("-series: %s", err)
+			}
+			start, end, err := parsePeriod(*from, *to, time.Now())
+			if err != nil {
+				return err
+			}
+			db, err := sql.Open("postgres", *connstring)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			for _, stmt := range []string{createTimeseriesSQL, ledger.CreateTableSQL} {
+				if _, err = db.Exec(stmt); err != nil {
+					return err
+				}
+			}
+
+			c := &fingrid.Client{APIKey: key}
+			for _, d := range datasets {
+				points, err := c.Data(context.Background(), d.ID, start, end)
+				if err != nil {
+					return err
+				}
+				n, err := storeTimeseries(db, d.Name, points)
+				if err != nil {
+					return fmt.Errorf("%s: %s", d.Name, err)
+				}
+				fmt.Printf("%s: %d points, %d rows changed\n", d.Name, len(points), n)
+			}
+			return nil
+		}
+	})
+}
+
+// dataset is a Fingrid dataset and the series name it is stored under.
+type dataset struct {
+	Name string
+	ID   int
+}
+
+func datasetNames() string {
+	names := make([]string, 0, len(fingrid.Datasets))
+	for name := range fingrid.Datasets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// parseDatasets parses a comma-separated list of dataset names or IDs.
+func parseDatasets(s string) (datasets []dataset, err error) {
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if id, ok := fingrid.Datasets[name]; ok {
+			datasets = append(datasets, dataset{name, id})
+			continue
+		}
+		id, err := strconv.Atoi(name)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("unknown dataset %q", name)
+		}
+		datasets = append(datasets, dataset{name, id})
+	}
+	if len(datasets) == 0 {
+		return nil, errors.New("no datasets")
+	}
+	return datasets, nil
+}
+
+// storeTimeseries upserts the points of series in one transaction and
+// records the import in the ledger.
+func storeTimeseries(db *sql.DB, series string, points []fingrid.Point) (int64, error) {
+	txn, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer txn.Rollback()
+
+	stmt, err := txn.Prepare(`INSERT INTO timeseries (source, series, ts, value) VALUES ($1, $2, $3, $4)
+    ON CONFLICT (source, series, ts) DO UPDATE SET value = EXCLUDED.value
+    WHERE timeseries.value IS DISTINCT FROM EXCLUDED.value`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+	var total int64
+	for _, p := range points {
+		res, err := stmt.Exec(ledger.SourceFingrid, series, p.Start, p.Value)
+		if err != nil {
+			return 0, err
+		}
+		n, _ := res.RowsAffected()
+		total += n
+	}
+	if err = ledger.Record(txn, ledger.SourceFingrid, total); err != nil {
+		return 0, err
+	}
+	return total, txn.Commit()
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDatasets(t *testing.T) {
+	got, err := parseDatasets("consumption, wind,245")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []dataset{{"consumption", 124}, {"wind", 75}, {"245", 245}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseDatasets = %v, want %v", got, want)
+	}
+	for _, in := range []string{"", "solar", "-1"} {
+		if _, err := parseDatasets(in); err == nil {
+			t.Errorf("parseDatasets(%q) did not return error", in)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/joneskoo/etget/internal/zoneinfo"
+	"github.com/joneskoo/etget/notz"
+	"github.com/lib/pq"
+)
+
+func init() {
+	register("fix-history", "TABLE", "Repair DST-confused timestamps of already imported rows", func(fs *flag.FlagSet) func([]string) error {
+		connstring := fs.String("connstring", "sslmode=disable", connstringUsage)
+		from := fs.String("from", "", "first day to repair, YYYY-MM-DD")
+		to := fs.String("to", "", "day after the last day to repair, YYYY-MM-DD")
+		storedAs := fs.String("stored-as", "UTC", "time zone the old importer wrongly gave the local times")
+		zone := fs.String("zone", "Europe/Helsinki", "time zone the source data is actually in")
+		apply := fs.Bool("apply", false, "rewrite the rows; by default only the changes are printed")
+		return func(args []string) error {
+			if len(args) != 1 {
+				return errors.New("want exactly one TABLE argument")
+			}
+			t, ok := lookupTable(args[0])
+			if !ok || !t.HasID {
+				return fmt.Errorf("cannot repair table %q", args[0])
+			}
+			storedLoc, err := zoneinfo.Load(*storedAs)
+			if err != nil {
+				return err
+			}
+			loc, err := zoneinfo.Load(*zone)
+			if err != nil {
+				return err
+			}
+			start, err := time.ParseInLocation("2006-01-02", *from, loc)
+			if err != nil {
+				return fmt.Errorf("-from: %s", err)
+			}
+			end, err := time.ParseInLocation("2006-01-02", *to, loc)
+			if err != nil {
+				return fmt.Errorf("-to: %s", err)
+			}
+
+			db, err := sql.Open("postgres", *connstring)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			rows, err := queryHistory(db, t, start, end)
+			if err != nil {
+				return err
+			}
+			changes, err := repairHistory(rows, storedLoc, loc)
+			if err != nil {
+				return err
+			}
+			for _, c := range changes {
+				fmt.Printf("%s id=%d %s: %s -> %s\n", t.Name, c.ID, c.Series, c.Old.Format(time.RFC3339), c.New.Format(time.RFC3339))
+			}
+			fmt.Printf("%d of %d rows to change\n", len(changes), len(rows))
+			if !*apply || len(changes) == 0 {
+				return nil
+			}
+			if err = applyHistory(db, t, changes); err != nil {
+				return err
+			}
+			fmt.Printf("OK! %d rows rewritten\n", len(changes))
+			return nil
+		}
+	})
+}
+
+// historyRow is the identity and timestamp of an imported row.
+type historyRow struct {
+	ID     int64
+	Series string
+	TS     time.Time
+}
+
+// historyChange is a timestamp correction of a row.
+type historyChange struct {
+	historyRow
+	Old, New time.Time
+}
+
+func queryHistory(db *sql.DB, t table, start, end time.Time) (rows []historyRow, err error) {
+	series := "''"
+	if t.Series != "" {
+		series = pq.QuoteIdentifier(t.Series)
+	}
+	res, err := db.Query(fmt.Sprintf("SELECT id, %s, ts FROM %s WHERE ts >= $1 AND ts < $2 ORDER BY 2, ts",
+		series, pq.QuoteIdentifier(t.Name)), start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	for res.Next() {
+		var r historyRow
+		if err = res.Scan(&r.ID, &r.Series, &r.TS); err != nil {
+			return nil, err
+		}
+		rows = append(rows, r)
+	}
+	return rows, res.Err()
+}
+
+// repairHistory reinterprets the wall clock time of each row in storedAs as
+// a wall clock time in loc, repairs the DST transitions of each series with
+// notz and returns the rows whose timestamp changes. rows must be ordered by
+// series and time.
+func repairHistory(rows []historyRow, storedAs, loc *time.Location) (changes []historyChange, err error) {
+	for start := 0; start < len(rows); {
+		end := start
+		for end < len(rows) && rows[end].Series == rows[start].Series {
+			end++
+		}
+		times := make(notz.Times, end-start)
+		for i, r := range rows[start:end] {
+			wall := r.TS.In(storedAs)
+			times[i] = time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, loc)
+		}
+		if err = notz.CheckedFixDST(times); err != nil {
+			return nil, fmt.Errorf("series %q: %s", rows[start].Series, err)
+		}
+		for i, r := range rows[start:end] {
+			if !times[i].Equal(r.TS) {
+				changes = append(changes, historyChange{historyRow: r, Old: r.TS, New: times[i]})
+			}
+		}
+		start = end
+	}
+	return changes, nil
+}
+
+// applyHistory rewrites the timestamps in one transaction. The rows are
+// deleted and reinserted because shifting them in place would violate the
+// unique timestamp constraint halfway through the update.
+func applyHistory(db *sql.DB, t table, changes []historyChange) error {
+	txn, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback()
+
+	if _, err = txn.Exec("CREATE TEMP TABLE _fix_history (id INTEGER, ts TIMESTAMPTZ) ON COMMIT DROP"); err != nil {
+		return err
+	}
+	stmt, err := txn.Prepare(pq.CopyInSchema("pg_temp", "_fix_history", "id", "ts"))
+	if err != nil {
+		return err
+	}
+	for _, c := range changes {
+		if _, err = stmt.Exec(c.ID, c.New); err != nil {
+			return err
+		}
+	}
+	if _, err = stmt.Exec(); err != nil {
+		return err
+	}
+	if err = stmt.Close(); err != nil {
+		return err
+	}
+
+	name := pq.QuoteIdentifier(t.Name)
+	for _, q := range []string{
+		"CREATE TEMP TABLE _fix_history_rows ON COMMIT DROP AS SELECT t.* FROM %s t JOIN pg_temp._fix_history USING (id)",
+		"UPDATE pg_temp._fix_history_rows r SET ts = f.ts FROM pg_temp._fix_history f WHERE r.id = f.id",
+		"DELETE FROM %s t USING pg_temp._fix_history f WHERE t.id = f.id",
+		"INSERT INTO %s SELECT * FROM pg_temp._fix_history_rows",
+	} {
+		if strings.Contains(q, "%s") {
+			q = fmt.Sprintf(q, name)
+		}
+		if _, err = txn.Exec(q); err != nil {
+			return err
+		}
+	}
+	return txn.Commit()
+}
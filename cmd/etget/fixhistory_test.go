@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRepairHistory(t *testing.T) {
+	helsinki, err := time.LoadLocation("Europe/Helsinki")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A legacy import stored Helsinki wall clock times as if they were UTC,
+	// around the 2015 autumn transition where 03:00 occurs twice.
+	rows := []historyRow{
+		{ID: 1, TS: time.Date(2015, 10, 25, 2, 0, 0, 0, time.UTC)},
+		{ID: 2, TS: time.Date(2015, 10, 25, 3, 0, 0, 0, time.UTC)},
+		{ID: 3, TS: time.Date(2015, 10, 25, 3, 0, 0, 0, time.UTC)},
+		{ID: 4, TS: time.Date(2015, 10, 25, 4, 0, 0, 0, time.UTC)},
+		{ID: 5, Series: "cottage", TS: time.Date(2015, 10, 25, 3, 0, 0, 0, time.UTC)},
+	}
+	changes, err := repairHistory(rows, time.UTC, helsinki)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int64]string{
+		1: "2015-10-24T23:00:00Z",
+		2: "2015-10-25T00:00:00Z",
+		3: "2015-10-25T01:00:00Z",
+		4: "2015-10-25T02:00:00Z",
+		5: "2015-10-25T01:00:00Z",
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("repairHistory returned %d changes, want %d: %+v", len(changes), len(want), changes)
+	}
+	for _, c := range changes {
+		if got := c.New.UTC().Format(time.RFC3339); got != want[c.ID] {
+			t.Errorf("id %d: new ts %s, want %s", c.ID, got, want[c.ID])
+		}
+	}
+}
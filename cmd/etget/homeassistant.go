@@ -0,0 +1,124 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"time"
+
+	"github.com/joneskoo/etget/internal/config"
+)
+
+func init() {
+	register("ha-export", "", "Export hourly consumption and cost as Home Assistant statistics", func(fs *flag.FlagSet) func([]string) error {
+		connstring := fs.String("connstring", "sslmode=disable", connstringUsage)
+		configFile := fs.String("config", config.DefaultFile, "configuration file with the contract")
+		meter := fs.String("meter", "default", "metering point")
+		from := fs.String("from", "", "first day, YYYY-MM-DD (default first day of this month)")
+		to := fs.String("to", "", "day after the last day, YYYY-MM-DD (default tomorrow)")
+		output := fs.String("o", "-", "output file, - for standard output")
+		return func(args []string) error {
+			if len(args) != 0 {
+				return errors.New("unexpected arguments")
+			}
+			cfg, err := config.Load(*configFile)
+			if err != nil {
+				return err
+			}
+			start, end, err := parsePeriod(*from, *to, time.Now())
+			if err != nil {
+				return err
+			}
+			db, err := sql.Open("postgres", *connstring)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			hours, err := queryHours(db, *meter, start, end)
+			if err != nil {
+				return err
+			}
+			w := os.Stdout
+			if *output != "-" {
+				if w, err = os.Create(*output); err != nil {
+					return err
+				}
+			}
+			if err = writeHAStatistics(w, cfg.Contract, *meter, hours); err != nil {
+				return err
+			}
+			return w.Close()
+		}
+	})
+}
+
+// haImport is a recorder/import_statistics message of the Home Assistant
+// WebSocket API, without the message id that the client adds.
+type haImport struct {
+	Type     string      `json:"type"`
+	Metadata haMetadata  `json:"metadata"`
+	Stats    []haStatRow `json:"stats"`
+}
+
+type haMetadata struct {
+	StatisticID string `json:"statistic_id"`
+	Source      string `json:"source"`
+	Name        string `json:"name"`
+	Unit        string `json:"unit_of_measurement"`
+	HasMean     bool   `json:"has_mean"`
+	HasSum      bool   `json:"has_sum"`
+}
+
+// haStatRow is an hour of a cumulative statistic: State is the value of
+// the hour and Sum the running total, which the energy dashboard uses.
+type haStatRow struct {
+	Start time.Time `json:"start"`
+	State float64   `json:"state"`
+	Sum   float64   `json:"sum"`
+}
+
+// haSource is the source of the external statistics, the part before the
+// colon in their ids.
+const haSource = "etget"
+
+// writeHAStatistics writes the consumption and net cost of hours as a
+// JSON array of two import messages. Sums start from zero at the first
+// hour, so a backfill should cover all history or end where Home
+// Assistant's own statistics begin.
+func writeHAStatistics(w io.Writer, c config.Contract, meter string, hours []hour) error {
+	energy := haImport{
+		Type: "recorder/import_statistics",
+		Metadata: haMetadata{
+			StatisticID: haSource + ":energy_" + meter,
+			Source:      haSource,
+			Name:        "Electricity consumption " + meter,
+			Unit:        "kWh",
+			HasSum:      true,
+		},
+	}
+	costs := haImport{
+		Type: "recorder/import_statistics",
+		Metadata: haMetadata{
+			StatisticID: haSource + ":cost_" + meter,
+			Source:      haSource,
+			Name:        "Electricity cost " + meter,
+			Unit:        "EUR",
+			HasSum:      true,
+		},
+	}
+	var kwh, eur float64
+	for _, h := range hours {
+		net := hourCost(c, h).Net()
+		kwh += h.KWh
+		eur += net
+		ts := h.Timestamp.UTC()
+		energy.Stats = append(energy.Stats, haStatRow{ts, h.KWh, kwh})
+		costs.Stats = append(costs.Stats, haStatRow{ts, net, eur})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode([]haImport{energy, costs})
+}
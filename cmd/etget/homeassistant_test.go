@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/joneskoo/etget/internal/config"
+)
+
+func TestWriteHAStatistics(t *testing.T) {
+	start := time.Date(2025, 1, 10, 0, 0, 0, 0, helsinki)
+	hours := []hour{
+		{Timestamp: start, KWh: 1.5, Spot: 100},
+		{Timestamp: start.Add(time.Hour), KWh: 2, Spot: 50},
+	}
+	c := config.Contract{MarginPerKWh: 0.01}
+	var buf bytes.Buffer
+	if err := writeHAStatistics(&buf, c, "home", hours); err != nil {
+		t.Fatal(err)
+	}
+	var got []haImport
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not JSON: %s\n%s", err, buf.String())
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d imports, want 2", len(got))
+	}
+	if id := got[0].Metadata.StatisticID; id != "etget:energy_home" {
+		t.Errorf("statistic_id = %q, want etget:energy_home", id)
+	}
+	energy := got[0].Stats
+	if len(energy) != 2 || energy[1].State != 2 || energy[1].Sum != 3.5 || !energy[0].Start.Equal(start) {
+		t.Errorf("energy stats = %+v, want 1.5 and 2 kWh summing to 3.5", energy)
+	}
+	cost := got[1].Stats
+	// 1.5 kWh * (0.100 + 0.01) + 2 kWh * (0.050 + 0.01)
+	if len(cost) != 2 || math.Abs(cost[1].Sum-0.285) > 1e-9 {
+		t.Errorf("cost stats = %+v, want sum 0.285 EUR", cost)
+	}
+}
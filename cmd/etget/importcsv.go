@@ -0,0 +1,247 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/joneskoo/etget/internal/ledger"
+	"github.com/lib/pq"
+)
+
+func init() {
+	register("import", "csv FILE...", "Load normalized CSV files into a table with COPY", func(fs *flag.FlagSet) func([]string) error {
+		connstring := fs.String("connstring", "sslmode=disable", connstringUsage)
+		tableName := fs.String("table", "elspot", "target table")
+		mapping := fs.String("map", "", "table columns and their 1-based CSV field numbers, e.g. ts=1,fi=3")
+		header := fs.Bool("header", true, "skip the first line of each file")
+		comma := fs.String("comma", ",", "field separator")
+		decimalComma := fs.Bool("decimal-comma", false, "numbers use a decimal comma")
+		return func(args []string) error {
+			if len(args) < 2 || args[0] != "csv" {
+				return errors.New("want csv and at least one FILE")
+			}
+			t, ok := lookupTable(*tableName)
+			if !ok {
+				return fmt.Errorf("unknown table %q", *tableName)
+			}
+			cols, err := parseColumnMap(*mapping, t)
+			if err != nil {
+				return fmt.Errorf("-map: %s", err)
+			}
+			sep := []rune(*comma)
+			if len(sep) != 1 {
+				return errors.New("-comma must be a single character")
+			}
+			db, err := sql.Open("postgres", *connstring)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			opts := csvOptions{Header: *header, Comma: sep[0], DecimalComma: *decimalComma}
+			n, err := copyCSV(db, t, cols, opts, args[1:])
+			if err != nil {
+				return err
+			}
+			fmt.Printf("OK! %s: %d rows affected\n", t.Name, n)
+			return nil
+		}
+	})
+}
+
+// csvColumn maps a table column to a CSV field.
+type csvColumn struct {
+	column
+	Field int // 0-based
+}
+
+// parseColumnMap parses the -map flag against the columns of t.
+func parseColumnMap(s string, t table) ([]csvColumn, error) {
+	if s == "" {
+		return nil, errors.New("no columns")
+	}
+	var cols []csvColumn
+	seen := make(map[string]bool)
+	for _, kv := range strings.Split(s, ",") {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%q is not column=field", kv)
+		}
+		name := strings.TrimSpace(parts[0])
+		field, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || field < 1 {
+			return nil, fmt.Errorf("field of %s must be a number from 1", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("column %s mapped twice", name)
+		}
+		seen[name] = true
+		c, ok := findColumn(t, name)
+		if !ok {
+			return nil, fmt.Errorf("table %s has no column %s", t.Name, name)
+		}
+		cols = append(cols, csvColumn{c, field - 1})
+	}
+	return cols, nil
+}
+
+func findColumn(t table, name string) (column, bool) {
+	for _, c := range t.Columns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return column{}, false
+}
+
+type csvOptions struct {
+	Header       bool
+	Comma        rune
+	DecimalComma bool
+}
+
+// csvValues returns the COPY values of the mapped fields of record. Empty
+// fields are NULL.
+func csvValues(record []string, cols []csvColumn, decimalComma bool) ([]interface{}, error) {
+	values := make([]interface{}, len(cols))
+	for i, c := range cols {
+		if c.Field >= len(record) {
+			return nil, fmt.Errorf("no field %d for %s", c.Field+1, c.Name)
+		}
+		v := strings.TrimSpace(record[c.Field])
+		if v == "" {
+			continue
+		}
+		if decimalComma && (c.Type == typeReal || c.Type == typeDouble) {
+			v = strings.Replace(v, ",", ".", 1)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// copyCSV streams the files into a temporary copy of t and inserts the
+// rows that are not in t yet, so that rerunning a load is harmless. Only
+// one record is held in memory at a time.
+func copyCSV(db *sql.DB, t table, cols []csvColumn, opts csvOptions, names []string) (rowsAffected int64, err error) {
+	return copyRows(db, t, cols, ledger.SourceCSV, func(stmt *sql.Stmt) ([]ledger.File, error) {
+		var files []ledger.File
+		for _, name := range names {
+			f, err := copyCSVFile(stmt, name, cols, opts)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", name, err)
+			}
+			files = append(files, f)
+		}
+		return files, nil
+	})
+}
+
+// copyRows inserts the rows that load writes to the COPY statement of a
+// temporary copy of t into t, skipping those already in it, and records
+// the import of the returned files as source.
+func copyRows(db *sql.DB, t table, cols []csvColumn, source string, load func(*sql.Stmt) ([]ledger.File, error)) (rowsAffected int64, err error) {
+	if _, err = db.Exec(ledger.CreateTableSQL); err != nil {
+		return 0, err
+	}
+	txn, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer txn.Rollback()
+
+	tmp := "_" + t.Name + "_csv"
+	_, err = txn.Exec(fmt.Sprintf("CREATE TEMP TABLE %s ON COMMIT DROP AS SELECT * FROM %s WITH NO DATA", pq.QuoteIdentifier(tmp), pq.QuoteIdentifier(t.Name)))
+	if err != nil {
+		return 0, fmt.Errorf("create temporary table: %s", err)
+	}
+	columns := make([]string, len(cols))
+	for i, c := range cols {
+		columns[i] = c.Name
+	}
+	stmt, err := txn.Prepare(pq.CopyInSchema("pg_temp", tmp, columns...))
+	if err != nil {
+		return 0, fmt.Errorf("copy into temporary table: %s", err)
+	}
+	files, err := load(stmt)
+	if err != nil {
+		return 0, err
+	}
+	if _, err = stmt.Exec(); err != nil {
+		return 0, fmt.Errorf("flush after loading data: %s", err)
+	}
+	if err = stmt.Close(); err != nil {
+		return 0, err
+	}
+
+	quoted := make([]string, len(cols))
+	for i, n := range columns {
+		quoted[i] = pq.QuoteIdentifier(n)
+	}
+	list := strings.Join(quoted, ", ")
+	res, err := txn.Exec(fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM pg_temp.%s ON CONFLICT DO NOTHING",
+		pq.QuoteIdentifier(t.Name), list, list, pq.QuoteIdentifier(tmp)))
+	if err != nil {
+		return 0, fmt.Errorf("load data from temporary table: %s", err)
+	}
+	if rowsAffected, err = res.RowsAffected(); err != nil {
+		return 0, err
+	}
+	if err = ledger.Record(txn, source, rowsAffected, files...); err != nil {
+		return 0, fmt.Errorf("record import: %s", err)
+	}
+	return rowsAffected, txn.Commit()
+}
+
+// copyCSVFile streams the records of file name into stmt and returns the
+// digest of the file.
+func copyCSVFile(stmt *sql.Stmt, name string, cols []csvColumn, opts csvOptions) (ledger.File, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return ledger.File{}, err
+	}
+	defer f.Close()
+	h := ledger.NewHash()
+	if err = copyCSVRecords(stmt, io.TeeReader(f, h), cols, opts); err != nil {
+		return ledger.File{}, err
+	}
+	abs := name
+	if a, err := filepath.Abs(name); err == nil {
+		abs = a
+	}
+	return h.File(abs), nil
+}
+
+// copyCSVRecords streams the CSV records of r into stmt.
+func copyCSVRecords(stmt *sql.Stmt, r io.Reader, cols []csvColumn, opts csvOptions) error {
+	cr := csv.NewReader(r)
+	cr.Comma = opts.Comma
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	for line := 1; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if line == 1 && opts.Header {
+			continue
+		}
+		values, err := csvValues(record, cols, opts.DecimalComma)
+		if err != nil {
+			return fmt.Errorf("line %d: %s", line, err)
+		}
+		if _, err = stmt.Exec(values...); err != nil {
+			return fmt.Errorf("line %d: %s", line, err)
+		}
+	}
+}
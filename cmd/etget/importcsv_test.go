@@ -0,0 +1,39 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseColumnMap(t *testing.T) {
+	elspot, _ := lookupTable("elspot")
+	got, err := parseColumnMap("ts=1, fi=3", elspot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []csvColumn{{column{"ts", typeTimestamp}, 0}, {column{"fi", typeReal}, 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseColumnMap = %v, want %v", got, want)
+	}
+	for _, bad := range []string{"", "ts", "ts=0", "ts=x", "ts=1,ts=2", "se3=2"} {
+		if _, err := parseColumnMap(bad, elspot); err == nil {
+			t.Errorf("parseColumnMap(%q) did not return error", bad)
+		}
+	}
+}
+
+func TestCSVValues(t *testing.T) {
+	elspot, _ := lookupTable("elspot")
+	cols, _ := parseColumnMap("ts=1,fi=3,status=4", elspot)
+	got, err := csvValues([]string{"2025-01-10T00:00:00Z", "x", " 16,39 ", ""}, cols, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{"2025-01-10T00:00:00Z", "16.39", nil}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("csvValues = %v, want %v", got, want)
+	}
+	if _, err := csvValues([]string{"2025-01-10T00:00:00Z"}, cols, false); err == nil {
+		t.Error("csvValues did not return error for a short record")
+	}
+}
@@ -0,0 +1,134 @@
+// The etget command queries and maintains the imported energy data.
+//
+// Usage:
+//
+//	etget COMMAND [flags] [args]
+//
+// Run "etget help" for the list of commands, and "etget COMMAND -h" for the
+// flags of a command. Data is imported with the separate import-elspot and
+// import-energiatili commands, or from normalized CSV with "etget import".
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/joneskoo/etget/internal/config"
+)
+
+// command is an etget subcommand.
+type command struct {
+	// Name is the subcommand name given on the command line.
+	Name string
+
+	// Args describes the positional arguments in the usage message.
+	Args string
+
+	// Short is a one-line description shown in "etget help".
+	Short string
+
+	// Flags are the flags of the command. They are defined by setup.
+	Flags *flag.FlagSet
+
+	// Run executes the command with the positional arguments.
+	Run func(args []string) error
+}
+
+var commands = map[string]*command{}
+
+// register adds a command. setup is called with the command's flag set to
+// define flags and returns the function running the command.
+func register(name, args, short string, setup func(fs *flag.FlagSet) func(args []string) error) {
+	c := &command{
+		Name:  name,
+		Args:  args,
+		Short: short,
+		Flags: flag.NewFlagSet(name, flag.ExitOnError),
+	}
+	c.Flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: etget %s [flags] %s\n\n%s.\n\n", c.Name, c.Args, c.Short)
+		c.Flags.PrintDefaults()
+	}
+	c.Run = setup(c.Flags)
+	if c.Flags.Lookup("config") != nil || c.Flags.Lookup("connstring") != nil {
+		c.Flags.String("profile", "", "configuration `profile` to use (default $"+config.ProfileEnv+")")
+	}
+	commands[name] = c
+}
+
+// applyProfile selects the -profile of fs for the configuration the
+// command loads, unless -connstring was given uses the connstring of the
+// configuration, and replaces a -meter name of the configuration with its
+// meter_id.
+func applyProfile(fs *flag.FlagSet) error {
+	if f := fs.Lookup("profile"); f == nil {
+		return nil
+	} else if name := f.Value.String(); name != "" {
+		os.Setenv(config.ProfileEnv, name)
+	}
+	given := false
+	fs.Visit(func(f *flag.Flag) { given = given || f.Name == "connstring" })
+	setConnstring := fs.Lookup("connstring") != nil && !given
+	meter := fs.Lookup("meter")
+	if !setConnstring && meter == nil {
+		return nil
+	}
+	file := config.DefaultFile
+	if f := fs.Lookup("config"); f != nil {
+		file = f.Value.String()
+	}
+	cfg, err := config.Load(file)
+	if err != nil {
+		return err
+	}
+	if meter != nil {
+		if err := fs.Set("meter", cfg.MeterID(meter.Value.String())); err != nil {
+			return err
+		}
+	}
+	if !setConnstring || cfg.Connstring == "" {
+		return nil
+	}
+	return fs.Set("connstring", cfg.Connstring)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: etget COMMAND [flags] [args]\n\nCommands:\n")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "   %-14s %s\n", name, commands[name].Short)
+	}
+	os.Exit(1)
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "help" || os.Args[1] == "-h" {
+		usage()
+	}
+	if os.Args[1] == completeCommand {
+		complete(os.Stdout, os.Args[2:])
+		return
+	}
+	c, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "etget: unknown command %q\n\n", os.Args[1])
+		usage()
+	}
+	c.Flags.Parse(os.Args[2:])
+	if err := applyProfile(c.Flags); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR %s: %s\n", c.Name, err)
+		os.Exit(1)
+	}
+	if err := c.Run(c.Flags.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR %s: %s\n", c.Name, err)
+		os.Exit(1)
+	}
+}
+
+const connstringUsage = "https://www.postgresql.org/docs/current/static/libpq-connect.html#LIBPQ-CONNSTRING"
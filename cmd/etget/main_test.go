@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/joneskoo/etget/internal/config"
+)
+
+func TestApplyProfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "etget")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Unsetenv(config.ProfileEnv)
+	file := filepath.Join(dir, "etget.json")
+	err = ioutil.WriteFile(file, []byte(`{"connstring": "host=local", "meters": {"cottage": "643000000087654321"}, "profiles": {"prod": {"connstring": "host=prod"}}}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		args      []string
+		want      string
+		wantMeter string
+	}{
+		{[]string{"-config", file}, "host=local", "default"},
+		{[]string{"-config", file, "-profile", "prod"}, "host=prod", "default"},
+		{[]string{"-config", file, "-profile", "prod", "-connstring", "host=flag"}, "host=flag", "default"},
+		{[]string{"-config", file, "-meter", "cottage"}, "host=local", "643000000087654321"},
+		{[]string{"-config", file, "-meter", "643000000012345678", "-connstring", "host=flag"}, "host=flag", "643000000012345678"},
+	}
+	for _, c := range cases {
+		os.Unsetenv(config.ProfileEnv)
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		connstring := fs.String("connstring", "sslmode=disable", "")
+		meter := fs.String("meter", "default", "")
+		fs.String("config", config.DefaultFile, "")
+		fs.String("profile", "", "")
+		if err := fs.Parse(c.args); err != nil {
+			t.Fatal(err)
+		}
+		if err := applyProfile(fs); err != nil {
+			t.Errorf("applyProfile(%q): %s", c.args, err)
+		}
+		if *connstring != c.want {
+			t.Errorf("applyProfile(%q): connstring = %q, want %q", c.args, *connstring, c.want)
+		}
+		if *meter != c.wantMeter {
+			t.Errorf("applyProfile(%q): meter = %q, want %q", c.args, *meter, c.wantMeter)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joneskoo/etget/api"
+	"github.com/joneskoo/etget/entsoe"
+	"github.com/joneskoo/etget/internal/ledger"
+	"github.com/joneskoo/etget/resample"
+)
+
+// spotHintaURL is the public spot-hinta.fi API, which needs no key.
+const spotHintaURL = "https://api.spot-hinta.fi"
+
+func init() {
+	register("now", "", "Print the Finnish prices of today and tomorrow from the fastest source", func(fs *flag.FlagSet) func([]string) error {
+		sources := fs.String("sources", "spot-hinta,server,entsoe", "comma-separated sources raced for the prices: spot-hinta (spot-hinta.fi), server (-server) and entsoe ($"+entsoeTokenEnv+"); those not configured are skipped")
+		serverURL := fs.String("server", "", "base `URL` of an etget status -listen server")
+		token := fs.String("token", "", "bearer token of -server")
+		timeout := fs.Duration("timeout", 2*time.Second, "give up if no source has answered within this time")
+		jsonOut := fs.Bool("json", false, "print a JSON array of {ts, price} instead of a table")
+		cache := fs.Bool("cache", false, "also store the prices in table elspot of -connstring")
+		connstring := fs.String("connstring", "sslmode=disable", connstringUsage)
+		notify := fs.Bool("notify", false, "with -cache, send NOTIFY "+ledger.NotifyChannel+" if the prices changed rows")
+		return func(args []string) error {
+			if len(args) != 0 {
+				return errors.New("unexpected arguments")
+			}
+			srcs, err := nowSources(strings.Split(*sources, ","), *serverURL, *token, os.Getenv(entsoeTokenEnv))
+			if err != nil {
+				return fmt.Errorf("-sources: %s", err)
+			}
+			now := time.Now()
+			start, end := nowWindow(now)
+			ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+			defer cancel()
+			src, points, err := fetchFastest(ctx, srcs, start, end)
+			if err != nil {
+				return err
+			}
+			if *cache {
+				db, err := sql.Open("postgres", *connstring)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+				if _, err := storePrices(db, src.ledger, points, *notify); err != nil {
+					return fmt.Errorf("-cache: %s", err)
+				}
+			}
+			fmt.Fprintf(os.Stderr, "%d hours from %s in %s\n", len(points), src.name, time.Since(now).Round(time.Millisecond))
+			if *jsonOut {
+				return writeNowJSON(os.Stdout, points)
+			}
+			return writeNow(os.Stdout, points, now)
+		}
+	})
+}
+
+// nowWindow returns today and tomorrow in Finland.
+func nowWindow(now time.Time) (start, end time.Time) {
+	t := now.In(helsinki)
+	start = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, helsinki)
+	return start, start.AddDate(0, 0, 2)
+}
+
+// nowSource fetches the Finnish prices between start and end.
+type nowSource struct {
+	name string
+
+	// ledger is the source of the prices in the import ledger.
+	ledger string
+
+	fetch func(ctx context.Context, start, end time.Time) ([]entsoe.Point, error)
+}
+
+// nowSources returns the named sources that are configured: server needs
+// serverURL and entsoe an API token.
+func nowSources(names []string, serverURL, token, entsoeToken string) ([]nowSource, error) {
+	var sources []nowSource
+	for _, name := range names {
+		switch strings.TrimSpace(name) {
+		case "spot-hinta":
+			c := &api.Client{BaseURL: spotHintaURL}
+			sources = append(sources, nowSource{"spot-hinta", ledger.SourceSpotHinta, spotHintaSource(c)})
+		case "server":
+			if serverURL != "" {
+				c := &api.Client{BaseURL: serverURL, Token: token}
+				sources = append(sources, nowSource{"server " + serverURL, ledger.SourceSync, spotHintaSource(c)})
+			}
+		case "entsoe":
+			if entsoeToken != "" {
+				c := &entsoe.Client{Token: entsoeToken}
+				sources = append(sources, nowSource{"entsoe", ledger.SourceEntsoe, func(ctx context.Context, start, end time.Time) ([]entsoe.Point, error) {
+					points, err := c.DayAheadPrices(ctx, "10YFI-1--------U", start, end)
+					if _, ok := err.(*entsoe.MissingError); ok {
+						// Tomorrow's prices are missing until published.
+						return points, nil
+					}
+					return points, err
+				}})
+			}
+		default:
+			return nil, fmt.Errorf("unknown source %q, want spot-hinta, server or entsoe", name)
+		}
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no source configured; give -server or set %s", entsoeTokenEnv)
+	}
+	return sources, nil
+}
+
+// spotHintaSource fetches prices from an API in the spot-hinta.fi layout,
+// which serves exactly today and tomorrow. Its resolution is the spacing
+// of the prices.
+func spotHintaSource(c *api.Client) func(ctx context.Context, start, end time.Time) ([]entsoe.Point, error) {
+	return func(ctx context.Context, start, end time.Time) ([]entsoe.Point, error) {
+		prices, err := c.TodayAndDayForward(ctx)
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(prices, func(i, j int) bool { return prices[i].DateTime.Before(prices[j].DateTime) })
+		resolution := time.Hour
+		for i := 1; i < len(prices); i++ {
+			if d := prices[i].DateTime.Sub(prices[i-1].DateTime); d > 0 && d < resolution {
+				resolution = d
+			}
+		}
+		var points []entsoe.Point
+		for _, p := range prices {
+			if p.DateTime.Before(start) || !p.DateTime.Before(end) {
+				continue
+			}
+			points = append(points, entsoe.Point{Start: p.DateTime, Resolution: resolution, Price: p.PriceNoTax * 1000})
+		}
+		return points, nil
+	}
+}
+
+// fetchFastest fetches from all sources at once and returns the first
+// that succeeds with prices, hourly, cancelling the others. If all fail,
+// the error lists their errors.
+func fetchFastest(ctx context.Context, sources []nowSource, start, end time.Time) (nowSource, []entsoe.Point, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type result struct {
+		src    nowSource
+		points []entsoe.Point
+		err    error
+	}
+	results := make(chan result, len(sources))
+	for _, src := range sources {
+		go func(src nowSource) {
+			points, err := src.fetch(ctx, start, end)
+			if err == nil {
+				points, err = hourlyPoints(points)
+			}
+			if err == nil && len(points) == 0 {
+				err = errors.New("no prices")
+			}
+			results <- result{src, points, err}
+		}(src)
+	}
+	var errs []string
+	for range sources {
+		r := <-results
+		if r.err == nil {
+			return r.src, r.points, nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %s", r.src.name, r.err))
+	}
+	return nowSource{}, nil, errors.New(strings.Join(errs, "; "))
+}
+
+// hourlyPoints averages prices of a finer resolution, such as the 15-minute
+// day-ahead prices, to the hourly prices of table elspot.
+func hourlyPoints(points []entsoe.Point) ([]entsoe.Point, error) {
+	if len(points) == 0 || points[0].Resolution == time.Hour {
+		return points, nil
+	}
+	in := make([]resample.Point, len(points))
+	for i, p := range points {
+		if p.Resolution != points[0].Resolution {
+			return nil, fmt.Errorf("mixed resolutions %s and %s", points[0].Resolution, p.Resolution)
+		}
+		in[i] = resample.Point{Time: p.Start, Value: p.Price}
+	}
+	out, err := resample.Convert(in, points[0].Resolution, time.Hour, resample.Mean)
+	if err != nil {
+		return nil, err
+	}
+	hourly := make([]entsoe.Point, len(out))
+	for i, p := range out {
+		hourly[i] = entsoe.Point{Start: p.Time, Resolution: time.Hour, Price: p.Value}
+	}
+	return hourly, nil
+}
+
+// writeNow prints the prices in Finnish time, marking the current hour.
+func writeNow(w io.Writer, points []entsoe.Point, now time.Time) error {
+	for _, p := range points {
+		mark := " "
+		if !now.Before(p.Start) && now.Before(p.Start.Add(time.Hour)) {
+			mark = "*"
+		}
+		if _, err := fmt.Fprintf(w, "%s %s %8.2f\n", mark, p.Start.In(helsinki).Format("2006-01-02 15:04"), p.Price); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeNowJSON(w io.Writer, points []entsoe.Point) error {
+	prices := make([]api.Price, len(points))
+	for i, p := range points {
+		prices[i] = api.Price{Timestamp: p.Start.UTC(), Price: p.Price}
+	}
+	return json.NewEncoder(w).Encode(prices)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joneskoo/etget/api"
+	"github.com/joneskoo/etget/entsoe"
+)
+
+func TestFetchFastest(t *testing.T) {
+	start, end := nowWindow(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	if want := time.Date(2026, 10, 15, 21, 0, 0, 0, time.UTC); !start.Equal(want) || !end.Equal(want.Add(48*time.Hour)) {
+		t.Fatalf("nowWindow = %s..%s, want two days from %s", start, end, want)
+	}
+	point := []entsoe.Point{{Start: start, Resolution: time.Hour, Price: 42}}
+	slow := nowSource{name: "slow", fetch: func(ctx context.Context, start, end time.Time) ([]entsoe.Point, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}
+	failing := nowSource{name: "failing", fetch: func(ctx context.Context, start, end time.Time) ([]entsoe.Point, error) {
+		return nil, errors.New("refused")
+	}}
+	empty := nowSource{name: "empty", fetch: func(ctx context.Context, start, end time.Time) ([]entsoe.Point, error) {
+		return nil, nil
+	}}
+	fast := nowSource{name: "fast", fetch: func(ctx context.Context, start, end time.Time) ([]entsoe.Point, error) {
+		return point, nil
+	}}
+
+	src, points, err := fetchFastest(context.Background(), []nowSource{slow, failing, empty, fast}, start, end)
+	if err != nil || src.name != "fast" || len(points) != 1 {
+		t.Errorf("fetchFastest = %s %v, %v; want fast", src.name, points, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, _, err = fetchFastest(ctx, []nowSource{slow, failing, empty}, start, end)
+	if err == nil || !strings.Contains(err.Error(), "failing: refused") || !strings.Contains(err.Error(), "empty: no prices") || !strings.Contains(err.Error(), "slow: context deadline exceeded") {
+		t.Errorf("fetchFastest error = %v, want the errors of all sources", err)
+	}
+}
+
+func TestSpotHintaSource(t *testing.T) {
+	day := time.Date(2026, 10, 16, 0, 0, 0, 0, helsinki)
+	var prices []api.SpotHintaPrice
+	for i := 0; i < 8; i++ {
+		prices = append(prices, api.SpotHintaPrice{DateTime: day.Add(time.Duration(i) * 15 * time.Minute), PriceNoTax: float64(i) / 1000})
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != api.SpotHintaForwardPath {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(prices)
+	}))
+	defer srv.Close()
+
+	fetch := spotHintaSource(&api.Client{BaseURL: srv.URL})
+	points, err := fetch(context.Background(), day, day.AddDate(0, 0, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 8 || points[0].Resolution != 15*time.Minute || points[3].Price != 3 {
+		t.Fatalf("points = %v, want 8 quarters in EUR/MWh", points)
+	}
+	hourly, err := hourlyPoints(points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hourly) != 2 || hourly[0].Price != 1.5 || hourly[1].Price != 5.5 || hourly[1].Resolution != time.Hour {
+		t.Errorf("hourlyPoints = %v, want means 1.5 and 5.5", hourly)
+	}
+
+	var buf bytes.Buffer
+	if err := writeNow(&buf, hourly, day.Add(90*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	want := "  2026-10-16 00:00     1.50\n* 2026-10-16 01:00     5.50\n"
+	if buf.String() != want {
+		t.Errorf("writeNow wrote:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/joneskoo/etget/api"
+	"github.com/joneskoo/etget/internal/ledger"
+	"golang.org/x/net/websocket"
+)
+
+// pricePush sends an api.PriceUpdate to the WebSocket clients of
+// api.PricesPath after each elspot import.
+type pricePush struct {
+	mu      sync.Mutex
+	clients map[chan []byte]bool
+}
+
+func newPricePush() *pricePush {
+	return &pricePush{clients: make(map[chan []byte]bool)}
+}
+
+// subscribe returns a channel receiving the broadcast messages.
+func (p *pricePush) subscribe() chan []byte {
+	ch := make(chan []byte, 4)
+	p.mu.Lock()
+	p.clients[ch] = true
+	p.mu.Unlock()
+	return ch
+}
+
+func (p *pricePush) unsubscribe(ch chan []byte) {
+	p.mu.Lock()
+	delete(p.clients, ch)
+	p.mu.Unlock()
+}
+
+// broadcast sends msg to every client. A client that has not read the
+// previous messages misses it rather than holding up the others.
+func (p *pricePush) broadcast(msg []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for ch := range p.clients {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
+
+// handler serves the WebSocket connections. Home automation clients send
+// no Origin header, so the origin is not checked; the bearer tokens of
+// the server still apply.
+func (p *pricePush) handler() http.Handler {
+	return websocket.Server{Handler: func(ws *websocket.Conn) {
+		ch := p.subscribe()
+		defer p.unsubscribe(ch)
+		closed := make(chan struct{})
+		go func() {
+			io.Copy(ioutil.Discard, ws)
+			close(closed)
+		}()
+		for {
+			select {
+			case msg := <-ch:
+				if err := websocket.Message.Send(ws, string(msg)); err != nil {
+					return
+				}
+			case <-closed:
+				return
+			}
+		}
+	}}
+}
+
+// watch polls the import ledger every interval and broadcasts the prices
+// after each elspot import finished since the server started. It does
+// not return.
+func (p *pricePush) watch(db *sql.DB, interval time.Duration) {
+	var last time.Time
+	for first := true; ; first = false {
+		entries, err := ledger.Latest(db)
+		if err != nil {
+			log.Printf("ERROR reading imports: %s", err)
+		} else if e, ok := newImport(entries, ledger.SourceElspot, last); ok {
+			last = e.FinishedAt
+			if !first {
+				if msg, err := priceUpdate(db, e, time.Now()); err != nil {
+					log.Printf("ERROR reading prices: %s", err)
+				} else {
+					p.broadcast(msg)
+				}
+			}
+		}
+		time.Sleep(interval)
+	}
+}
+
+// newImport returns the latest import of source if it finished after last.
+func newImport(entries []ledger.Entry, source string, last time.Time) (ledger.Entry, bool) {
+	for _, e := range entries {
+		if e.Source == source && e.FinishedAt.After(last) {
+			return e, true
+		}
+	}
+	return ledger.Entry{}, false
+}
+
+// priceUpdate encodes the update message of import e with the prices from
+// the start of the current hour on.
+func priceUpdate(db *sql.DB, e ledger.Entry, now time.Time) ([]byte, error) {
+	rows, err := db.Query("SELECT ts, fi FROM elspot WHERE ts >= $1 AND fi IS NOT NULL ORDER BY ts", now.Truncate(time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	u := api.PriceUpdate{
+		Import: api.Import{Source: e.Source, FinishedAt: e.FinishedAt.UTC(), RowsAffected: e.RowsAffected},
+		Prices: []api.Price{},
+	}
+	for rows.Next() {
+		var p api.Price
+		if err := rows.Scan(&p.Timestamp, &p.Price); err != nil {
+			return nil, err
+		}
+		p.Timestamp = p.Timestamp.UTC()
+		u.Prices = append(u.Prices, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(u)
+}
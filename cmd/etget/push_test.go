@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joneskoo/etget/internal/ledger"
+)
+
+func TestNewImport(t *testing.T) {
+	at := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	entries := []ledger.Entry{
+		{Source: ledger.SourceElspot, FinishedAt: at},
+		{Source: ledger.SourceEnergiatili, FinishedAt: at.Add(time.Hour)},
+	}
+	if e, ok := newImport(entries, ledger.SourceElspot, at.Add(-time.Minute)); !ok || !e.FinishedAt.Equal(at) {
+		t.Errorf("newImport after earlier import = %+v, %v", e, ok)
+	}
+	if _, ok := newImport(entries, ledger.SourceElspot, at); ok {
+		t.Error("newImport reported the import already seen")
+	}
+}
+
+func TestPricePushBroadcast(t *testing.T) {
+	p := newPricePush()
+	a, b := p.subscribe(), p.subscribe()
+	p.broadcast([]byte("1"))
+	for _, ch := range []chan []byte{a, b} {
+		if msg := <-ch; string(msg) != "1" {
+			t.Errorf("got %q, want 1", msg)
+		}
+	}
+
+	// A client that does not read must not block the others.
+	p.unsubscribe(b)
+	for i := 0; i < cap(a)+1; i++ {
+		p.broadcast([]byte("x"))
+	}
+	if len(a) != cap(a) {
+		t.Errorf("client buffer has %d messages, want %d", len(a), cap(a))
+	}
+	if len(b) != 0 {
+		t.Errorf("unsubscribed client got %d messages", len(b))
+	}
+}
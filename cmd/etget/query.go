@@ -0,0 +1,178 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+func init() {
+	register("query", "NAME", "Run a canned query; without NAME, list the queries", func(fs *flag.FlagSet) func([]string) error {
+		connstring := fs.String("connstring", "sslmode=disable", connstringUsage)
+		meter := fs.String("meter", "default", "metering point")
+		month := fs.String("month", "", "month to query, YYYY-MM (overrides -from and -to)")
+		from := fs.String("from", "", "first day, YYYY-MM-DD (default first day of this month)")
+		to := fs.String("to", "", "day after the last day, YYYY-MM-DD (default tomorrow)")
+		limit := fs.Int("limit", 10, "maximum number of rows of top lists")
+		return func(args []string) error {
+			if len(args) == 0 {
+				listQueries(os.Stdout)
+				return nil
+			}
+			if len(args) != 1 {
+				return errors.New("want at most one NAME argument")
+			}
+			q, ok := cannedQueries[args[0]]
+			if !ok {
+				return fmt.Errorf("unknown query %q; run etget query for the list", args[0])
+			}
+			start, end, err := parsePeriod(*from, *to, time.Now())
+			if err != nil {
+				return err
+			}
+			if *month != "" {
+				if start, end, err = parseMonth(*month); err != nil {
+					return fmt.Errorf("-month: %s", err)
+				}
+			}
+			params := map[string]interface{}{"start": start, "end": end, "meter": *meter, "limit": *limit}
+			qargs := make([]interface{}, len(q.Params))
+			for i, p := range q.Params {
+				qargs[i] = params[p]
+			}
+
+			db, err := sql.Open("postgres", *connstring)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			return runQuery(os.Stdout, db, q.SQL, qargs...)
+		}
+	})
+}
+
+// cannedQuery is a named query for users who do not write SQL. The query
+// formats its own columns as text.
+type cannedQuery struct {
+	Short string
+	SQL   string
+
+	// Params names the values of the placeholders $1, $2, ...: start,
+	// end, meter or limit.
+	Params []string
+}
+
+var cannedQueries = map[string]cannedQuery{
+	"top-hours": {
+		Short: "most expensive hours",
+		SQL: `SELECT to_char(ts AT TIME ZONE 'Europe/Helsinki', 'YYYY-MM-DD HH24:MI') AS hour,
+    to_char(fi, 'FM9990.00') AS "EUR/MWh"
+    FROM elspot WHERE ts >= $1 AND ts < $2 AND fi IS NOT NULL
+    ORDER BY fi DESC, ts LIMIT $3`,
+		Params: []string{"start", "end", "limit"},
+	},
+	"cheap-hours": {
+		Short: "cheapest hours",
+		SQL: `SELECT to_char(ts AT TIME ZONE 'Europe/Helsinki', 'YYYY-MM-DD HH24:MI') AS hour,
+    to_char(fi, 'FM9990.00') AS "EUR/MWh"
+    FROM elspot WHERE ts >= $1 AND ts < $2 AND fi IS NOT NULL
+    ORDER BY fi, ts LIMIT $3`,
+		Params: []string{"start", "end", "limit"},
+	},
+	"intraday-vs-dayahead": {
+		Short: "Finnish intraday volume-weighted price against the day-ahead price",
+		SQL: `SELECT to_char(i.ts AT TIME ZONE 'Europe/Helsinki', 'YYYY-MM-DD HH24:MI') AS hour,
+    to_char(e.fi, 'FM9990.00') AS "day-ahead",
+    to_char(i.vwap, 'FM9990.00') AS intraday,
+    to_char(i.vwap - e.fi, 'FM9990.00') AS difference,
+    to_char(i.volume, 'FM999990.0') AS "MW traded"
+    FROM intraday i JOIN elspot e USING (ts)
+    WHERE i.area = 'FI' AND i.ts >= $1 AND i.ts < $2
+    ORDER BY abs(i.vwap - e.fi) DESC, i.ts LIMIT $3`,
+		Params: []string{"start", "end", "limit"},
+	},
+	"monthly-totals": {
+		Short: "consumption and average spot price of each month",
+		SQL: `SELECT to_char(e.ts AT TIME ZONE 'Europe/Helsinki', 'YYYY-MM') AS month,
+    to_char(sum(e.kwh), 'FM999990.0') AS kwh,
+    to_char(avg(p.fi), 'FM9990.00') AS "avg EUR/MWh",
+    to_char(sum(e.kwh * p.fi) / nullif(sum(e.kwh), 0), 'FM9990.00') AS "weighted EUR/MWh"
+    FROM energiatili e JOIN elspot p USING (ts)
+    WHERE e.meter_id = $1 AND e.ts >= $2 AND e.ts < $3
+    GROUP BY 1 ORDER BY 1`,
+		Params: []string{"meter", "start", "end"},
+	},
+	"daily-totals": {
+		Short: "consumption and average spot price of each day",
+		SQL: `SELECT to_char(e.ts AT TIME ZONE 'Europe/Helsinki', 'YYYY-MM-DD') AS day,
+    to_char(sum(e.kwh), 'FM99990.0') AS kwh,
+    to_char(avg(p.fi), 'FM9990.00') AS "avg EUR/MWh",
+    to_char(sum(e.kwh * p.fi) / nullif(sum(e.kwh), 0), 'FM9990.00') AS "weighted EUR/MWh"
+    FROM energiatili e JOIN elspot p USING (ts)
+    WHERE e.meter_id = $1 AND e.ts >= $2 AND e.ts < $3
+    GROUP BY 1 ORDER BY 1`,
+		Params: []string{"meter", "start", "end"},
+	},
+}
+
+func listQueries(w io.Writer) {
+	names := make([]string, 0, len(cannedQueries))
+	for name := range cannedQueries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "   %-16s %s\n", name, cannedQueries[name].Short)
+	}
+}
+
+// parseMonth returns the bounds of the month s in Finnish time.
+func parseMonth(s string) (start, end time.Time, err error) {
+	start, err = time.ParseInLocation("2006-01", s, helsinki)
+	if err != nil {
+		return start, end, err
+	}
+	return start, start.AddDate(0, 1, 0), nil
+}
+
+// runQuery prints the result of a query with text columns as a table.
+func runQuery(w io.Writer, db *sql.DB, query string, args ...interface{}) error {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, strings.Join(cols, "\t")+"\t")
+	values := make([]sql.NullString, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	record := make([]string, len(cols))
+	for rows.Next() {
+		if err = rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		for i, v := range values {
+			record[i] = v.String
+		}
+		fmt.Fprintln(tw, strings.Join(record, "\t")+"\t")
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// TestCannedQueryParams checks that each query has a parameter for every
+// placeholder; Postgres rejects unused parameters.
+func TestCannedQueryParams(t *testing.T) {
+	placeholder := regexp.MustCompile(`\$(\d+)`)
+	known := map[string]bool{"start": true, "end": true, "meter": true, "limit": true}
+	for name, q := range cannedQueries {
+		used := map[string]bool{}
+		for _, m := range placeholder.FindAllStringSubmatch(q.SQL, -1) {
+			used[m[1]] = true
+		}
+		if len(used) != len(q.Params) {
+			t.Errorf("%s: %d placeholders, %d params", name, len(used), len(q.Params))
+		}
+		for i, p := range q.Params {
+			if !used[fmt.Sprint(i+1)] {
+				t.Errorf("%s: param %s ($%d) is not used", name, p, i+1)
+			}
+			if !known[p] {
+				t.Errorf("%s: unknown param %q", name, p)
+			}
+		}
+	}
+}
+
+func TestParseMonth(t *testing.T) {
+	start, end, err := parseMonth("2024-12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 12, 1, 0, 0, 0, 0, helsinki); !start.Equal(want) {
+		t.Errorf("start = %s, want %s", start, want)
+	}
+	if want := time.Date(2025, 1, 1, 0, 0, 0, 0, helsinki); !end.Equal(want) {
+		t.Errorf("end = %s, want %s", end, want)
+	}
+	if _, _, err := parseMonth("2024-13"); err == nil {
+		t.Error("parseMonth(\"2024-13\") did not return error")
+	}
+}
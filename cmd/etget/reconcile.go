@@ -0,0 +1,280 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joneskoo/etget/internal/config"
+)
+
+func init() {
+	register("reconcile", "-invoice FILE", "Compare invoice line items with the costs computed from the database", func(fs *flag.FlagSet) func([]string) error {
+		connstring := fs.String("connstring", "sslmode=disable", connstringUsage)
+		configFile := fs.String("config", config.DefaultFile, "configuration file with the contract")
+		meter := fs.String("meter", "default", "metering point")
+		invoice := fs.String("invoice", "", "CSV file of invoice line items with columns date, item (energy, transfer, sold or fee), kwh and eur")
+		comma := fs.String("comma", ",", "field separator of the invoice")
+		decimalComma := fs.Bool("decimal-comma", false, "numbers of the invoice use a decimal comma")
+		tolerance := fs.Float64("tolerance", 0.01, "largest difference in EUR that is not reported")
+		return func(args []string) error {
+			if len(args) != 0 {
+				return errors.New("unexpected arguments")
+			}
+			if *invoice == "" {
+				return errors.New("-invoice is required")
+			}
+			sep := []rune(*comma)
+			if len(sep) != 1 {
+				return errors.New("-comma must be a single character")
+			}
+			cfg, err := config.Load(*configFile)
+			if err != nil {
+				return err
+			}
+			f, err := os.Open(*invoice)
+			if err != nil {
+				return err
+			}
+			lines, err := readInvoice(f, sep[0], *decimalComma)
+			f.Close()
+			if err != nil {
+				return fmt.Errorf("%s: %s", *invoice, err)
+			}
+			if len(lines) == 0 {
+				return fmt.Errorf("%s: no line items", *invoice)
+			}
+			start, end := invoicePeriod(lines)
+
+			db, err := sql.Open("postgres", *connstring)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			hours, err := queryHours(db, *meter, start, end)
+			if err != nil {
+				return err
+			}
+
+			computed := dailyCosts(cfg.Contract, hours)
+			fees := periodFees(cfg.Contract, start, end, hours)
+			computed[invoiceKey{Item: itemFee}] = invoiceAmount{EUR: fees.Energy + fees.Transfer}
+			ds := reconcile(sumInvoice(lines), computed, *tolerance)
+			for _, d := range ds {
+				fmt.Println(d)
+			}
+			if len(ds) > 0 {
+				return fmt.Errorf("%d discrepancies between %s and %s", len(ds), start.Format("2006-01-02"), end.AddDate(0, 0, -1).Format("2006-01-02"))
+			}
+			fmt.Printf("OK! %s to %s: invoice matches computed costs\n", start.Format("2006-01-02"), end.AddDate(0, 0, -1).Format("2006-01-02"))
+			return nil
+		}
+	})
+}
+
+// Invoice line items. Fees are reconciled for the whole period, as they
+// are billed per month.
+const (
+	itemEnergy   = "energy"
+	itemTransfer = "transfer"
+	itemSold     = "sold"
+	itemFee      = "fee"
+)
+
+// invoiceLine is a line item of the invoice. Sold is a credit, given as a
+// positive amount.
+type invoiceLine struct {
+	Day    time.Time
+	Item   string
+	KWh    float64
+	HasKWh bool
+	EUR    float64
+}
+
+// readInvoice reads the line items of an invoice CSV. The header names the
+// columns date (YYYY-MM-DD), item, eur and optionally kwh, in any order.
+func readInvoice(r io.Reader, comma rune, decimalComma bool) ([]invoiceLine, error) {
+	cr := csv.NewReader(r)
+	cr.Comma = comma
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("header: %s", err)
+	}
+	fields := map[string]int{"kwh": -1}
+	for i, name := range header {
+		fields[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"date", "item", "eur"} {
+		if _, ok := fields[name]; !ok {
+			return nil, fmt.Errorf("header: no column %s", name)
+		}
+	}
+	number := func(s string) (float64, error) {
+		s = strings.TrimSpace(s)
+		if decimalComma {
+			s = strings.Replace(s, ",", ".", 1)
+		}
+		return strconv.ParseFloat(s, 64)
+	}
+
+	var lines []invoiceLine
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(name string) string {
+			if i := fields[name]; i >= 0 && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		var l invoiceLine
+		if l.Day, err = time.ParseInLocation("2006-01-02", field("date"), helsinki); err != nil {
+			return nil, fmt.Errorf("line %d: date: %s", line, err)
+		}
+		switch l.Item = strings.ToLower(field("item")); l.Item {
+		case itemEnergy, itemTransfer, itemSold, itemFee:
+		default:
+			return nil, fmt.Errorf("line %d: unknown item %q, want energy, transfer, sold or fee", line, l.Item)
+		}
+		if l.EUR, err = number(field("eur")); err != nil {
+			return nil, fmt.Errorf("line %d: eur: %s", line, err)
+		}
+		if s := field("kwh"); s != "" {
+			if l.KWh, err = number(s); err != nil {
+				return nil, fmt.Errorf("line %d: kwh: %s", line, err)
+			}
+			l.HasKWh = true
+		}
+		lines = append(lines, l)
+	}
+}
+
+// invoicePeriod returns the days the invoice covers.
+func invoicePeriod(lines []invoiceLine) (start, end time.Time) {
+	start, end = lines[0].Day, lines[0].Day
+	for _, l := range lines[1:] {
+		if l.Day.Before(start) {
+			start = l.Day
+		}
+		if l.Day.After(end) {
+			end = l.Day
+		}
+	}
+	return start, end.AddDate(0, 0, 1)
+}
+
+// invoiceKey identifies what is reconciled: an item on a day, or the fees
+// of the whole period with a zero Day.
+type invoiceKey struct {
+	Day  string
+	Item string
+}
+
+type invoiceAmount struct {
+	KWh    float64
+	HasKWh bool
+	EUR    float64
+}
+
+func (a invoiceAmount) add(b invoiceAmount) invoiceAmount {
+	return invoiceAmount{KWh: a.KWh + b.KWh, HasKWh: a.HasKWh || b.HasKWh, EUR: a.EUR + b.EUR}
+}
+
+// sumInvoice sums the line items by day and item.
+func sumInvoice(lines []invoiceLine) map[invoiceKey]invoiceAmount {
+	sums := make(map[invoiceKey]invoiceAmount)
+	for _, l := range lines {
+		k := invoiceKey{Day: l.Day.Format("2006-01-02"), Item: l.Item}
+		if l.Item == itemFee {
+			k.Day = ""
+		}
+		sums[k] = sums[k].add(invoiceAmount{KWh: l.KWh, HasKWh: l.HasKWh, EUR: l.EUR})
+	}
+	return sums
+}
+
+// dailyCosts sums the costs of the hours by day and item, as hourCost
+// computes them for the cost command.
+func dailyCosts(c config.Contract, hours []hour) map[invoiceKey]invoiceAmount {
+	sums := make(map[invoiceKey]invoiceAmount)
+	for _, h := range hours {
+		day := h.Timestamp.In(helsinki).Format("2006-01-02")
+		hc := hourCost(c, h)
+		for _, a := range []struct {
+			item     string
+			kwh, eur float64
+		}{
+			{itemEnergy, h.KWh, hc.Energy},
+			{itemTransfer, h.KWh, hc.Transfer},
+			{itemSold, h.SoldKWh, hc.Sold},
+		} {
+			k := invoiceKey{Day: day, Item: a.item}
+			sums[k] = sums[k].add(invoiceAmount{KWh: a.kwh, HasKWh: true, EUR: a.eur})
+		}
+	}
+	return sums
+}
+
+// discrepancy is a day and item whose invoiced and computed amounts
+// differ.
+type discrepancy struct {
+	invoiceKey
+	Invoiced, Computed invoiceAmount
+}
+
+func (d discrepancy) String() string {
+	day := d.Day
+	if day == "" {
+		day = "period"
+	}
+	s := fmt.Sprintf("%-10s %-8s invoiced %9.2f EUR computed %9.2f EUR diff %+8.2f EUR", day, d.Item, d.Invoiced.EUR, d.Computed.EUR, d.Invoiced.EUR-d.Computed.EUR)
+	if d.Invoiced.HasKWh && d.Computed.HasKWh {
+		s += fmt.Sprintf(", invoiced %.3f kWh computed %.3f kWh", d.Invoiced.KWh, d.Computed.KWh)
+	}
+	return s
+}
+
+// reconcile returns the days and items whose invoiced and computed
+// amounts differ by more than tolerance EUR, or by more than 0.001 kWh
+// where the invoice gives the energy. Items missing on either side count
+// as zero, so days the invoice leaves out are reported if they have
+// costs.
+func reconcile(invoiced, computed map[invoiceKey]invoiceAmount, tolerance float64) []discrepancy {
+	keys := make(map[invoiceKey]bool)
+	for k := range invoiced {
+		keys[k] = true
+	}
+	for k := range computed {
+		keys[k] = true
+	}
+	var ds []discrepancy
+	for k := range keys {
+		i, c := invoiced[k], computed[k]
+		kwhOff := i.HasKWh && c.HasKWh && math.Abs(i.KWh-c.KWh) > 0.001
+		if math.Abs(i.EUR-c.EUR) > tolerance || kwhOff {
+			ds = append(ds, discrepancy{k, i, c})
+		}
+	}
+	sort.Slice(ds, func(a, b int) bool {
+		if ds[a].Day != ds[b].Day {
+			return ds[a].Day < ds[b].Day
+		}
+		return ds[a].Item < ds[b].Item
+	})
+	return ds
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joneskoo/etget/internal/config"
+)
+
+func TestReadInvoice(t *testing.T) {
+	in := "Item;Date;kWh;EUR\nenergy;2016-01-10;2,5;0,16\nfee;2016-01-31;;3,90\n"
+	lines, err := readInvoice(strings.NewReader(in), ';', true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("readInvoice returned %d lines, want 2", len(lines))
+	}
+	if l := lines[0]; l.Item != itemEnergy || l.Day.Format("2006-01-02") != "2016-01-10" || !l.HasKWh || l.KWh != 2.5 || l.EUR != 0.16 {
+		t.Errorf("line 0 = %+v", l)
+	}
+	if l := lines[1]; l.Item != itemFee || l.HasKWh || l.EUR != 3.90 {
+		t.Errorf("line 1 = %+v", l)
+	}
+
+	for _, bad := range []string{
+		"date,eur\n",
+		"date,item,eur\n2016-01-10,discount,1\n",
+		"date,item,eur\n10.1.2016,energy,1\n",
+		"date,item,eur\n2016-01-10,energy,x\n",
+	} {
+		if _, err := readInvoice(strings.NewReader(bad), ',', false); err == nil {
+			t.Errorf("readInvoice(%q) did not return error", bad)
+		}
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	contract := config.Contract{VAT: 0.24, MarginPerKWh: 0.002, Transfer: config.Transfer{DayPerKWh: 0.05}}
+	day1 := time.Date(2016, 1, 10, 12, 0, 0, 0, helsinki)
+	day2 := day1.AddDate(0, 0, 1)
+	// 2 kWh at 50 EUR/MWh: energy 0.128 EUR, transfer 0.10 EUR
+	computed := dailyCosts(contract, []hour{
+		{Timestamp: day1, KWh: 2, Spot: 50},
+		{Timestamp: day2, KWh: 2, Spot: 50},
+	})
+	invoiced := sumInvoice([]invoiceLine{
+		{Day: day1, Item: itemEnergy, KWh: 2, HasKWh: true, EUR: 0.13},
+		{Day: day1, Item: itemTransfer, EUR: 0.10},
+		{Day: day2, Item: itemEnergy, KWh: 2.5, HasKWh: true, EUR: 0.128},
+		{Day: day2, Item: itemTransfer, EUR: 0.40},
+	})
+	ds := reconcile(invoiced, computed, 0.01)
+	var got []string
+	for _, d := range ds {
+		got = append(got, d.Day+" "+d.Item)
+	}
+	want := "2016-01-11 energy,2016-01-11 transfer"
+	if strings.Join(got, ",") != want {
+		t.Errorf("reconcile = %v, want %s", got, want)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/joneskoo/etget/elspot"
+	"github.com/joneskoo/etget/internal/archive"
+	"github.com/joneskoo/etget/internal/config"
+	"github.com/joneskoo/etget/internal/ledger"
+	"github.com/lib/pq"
+)
+
+func init() {
+	register("replay", "", "Parse the archived inputs of elspot imports again and store the prices that changed", func(fs *flag.FlagSet) func([]string) error {
+		connstring := fs.String("connstring", "sslmode=disable", connstringUsage)
+		configFile := fs.String("config", config.DefaultFile, "configuration file with the elspot column mapping")
+		dir := fs.String("archive", "", "the -archive `directory` of import-elspot")
+		since := fs.String("since", "", "replay the inputs of the imports since this month or day, YYYY-MM or YYYY-MM-DD")
+		areaList := fs.String("areas", "FI", "comma-separated `areas` whose price columns of table elspot are corrected")
+		dryRun := fs.Bool("n", false, "count the corrections without storing them")
+		return func(args []string) error {
+			if len(args) != 0 {
+				return errors.New("unexpected arguments")
+			}
+			if *dir == "" {
+				return errors.New("-archive is required")
+			}
+			start, err := parseSince(*since)
+			if err != nil {
+				return fmt.Errorf("-since: %s", err)
+			}
+			cfg, err := config.Load(*configFile)
+			if err != nil {
+				return err
+			}
+			columns, err := replayColumns(*areaList, cfg.Elspot.Columns)
+			if err != nil {
+				return fmt.Errorf("-areas: %s", err)
+			}
+			db, err := sql.Open("postgres", *connstring)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			files, err := ledger.FilesSince(db, ledger.SourceElspot, start)
+			if err != nil {
+				return fmt.Errorf("read imported files: %s", err)
+			}
+			p := elspot.Parser{Columns: cfg.Elspot.Columns, Ignore: cfg.Elspot.Ignore}
+			records, replayed, err := parseArchived(&archive.Archive{Dir: *dir}, p, files)
+			if err != nil {
+				return err
+			}
+			if len(replayed) == 0 {
+				return fmt.Errorf("none of the %d files imported since %s are in the archive", len(files), start.Format("2006-01-02"))
+			}
+			n, err := storeReplay(db, columns, records, replayed, *dryRun)
+			if err != nil {
+				return err
+			}
+			verb := "corrected"
+			if *dryRun {
+				verb = "would be corrected"
+			}
+			fmt.Printf("OK! %d of %d files replayed, %d hours %s\n", len(replayed), len(files), n, verb)
+			return nil
+		}
+	})
+}
+
+// parseSince parses a month YYYY-MM or day YYYY-MM-DD in Finnish time.
+func parseSince(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, errors.New("a month or day is required")
+	}
+	for _, layout := range []string{"2006-01", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, helsinki); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not YYYY-MM or YYYY-MM-DD", s)
+}
+
+// replayColumn is an area of the records and its column of table elspot.
+type replayColumn struct {
+	Area, Column string
+}
+
+var replayColumnName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// replayColumns returns the columns of areas as import-elspot -areas names
+// them: fi for the Finnish price, which the elspot column mapping may
+// rename, and the lowercased area for the others.
+func replayColumns(areas string, mapping map[string]string) ([]replayColumn, error) {
+	main := "FI"
+	for from, to := range mapping {
+		if strings.EqualFold(from, "FI") {
+			main = to
+		}
+	}
+	cols := []replayColumn{{main, "fi"}}
+	for _, area := range strings.Split(areas, ",") {
+		area = strings.TrimSpace(area)
+		if area == "" || area == main || strings.EqualFold(area, "FI") {
+			continue
+		}
+		col := strings.ToLower(area)
+		if !replayColumnName.MatchString(col) {
+			return nil, fmt.Errorf("area %q is not a valid column name", area)
+		}
+		cols = append(cols, replayColumn{area, col})
+	}
+	return cols, nil
+}
+
+// parseArchived parses the archived copies of files with p, merging them
+// so that the later files win as they did when imported. Files missing
+// from the archive, such as those imported before it was set up, are
+// skipped with a log message; replayed are the files parsed.
+func parseArchived(a *archive.Archive, p elspot.Parser, files []ledger.File) (records []elspot.Record, replayed []ledger.File, err error) {
+	var sets [][]elspot.Record
+	for _, f := range files {
+		doc, err := a.Get(f.SHA256)
+		if os.IsNotExist(err) {
+			log.Printf("%s (%s): not archived, skipped", f.Name, f.SHA256[:12])
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		fp := p
+		if loc, _ := elspot.DetectLocation(doc); loc != nil {
+			fp.Location = loc
+		}
+		rs, err := fp.Parse(bytes.NewReader(doc))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s (%s): %s", f.Name, f.SHA256[:12], err)
+		}
+		sets = append(sets, rs)
+		replayed = append(replayed, f)
+	}
+	return elspot.Merge(sets...), replayed, nil
+}
+
+// replaySQL upserts the prices of one hour. Stored prices are replaced
+// only where the new parse differs, and final prices are never replaced
+// by provisional or estimated ones, which newer sources may since have
+// confirmed.
+func replaySQL(columns []replayColumn) string {
+	names := make([]string, len(columns))
+	params := make([]string, len(columns))
+	old := make([]string, len(columns))
+	merged := make([]string, len(columns))
+	set := make([]string, len(columns))
+	for i, c := range columns {
+		col := pq.QuoteIdentifier(c.Column)
+		names[i] = col
+		params[i] = fmt.Sprintf("$%d", i+2)
+		old[i] = "t." + col
+		merged[i] = fmt.Sprintf("COALESCE(EXCLUDED.%[1]s, t.%[1]s)", col)
+		set[i] = col + " = " + merged[i]
+	}
+	return fmt.Sprintf(`INSERT INTO elspot AS t (ts, %s, status) VALUES ($1, %s, $%d)
+    ON CONFLICT (ts) DO UPDATE SET %s, status = EXCLUDED.status
+    WHERE (%s, t.status) IS DISTINCT FROM (%s, EXCLUDED.status)
+    AND NOT (t.status = 'final' AND EXCLUDED.status <> 'final')`,
+		strings.Join(names, ", "), strings.Join(params, ", "), len(columns)+2,
+		strings.Join(set, ", "), strings.Join(old, ", "), strings.Join(merged, ", "))
+}
+
+// storeReplay upserts the records in one transaction recorded in the
+// ledger with the replayed files, returning the hours inserted or changed.
+// A dry run rolls the transaction back.
+func storeReplay(db *sql.DB, columns []replayColumn, records []elspot.Record, files []ledger.File, dryRun bool) (int64, error) {
+	txn, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer txn.Rollback()
+	stmt, err := txn.Prepare(replaySQL(columns))
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+	values := make([]interface{}, len(columns)+2)
+	var total int64
+	for _, r := range records {
+		values[0] = r.Timestamp
+		empty := true
+		for i, c := range columns {
+			values[i+1] = nil
+			if v := r.Prices[c.Area]; v != "" {
+				values[i+1] = v
+				empty = false
+			}
+		}
+		if empty {
+			continue
+		}
+		values[len(values)-1] = elspot.StatusFinal
+		for _, c := range columns {
+			if s := r.Status(c.Area); s != elspot.StatusFinal {
+				values[len(values)-1] = s
+				break
+			}
+		}
+		res, err := stmt.Exec(values...)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %s", r.Timestamp.Format(time.RFC3339), err)
+		}
+		n, _ := res.RowsAffected()
+		total += n
+	}
+	if dryRun {
+		return total, nil
+	}
+	if err := ledger.Record(txn, ledger.SourceReplay, total, files...); err != nil {
+		return 0, err
+	}
+	return total, txn.Commit()
+}
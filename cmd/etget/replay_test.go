@@ -0,0 +1,79 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/joneskoo/etget/elspot"
+	"github.com/joneskoo/etget/internal/archive"
+	"github.com/joneskoo/etget/internal/ledger"
+)
+
+func TestParseArchived(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	a, err := archive.Open(dir, "gzip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := ioutil.ReadFile("../../elspot/testdata/dst-autumn.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := a.Put(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := []ledger.File{
+		{Name: "/data/old.xls", SHA256: strings.Repeat("0", 64)},
+		{Name: "/data/dst-autumn.xls", SHA256: sum},
+	}
+	records, replayed, err := parseArchived(a, elspot.Parser{}, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replayed) != 1 || replayed[0].SHA256 != sum {
+		t.Errorf("replayed %v, want only the archived file", replayed)
+	}
+	want, err := elspot.Parse(strings.NewReader(string(doc)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(want) || len(records) == 0 {
+		t.Errorf("got %d records, want %d", len(records), len(want))
+	}
+}
+
+func TestReplaySQL(t *testing.T) {
+	got := replaySQL([]replayColumn{{"FI", "fi"}, {"SE3", "se3"}})
+	for _, want := range []string{
+		`INSERT INTO elspot AS t (ts, "fi", "se3", status) VALUES ($1, $2, $3, $4)`,
+		`"se3" = COALESCE(EXCLUDED."se3", t."se3")`,
+		`WHERE (t."fi", t."se3", t.status) IS DISTINCT FROM`,
+		`NOT (t.status = 'final' AND EXCLUDED.status <> 'final')`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("replaySQL missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestReplayColumns(t *testing.T) {
+	got, err := replayColumns("FI, SE3,EE", map[string]string{"fi": "finland"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []replayColumn{{"finland", "fi"}, {"SE3", "se3"}, {"EE", "ee"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("replayColumns = %v, want %v", got, want)
+	}
+	if _, err := replayColumns("SE-3", nil); err == nil {
+		t.Error("replayColumns accepted an area that is not a column name")
+	}
+}
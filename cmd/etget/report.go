@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/joneskoo/etget/internal/config"
+)
+
+func init() {
+	register("report", "", "Render yesterday's consumption and today's prices as HTML", func(fs *flag.FlagSet) func([]string) error {
+		connstring := fs.String("connstring", "sslmode=disable", connstringUsage)
+		configFile := fs.String("config", config.DefaultFile, "configuration file with the contract and SMTP settings")
+		meter := fs.String("meter", "default", "metering point")
+		output := fs.String("o", "", "write the report to this file instead of sending it by email")
+		return func(args []string) error {
+			if len(args) != 0 {
+				return errors.New("unexpected arguments")
+			}
+			cfg, err := config.Load(*configFile)
+			if err != nil {
+				return err
+			}
+			if *output == "" && cfg.Report.SMTP.Addr == "" {
+				return errors.New("no report.smtp.addr in configuration; use -o to write a file")
+			}
+			db, err := sql.Open("postgres", *connstring)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			now := time.Now().In(helsinki)
+			today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, helsinki)
+			yesterday := today.AddDate(0, 0, -1)
+			hours, err := queryHours(db, *meter, yesterday, today)
+			if err != nil {
+				return err
+			}
+			prices, err := queryPrices(db, today, today.AddDate(0, 0, 1))
+			if err != nil {
+				return err
+			}
+			r := buildReport(cfg.Contract, yesterday, hours, prices)
+			var body bytes.Buffer
+			if err = reportTemplate.Execute(&body, r); err != nil {
+				return err
+			}
+			if *output != "" {
+				return ioutil.WriteFile(*output, body.Bytes(), 0644)
+			}
+			subject := fmt.Sprintf("Electricity %s: %.1f kWh, %.2f EUR", yesterday.Format("2006-01-02"), r.KWh, r.Total)
+			return sendMail(cfg.Report.SMTP, subject, body.Bytes())
+		}
+	})
+}
+
+// price is the spot price of an hour in EUR/MWh without VAT.
+type price struct {
+	Timestamp time.Time
+	Spot      float64
+}
+
+func queryPrices(db *sql.DB, start, end time.Time) (prices []price, err error) {
+	rows, err := db.Query("SELECT ts, fi FROM elspot WHERE ts >= $1 AND ts < $2 AND fi IS NOT NULL ORDER BY ts", start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p price
+		if err = rows.Scan(&p.Timestamp, &p.Spot); err != nil {
+			return nil, err
+		}
+		prices = append(prices, p)
+	}
+	return prices, rows.Err()
+}
+
+// report is the data of the report template.
+type report struct {
+	Day   time.Time
+	Hours []reportHour
+
+	KWh, Energy, Transfer, Total float64
+
+	// SoldKWh and Sold are the production sold and its credit.
+	SoldKWh, Sold float64
+
+	// Prices is today's price curve. MaxSpot scales its bars.
+	Prices  []price
+	MaxSpot float64
+}
+
+type reportHour struct {
+	hour
+	Cost float64
+}
+
+// buildReport computes the totals of the day's hours. Monthly fees are
+// not included.
+func buildReport(c config.Contract, day time.Time, hours []hour, prices []price) report {
+	r := report{Day: day, Prices: prices}
+	for _, h := range hours {
+		hc := hourCost(c, h)
+		r.Hours = append(r.Hours, reportHour{hour: h, Cost: hc.Net()})
+		r.KWh += h.KWh
+		r.Energy += hc.Energy
+		r.Transfer += hc.Transfer
+		r.SoldKWh += h.SoldKWh
+		r.Sold += hc.Sold
+	}
+	r.Total = r.Energy + r.Transfer - r.Sold
+	for _, p := range prices {
+		if p.Spot > r.MaxSpot {
+			r.MaxSpot = p.Spot
+		}
+	}
+	return r
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"hour": func(t time.Time) string { return t.In(helsinki).Format("15:04") },
+	"bar": func(v, max float64) int {
+		if max <= 0 || v <= 0 {
+			return 0
+		}
+		return int(v / max * 200)
+	},
+}).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Electricity {{.Day.Format "2006-01-02"}}</title></head>
+<body style="font-family: sans-serif">
+<h1>Electricity {{.Day.Format "2006-01-02"}}</h1>
+<p>{{printf "%.1f" .KWh}} kWh: energy {{printf "%.2f" .Energy}} EUR + transfer {{printf "%.2f" .Transfer}} EUR{{if .SoldKWh}} - sold {{printf "%.1f" .SoldKWh}} kWh {{printf "%.2f" .Sold}} EUR{{end}} = <b>{{printf "%.2f" .Total}} EUR</b></p>
+<table>
+<tr><th>Hour</th><th>kWh</th><th>EUR/MWh</th><th>EUR</th></tr>
+{{range .Hours}}<tr><td>{{hour .Timestamp}}</td><td>{{printf "%.3f" .KWh}}</td><td>{{printf "%.2f" .Spot}}</td><td>{{printf "%.4f" .Cost}}</td></tr>
+{{end}}</table>
+<h2>Today's spot prices</h2>
+{{if .Prices}}<table>
+{{$max := .MaxSpot}}{{range .Prices}}<tr><td>{{hour .Timestamp}}</td><td>{{printf "%.2f" .Spot}}</td><td><div style="background: #48c; height: 1em; width: {{bar .Spot $max}}px"></div></td></tr>
+{{end}}</table>{{else}}<p>Not published yet.</p>{{end}}
+</body></html>
+`))
+
+// sendMail sends the HTML body to the recipients of s.
+func sendMail(s config.SMTP, subject string, body []byte) error {
+	if s.From == "" || len(s.To) == 0 {
+		return errors.New("report.smtp needs from and to addresses")
+	}
+	var auth smtp.Auth
+	if s.Username != "" {
+		host, _, err := net.SplitHostPort(s.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+	return smtp.SendMail(s.Addr, auth, s.From, s.To, mailMessage(s, subject, body))
+}
+
+// mailMessage formats an RFC 5322 message with an HTML body.
+func mailMessage(s config.SMTP, subject string, body []byte) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", s.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(s.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/html; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.Write(body)
+	return b.Bytes()
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joneskoo/etget/internal/config"
+)
+
+func TestReport(t *testing.T) {
+	day := time.Date(2019, 3, 1, 0, 0, 0, 0, helsinki)
+	c := config.Contract{VAT: 0.24, Transfer: config.Transfer{DayPerKWh: 0.05, NightPerKWh: 0.05}}
+	hours := []hour{
+		{Timestamp: day, KWh: 1, Spot: 50},
+		{Timestamp: day.Add(time.Hour), KWh: 2, Spot: 100},
+	}
+	prices := []price{{day.AddDate(0, 0, 1), 40}, {day.AddDate(0, 0, 1).Add(time.Hour), 80}}
+	r := buildReport(c, day, hours, prices)
+	// energy 1*0.062 + 2*0.124, transfer 3*0.05
+	if r.KWh != 3 || math.Abs(r.Energy-0.31) > 1e-9 || math.Abs(r.Transfer-0.15) > 1e-9 || math.Abs(r.Total-0.46) > 1e-9 {
+		t.Errorf("buildReport totals = %.3f kWh %.4f + %.4f = %.4f, want 3 kWh 0.31 + 0.15 = 0.46", r.KWh, r.Energy, r.Transfer, r.Total)
+	}
+	if r.MaxSpot != 80 {
+		t.Errorf("MaxSpot = %v, want 80", r.MaxSpot)
+	}
+
+	var b bytes.Buffer
+	if err := reportTemplate.Execute(&b, r); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Electricity 2019-03-01", "<b>0.46 EUR</b>", "width: 100px", "width: 200px"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("report does not contain %q:\n%s", want, b.String())
+		}
+	}
+}
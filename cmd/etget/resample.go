@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joneskoo/etget/resample"
+)
+
+func init() {
+	register("resample", "FILE", "Convert a CSV time series between resolutions, e.g. 15-minute and hourly", func(fs *flag.FlagSet) func([]string) error {
+		from := fs.Duration("from", 15*time.Minute, "resolution of the input")
+		to := fs.Duration("to", time.Hour, "resolution of the output")
+		method := fs.String("method", "mean", "mean for prices and other rates, sum for energy and other quantities")
+		output := fs.String("o", "-", "output file, - for standard output")
+		return func(args []string) error {
+			if len(args) != 1 {
+				return errors.New("want exactly one FILE argument, - for standard input")
+			}
+			m, err := resample.ParseMethod(*method)
+			if err != nil {
+				return fmt.Errorf("-method: %s", err)
+			}
+			r := os.Stdin
+			if args[0] != "-" {
+				if r, err = os.Open(args[0]); err != nil {
+					return err
+				}
+				defer r.Close()
+			}
+			w := os.Stdout
+			if *output != "-" {
+				if w, err = os.Create(*output); err != nil {
+					return err
+				}
+			}
+			if err = resampleCSV(w, r, *from, *to, m); err != nil {
+				return err
+			}
+			return w.Close()
+		}
+	})
+}
+
+// resampleCSV converts the CSV series read from r. The first column is
+// an RFC 3339 timestamp and the others are values, each converted on its
+// own; empty fields are missing values. The output has the header of the
+// input and timestamps in UTC.
+func resampleCSV(w io.Writer, r io.Reader, from, to time.Duration, m resample.Method) error {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("reading header: %s", err)
+	}
+	if len(header) < 2 {
+		return errors.New("want a timestamp column and at least one value column")
+	}
+	columns := make([][]resample.Point, len(header)-1)
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		ts, err := time.Parse(time.RFC3339, strings.TrimSpace(record[0]))
+		if err != nil {
+			return fmt.Errorf("line %d: %s", line, err)
+		}
+		for i, field := range record[1:] {
+			if field = strings.TrimSpace(field); field == "" {
+				continue
+			}
+			v, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return fmt.Errorf("line %d: %s: %s", line, header[i+1], err)
+			}
+			columns[i] = append(columns[i], resample.Point{Time: ts, Value: v})
+		}
+	}
+
+	rows := make(map[int64][]string)
+	var times []time.Time
+	for i, points := range columns {
+		converted, err := resample.Convert(points, from, to, m)
+		if err != nil {
+			return fmt.Errorf("%s: %s", header[i+1], err)
+		}
+		for _, p := range converted {
+			row, ok := rows[p.Time.Unix()]
+			if !ok {
+				row = make([]string, len(header))
+				row[0] = p.Time.UTC().Format(time.RFC3339)
+				rows[p.Time.Unix()] = row
+				times = append(times, p.Time)
+			}
+			row[i+1] = strconv.FormatFloat(p.Value, 'g', -1, 64)
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	cw := csv.NewWriter(w)
+	cw.Write(header)
+	for _, ts := range times {
+		cw.Write(rows[ts.Unix()])
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joneskoo/etget/resample"
+)
+
+func TestResampleCSV(t *testing.T) {
+	in := `ts,fi,ee
+2025-10-01T03:00:00+03:00,1,4
+2025-10-01T00:15:00Z,2,
+2025-10-01T00:30:00Z,3,4
+2025-10-01T00:45:00Z,6,4
+2025-10-01T01:00:00Z,5,
+`
+	var out bytes.Buffer
+	if err := resampleCSV(&out, strings.NewReader(in), 15*time.Minute, time.Hour, resample.Mean); err != nil {
+		t.Fatal(err)
+	}
+	// ee misses a quarter of the first hour, and the second hour is
+	// incomplete in both columns.
+	want := "ts,fi,ee\n2025-10-01T00:00:00Z,3,\n"
+	if out.String() != want {
+		t.Errorf("resampleCSV = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	if err := resampleCSV(&out, strings.NewReader("ts,kwh\n2025-10-01T00:00:00Z,2\n"), time.Hour, 30*time.Minute, resample.Sum); err != nil {
+		t.Fatal(err)
+	}
+	if want := "ts,kwh\n2025-10-01T00:00:00Z,1\n2025-10-01T00:30:00Z,1\n"; out.String() != want {
+		t.Errorf("resampleCSV split = %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,447 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func init() {
+	register("schema", "", "Print table DDL for an analytics warehouse", func(fs *flag.FlagSet) func([]string) error {
+		dialect := fs.String("dialect", "duckdb", "SQL dialect: bigquery, snowflake or duckdb")
+		dataset := fs.String("dataset", "", "dataset or schema to qualify table names with")
+		connstring := fs.String("connstring", "", "read the columns from this database, including those added by import options such as import-elspot -areas, instead of using the built-in list")
+		return func(args []string) error {
+			if len(args) != 0 {
+				return errors.New("unexpected arguments")
+			}
+			ts := tables
+			if *connstring != "" {
+				db, err := sql.Open("postgres", *connstring)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+				ts = make([]table, len(tables))
+				for i, t := range tables {
+					if ts[i], err = liveTable(db, t); err != nil {
+						return fmt.Errorf("%s: %s", t.Name, err)
+					}
+				}
+			}
+			return writeSchema(os.Stdout, *dialect, *dataset, ts)
+		}
+	})
+	register("export", "TABLE", "Export a table as CSV for loading into a warehouse", func(fs *flag.FlagSet) func([]string) error {
+		connstring := fs.String("connstring", "sslmode=disable", connstringUsage)
+		output := fs.String("o", "-", "output file, - for standard output")
+		metadata := fs.Bool("metadata", false, "start the file with # comment lines describing the data: currency, unit, resolution, source and generation time")
+		anonymize := fs.Bool("anonymize", false, "for sharing consumption: rename metering points meter1, meter2…, empty the temperature and move all timestamps back by the same random number of weeks")
+		return func(args []string) error {
+			if len(args) != 1 {
+				return errors.New("want exactly one TABLE argument")
+			}
+			t, ok := lookupTable(args[0])
+			if !ok {
+				return fmt.Errorf("unknown table %q", args[0])
+			}
+			var anon *anonymizer
+			if *anonymize {
+				if !t.Personal {
+					return fmt.Errorf("-anonymize: table %s holds no personal data", t.Name)
+				}
+				a, err := newAnonymizer()
+				if err != nil {
+					return err
+				}
+				anon = a
+			}
+			db, err := sql.Open("postgres", *connstring)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			if t, err = liveTable(db, t); err != nil {
+				return err
+			}
+			w := os.Stdout
+			if *output != "-" {
+				if w, err = os.Create(*output); err != nil {
+					return err
+				}
+			}
+			if *metadata {
+				if err = writeMetadata(w, t, time.Now()); err != nil {
+					return err
+				}
+			}
+			if err = exportCSV(w, db, t, rowFilter{}, anon); err != nil {
+				return err
+			}
+			return w.Close()
+		}
+	})
+}
+
+// columnType is the portable type of a column.
+type columnType int
+
+const (
+	typeTimestamp columnType = iota
+	typeInteger
+	typeReal
+	typeDouble
+	typeText
+	typeBoolean
+)
+
+type column struct {
+	Name string
+	Type columnType
+}
+
+type table struct {
+	Name    string
+	Columns []column
+
+	// Series is the column separating independent time series in the
+	// table, or empty if the table holds a single series.
+	Series string
+
+	// HasID is set if the table has a serial id column.
+	HasID bool
+
+	// Personal is set if the table holds data of a household, which
+	// export -anonymize can hide. Private are its columns that could
+	// still locate the household, emptied by -anonymize.
+	Personal bool
+	Private  []string
+
+	Meta metadata
+}
+
+// metadata describes the values of a table for the export -metadata
+// header. Empty fields are left out.
+type metadata struct {
+	Currency   string
+	Unit       string
+	Resolution string // ISO 8601 duration
+	Source     string
+}
+
+// tables describes the tables created by the importers, excluding the
+// Postgres-only surrogate keys. The columns are those every database has;
+// liveTable adds those of import options such as import-elspot -areas.
+var tables = []table{
+	{
+		Name:    "elspot",
+		Columns: []column{{"ts", typeTimestamp}, {"fi", typeReal}, {"status", typeText}},
+		HasID:   true,
+		Meta:    metadata{"EUR", "EUR/MWh", "PT1H", "Nord Pool day-ahead (elspot)"},
+	},
+	{
+		Name:    "elspot_area",
+		Columns: []column{{"area", typeText}, {"ts", typeTimestamp}, {"price", typeReal}, {"status", typeText}},
+		Series:  "area",
+		Meta:    metadata{"EUR", "EUR/MWh", "PT1H", "Nord Pool day-ahead (elspot)"},
+	},
+	{
+		Name:    "intraday",
+		Columns: []column{{"area", typeText}, {"ts", typeTimestamp}, {"vwap", typeDouble}, {"volume", typeDouble}, {"trades", typeInteger}},
+		Series:  "area",
+		Meta:    metadata{"EUR", "EUR/MWh (vwap), MW (volume)", "PT1H", "Nord Pool intraday (Elbas)"},
+	},
+	{
+		Name:    "elspot_capacity",
+		Columns: []column{{"ts", typeTimestamp}, {"from_area", typeText}, {"to_area", typeText}, {"mw", typeDouble}, {"status", typeText}},
+		Meta:    metadata{Unit: "MW", Resolution: "PT1H", Source: "Nord Pool day-ahead capacities"},
+	},
+	{
+		Name:    "elspot_flow",
+		Columns: []column{{"ts", typeTimestamp}, {"from_area", typeText}, {"to_area", typeText}, {"mw", typeDouble}, {"status", typeText}},
+		Meta:    metadata{Unit: "MW", Resolution: "PT1H", Source: "Nord Pool physical flows"},
+	},
+	{
+		Name:    "timeseries",
+		Columns: []column{{"source", typeText}, {"series", typeText}, {"ts", typeTimestamp}, {"value", typeDouble}},
+		Series:  "series",
+		Meta:    metadata{Unit: "MW", Source: "Fingrid open data"},
+	},
+	{
+		Name:    "energiatili",
+		Columns: []column{{"meter_id", typeText}, {"ts", typeTimestamp}, {"kwh", typeDouble}, {"temp", typeReal}, {"produced_kwh", typeDouble}, {"estimated", typeBoolean}},
+		Series:  "meter_id",
+		HasID:   true,
+		// The outdoor temperature would date and place the rows.
+		Personal: true,
+		Private:  []string{"temp"},
+		Meta:     metadata{Unit: "kWh", Resolution: "PT1H", Source: "energiatili.fi"},
+	},
+}
+
+func lookupTable(name string) (table, bool) {
+	for _, t := range tables {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return table{}, false
+}
+
+// columnsSQL lists the columns of table $1 of the current schema.
+const columnsSQL = `SELECT column_name, data_type FROM information_schema.columns
+    WHERE table_schema = current_schema() AND table_name = $1
+    ORDER BY ordinal_position`
+
+// liveTable returns t with the columns its table has in db, in their
+// order and with their current types: the import options add columns,
+// e.g. import-elspot -areas, -record-corrections and -storage, which the
+// built-in list cannot know. The surrogate id is left out. A table db
+// does not have is returned as is.
+func liveTable(db *sql.DB, t table) (table, error) {
+	rows, err := db.Query(columnsSQL, t.Name)
+	if err != nil {
+		return t, fmt.Errorf("read columns: %s", err)
+	}
+	defer rows.Close()
+	var columns []column
+	for rows.Next() {
+		var name, dataType string
+		if err := rows.Scan(&name, &dataType); err != nil {
+			return t, err
+		}
+		if t.HasID && name == "id" {
+			continue
+		}
+		columns = append(columns, column{name, postgresType(dataType)})
+	}
+	if err := rows.Err(); err != nil {
+		return t, err
+	}
+	if len(columns) > 0 {
+		t.Columns = columns
+	}
+	return t, nil
+}
+
+// postgresType returns the portable type of a column of data type
+// dataType as information_schema names it. Unknown types are exported as
+// text.
+func postgresType(dataType string) columnType {
+	switch dataType {
+	case "timestamp with time zone", "timestamp without time zone":
+		return typeTimestamp
+	case "smallint", "integer", "bigint":
+		return typeInteger
+	case "real":
+		return typeReal
+	case "double precision", "numeric":
+		return typeDouble
+	case "boolean":
+		return typeBoolean
+	}
+	return typeText
+}
+
+var dialectTypes = map[string]map[columnType]string{
+	"bigquery": {
+		typeTimestamp: "TIMESTAMP",
+		typeInteger:   "INT64",
+		typeReal:      "FLOAT64",
+		typeDouble:    "FLOAT64",
+		typeText:      "STRING",
+		typeBoolean:   "BOOL",
+	},
+	"snowflake": {
+		typeTimestamp: "TIMESTAMP_TZ",
+		typeInteger:   "NUMBER",
+		typeReal:      "FLOAT",
+		typeDouble:    "FLOAT",
+		typeText:      "VARCHAR",
+		typeBoolean:   "BOOLEAN",
+	},
+	"duckdb": {
+		typeTimestamp: "TIMESTAMPTZ",
+		typeInteger:   "BIGINT",
+		typeReal:      "REAL",
+		typeDouble:    "DOUBLE",
+		typeText:      "VARCHAR",
+		typeBoolean:   "BOOLEAN",
+	},
+}
+
+// writeSchema writes CREATE TABLE statements for ts in dialect.
+func writeSchema(w io.Writer, dialect, dataset string, ts []table) error {
+	types, ok := dialectTypes[dialect]
+	if !ok {
+		return fmt.Errorf("unknown dialect %q", dialect)
+	}
+	for i, t := range ts {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		name := t.Name
+		if dataset != "" {
+			name = dataset + "." + name
+		}
+		fmt.Fprintf(w, "CREATE TABLE IF NOT EXISTS %s (\n", name)
+		for j, c := range t.Columns {
+			sep := ","
+			if j == len(t.Columns)-1 {
+				sep = ""
+			}
+			fmt.Fprintf(w, "    %s %s%s\n", c.Name, types[c.Type], sep)
+		}
+		fmt.Fprint(w, ")")
+		if dialect == "bigquery" {
+			fmt.Fprint(w, "\nPARTITION BY DATE(ts)")
+		}
+		fmt.Fprintln(w, ";")
+	}
+	return nil
+}
+
+// writeMetadata writes the metadata of t as # comment lines. Most CSV
+// readers skip them with an option, e.g. comment="#" in pandas.
+func writeMetadata(w io.Writer, t table, now time.Time) error {
+	fields := []struct{ key, value string }{
+		{"table", t.Name},
+		{"currency", t.Meta.Currency},
+		{"unit", t.Meta.Unit},
+		{"resolution", t.Meta.Resolution},
+		{"source", t.Meta.Source},
+		{"generated", now.UTC().Format(time.RFC3339)},
+	}
+	for _, f := range fields {
+		if f.value == "" {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "# %s: %s\n", f.key, f.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rowFilter selects the rows of a table to export. The zero value selects
+// all rows.
+type rowFilter struct {
+	// Series, if set, is the value of the series column of the rows.
+	Series string
+
+	// After, if set, selects the rows after it.
+	After time.Time
+}
+
+// where returns the WHERE clause of f for t and its arguments.
+func (f rowFilter) where(t table) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	if !f.After.IsZero() {
+		args = append(args, f.After)
+		conds = append(conds, fmt.Sprintf("ts > $%d", len(args)))
+	}
+	if f.Series != "" && t.Series != "" {
+		args = append(args, f.Series)
+		conds = append(conds, fmt.Sprintf("%s = $%d", pq.QuoteIdentifier(t.Series), len(args)))
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// exportCSV writes the rows of t selected by f as CSV with a header row,
+// anonymized by anon unless it is nil. Timestamps are RFC 3339 in UTC and
+// NULLs are empty fields, which all supported warehouses load without
+// options.
+func exportCSV(w io.Writer, db *sql.DB, t table, f rowFilter, anon *anonymizer) error {
+	names := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		names[i] = pq.QuoteIdentifier(c.Name)
+	}
+	where, args := f.where(t)
+	rows, err := db.Query(fmt.Sprintf("SELECT %s FROM %s%s ORDER BY ts", strings.Join(names, ", "), pq.QuoteIdentifier(t.Name), where), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	cw := csv.NewWriter(w)
+	header := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		header[i] = c.Name
+	}
+	cw.Write(header)
+
+	values := make([]interface{}, len(t.Columns))
+	for i, c := range t.Columns {
+		switch c.Type {
+		case typeTimestamp:
+			values[i] = new(pq.NullTime)
+		case typeInteger:
+			values[i] = new(sql.NullInt64)
+		case typeReal, typeDouble:
+			values[i] = new(sql.NullFloat64)
+		case typeBoolean:
+			values[i] = new(sql.NullBool)
+		default:
+			values[i] = new(sql.NullString)
+		}
+	}
+	record := make([]string, len(t.Columns))
+	for rows.Next() {
+		if err = rows.Scan(values...); err != nil {
+			return err
+		}
+		if anon != nil {
+			anon.apply(t, values)
+		}
+		for i, v := range values {
+			record[i] = formatValue(v)
+		}
+		if err = cw.Write(record); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case *pq.NullTime:
+		if v.Valid {
+			return v.Time.UTC().Format(time.RFC3339)
+		}
+	case *sql.NullInt64:
+		if v.Valid {
+			return strconv.FormatInt(v.Int64, 10)
+		}
+	case *sql.NullFloat64:
+		if v.Valid {
+			return strconv.FormatFloat(v.Float64, 'g', -1, 64)
+		}
+	case *sql.NullBool:
+		if v.Valid {
+			return strconv.FormatBool(v.Bool)
+		}
+	case *sql.NullString:
+		if v.Valid {
+			return v.String
+		}
+	}
+	return ""
+}
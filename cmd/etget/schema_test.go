@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteSchema(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeSchema(&buf, "bigquery", "energy", tables); err != nil {
+		t.Fatal(err)
+	}
+	want := `CREATE TABLE IF NOT EXISTS energy.elspot (
+    ts TIMESTAMP,
+    fi FLOAT64,
+    status STRING
+)
+PARTITION BY DATE(ts);
+`
+	if !strings.HasPrefix(buf.String(), want) {
+		t.Errorf("writeSchema(bigquery) = %s, want prefix %s", buf.String(), want)
+	}
+
+	if err := writeSchema(&buf, "oracle", "", tables); err == nil {
+		t.Error("writeSchema(oracle) did not return error for unknown dialect")
+	}
+}
+
+func TestPostgresType(t *testing.T) {
+	tests := map[string]columnType{
+		"timestamp with time zone": typeTimestamp,
+		"integer":                  typeInteger,
+		"real":                     typeReal,
+		"numeric":                  typeDouble,
+		"double precision":         typeDouble,
+		"boolean":                  typeBoolean,
+		"text":                     typeText,
+		"character varying":        typeText,
+	}
+	for dataType, want := range tests {
+		if got := postgresType(dataType); got != want {
+			t.Errorf("postgresType(%q) = %d, want %d", dataType, got, want)
+		}
+	}
+}
+
+func TestWriteMetadata(t *testing.T) {
+	var buf bytes.Buffer
+	tbl, _ := lookupTable("energiatili")
+	now := time.Date(2025, 1, 10, 12, 0, 0, 0, helsinki)
+	if err := writeMetadata(&buf, tbl, now); err != nil {
+		t.Fatal(err)
+	}
+	want := `# table: energiatili
+# unit: kWh
+# resolution: PT1H
+# source: energiatili.fi
+# generated: 2025-01-10T10:00:00Z
+`
+	if buf.String() != want {
+		t.Errorf("writeMetadata = %s, want %s", buf.String(), want)
+	}
+}
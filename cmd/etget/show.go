@@ -0,0 +1,98 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+func init() {
+	register("show", "", "Show the spot prices of a day as a colored chart", func(fs *flag.FlagSet) func([]string) error {
+		connstring := fs.String("connstring", "sslmode=disable", connstringUsage)
+		day := fs.String("day", "", "day to show, YYYY-MM-DD (default today)")
+		color := fs.Bool("color", terminal.IsTerminal(int(os.Stdout.Fd())), "color the bars by price (default if the output is a terminal)")
+		return func(args []string) error {
+			if len(args) != 0 {
+				return errors.New("unexpected arguments")
+			}
+			now := time.Now().In(helsinki)
+			start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, helsinki)
+			if *day != "" {
+				var err error
+				if start, err = time.ParseInLocation("2006-01-02", *day, helsinki); err != nil {
+					return fmt.Errorf("-day: %s", err)
+				}
+			}
+			db, err := sql.Open("postgres", *connstring)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			prices, err := queryPrices(db, start, start.AddDate(0, 0, 1))
+			if err != nil {
+				return err
+			}
+			if len(prices) == 0 {
+				return fmt.Errorf("no prices for %s", start.Format("2006-01-02"))
+			}
+			return writeChart(os.Stdout, prices, now, *color)
+		}
+	})
+}
+
+// heatColors are the xterm 256-color codes from cheap (green) to
+// expensive (red).
+var heatColors = []int{46, 82, 118, 154, 190, 226, 220, 214, 208, 202, 196}
+
+// chartWidth is the width of the bar of the most expensive hour.
+const chartWidth = 40
+
+// writeChart writes a bar per hour of prices, scaled and, with color,
+// colored between the cheapest and the most expensive hour of the day.
+// The hour containing now is marked.
+func writeChart(w io.Writer, prices []price, now time.Time, color bool) error {
+	lo, hi := prices[0].Spot, prices[0].Spot
+	for _, p := range prices {
+		if p.Spot < lo {
+			lo = p.Spot
+		}
+		if p.Spot > hi {
+			hi = p.Spot
+		}
+	}
+	// Bars start from zero unless prices go negative.
+	base := lo
+	if base > 0 {
+		base = 0
+	}
+	for _, p := range prices {
+		n := 0
+		if hi > base {
+			n = int((p.Spot-base)/(hi-base)*chartWidth + 0.5)
+		}
+		bar := strings.Repeat("█", n) + strings.Repeat(" ", chartWidth-n)
+		if color {
+			level := 0
+			if hi > lo {
+				level = int((p.Spot - lo) / (hi - lo) * float64(len(heatColors)-1))
+			}
+			bar = fmt.Sprintf("\x1b[38;5;%dm%s\x1b[0m", heatColors[level], bar)
+		}
+		mark := ""
+		if !now.Before(p.Timestamp) && now.Before(p.Timestamp.Add(time.Hour)) {
+			mark = " ◀ now"
+		}
+		if _, err := fmt.Fprintf(w, "%s %s %7.2f%s\n", p.Timestamp.In(helsinki).Format("15:04"), bar, p.Spot, mark); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "min %.2f, max %.2f EUR/MWh\n", lo, hi)
+	return err
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteChart(t *testing.T) {
+	day := time.Date(2025, 1, 10, 0, 0, 0, 0, helsinki)
+	prices := []price{
+		{day, 10},
+		{day.Add(time.Hour), 40},
+		{day.Add(2 * time.Hour), -5},
+	}
+	var buf bytes.Buffer
+	if err := writeChart(&buf, prices, day.Add(90*time.Minute), false); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("writeChart wrote %d lines, want 4:\n%s", len(lines), buf.String())
+	}
+	for i, want := range []int{13, 40, 0} {
+		if got := strings.Count(lines[i], "█"); got != want {
+			t.Errorf("line %d: bar of %d, want %d: %q", i, got, want, lines[i])
+		}
+	}
+	if !strings.HasSuffix(lines[1], "◀ now") || strings.Contains(lines[0], "now") {
+		t.Errorf("current hour not marked on line 1:\n%s", buf.String())
+	}
+	if want := "min -5.00, max 40.00 EUR/MWh"; lines[3] != want {
+		t.Errorf("summary = %q, want %q", lines[3], want)
+	}
+
+	buf.Reset()
+	writeChart(&buf, prices, day, true)
+	if !strings.Contains(buf.String(), "\x1b[38;5;196m") || !strings.Contains(buf.String(), "\x1b[38;5;46m") {
+		t.Errorf("colored chart lacks the cheapest and most expensive colors:\n%q", buf.String())
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joneskoo/etget/internal/config"
+)
+
+func init() {
+	register("simulate", "", "Estimate savings from shifting flexible load to the cheapest hours", func(fs *flag.FlagSet) func([]string) error {
+		connstring := fs.String("connstring", "sslmode=disable", connstringUsage)
+		configFile := fs.String("config", config.DefaultFile, "configuration file with the contract")
+		meter := fs.String("meter", "default", "metering point")
+		from := fs.String("from", "", "first day, YYYY-MM-DD (default first day of this month)")
+		to := fs.String("to", "", "day after the last day, YYYY-MM-DD (default tomorrow)")
+		shiftable := fs.String("shiftable", "", "flexible consumption per day, e.g. 3kWh")
+		window := fs.String("window", "22:00-07:00", "local hours the flexible load can run in")
+		return func(args []string) error {
+			if len(args) != 0 {
+				return errors.New("unexpected arguments")
+			}
+			kwh, err := parseKWh(*shiftable)
+			if err != nil {
+				return fmt.Errorf("-shiftable: %s", err)
+			}
+			w, err := parseWindow(*window)
+			if err != nil {
+				return fmt.Errorf("-window: %s", err)
+			}
+			cfg, err := config.Load(*configFile)
+			if err != nil {
+				return err
+			}
+			start, end, err := parsePeriod(*from, *to, time.Now())
+			if err != nil {
+				return err
+			}
+			db, err := sql.Open("postgres", *connstring)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			// The window of the last day may extend to the next morning.
+			hours, err := queryHours(db, *meter, start, end.AddDate(0, 0, 1))
+			if err != nil {
+				return err
+			}
+			var total float64
+			fmt.Printf("%-7s %5s %10s %10s\n", "month", "days", "kWh", "saved EUR")
+			for _, m := range simulateMonths(cfg.Contract, hours, kwh, w, end) {
+				fmt.Printf("%-7s %5d %10.1f %10.2f\n", m.Month.Format("2006-01"), m.Days, m.KWh, m.Saved)
+				total += m.Saved
+			}
+			fmt.Printf("%-7s %5s %10s %10.2f\n", "total", "", "", total)
+			return nil
+		}
+	})
+}
+
+// parseKWh parses an amount of energy with an optional kWh suffix.
+func parseKWh(s string) (float64, error) {
+	if s == "" {
+		return 0, errors.New("amount is required")
+	}
+	v, err := strconv.ParseFloat(strings.TrimSuffix(s, "kWh"), 64)
+	if err != nil {
+		return 0, err
+	}
+	if v <= 0 {
+		return 0, errors.New("amount must be positive")
+	}
+	return v, nil
+}
+
+// hourWindow is a range of local hours [Start, End). If Start > End the
+// window spans midnight.
+type hourWindow struct {
+	Start, End int
+}
+
+// parseWindow parses a window of whole hours such as "22:00-07:00".
+func parseWindow(s string) (w hourWindow, err error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return w, errors.New("want HH:00-HH:00")
+	}
+	var h [2]int
+	for i, p := range parts {
+		t, err := time.Parse("15:04", strings.TrimSpace(p))
+		if err != nil {
+			return w, err
+		}
+		if t.Minute() != 0 {
+			return w, fmt.Errorf("%s: prices are hourly, want whole hours", p)
+		}
+		h[i] = t.Hour()
+	}
+	if h[0] == h[1] {
+		return w, errors.New("empty window")
+	}
+	return hourWindow{h[0], h[1]}, nil
+}
+
+// day returns the local day whose window contains the hour starting at
+// ts, and false if ts is outside the window. For a window spanning
+// midnight the morning hours belong to the previous day.
+func (w hourWindow) day(ts time.Time) (time.Time, bool) {
+	local := ts.In(helsinki)
+	h := local.Hour()
+	d := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, helsinki)
+	switch {
+	case w.Start < w.End:
+		return d, h >= w.Start && h < w.End
+	case h >= w.Start:
+		return d, true
+	case h < w.End:
+		return d.AddDate(0, 0, -1), true
+	}
+	return d, false
+}
+
+// monthSavings is the estimated saving of one month.
+type monthSavings struct {
+	Month time.Time
+	Days  int
+	KWh   float64
+	Saved float64
+}
+
+// simulateMonths estimates the saving of moving up to shiftable kWh of
+// each day's consumption from the day's average cost to the cheapest hour
+// of the window following it. Load is only moved when that is cheaper.
+// Days starting at or after end are left out.
+func simulateMonths(c config.Contract, hours []hour, shiftable float64, w hourWindow, end time.Time) (months []monthSavings) {
+	type dayCost struct {
+		kwh, cost float64
+		cheapest  float64
+		hasWindow bool
+	}
+	days := make(map[time.Time]*dayCost)
+	var order []time.Time
+	get := func(d time.Time) *dayCost {
+		dc, ok := days[d]
+		if !ok {
+			dc = &dayCost{}
+			days[d] = dc
+			order = append(order, d)
+		}
+		return dc
+	}
+	for _, h := range hours {
+		local := h.Timestamp.In(helsinki)
+		d := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, helsinki)
+		dc := get(d)
+		hc := hourCost(c, h)
+		dc.kwh += h.KWh
+		dc.cost += hc.Energy + hc.Transfer
+
+		if wd, ok := w.day(h.Timestamp); ok {
+			one := hourCost(c, hour{Timestamp: h.Timestamp, KWh: 1, Spot: h.Spot})
+			wc := get(wd)
+			if perKWh := one.Energy + one.Transfer; !wc.hasWindow || perKWh < wc.cheapest {
+				wc.cheapest = perKWh
+				wc.hasWindow = true
+			}
+		}
+	}
+
+	for _, d := range order {
+		dc := days[d]
+		if !d.Before(end) || dc.kwh <= 0 || !dc.hasWindow {
+			continue
+		}
+		kwh := shiftable
+		if kwh > dc.kwh {
+			kwh = dc.kwh
+		}
+		saved := kwh * (dc.cost/dc.kwh - dc.cheapest)
+		if saved < 0 {
+			saved = 0
+		}
+		month := time.Date(d.Year(), d.Month(), 1, 0, 0, 0, 0, helsinki)
+		if len(months) == 0 || !months[len(months)-1].Month.Equal(month) {
+			months = append(months, monthSavings{Month: month})
+		}
+		m := &months[len(months)-1]
+		m.Days++
+		m.KWh += kwh
+		m.Saved += saved
+	}
+	return months
+}
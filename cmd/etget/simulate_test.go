@@ -0,0 +1,53 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/joneskoo/etget/internal/config"
+)
+
+func TestParseWindow(t *testing.T) {
+	cases := []struct {
+		in   string
+		want hourWindow
+	}{
+		{"22:00-07:00", hourWindow{22, 7}},
+		{"1:00-6:00", hourWindow{1, 6}},
+	}
+	for _, c := range cases {
+		got, err := parseWindow(c.in)
+		if err != nil || got != c.want {
+			t.Errorf("parseWindow(%q) = %v, %v, want %v", c.in, got, err, c.want)
+		}
+	}
+	for _, in := range []string{"", "22:00", "22:30-07:00", "07:00-07:00"} {
+		if _, err := parseWindow(in); err == nil {
+			t.Errorf("parseWindow(%q) did not return error", in)
+		}
+	}
+}
+
+func TestSimulateMonths(t *testing.T) {
+	day := time.Date(2019, 1, 31, 0, 0, 0, 0, helsinki)
+	at := func(h int) time.Time { return day.Add(time.Duration(h) * time.Hour) }
+	hours := []hour{
+		{Timestamp: at(12), KWh: 2, Spot: 100},
+		{Timestamp: at(18), KWh: 2, Spot: 200},
+		{Timestamp: at(23), KWh: 1, Spot: 50},
+		// Next morning, still in the window of January 31.
+		{Timestamp: at(27), KWh: 1, Spot: 20},
+	}
+	w := hourWindow{22, 7}
+	months := simulateMonths(config.Contract{}, hours, 3, w, day.AddDate(0, 0, 1))
+	if len(months) != 1 {
+		t.Fatalf("simulateMonths returned %d months, want 1: %+v", len(months), months)
+	}
+	// Average cost of Jan 31 is (0.2+0.4+0.05)/5 = 0.13 EUR/kWh and the
+	// cheapest window hour costs 0.02 EUR/kWh.
+	m := months[0]
+	if m.Month.Month() != time.January || m.Days != 1 || m.KWh != 3 || math.Abs(m.Saved-3*0.11) > 1e-9 {
+		t.Errorf("simulateMonths = %+v, want January, 1 day, 3 kWh, 0.33 EUR", m)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/joneskoo/etget/api"
+)
+
+// spotHintaHandler serves the Finnish prices in the layout of the
+// spot-hinta.fi API, with vat added for PriceWithTax.
+func spotHintaHandler(db *sql.DB, vat float64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now().In(helsinki)
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, helsinki)
+		days := 1
+		if r.URL.Path == api.SpotHintaForwardPath {
+			days = 2
+		}
+		prices, err := queryPrices(db, today, today.AddDate(0, 0, days))
+		if err != nil {
+			log.Printf("ERROR reading prices: %s", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		hours := spotHintaPrices(prices, vat)
+		var v interface{} = hours
+		if r.URL.Path == api.SpotHintaNowPath {
+			i := sort.Search(len(hours), func(i int) bool { return hours[i].DateTime.After(now) })
+			if i == 0 || now.Sub(hours[i-1].DateTime) >= time.Hour {
+				http.NotFound(w, r)
+				return
+			}
+			v = hours[i-1]
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	})
+}
+
+// spotHintaPrices converts prices in time order to the
+// spot-hinta.fi layout, ranking the hours within each Finnish day.
+func spotHintaPrices(prices []price, vat float64) []api.SpotHintaPrice {
+	hours := make([]api.SpotHintaPrice, len(prices))
+	for i, p := range prices {
+		noTax := p.Spot / 1000
+		hours[i] = api.SpotHintaPrice{
+			DateTime:     p.Timestamp.In(helsinki),
+			PriceNoTax:   round5(noTax),
+			PriceWithTax: round5(noTax * (1 + vat)),
+		}
+	}
+	for start := 0; start < len(hours); {
+		day := hours[start].DateTime.Format("2006-01-02")
+		end := start
+		for end < len(hours) && hours[end].DateTime.Format("2006-01-02") == day {
+			end++
+		}
+		order := make([]int, end-start)
+		for i := range order {
+			order[i] = start + i
+		}
+		sort.SliceStable(order, func(a, b int) bool { return prices[order[a]].Spot < prices[order[b]].Spot })
+		for rank, i := range order {
+			hours[i].Rank = rank + 1
+		}
+		start = end
+	}
+	return hours
+}
+
+// round5 rounds to the 5 decimals of the spot-hinta.fi prices.
+func round5(v float64) float64 {
+	return math.Round(v*1e5) / 1e5
+}
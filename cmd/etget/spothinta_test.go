@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSpotHintaPrices(t *testing.T) {
+	day := time.Date(2026, 10, 15, 21, 0, 0, 0, time.UTC) // 00:00 in Helsinki
+	prices := []price{
+		{Timestamp: day.Add(-time.Hour), Spot: 1},
+		{Timestamp: day, Spot: 30},
+		{Timestamp: day.Add(time.Hour), Spot: 10.123456},
+		{Timestamp: day.Add(2 * time.Hour), Spot: 20},
+	}
+	hours := spotHintaPrices(prices, 0.255)
+	var ranks []int
+	for _, h := range hours {
+		ranks = append(ranks, h.Rank)
+	}
+	if want := []int{1, 3, 1, 2}; !reflect.DeepEqual(ranks, want) {
+		t.Errorf("ranks = %v, want %v", ranks, want)
+	}
+	if h := hours[2]; h.PriceNoTax != 0.01012 || h.PriceWithTax != 0.0127 {
+		t.Errorf("hours[2] prices = %v, %v; want 0.01012, 0.0127", h.PriceNoTax, h.PriceWithTax)
+	}
+	b, err := json.Marshal(hours[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"Rank":3,"DateTime":"2026-10-16T00:00:00+03:00","PriceNoTax":0.03,"PriceWithTax":0.03765}`; string(b) != want {
+		t.Errorf("JSON = %s, want %s", b, want)
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/joneskoo/etget/api"
+	"github.com/joneskoo/etget/internal/config"
+	"github.com/joneskoo/etget/internal/dbpool"
+	"github.com/joneskoo/etget/internal/ledger"
+	"github.com/joneskoo/etget/internal/pubcal"
+	"github.com/joneskoo/etget/internal/server"
+	"github.com/joneskoo/etget/internal/spool"
+)
+
+func init() {
+	register("status", "", "Show the last successful import of each source", func(fs *flag.FlagSet) func([]string) error {
+		connstring := fs.String("connstring", "sslmode=disable", connstringUsage)
+		configFile := fs.String("config", config.DefaultFile, "configuration file with server authentication and the VAT of spot-hinta.fi prices")
+		listen := fs.String("listen", "", "serve Prometheus metrics at /metrics, JSON at "+api.ImportsPath+", new prices over WebSocket at "+api.PricesPath+" and spot-hinta.fi compatible prices at "+api.SpotHintaForwardPath+", "+api.SpotHintaTodayPath+" and "+api.SpotHintaNowPath+" on this address")
+		pushInterval := fs.Duration("push-interval", time.Minute, "how often to check for new prices to push to WebSocket clients")
+		serveSync := fs.Bool("serve-sync", false, "with -listen, also serve all tables to etget sync at "+api.SyncPath+"; requires server tokens or client certificates")
+		serveConsumption := fs.Bool("serve-consumption", false, "with -listen, also serve the hours of a meter at "+api.ConsumptionPath+"?meter=NAME; requires server tokens or client certificates")
+		acceptSync := fs.Bool("accept-sync", false, "with -serve-sync, add the rows posted by etget sync of a peer to the tables; without it they are only read")
+		queueDir := fs.String("queue", "", "also report in /metrics the pending and dead-letter inputs of the import-elspot -queue `directory`")
+		var pool dbpool.Options
+		pool.RegisterFlags(fs)
+		return func(args []string) error {
+			if len(args) != 0 {
+				return errors.New("unexpected arguments")
+			}
+			if *acceptSync && !*serveSync {
+				return errors.New("-accept-sync requires -serve-sync")
+			}
+			if *listen == "" {
+				if *serveSync {
+					return errors.New("-serve-sync requires -listen")
+				}
+				if *serveConsumption {
+					return errors.New("-serve-consumption requires -listen")
+				}
+				db, err := sql.Open("postgres", *connstring)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+
+				entries, err := ledger.Latest(db)
+				if err != nil {
+					return err
+				}
+				for _, e := range entries {
+					fmt.Printf("%-12s %s (%s ago), %d rows\n", e.Source, e.FinishedAt.Format(time.RFC3339),
+						time.Since(e.FinishedAt).Round(time.Second), e.RowsAffected)
+				}
+				return nil
+			}
+
+			cfg, err := config.Load(*configFile)
+			if err != nil {
+				return err
+			}
+			auth := cfg.Server.WithEnv()
+			if *serveSync && !auth.Authenticated() {
+				// The tables include the consumption of every meter.
+				return fmt.Errorf("-serve-sync requires authentication: set server tokens in %s or $%s, or a client CA", *configFile, server.TokensEnv)
+			}
+			if *serveConsumption && !auth.Authenticated() {
+				return fmt.Errorf("-serve-consumption requires authentication: set server tokens in %s or $%s, or a client CA", *configFile, server.TokensEnv)
+			}
+			cal, err := cfg.Calendar.New(helsinki)
+			if err != nil {
+				return fmt.Errorf("config %s: %s", *configFile, err)
+			}
+			db, err := pool.Or(cfg.Database).Open(*connstring)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			mux := http.NewServeMux()
+			mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+				entries, err := ledger.Latest(db)
+				if err != nil {
+					log.Printf("ERROR reading imports: %s", err)
+					http.Error(w, "internal error", http.StatusInternalServerError)
+					return
+				}
+				days, err := priceDays(db, cal, time.Now())
+				if err != nil {
+					log.Printf("ERROR reading price hours: %s", err)
+					http.Error(w, "internal error", http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+				writeMetrics(w, entries)
+				writePriceMetrics(w, days, time.Now())
+				if *queueDir != "" {
+					pending, dead, err := spool.Spool{Dir: *queueDir}.Count()
+					if err != nil {
+						log.Printf("ERROR reading queue: %s", err)
+						return
+					}
+					writeQueueMetrics(w, pending, dead)
+				}
+			})
+			mux.HandleFunc(api.ImportsPath, func(w http.ResponseWriter, r *http.Request) {
+				entries, err := ledger.Latest(db)
+				if err != nil {
+					log.Printf("ERROR reading imports: %s", err)
+					http.Error(w, "internal error", http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				writeImports(w, entries)
+			})
+			spotHinta := spotHintaHandler(db, cfg.Contract.VAT)
+			for _, path := range []string{api.SpotHintaTodayPath, api.SpotHintaForwardPath, api.SpotHintaNowPath} {
+				mux.Handle(path, spotHinta)
+			}
+			if *serveSync {
+				mux.Handle(api.SyncPath, syncHandler(db, *acceptSync))
+			}
+			if *serveConsumption {
+				mux.Handle(api.ConsumptionPath, consumptionHandler(db, cfg))
+			}
+			push := newPricePush()
+			mux.Handle(api.PricesPath, push.handler())
+			go push.watch(db, *pushInterval)
+			return server.ListenAndServe(*listen, mux, auth)
+		}
+	})
+}
+
+// writeImports writes entries as the JSON response of api.ImportsPath.
+func writeImports(w io.Writer, entries []ledger.Entry) error {
+	imports := make([]api.Import, len(entries))
+	for i, e := range entries {
+		imports[i] = api.Import{Source: e.Source, FinishedAt: e.FinishedAt.UTC(), RowsAffected: e.RowsAffected}
+	}
+	return json.NewEncoder(w).Encode(imports)
+}
+
+// consumptionHandler serves the hours of a meter at api.ConsumptionPath.
+func consumptionHandler(db *sql.DB, cfg *config.Config) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		meter := q.Get("meter")
+		if meter == "" {
+			http.Error(w, "meter is required", http.StatusBadRequest)
+			return
+		}
+		start, end, err := parsePeriod(q.Get("from"), q.Get("to"), time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		hours, err := queryHours(db, cfg.MeterID(meter), start, end)
+		if _, ok := err.(missingPricesError); ok {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			log.Printf("ERROR reading consumption: %s", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		writeHours(w, hours)
+	})
+}
+
+// writeHours writes hours as the JSON response of api.ConsumptionPath.
+func writeHours(w io.Writer, hours []hour) error {
+	out := make([]api.Hour, len(hours))
+	for i, h := range hours {
+		out[i] = api.Hour{Timestamp: h.Timestamp.UTC(), KWh: h.KWh, SoldKWh: h.SoldKWh, Price: h.Spot}
+	}
+	return json.NewEncoder(w).Encode(out)
+}
+
+// priceDayLabels label the days of priceDays in metrics. Relative days keep
+// the number of series constant.
+var priceDayLabels = []string{"today", "tomorrow"}
+
+// priceDays returns the status of today's and tomorrow's prices.
+func priceDays(db *sql.DB, cal *pubcal.Calendar, now time.Time) ([]pubcal.Status, error) {
+	today := cal.Day(now)
+	var days []pubcal.Status
+	for _, day := range []time.Time{today, cal.Day(today.Add(36 * time.Hour))} {
+		var rows int
+		err := db.QueryRow("SELECT count(fi) FROM elspot WHERE ts >= $1 AND ts < $2",
+			day, cal.Day(day.Add(36*time.Hour))).Scan(&rows)
+		if err != nil {
+			return nil, err
+		}
+		days = append(days, cal.Check(day, rows))
+	}
+	return days, nil
+}
+
+// writePriceMetrics writes the hours of days with prices against the hours
+// of the day, and whether the prices are overdue, so that alerts neither
+// fire before the prices are due nor on the short and long days of clock
+// changes.
+func writePriceMetrics(w io.Writer, days []pubcal.Status, now time.Time) {
+	fmt.Fprintln(w, "# HELP etget_price_hours Hours of the delivery day with a spot price.")
+	fmt.Fprintln(w, "# TYPE etget_price_hours gauge")
+	for i, d := range days {
+		fmt.Fprintf(w, "etget_price_hours{day=%q} %d\n", priceDayLabels[i], d.Rows)
+	}
+	fmt.Fprintln(w, "# HELP etget_price_expected_hours Hours of the delivery day: 23 or 25 when the clocks change, otherwise 24.")
+	fmt.Fprintln(w, "# TYPE etget_price_expected_hours gauge")
+	for i, d := range days {
+		fmt.Fprintf(w, "etget_price_expected_hours{day=%q} %d\n", priceDayLabels[i], d.Hours)
+	}
+	fmt.Fprintln(w, "# HELP etget_price_overdue 1 if the prices of the delivery day are incomplete after they were due by the publication calendar.")
+	fmt.Fprintln(w, "# TYPE etget_price_overdue gauge")
+	for i, d := range days {
+		overdue := 0
+		if d.Overdue(now) {
+			overdue = 1
+		}
+		fmt.Fprintf(w, "etget_price_overdue{day=%q} %d\n", priceDayLabels[i], overdue)
+	}
+}
+
+// writeQueueMetrics writes the number of queued inputs by state.
+func writeQueueMetrics(w io.Writer, pending, dead int) {
+	fmt.Fprintln(w, "# HELP etget_queue_files Inputs of import-elspot -queue waiting for a retry (pending) or given up (dead).")
+	fmt.Fprintln(w, "# TYPE etget_queue_files gauge")
+	fmt.Fprintf(w, "etget_queue_files{state=\"pending\"} %d\n", pending)
+	fmt.Fprintf(w, "etget_queue_files{state=\"dead\"} %d\n", dead)
+}
+
+// writeMetrics writes entries in the Prometheus text exposition format.
+func writeMetrics(w io.Writer, entries []ledger.Entry) {
+	fmt.Fprintln(w, "# HELP etget_last_import_timestamp_seconds Time of the last successful import.")
+	fmt.Fprintln(w, "# TYPE etget_last_import_timestamp_seconds gauge")
+	for _, e := range entries {
+		fmt.Fprintf(w, "etget_last_import_timestamp_seconds{source=%q} %d\n", e.Source, e.FinishedAt.Unix())
+	}
+	fmt.Fprintln(w, "# HELP etget_last_import_rows Rows affected by the last successful import.")
+	fmt.Fprintln(w, "# TYPE etget_last_import_rows gauge")
+	for _, e := range entries {
+		fmt.Fprintf(w, "etget_last_import_rows{source=%q} %d\n", e.Source, e.RowsAffected)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joneskoo/etget/internal/ledger"
+	"github.com/joneskoo/etget/internal/pubcal"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	entries := []ledger.Entry{
+		{Source: "elspot", FinishedAt: time.Unix(1500000000, 0), RowsAffected: 24},
+	}
+	var buf bytes.Buffer
+	writeMetrics(&buf, entries)
+	for _, want := range []string{
+		"etget_last_import_timestamp_seconds{source=\"elspot\"} 1500000000\n",
+		"etget_last_import_rows{source=\"elspot\"} 24\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("writeMetrics() output missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestWriteQueueMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	writeQueueMetrics(&buf, 3, 1)
+	for _, want := range []string{
+		"etget_queue_files{state=\"pending\"} 3\n",
+		"etget_queue_files{state=\"dead\"} 1\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("writeQueueMetrics() output missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestWriteImports(t *testing.T) {
+	entries := []ledger.Entry{
+		{Source: "elspot", FinishedAt: time.Unix(1500000000, 0), RowsAffected: 24},
+	}
+	var buf bytes.Buffer
+	if err := writeImports(&buf, entries); err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"source":"elspot","finished_at":"2017-07-14T02:40:00Z","rows_affected":24}]` + "\n"
+	if buf.String() != want {
+		t.Errorf("writeImports() = %s, want %s", buf.String(), want)
+	}
+}
+
+func TestWriteHours(t *testing.T) {
+	hours := []hour{{Timestamp: time.Unix(1500000000, 0).In(helsinki), KWh: 1.5, SoldKWh: 0.25, Spot: 30.5}}
+	var buf bytes.Buffer
+	if err := writeHours(&buf, hours); err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"ts":"2017-07-14T02:40:00Z","kwh":1.5,"sold_kwh":0.25,"price":30.5}]` + "\n"
+	if buf.String() != want {
+		t.Errorf("writeHours() = %s, want %s", buf.String(), want)
+	}
+}
+
+func TestWritePriceMetrics(t *testing.T) {
+	cal, err := pubcal.Options{}.New(helsinki)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 10, 24, 15, 0, 0, 0, helsinki)
+	days := []pubcal.Status{
+		cal.Check(now, 24),
+		// The day summer time ends has 25 hours.
+		cal.Check(now.AddDate(0, 0, 1), 24),
+	}
+	var buf bytes.Buffer
+	writePriceMetrics(&buf, days, now)
+	for _, want := range []string{
+		"etget_price_hours{day=\"today\"} 24\n",
+		"etget_price_expected_hours{day=\"tomorrow\"} 25\n",
+		"etget_price_overdue{day=\"today\"} 0\n",
+		"etget_price_overdue{day=\"tomorrow\"} 1\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("writePriceMetrics() output missing %q:\n%s", want, buf.String())
+		}
+	}
+}
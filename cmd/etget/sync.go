@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joneskoo/etget/api"
+	"github.com/joneskoo/etget/internal/ledger"
+	"github.com/lib/pq"
+)
+
+func init() {
+	register("sync", "", "Exchange rows missing on either side with another etget server", func(fs *flag.FlagSet) func([]string) error {
+		connstring := fs.String("connstring", "sslmode=disable", connstringUsage)
+		peer := fs.String("peer", "", "base `URL` of the etget status -listen -serve-sync server to sync with")
+		token := fs.String("token", "", "bearer token of the peer")
+		tableNames := fs.String("tables", "", "comma-separated tables to sync (default all)")
+		timeout := fs.Duration("timeout", 10*time.Minute, "give up after this long")
+		return func(args []string) error {
+			if len(args) != 0 {
+				return errors.New("unexpected arguments")
+			}
+			if *peer == "" {
+				return errors.New("-peer is required")
+			}
+			ts := tables
+			if *tableNames != "" {
+				ts = nil
+				for _, name := range strings.Split(*tableNames, ",") {
+					t, ok := lookupTable(strings.TrimSpace(name))
+					if !ok {
+						return fmt.Errorf("unknown table %q", name)
+					}
+					ts = append(ts, t)
+				}
+			}
+			db, err := sql.Open("postgres", *connstring)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+			defer cancel()
+			client := &api.Client{BaseURL: *peer, Token: *token}
+			for _, t := range ts {
+				pulled, pushed, err := syncTable(ctx, db, client, t)
+				if err == errNoTable {
+					fmt.Printf("%-16s skipped, not in this database\n", t.Name)
+					continue
+				}
+				if err != nil {
+					return fmt.Errorf("%s: %s", t.Name, err)
+				}
+				fmt.Printf("%-16s %d rows pulled, %d rows pushed\n", t.Name, pulled, pushed)
+			}
+			return nil
+		}
+	})
+}
+
+// errNoTable is returned for a table the database does not have.
+var errNoTable = errors.New("no such table")
+
+// syncTable compares the latest row of each series of t with the peer and
+// copies the newer rows of either side to the other. Rows missing before
+// the latest row of the other side are not noticed.
+func syncTable(ctx context.Context, db *sql.DB, client *api.Client, t table) (pulled, pushed int64, err error) {
+	if t, err = liveTable(db, t); err != nil {
+		return 0, 0, err
+	}
+	local, err := syncState(db, t)
+	if err != nil {
+		return 0, 0, err
+	}
+	remote, err := client.SyncState(ctx, t.Name)
+	if err != nil {
+		return 0, 0, err
+	}
+	pull, push := planSync(local.Latest, remote.Latest)
+	for _, f := range pull {
+		rows, err := client.SyncRows(ctx, t.Name, f.Series, f.After)
+		if err != nil {
+			return pulled, pushed, err
+		}
+		n, err := loadSyncRows(db, t, rows)
+		rows.Close()
+		if err != nil {
+			return pulled, pushed, fmt.Errorf("load rows from peer: %s", err)
+		}
+		pulled += n
+	}
+	for _, f := range push {
+		pr, pw := io.Pipe()
+		go func(f rowFilter) {
+			pw.CloseWithError(exportCSV(pw, db, t, f, nil))
+		}(f)
+		res, err := client.PostSyncRows(ctx, t.Name, pr)
+		pr.Close()
+		if err != nil {
+			return pulled, pushed, err
+		}
+		pushed += res.RowsAffected
+	}
+	return pulled, pushed, nil
+}
+
+// planSync returns the rows to pull from the peer and to push to it, given
+// the latest row of each series on both sides. A series one side lacks is
+// copied whole.
+func planSync(local, remote map[string]time.Time) (pull, push []rowFilter) {
+	var series []string
+	for s := range local {
+		series = append(series, s)
+	}
+	for s := range remote {
+		if _, ok := local[s]; !ok {
+			series = append(series, s)
+		}
+	}
+	sort.Strings(series)
+	for _, s := range series {
+		l, lok := local[s]
+		r, rok := remote[s]
+		if rok && (!lok || r.After(l)) {
+			pull = append(pull, rowFilter{Series: s, After: l})
+		}
+		if lok && (!rok || l.After(r)) {
+			push = append(push, rowFilter{Series: s, After: r})
+		}
+	}
+	return pull, push
+}
+
+// syncState returns the latest row of each series of t, or errNoTable if
+// the database does not have t.
+func syncState(db *sql.DB, t table) (api.SyncState, error) {
+	state := api.SyncState{Table: t.Name, Latest: make(map[string]time.Time)}
+	var exists bool
+	if err := db.QueryRow("SELECT to_regclass($1) IS NOT NULL", t.Name).Scan(&exists); err != nil {
+		return state, err
+	}
+	if !exists {
+		return state, errNoTable
+	}
+	query := fmt.Sprintf("SELECT '', max(ts) FROM %s", pq.QuoteIdentifier(t.Name))
+	if t.Series != "" {
+		query = fmt.Sprintf("SELECT %[1]s, max(ts) FROM %[2]s GROUP BY %[1]s", pq.QuoteIdentifier(t.Series), pq.QuoteIdentifier(t.Name))
+	}
+	rows, err := db.Query(query)
+	if err != nil {
+		return state, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var series string
+		var latest pq.NullTime
+		if err := rows.Scan(&series, &latest); err != nil {
+			return state, err
+		}
+		if latest.Valid {
+			state.Latest[series] = latest.Time.UTC()
+		}
+	}
+	return state, rows.Err()
+}
+
+// syncColumns maps the fields of header, the header row of the CSV rows
+// of t exchanged by sync, to the columns of t. The peer may lack columns,
+// e.g. price areas it does not import, which are left empty; a column t
+// lacks is an error rather than dropped, as are missing ts and series
+// columns.
+func syncColumns(t table, header string) ([]csvColumn, error) {
+	byName := make(map[string]column, len(t.Columns))
+	for _, c := range t.Columns {
+		byName[c.Name] = c
+	}
+	var cols []csvColumn
+	seen := make(map[string]bool)
+	for i, name := range strings.Split(header, ",") {
+		c, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("column %q of the peer is not in table %s", name, t.Name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("column %q repeated", name)
+		}
+		seen[name] = true
+		cols = append(cols, csvColumn{column: c, Field: i})
+	}
+	for _, name := range []string{"ts", t.Series} {
+		if name != "" && !seen[name] {
+			return nil, fmt.Errorf("no column %q in %q", name, header)
+		}
+	}
+	return cols, nil
+}
+
+// loadSyncRows adds the CSV rows of t read from r to the database, skipping
+// those it already has. The header row names the columns, so that servers
+// of different versions or import options do not mix up columns.
+func loadSyncRows(db *sql.DB, t table, r io.Reader) (int64, error) {
+	br := bufio.NewReader(r)
+	header, err := br.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	cols, err := syncColumns(t, strings.TrimRight(header, "\r\n"))
+	if err != nil {
+		return 0, err
+	}
+	return copyRows(db, t, cols, ledger.SourceSync, func(stmt *sql.Stmt) ([]ledger.File, error) {
+		return nil, copyCSVRecords(stmt, br, cols, csvOptions{Comma: ','})
+	})
+}
+
+// syncHandler serves the tables to etget sync of a peer under
+// api.SyncPath: the latest rows of a table at its name, and its rows as
+// CSV at the name followed by api.SyncRowsSuffix. If writable, rows posted
+// there are added to the table.
+func syncHandler(db *sql.DB, writable bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, api.SyncPath)
+		rowsPath := strings.HasSuffix(name, api.SyncRowsSuffix)
+		t, ok := lookupTable(strings.TrimSuffix(name, api.SyncRowsSuffix))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		t, err := liveTable(db, t)
+		if err != nil {
+			log.Printf("ERROR reading %s: %s", t.Name, err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		state, err := syncState(db, t)
+		if err == errNoTable {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			log.Printf("ERROR reading %s: %s", t.Name, err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		switch {
+		case !rowsPath && r.Method == "GET":
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(state)
+		case rowsPath && r.Method == "GET":
+			f := rowFilter{Series: r.URL.Query().Get("series")}
+			if after := r.URL.Query().Get("after"); after != "" {
+				if f.After, err = time.Parse(time.RFC3339, after); err != nil {
+					http.Error(w, "after: "+err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+			w.Header().Set("Content-Type", "text/csv")
+			if err := exportCSV(w, db, t, f, nil); err != nil {
+				log.Printf("ERROR exporting %s: %s", t.Name, err)
+			}
+		case rowsPath && r.Method == "POST" && writable:
+			n, err := loadSyncRows(db, t, r.Body)
+			if err != nil {
+				log.Printf("ERROR loading %s rows from peer: %s", t.Name, err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(api.SyncResult{RowsAffected: n})
+		case rowsPath && r.Method == "POST":
+			http.Error(w, "read-only, start etget status with -serve-sync -accept-sync", http.StatusForbidden)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPlanSync(t *testing.T) {
+	t1 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+	local := map[string]time.Time{"FI": t2, "SE1": t1, "EE": t1}
+	remote := map[string]time.Time{"FI": t1, "SE1": t2, "EE": t1, "NO1": t1}
+	pull, push := planSync(local, remote)
+	wantPull := []rowFilter{{Series: "NO1"}, {Series: "SE1", After: t1}}
+	wantPush := []rowFilter{{Series: "FI", After: t1}}
+	if !reflect.DeepEqual(pull, wantPull) {
+		t.Errorf("pull = %+v, want %+v", pull, wantPull)
+	}
+	if !reflect.DeepEqual(push, wantPush) {
+		t.Errorf("push = %+v, want %+v", push, wantPush)
+	}
+}
+
+func TestRowFilterWhere(t *testing.T) {
+	area, _ := lookupTable("elspot_area")
+	single, _ := lookupTable("elspot")
+	after := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		t     table
+		f     rowFilter
+		where string
+		args  int
+	}{
+		{area, rowFilter{}, "", 0},
+		{area, rowFilter{Series: "FI"}, ` WHERE "area" = $1`, 1},
+		{area, rowFilter{Series: "FI", After: after}, ` WHERE ts > $1 AND "area" = $2`, 2},
+		{single, rowFilter{Series: "FI", After: after}, ` WHERE ts > $1`, 1},
+	}
+	for _, tt := range tests {
+		where, args := tt.f.where(tt.t)
+		if where != tt.where || len(args) != tt.args {
+			t.Errorf("%s %+v: where() = %q, %d args; want %q, %d", tt.t.Name, tt.f, where, len(args), tt.where, tt.args)
+		}
+	}
+}
+
+func TestSyncColumns(t *testing.T) {
+	elspot := table{Name: "elspot", Columns: []column{{"ts", typeTimestamp}, {"fi", typeReal}, {"se3", typeDouble}, {"status", typeText}}}
+	area, _ := lookupTable("elspot_area")
+	tests := []struct {
+		t      table
+		header string
+		want   []csvColumn
+		err    bool
+	}{
+		{elspot, "ts,fi,se3,status", []csvColumn{{column{"ts", typeTimestamp}, 0}, {column{"fi", typeReal}, 1}, {column{"se3", typeDouble}, 2}, {column{"status", typeText}, 3}}, false},
+		{elspot, "status,ts,fi", []csvColumn{{column{"status", typeText}, 0}, {column{"ts", typeTimestamp}, 1}, {column{"fi", typeReal}, 2}}, false},
+		{elspot, "ts,fi,no1", nil, true},
+		{elspot, "ts,fi,fi", nil, true},
+		{elspot, "fi,status", nil, true},
+		{area, "ts,price,status", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := syncColumns(tt.t, tt.header)
+		if (err != nil) != tt.err || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("syncColumns(%s, %q) = %v, %v; want %v, error %v", tt.t.Name, tt.header, got, err, tt.want, tt.err)
+		}
+	}
+}
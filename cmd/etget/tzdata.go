@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/joneskoo/etget/internal/zoneinfo"
+)
+
+func init() {
+	register("tzdata", "", "Show the pinned time zone data and check the system zone database against it", func(fs *flag.FlagSet) func([]string) error {
+		verify := fs.Bool("verify", false, "compare the system zone database with the pinned data hour by hour from 1990 to 2037 and fail if they differ")
+		return func(args []string) error {
+			fmt.Printf("pinned tzdata %s: %s\n", zoneinfo.Version, strings.Join(zoneinfo.Zones(), ", "))
+			if !*verify {
+				return nil
+			}
+			from := time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+			to := time.Date(2038, 1, 1, 0, 0, 0, 0, time.UTC)
+			mismatches := zoneinfo.Verify(from, to)
+			for _, m := range mismatches {
+				fmt.Println(m)
+			}
+			if len(mismatches) > 0 {
+				return fmt.Errorf("%d zones differ from pinned tzdata %s; the pinned data is only used for zones the system lacks", len(mismatches), zoneinfo.Version)
+			}
+			fmt.Println("OK! system zone database matches")
+			return nil
+		}
+	})
+}
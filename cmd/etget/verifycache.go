@@ -0,0 +1,62 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/joneskoo/etget/internal/ledger"
+)
+
+func init() {
+	register("verify-cache", "", "Check cached input files against the digests recorded at import", func(fs *flag.FlagSet) func([]string) error {
+		connstring := fs.String("connstring", "sslmode=disable", connstringUsage)
+		return func(args []string) error {
+			if len(args) != 0 {
+				return errors.New("unexpected arguments")
+			}
+			db, err := sql.Open("postgres", *connstring)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			files, err := ledger.Files(db)
+			if err != nil {
+				return err
+			}
+			changed := 0
+			for _, want := range files {
+				status := verifyFile(want)
+				if status != "OK" && status != "SKIP" {
+					changed++
+				}
+				fmt.Printf("%-7s %s\n", status, want.Name)
+			}
+			if changed > 0 {
+				return fmt.Errorf("%d of %d files differ from what was imported", changed, len(files))
+			}
+			return nil
+		}
+	})
+}
+
+// verifyFile returns OK, CHANGED, MISSING or SKIP (not a local file).
+func verifyFile(want ledger.File) string {
+	if u, err := url.Parse(want.Name); err == nil && u.Scheme != "" {
+		return "SKIP"
+	}
+	got, err := ledger.HashFile(want.Name)
+	switch {
+	case os.IsNotExist(err):
+		return "MISSING"
+	case err != nil:
+		return "ERROR"
+	case got.SHA256 != want.SHA256 || got.Size != want.Size:
+		return "CHANGED"
+	}
+	return "OK"
+}
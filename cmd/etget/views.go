@@ -0,0 +1,129 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+func init() {
+	register("views", "create|refresh [VIEW...]", "Create or refresh the materialized views of dashboards", func(fs *flag.FlagSet) func([]string) error {
+		connstring := fs.String("connstring", "sslmode=disable", connstringUsage)
+		concurrently := fs.Bool("concurrently", true, "refresh without locking out readers of the views")
+		return func(args []string) error {
+			if len(args) == 0 {
+				return errors.New("want create or refresh")
+			}
+			vs, err := selectViews(args[1:])
+			if err != nil {
+				return err
+			}
+			var stmts []string
+			switch args[0] {
+			case "create":
+				for _, v := range vs {
+					stmts = append(stmts, v.createSQL()...)
+				}
+			case "refresh":
+				for _, v := range vs {
+					stmts = append(stmts, v.refreshSQL(*concurrently))
+				}
+			default:
+				return fmt.Errorf("unknown action %q, want create or refresh", args[0])
+			}
+
+			db, err := sql.Open("postgres", *connstring)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			for _, stmt := range stmts {
+				if _, err := db.Exec(stmt); err != nil {
+					return fmt.Errorf("%s: %s", strings.SplitN(stmt, " AS", 2)[0], err)
+				}
+			}
+			for _, v := range vs {
+				fmt.Fprintf(os.Stdout, "OK! %s %s\n", args[0], v.Name)
+			}
+			return nil
+		}
+	})
+}
+
+// view is a materialized view precomputing an aggregate of the hourly
+// tables for dashboards.
+type view struct {
+	Name string
+	SQL  string
+
+	// Key are the columns of the unique index that REFRESH MATERIALIZED
+	// VIEW CONCURRENTLY requires.
+	Key []string
+}
+
+var views = []view{
+	{
+		Name: "elspot_daily",
+		SQL: `SELECT (ts AT TIME ZONE 'Europe/Helsinki')::date AS day,
+    avg(sys) AS sys_avg, avg(fi) AS fi_avg, min(fi) AS fi_min, max(fi) AS fi_max,
+    count(*) AS hours
+    FROM elspot GROUP BY 1`,
+		Key: []string{"day"},
+	},
+	{
+		Name: "energiatili_monthly",
+		SQL: `SELECT e.meter_id, date_trunc('month', e.ts AT TIME ZONE 'Europe/Helsinki')::date AS month,
+    sum(e.kwh) AS kwh, sum(e.produced_kwh) AS produced_kwh,
+    sum(e.kwh * p.fi) / 1000 AS spot_cost,
+    sum(e.kwh * p.fi) / nullif(sum(e.kwh), 0) AS weighted_fi
+    FROM energiatili e JOIN elspot p USING (ts)
+    GROUP BY 1, 2`,
+		Key: []string{"meter_id", "month"},
+	},
+}
+
+// selectViews returns the named views, or all views if names is empty.
+func selectViews(names []string) ([]view, error) {
+	if len(names) == 0 {
+		return views, nil
+	}
+	byName := make(map[string]view, len(views))
+	for _, v := range views {
+		byName[v.Name] = v
+	}
+	selected := make([]view, len(names))
+	for i, name := range names {
+		v, ok := byName[name]
+		if !ok {
+			known := make([]string, 0, len(views))
+			for _, v := range views {
+				known = append(known, v.Name)
+			}
+			sort.Strings(known)
+			return nil, fmt.Errorf("unknown view %q, want one of %s", name, strings.Join(known, ", "))
+		}
+		selected[i] = v
+	}
+	return selected, nil
+}
+
+// createSQL returns the statements creating the view and its unique index.
+// Existing views are kept, so create can be run again after an upgrade
+// adds views.
+func (v view) createSQL() []string {
+	return []string{
+		fmt.Sprintf("CREATE MATERIALIZED VIEW IF NOT EXISTS %s AS %s", v.Name, v.SQL),
+		fmt.Sprintf("CREATE UNIQUE INDEX IF NOT EXISTS %s_key ON %s (%s)", v.Name, v.Name, strings.Join(v.Key, ", ")),
+	}
+}
+
+func (v view) refreshSQL(concurrently bool) string {
+	if concurrently {
+		return "REFRESH MATERIALIZED VIEW CONCURRENTLY " + v.Name
+	}
+	return "REFRESH MATERIALIZED VIEW " + v.Name
+}
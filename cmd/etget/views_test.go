@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSelectViews(t *testing.T) {
+	all, err := selectViews(nil)
+	if err != nil || len(all) != len(views) {
+		t.Fatalf("selectViews(nil) = %d views, %v; want all %d", len(all), err, len(views))
+	}
+	got, err := selectViews([]string{"energiatili_monthly"})
+	if err != nil || len(got) != 1 || got[0].Name != "energiatili_monthly" {
+		t.Errorf("selectViews(energiatili_monthly) = %v, %v", got, err)
+	}
+	if _, err := selectViews([]string{"nope"}); err == nil {
+		t.Error("selectViews(nope) did not return error")
+	}
+}
+
+// TestViewKeys checks that the unique index columns are output columns of
+// the view; without the index concurrent refresh fails.
+func TestViewKeys(t *testing.T) {
+	for _, v := range views {
+		if len(v.Key) == 0 {
+			t.Errorf("%s: no unique key", v.Name)
+		}
+		for _, k := range v.Key {
+			if !strings.Contains(v.SQL, " AS "+k) && !strings.Contains(v.SQL, "SELECT "+k) && !strings.Contains(v.SQL, "."+k+",") {
+				t.Errorf("%s: key column %s not selected", v.Name, k)
+			}
+		}
+	}
+}
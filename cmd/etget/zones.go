@@ -0,0 +1,182 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joneskoo/etget/zones"
+)
+
+func init() {
+	register("zones", "", "Print the bidding zones and their changes, or load them into the database", func(fs *flag.FlagSet) func([]string) error {
+		connstring := fs.String("connstring", "sslmode=disable", connstringUsage)
+		load := fs.Bool("load", false, "replace the rows of tables "+zonesTable+" and "+lineageTable+" with the bundled zones and create view "+stitchedView+", instead of printing them")
+		return func(args []string) error {
+			if len(args) != 0 {
+				return errors.New("unexpected arguments")
+			}
+			if !*load {
+				return writeZones(os.Stdout, zones.All())
+			}
+			db, err := sql.Open("postgres", *connstring)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			n, err := loadZones(db, zones.All())
+			if err != nil {
+				return err
+			}
+			fmt.Printf("OK! %d zones loaded into %s\n", n, zonesTable)
+			n, err = loadLineage(db, zones.All())
+			if err != nil {
+				return err
+			}
+			fmt.Printf("OK! %d predecessor periods loaded into %s, view %s created\n", n, lineageTable, stitchedView)
+			return nil
+		}
+	})
+}
+
+const zonesTable = "bidding_zones"
+
+const createZonesSQL = `CREATE TABLE IF NOT EXISTS ` + zonesTable + ` (
+    code TEXT NOT NULL,
+    country TEXT NOT NULL,
+    location TEXT NOT NULL,
+    currency TEXT NOT NULL,
+    valid_from DATE,
+    valid_to DATE,
+    names TEXT[] NOT NULL DEFAULT '{}',
+    UNIQUE (code, valid_from)
+)`
+
+// loadZones replaces the rows of the zones table in one transaction, so
+// periods removed from the dataset disappear from the table too.
+func loadZones(db *sql.DB, zs []zones.Zone) (int, error) {
+	if _, err := db.Exec(createZonesSQL); err != nil {
+		return 0, fmt.Errorf("create table %s: %s", zonesTable, err)
+	}
+	txn, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer txn.Rollback()
+	if _, err := txn.Exec("DELETE FROM " + zonesTable); err != nil {
+		return 0, fmt.Errorf("delete zones: %s", err)
+	}
+	for _, z := range zs {
+		_, err := txn.Exec("INSERT INTO "+zonesTable+" (code, country, location, currency, valid_from, valid_to, names) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			z.Code, z.Country, z.Location, z.Currency, zoneDate(z.From), zoneDate(z.To), "{"+strings.Join(z.Names, ",")+"}")
+		if err != nil {
+			return 0, fmt.Errorf("insert zone %s: %s", z.Code, err)
+		}
+	}
+	return len(zs), txn.Commit()
+}
+
+const lineageTable = "bidding_zone_lineage"
+
+// createLineageSQL creates the table of predecessor zones whose prices
+// stand for a zone before it began.
+const createLineageSQL = `CREATE TABLE IF NOT EXISTS ` + lineageTable + ` (
+    code TEXT NOT NULL,
+    source TEXT NOT NULL,
+    valid_from TIMESTAMPTZ,
+    valid_to TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (code, source)
+)`
+
+const stitchedView = "elspot_area_stitched"
+
+// createStitchedSQL creates the view continuing the price series of each
+// area with the prices of its predecessors; source_area tells which zone
+// a price was set in.
+const createStitchedSQL = `CREATE OR REPLACE VIEW ` + stitchedView + ` AS
+    SELECT a.area, a.ts, a.price, a.status, a.area AS source_area FROM elspot_area a
+    UNION ALL
+    SELECT l.code, a.ts, a.price, a.status, a.area FROM elspot_area a
+    JOIN ` + lineageTable + ` l ON a.area = l.source
+    WHERE (l.valid_from IS NULL OR a.ts >= l.valid_from) AND a.ts < l.valid_to`
+
+// lineageRows returns the predecessor periods of every zone, the sources
+// of its lineage other than itself.
+func lineageRows(zs []zones.Zone) (rows [][]interface{}) {
+	seen := make(map[string]bool)
+	for _, z := range zs {
+		if seen[z.Code] {
+			continue
+		}
+		seen[z.Code] = true
+		for _, s := range zones.Lineage(z.Code) {
+			if s.Code == z.Code {
+				continue
+			}
+			var from interface{}
+			if !s.From.IsZero() {
+				from = s.From
+			}
+			rows = append(rows, []interface{}{z.Code, s.Code, from, s.To})
+		}
+	}
+	return rows
+}
+
+// loadLineage replaces the rows of the lineage table and creates the view
+// over it. The view needs table elspot_area of import-elspot -per-area.
+func loadLineage(db *sql.DB, zs []zones.Zone) (int, error) {
+	if _, err := db.Exec(createLineageSQL); err != nil {
+		return 0, fmt.Errorf("create table %s: %s", lineageTable, err)
+	}
+	txn, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer txn.Rollback()
+	if _, err := txn.Exec("DELETE FROM " + lineageTable); err != nil {
+		return 0, fmt.Errorf("delete lineage: %s", err)
+	}
+	rows := lineageRows(zs)
+	for _, r := range rows {
+		if _, err := txn.Exec("INSERT INTO "+lineageTable+" (code, source, valid_from, valid_to) VALUES ($1, $2, $3, $4)", r...); err != nil {
+			return 0, fmt.Errorf("insert lineage of %s: %s", r[0], err)
+		}
+	}
+	if _, err := txn.Exec(createStitchedSQL); err != nil {
+		return 0, fmt.Errorf("create view %s: %s", stitchedView, err)
+	}
+	return len(rows), txn.Commit()
+}
+
+// zoneDate returns the date of t, or nil for an open end of the period.
+func zoneDate(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.Format("2006-01-02")
+}
+
+// writeZones prints the zones as a table; open ends of the validity
+// periods are blank.
+func writeZones(w io.Writer, zs []zones.Zone) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "CODE\tCOUNTRY\tTIME ZONE\tCURRENCY\tFROM\tTO\tPREDECESSOR\tNAMES")
+	for _, z := range zs {
+		var from, to string
+		if !z.From.IsZero() {
+			from = z.From.Format("2006-01-02")
+		}
+		if !z.To.IsZero() {
+			to = z.To.Format("2006-01-02")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", z.Code, z.Country, z.Location, z.Currency, from, to, z.Predecessor, strings.Join(z.Names, " "))
+	}
+	return tw.Flush()
+}
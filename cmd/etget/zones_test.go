@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joneskoo/etget/zones"
+)
+
+func TestWriteZones(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeZones(&buf, zones.All()); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(zones.All())+1 {
+		t.Errorf("got %d lines, want header and %d zones", len(lines), len(zones.All()))
+	}
+	rows := make(map[string]bool)
+	for _, l := range lines {
+		rows[strings.Join(strings.Fields(l), " ")] = true
+	}
+	for _, want := range []string{
+		"SE SE Europe/Stockholm SEK 2011-11-01",
+		"NO1 NO Europe/Oslo NOK Oslo",
+		"EE EE Europe/Tallinn EEK 2010-04-01 2011-01-01",
+		"SE3 SE Europe/Stockholm SEK 2011-11-01 SE",
+	} {
+		if !rows[want] {
+			t.Errorf("output missing row %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestLineageRows(t *testing.T) {
+	got := make(map[string]string)
+	for _, r := range lineageRows(zones.All()) {
+		got[r[0].(string)] += r[1].(string)
+		if r[3].(time.Time).IsZero() {
+			t.Errorf("lineage of %s from %s has no end", r[0], r[1])
+		}
+	}
+	want := map[string]string{"SE1": "SE", "SE2": "SE", "SE3": "SE", "SE4": "SE", "DE-LU": "DE-AT-LU", "AT": "DE-AT-LU"}
+	if len(got) != len(want) {
+		t.Errorf("lineageRows = %v, want %v", got, want)
+	}
+	for code, source := range want {
+		if got[code] != source {
+			t.Errorf("lineage of %s = %q, want %q", code, got[code], source)
+		}
+	}
+}
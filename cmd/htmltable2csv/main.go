@@ -0,0 +1,152 @@
+// The htmltable2csv command extracts the tables of an HTML file as CSV or
+// JSON.
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joneskoo/etget/htmltable"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [flags] FILE\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "   FILE	HTML file, - for standard input\n\n")
+	flag.PrintDefaults()
+	os.Exit(1)
+}
+
+func main() {
+	format := flag.String("format", "csv", "output format: csv or json")
+	index := flag.Int("index", -1, "extract only the table at this 0-based `index` (default all tables)")
+	match := flag.String("match", "", "extract only tables with a header cell containing this `text`")
+	var limits htmltable.Limits
+	flag.Var(&limits, "limits", "refuse files exceeding any of these `limits`: bytes=N,tables=N,rows=N,cell=N (default "+htmltable.DefaultLimits.String()+")")
+	dir := flag.String("dir", "", "write each table to table-N.csv or table-N.json in this `directory` instead of standard output")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+	}
+	if *format != "csv" && *format != "json" {
+		log.Fatalf("ERROR -format %q, want csv or json", *format)
+	}
+
+	in := os.Stdin
+	if name := flag.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			log.Fatalf("ERROR %s", err)
+		}
+		defer f.Close()
+		in = f
+	}
+	tables, err := limits.Parse(in)
+	if err != nil {
+		log.Fatalf("ERROR %s", err)
+	}
+	selected, err := selectTables(tables, *index, *match)
+	if err != nil {
+		log.Fatalf("ERROR %s", err)
+	}
+
+	if *dir == "" {
+		if err := write(os.Stdout, *format, selected); err != nil {
+			log.Fatalf("ERROR writing output: %s", err)
+		}
+		return
+	}
+	for _, t := range selected {
+		name := filepath.Join(*dir, fmt.Sprintf("table-%d.%s", t.Index, *format))
+		f, err := os.Create(name)
+		if err != nil {
+			log.Fatalf("ERROR %s", err)
+		}
+		err = write(f, *format, []indexedTable{t})
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			log.Fatalf("ERROR writing %s: %s", name, err)
+		}
+		fmt.Println(name)
+	}
+}
+
+// indexedTable is a table with its position in the file, so that output
+// file names stay the same whichever tables are selected.
+type indexedTable struct {
+	Index   int        `json:"index"`
+	Headers [][]string `json:"headers"`
+	Rows    [][]string `json:"rows"`
+}
+
+// selectTables returns the table at index, or all tables if index is
+// negative, keeping those with a header cell containing match.
+func selectTables(tables []htmltable.Table, index int, match string) ([]indexedTable, error) {
+	if index >= len(tables) {
+		return nil, fmt.Errorf("-index %d, file has %d tables", index, len(tables))
+	}
+	var selected []indexedTable
+	for i, t := range tables {
+		if index >= 0 && i != index {
+			continue
+		}
+		if match != "" && !headerContains(t, match) {
+			continue
+		}
+		selected = append(selected, indexedTable{Index: i, Headers: t.Headers, Rows: t.Rows})
+	}
+	if len(selected) == 0 {
+		return nil, errors.New("no table found")
+	}
+	return selected, nil
+}
+
+func headerContains(t htmltable.Table, s string) bool {
+	for _, row := range t.Headers {
+		for _, cell := range row {
+			if strings.Contains(cell, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// write writes tables to w. Several CSV tables are separated by an empty
+// line; JSON is an array of tables.
+func write(w io.Writer, format string, tables []indexedTable) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if len(tables) == 1 {
+			return enc.Encode(tables[0])
+		}
+		return enc.Encode(tables)
+	}
+	for i, t := range tables {
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		cw := csv.NewWriter(w)
+		if err := cw.WriteAll(t.Headers); err != nil {
+			return err
+		}
+		if err := cw.WriteAll(t.Rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/joneskoo/etget/htmltable"
+)
+
+var testTables = []htmltable.Table{
+	{Headers: [][]string{{"Date", "SYS"}}, Rows: [][]string{{"01-01-2016", "16,39"}}},
+	{Headers: [][]string{{"Name", "Value"}}, Rows: [][]string{{"a", "1"}, {"b", "2"}}},
+}
+
+func TestSelectTables(t *testing.T) {
+	tests := []struct {
+		index   int
+		match   string
+		want    []int
+		wantErr bool
+	}{
+		{-1, "", []int{0, 1}, false},
+		{1, "", []int{1}, false},
+		{-1, "SYS", []int{0}, false},
+		{0, "Value", nil, true},
+		{2, "", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := selectTables(testTables, tt.index, tt.match)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("selectTables(%d, %q) error = %v, want error %v", tt.index, tt.match, err, tt.wantErr)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("selectTables(%d, %q) = %d tables, want %d", tt.index, tt.match, len(got), len(tt.want))
+			continue
+		}
+		for i := range got {
+			if got[i].Index != tt.want[i] {
+				t.Errorf("selectTables(%d, %q)[%d].Index = %d, want %d", tt.index, tt.match, i, got[i].Index, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	tables, _ := selectTables(testTables, -1, "")
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"csv", "Date,SYS\n01-01-2016,\"16,39\"\n\nName,Value\na,1\nb,2\n"},
+		{"json", `[
+  {
+    "index": 0,
+    "headers": [
+      [
+        "Date",
+        "SYS"
+      ]
+    ],
+    "rows": [
+      [
+        "01-01-2016",
+        "16,39"
+      ]
+    ]
+  },
+  {
+    "index": 1,
+    "headers": [
+      [
+        "Name",
+        "Value"
+      ]
+    ],
+    "rows": [
+      [
+        "a",
+        "1"
+      ],
+      [
+        "b",
+        "2"
+      ]
+    ]
+  }
+]
+`},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := write(&buf, tt.format, tables); err != nil {
+			t.Fatalf("write(%s): %s", tt.format, err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("write(%s) =\n%s\nwant\n%s", tt.format, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/joneskoo/etget/elspot"
+	"github.com/joneskoo/etget/internal/drift"
+	"github.com/lib/pq"
+)
+
+// areaColumn is an area loaded into a price column of table elspot.
+type areaColumn struct {
+	Area   string
+	Column string
+}
+
+// columnName matches the column names derived from area codes.
+var columnName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// parseAreas returns the price columns of the comma-separated -areas
+// list. The Finnish price is always loaded into column fi, whatever the
+// config renames it to; other areas into a column named by the
+// lowercased area name, after any renaming.
+func parseAreas(list string) ([]areaColumn, error) {
+	cols := []areaColumn{{mainArea, "fi"}}
+	for _, area := range strings.Split(list, ",") {
+		area = strings.TrimSpace(area)
+		// FI names the Finnish price also when the config renames it.
+		if area == "" || strings.EqualFold(area, mainArea) || strings.EqualFold(area, "FI") {
+			continue
+		}
+		col := strings.ToLower(area)
+		if !columnName.MatchString(col) {
+			return nil, fmt.Errorf("area %q is not a valid column name", area)
+		}
+		cols = append(cols, areaColumn{area, col})
+	}
+	return cols, nil
+}
+
+// columnTypes returns the types of the columns of table in the current
+// schema as information_schema names them, with the precision and scale
+// of numeric columns, e.g. "numeric(10,2)". It is empty if the table does
+// not exist yet.
+func columnTypes(db *sql.DB, table string) (map[string]string, error) {
+	rows, err := db.Query(`SELECT column_name, data_type, numeric_precision, numeric_scale
+    FROM information_schema.columns
+    WHERE table_schema = current_schema() AND table_name = $1`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	types := make(map[string]string)
+	for rows.Next() {
+		var name, typ string
+		var precision, scale sql.NullInt64
+		if err := rows.Scan(&name, &typ, &precision, &scale); err != nil {
+			return nil, err
+		}
+		if typ == "numeric" && precision.Valid {
+			typ = fmt.Sprintf("numeric(%d,%d)", precision.Int64, scale.Int64)
+		}
+		types[name] = typ
+	}
+	return types, rows.Err()
+}
+
+// selectColumns splits cols into those present in existing and those
+// missing. With add, the missing columns are loaded after being created
+// with type typ by the returned statements; otherwise they are skipped.
+// Column fi is created with the table and always loaded.
+func selectColumns(cols []areaColumn, existing map[string]bool, add bool, typ string) (load []areaColumn, ddl []string, skipped []areaColumn) {
+	for _, c := range cols {
+		switch {
+		case c.Column == "fi" || existing[c.Column]:
+			load = append(load, c)
+		case add:
+			load = append(load, c)
+			ddl = append(ddl, addColumnSQL(c.Column, typ))
+		default:
+			skipped = append(skipped, c)
+		}
+	}
+	return load, ddl, skipped
+}
+
+// upsertSQL copies rows from the temporary table %[2]s into the target
+// table %[1]s, letting final values replace provisional and estimated
+// ones. The extra
+// columns are copied and updated along with the prices.
+func upsertSQL(cols []areaColumn, extra ...string) string {
+	var names, set []string
+	for _, c := range cols {
+		names = append(names, pq.QuoteIdentifier(c.Column))
+	}
+	for _, name := range extra {
+		names = append(names, pq.QuoteIdentifier(name))
+	}
+	for _, name := range names {
+		set = append(set, fmt.Sprintf("%[1]s = EXCLUDED.%[1]s", name))
+	}
+	list := strings.Join(names, ", ")
+	return `INSERT INTO %[1]s AS t (ts, ` + list + `, status)
+    SELECT ts, ` + list + `, status FROM %[2]s
+    ON CONFLICT (ts) DO UPDATE SET ` + strings.Join(set, ", ") + `, status = EXCLUDED.status
+    WHERE t.status <> 'final'`
+}
+
+// rowStatus returns the status of the row of r loaded into columns: that
+// of a loaded price marked provisional or estimated, otherwise final.
+// Marks on prices that are not loaded, such as SYS, do not count.
+func rowStatus(r elspot.Record, columns []areaColumn) string {
+	for _, c := range columns {
+		if s := r.Status(c.Area); s != elspot.StatusFinal {
+			return s
+		}
+	}
+	return elspot.StatusFinal
+}
+
+// tableColumns are the columns loaded into a table.
+type tableColumns struct {
+	Table   string
+	Columns []drift.Column
+}
+
+// expectedColumns returns the columns loaded into each table, with the
+// price columns created as typ. Prices load into any numeric type.
+func expectedColumns(cols []areaColumn, typ string) []tableColumns {
+	prices := []string{strings.ToLower(typ)}
+	for _, t := range []string{"real", "double precision", "numeric"} {
+		if t != prices[0] {
+			prices = append(prices, t)
+		}
+	}
+	ts := drift.Column{Name: "ts", Types: []string{"timestamp with time zone"}}
+	status := drift.Column{Name: "status", Types: []string{"text"}}
+	want := []drift.Column{ts}
+	for _, c := range cols {
+		want = append(want, drift.Column{Name: c.Column, Types: prices})
+	}
+	if recordCorrections {
+		want = append(want, drift.Column{Name: "dst_corrected", Types: []string{"boolean"}}, drift.Column{Name: "original_local", Types: []string{"text"}})
+	}
+	tables := []tableColumns{{targetTable, append(want, status)}}
+	if perArea {
+		tables = append(tables, tableColumns{areaTable, []drift.Column{{Name: "area", Types: []string{"text"}}, ts, {Name: "price", Types: prices}, status}})
+	}
+	return tables
+}
+
+// statsSQL returns the query counting the rows of the temporary table
+// %[2]s that are new, identical and conflicting in the target table
+// %[1]s, comparing the same columns as upsertSQL.
+func statsSQL(cols []areaColumn, extra ...string) string {
+	var names []string
+	for _, c := range cols {
+		names = append(names, pq.QuoteIdentifier(c.Column))
+	}
+	for _, name := range extra {
+		names = append(names, pq.QuoteIdentifier(name))
+	}
+	names = append(names, "status")
+	in := "(s." + strings.Join(names, ", s.") + ")"
+	stored := "(t." + strings.Join(names, ", t.") + ")"
+	return `SELECT count(*) FILTER (WHERE t.ts IS NULL),
+    count(*) FILTER (WHERE t.ts IS NOT NULL AND ` + in + ` IS NOT DISTINCT FROM ` + stored + `),
+    count(*) FILTER (WHERE t.ts IS NOT NULL AND ` + in + ` IS DISTINCT FROM ` + stored + `)
+    FROM %[2]s s LEFT JOIN %[1]s t ON t.ts = s.ts`
+}
+
+// writeAreaReport writes which of the areas found in the files and the
+// -areas cols are loaded into table elspot of database name, with the
+// flags and DDL that would load the rest. load are the columns
+// selectColumns chose to load and existing the columns of the table.
+func writeAreaReport(w io.Writer, name string, found []string, cols, load []areaColumn, existing map[string]bool, typ string) {
+	inFiles := make(map[string]bool, len(found))
+	for _, area := range found {
+		inFiles[area] = true
+	}
+	requested := make(map[string]bool, len(cols))
+	for _, c := range cols {
+		requested[c.Area] = true
+	}
+	loaded := make(map[string]bool, len(load))
+	for _, c := range load {
+		loaded[c.Area] = true
+	}
+
+	fmt.Fprintf(w, "%s: areas in files vs table %s:\n", name, targetTable)
+	suggest := make([]string, 0, len(cols))
+	for _, c := range cols {
+		suggest = append(suggest, c.Area)
+	}
+	var ddl []string
+	for _, area := range found {
+		col := strings.ToLower(area)
+		if area == mainArea {
+			col = "fi"
+		}
+		var note string
+		switch {
+		case loaded[area]:
+			note = "loaded"
+		case !columnName.MatchString(col):
+			note = "not loaded: not a valid column name, rename it in elspot.columns of the config file"
+		case requested[area]:
+			note = "not loaded: no column, use -auto-add-columns"
+			ddl = append(ddl, addColumnSQL(col, typ))
+		case existing[col]:
+			note = "not loaded: not in -areas"
+			suggest = append(suggest, area)
+		default:
+			note = "not loaded: not in -areas, no column"
+			suggest = append(suggest, area)
+			ddl = append(ddl, addColumnSQL(col, typ))
+		}
+		fmt.Fprintf(w, "  %-8s %-8s %s\n", area, col, note)
+	}
+	for _, c := range cols {
+		if !inFiles[c.Area] {
+			fmt.Fprintf(w, "  %-8s %-8s in -areas, not in files\n", c.Area, c.Column)
+		}
+	}
+	if len(suggest) == len(cols) && len(ddl) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "to load all areas:\n")
+	if len(suggest) > len(cols) {
+		fmt.Fprintf(w, "  -areas %s\n", strings.Join(suggest, ","))
+	}
+	for _, stmt := range ddl {
+		fmt.Fprintf(w, "  %s;\n", stmt)
+	}
+}
+
+func addColumnSQL(column, typ string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s",
+		pq.QuoteIdentifier(targetTable), pq.QuoteIdentifier(column), typ)
+}
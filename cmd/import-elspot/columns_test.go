@@ -0,0 +1,168 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/joneskoo/etget/elspot"
+	"github.com/joneskoo/etget/internal/drift"
+)
+
+func TestParseAreas(t *testing.T) {
+	got, err := parseAreas("SE3, FI,EE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []areaColumn{{"FI", "fi"}, {"SE3", "se3"}, {"EE", "ee"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseAreas = %v, want %v", got, want)
+	}
+	if _, err := parseAreas("Kr.sand"); err == nil {
+		t.Error("parseAreas(Kr.sand) did not return error")
+	}
+}
+
+func TestParseAreasRenamedFI(t *testing.T) {
+	defer func(area string) { mainArea = area }(mainArea)
+	mainArea = "finland"
+	for _, list := range []string{"FI", "finland,SE3", "fi, SE3"} {
+		got, err := parseAreas(list)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got[0] != (areaColumn{"finland", "fi"}) {
+			t.Errorf("parseAreas(%q) = %v, want the Finnish price first", list, got)
+		}
+		for _, c := range got[1:] {
+			if c.Column == "fi" {
+				t.Errorf("parseAreas(%q) = %v, loads column fi twice", list, got)
+			}
+		}
+	}
+}
+
+// TestRenamedColumns checks that renamed areas load into columns of their
+// new name, except the Finnish price, which stays in column fi.
+func TestRenamedColumns(t *testing.T) {
+	defer func(area string) { mainArea = area }(mainArea)
+	mainArea = "finland"
+	cols, err := parseAreas("finland,stockholm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []areaColumn{{"finland", "fi"}, {"stockholm", "stockholm"}}
+	if !reflect.DeepEqual(cols, want) {
+		t.Errorf("parseAreas = %v, want %v", cols, want)
+	}
+	existing := map[string]bool{"ts": true, "fi": true, "stockholm": true}
+	load, _, _ := selectColumns(cols, existing, false, "REAL")
+	var b strings.Builder
+	writeAreaReport(&b, "db", []string{"finland", "stockholm"}, cols, load, existing, "REAL")
+	wantReport := `db: areas in files vs table elspot:
+  finland  fi       loaded
+  stockholm stockholm loaded
+`
+	if b.String() != wantReport {
+		t.Errorf("report:\n%s\nwant:\n%s", b.String(), wantReport)
+	}
+}
+
+func TestSelectColumns(t *testing.T) {
+	cols := []areaColumn{{"FI", "fi"}, {"SE3", "se3"}, {"EE", "ee"}}
+	existing := map[string]bool{"ts": true, "fi": true, "se3": true}
+
+	load, ddl, skipped := selectColumns(cols, existing, false, "REAL")
+	if want := cols[:2]; !reflect.DeepEqual(load, want) {
+		t.Errorf("load = %v, want %v", load, want)
+	}
+	if len(ddl) != 0 {
+		t.Errorf("ddl = %v, want none", ddl)
+	}
+	if want := cols[2:]; !reflect.DeepEqual(skipped, want) {
+		t.Errorf("skipped = %v, want %v", skipped, want)
+	}
+
+	load, ddl, skipped = selectColumns(cols, existing, true, "REAL")
+	if !reflect.DeepEqual(load, cols) || len(skipped) != 0 {
+		t.Errorf("load, skipped = %v, %v; want all loaded", load, skipped)
+	}
+	if want := []string{`ALTER TABLE "elspot" ADD COLUMN IF NOT EXISTS "ee" REAL`}; !reflect.DeepEqual(ddl, want) {
+		t.Errorf("ddl = %q, want %q", ddl, want)
+	}
+}
+
+func TestUpsertSQLExtra(t *testing.T) {
+	got := upsertSQL([]areaColumn{{"FI", "fi"}}, correctionColumns...)
+	for _, want := range []string{
+		`(ts, "fi", "dst_corrected", "original_local", status)`,
+		`"original_local" = EXCLUDED."original_local", status = EXCLUDED.status`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("upsertSQL = %s\nwant it to contain %s", got, want)
+		}
+	}
+}
+
+// TestExpectedColumns checks that a table created by this version passes
+// the drift check and one with a text price column does not.
+func TestExpectedColumns(t *testing.T) {
+	defer func(c, a bool) { recordCorrections, perArea = c, a }(recordCorrections, perArea)
+	recordCorrections, perArea = true, true
+	tables := expectedColumns([]areaColumn{{"FI", "fi"}, {"SE3", "se3"}}, "DOUBLE PRECISION")
+	if len(tables) != 2 || tables[0].Table != targetTable || tables[1].Table != areaTable {
+		t.Fatalf("expectedColumns = %v, want %s and %s", tables, targetTable, areaTable)
+	}
+	have := map[string]string{
+		"id": "integer", "ts": "timestamp with time zone", "fi": "real", "se3": "double precision",
+		"status": "text", "dst_corrected": "boolean", "original_local": "text",
+	}
+	if r := drift.Compare(targetTable, have, tables[0].Columns); r != nil {
+		t.Errorf("current table drifted: %s", r)
+	}
+	have["se3"] = "text"
+	r := drift.Compare(targetTable, have, tables[0].Columns)
+	if r == nil || len(r.Fixes()) != 1 || r.Fixes()[0] != "ALTER TABLE elspot ALTER COLUMN se3 TYPE double precision USING se3::double precision" {
+		t.Errorf("text se3 report = %v", r)
+	}
+}
+
+func TestWriteAreaReport(t *testing.T) {
+	cols := []areaColumn{{"FI", "fi"}, {"SE3", "se3"}, {"EE", "ee"}}
+	existing := map[string]bool{"ts": true, "fi": true, "se3": true, "sys": true}
+	load, _, _ := selectColumns(cols, existing, false, "REAL")
+	var b strings.Builder
+	writeAreaReport(&b, "db", []string{"FI", "NO6", "SE3", "SYS"}, cols, load, existing, "REAL")
+	want := `db: areas in files vs table elspot:
+  FI       fi       loaded
+  NO6      no6      not loaded: not in -areas, no column
+  SE3      se3      loaded
+  SYS      sys      not loaded: not in -areas
+  EE       ee       in -areas, not in files
+to load all areas:
+  -areas FI,SE3,EE,NO6,SYS
+  ALTER TABLE "elspot" ADD COLUMN IF NOT EXISTS "no6" REAL;
+`
+	if got := b.String(); got != want {
+		t.Errorf("writeAreaReport wrote\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRowStatus(t *testing.T) {
+	cols := []areaColumn{{"FI", "fi"}, {"SE3", "se3"}}
+	cases := []struct {
+		quality map[string]string
+		want    string
+	}{
+		{nil, statusFinal},
+		{map[string]string{"SYS": elspot.StatusProvisional}, statusFinal},
+		{map[string]string{"SE3": elspot.StatusEstimated}, elspot.StatusEstimated},
+		{map[string]string{"FI": elspot.StatusProvisional, "SYS": elspot.StatusProvisional}, statusProvisional},
+	}
+	for _, c := range cases {
+		r := elspot.Record{Quality: c.quality, Provisional: c.quality != nil}
+		if got := rowStatus(r, cols); got != c.want {
+			t.Errorf("rowStatus(%v) = %s, want %s", c.quality, got, c.want)
+		}
+	}
+}
@@ -2,154 +2,602 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
-
 	"time"
 
+	"github.com/joneskoo/etget/elspot"
 	"github.com/joneskoo/etget/htmltable"
-	"github.com/joneskoo/etget/notz"
+	"github.com/joneskoo/etget/internal/archive"
+	"github.com/joneskoo/etget/internal/config"
+	"github.com/joneskoo/etget/internal/drift"
+	"github.com/joneskoo/etget/internal/ledger"
+	"github.com/joneskoo/etget/internal/mmap"
+	"github.com/joneskoo/etget/internal/partition"
+	"github.com/joneskoo/etget/internal/preset"
+	"github.com/joneskoo/etget/internal/pricecache"
+	"github.com/joneskoo/etget/internal/progress"
+	"github.com/joneskoo/etget/internal/runreport"
+	"github.com/joneskoo/etget/internal/spool"
+	"github.com/joneskoo/etget/internal/target"
+	"github.com/joneskoo/etget/internal/telemetry"
+	"github.com/joneskoo/etget/internal/zoneinfo"
+	"github.com/joneskoo/etget/zones"
 	"github.com/lib/pq"
 )
 
-const timeLayout = "02-01-2006 15"
+// exitQueued is the exit status of a run that queued its inputs with
+// -queue after a load failed, telling schedulers that nothing was loaded
+// apart from an ERROR exit (1).
+const exitQueued = 3
 
 func usage() {
-	fmt.Fprintf(os.Stderr, "Usage: %s ELSPOT\n\n", os.Args[0])
-	fmt.Fprintf(os.Stderr, "   ELSPOT	elspot 'xls' file name or URL\n\n")
+	fmt.Fprintf(os.Stderr, "Usage: %s [ELSPOT...]\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "   ELSPOT	elspot 'xls' file name or URL; where files overlap, the newest wins\n")
+	fmt.Fprintf(os.Stderr, "        	(default the elspot sources of the configuration file)\n\n")
 	flag.PrintDefaults()
 	os.Exit(1)
 }
 
-var traceTimings bool
+var (
+	traceTimings bool
+	perArea      bool
+	debugRows    int
+
+	// partitionMonthly creates the target table partitioned by month.
+	partitionMonthly bool
+
+	// storage is the -storage type of the price columns.
+	storage string
+
+	// parser reads the input files, with the -time-layout and
+	// -time-location overrides and the column mapping of the config file.
+	parser elspot.Parser
+
+	// mainArea is the key in elspot.Record.Prices of the Finnish price
+	// loaded into the fi column, after any renaming in the config file.
+	mainArea = "FI"
+
+	// priceColumns are the -areas loaded into columns of table elspot.
+	priceColumns = []areaColumn{{mainArea, "fi"}}
+
+	// recordCorrections stores which hours had their offset inferred at
+	// the end of summer time.
+	recordCorrections bool
+
+	// chunkMonthly commits each month of the records separately.
+	chunkMonthly bool
+
+	// autoAddColumns adds missing -areas columns instead of skipping them.
+	autoAddColumns bool
+
+	// areaReport prints the areas found in the files against the columns
+	// of each target.
+	areaReport bool
+
+	// notify sends ledger.NotifyChannel notifications of the hours loaded.
+	notify bool
+
+	// zoneCodes stores areas in the area table under their bidding zone
+	// codes, e.g. NO1 for Oslo.
+	zoneCodes bool
+
+	// mmapFiles maps local input files into memory instead of reading
+	// them.
+	mmapFiles bool
+
+	// rawArchive keeps a copy of every input, if -archive is set.
+	rawArchive *archive.Archive
+
+	// events writes the -progress-json events; nil without the flag.
+	events *progress.Reporter
+
+	// queue holds the inputs of runs that could not be loaded, if -queue
+	// is set.
+	queue *spool.Spool
+
+	// warnf records a warning in the run report.
+	warnf = log.Printf
+)
 
 func main() {
-	connstring := flag.String("connstring", "sslmode=disable", "https://www.postgresql.org/docs/current/static/libpq-connect.html#LIBPQ-CONNSTRING")
-	flag.BoolVar(&traceTimings, "trace", false, "trace execution time")
+	connstrings := target.NewList("sslmode=disable")
+	flag.Var(connstrings, "connstring", "https://www.postgresql.org/docs/current/static/libpq-connect.html#LIBPQ-CONNSTRING; repeat to load several databases")
+	ddlConnstring := flag.String("ddl-connstring", "", "connection string of the table owner role used to create tables (default -connstring)")
+	flag.BoolVar(&traceTimings, "trace", false, "trace execution time, allocations and peak memory of each stage")
+	flag.BoolVar(&partitionMonthly, "partition-monthly", false, "create table "+targetTable+" partitioned by month, adding missing partitions on import")
+	flag.StringVar(&storage, "storage", "", "convert price columns to `type` "+storageFloat+" (DOUBLE PRECISION) or "+storageDecimal+" (NUMERIC(10,2)); default keeps the existing type")
+	areaList := flag.String("areas", "FI", "comma-separated `areas` loaded into price columns of table "+targetTable+", named by the lowercased area code")
+	presetName := flag.String("preset", "", "country preset ("+strings.Join(preset.Names(), ", ")+") whose bidding areas are the default -areas")
+	flag.BoolVar(&autoAddColumns, "auto-add-columns", false, "add missing -areas columns to table "+targetTable+" instead of skipping them")
+	flag.BoolVar(&chunkMonthly, "chunk-monthly", false, "commit each month separately, so that an interrupted load of large files resumes after the last committed month when run again")
+	flag.BoolVar(&recordCorrections, "record-corrections", false, "store in columns dst_corrected and original_local of table "+targetTable+" which hours repeated at the end of summer time had their offset inferred")
+	flag.BoolVar(&areaReport, "area-report", false, "print which areas of the files each target has columns for, with the -areas and DDL to load the rest")
+	flag.BoolVar(&perArea, "per-area", false, "also load every area into table "+areaTable+", partitioned by area and, with -partition-monthly, by month")
+	flag.BoolVar(&notify, "notify", false, "send NOTIFY "+ledger.NotifyChannel+" with the JSON time range of the changed hours when a load commits, for listeners that react to new prices")
+	flag.BoolVar(&zoneCodes, "zone-codes", false, "with -per-area, store city names of old files such as Oslo under their zone code (NO1), and warn of prices of zones that did not exist at the time")
+	flag.Var(&parser.Limits, "html-limits", "refuse HTML files exceeding any of these `limits`: bytes=N,tables=N,rows=N,cell=N (default "+htmltable.DefaultLimits.String()+")")
+	numbers := flag.String("numbers", "auto", "number `format` of the prices: comma for 1.234,56, point for 1,234.56, or auto to decide per value")
+	flag.StringVar(&parser.TimeLayout, "time-layout", elspot.DefaultTimeLayout, "Go time layout of the date and hour columns joined by a space")
+	timeLocation := flag.String("time-location", "Europe/Paris", "time zone of the timestamps in files that do not name one")
+	flag.IntVar(&debugRows, "debug-rows", 0, "print the first `N` parsed rows of each file and exit")
+	allowAnomalies := flag.Bool("allow-anomalies", false, "import prices that differ implausibly from the hours around them")
+	anomalyFactor := flag.Float64("anomaly-factor", 10, "price ratio to both neighbouring hours that is an anomaly")
+	filterExpr := flag.String("filter", "", "load only the prices matching this `expression`, e.g. 'area==FI && price>100'; fields are area, price, hour, date, time and provisional")
+	werror := flag.Bool("werror", false, "fail if parsing an input gives warnings, such as unparsable prices or skipped rows")
+	configFile := flag.String("config", config.DefaultFile, "configuration file with the elspot column mapping and the hooks run after the import")
+	profile := flag.String("profile", "", "configuration `profile` to use (default $"+config.ProfileEnv+")")
+	redisURL := flag.String("redis", "", "after loading, cache the next 48 hours of -areas prices in the Redis server at `URL` (redis://[:password@]host[:port][/db])")
+	flag.BoolVar(&mmapFiles, "mmap", false, "map local input files into memory instead of copying them to the heap, for very large historical files")
+	queueDir := flag.String("queue", "", "when a database cannot be loaded, queue the inputs in this `directory` and retry them on the next runs")
+	queueAttempts := flag.Int("queue-attempts", spool.DefaultMaxAttempts, "failed loads after which a queued input is moved to the dead letters of -queue")
+	queueMax := flag.Int("queue-max", 100, "most inputs waiting in -queue; when it is full, failing runs exit with an error instead")
+	runReportDir := flag.String("run-report", "", "write a JSON report of the run to this `directory`")
+	archiveDir := flag.String("archive", "", "keep a compressed copy of every input in this `directory`, named by its SHA-256 as in the ledger, to reproduce imports later")
+	archiveCodec := flag.String("archive-compression", archive.DefaultCodec, "compression of -archive files: "+strings.Join(archive.Codecs(), ", "))
+	progressJSON := flag.Bool("progress-json", false, "write progress events (stage, rows, percent, eta) to standard error as JSON lines, for programs running the import")
 	flag.Usage = usage
 	flag.Parse()
 
-	if flag.NArg() != 1 {
-		flag.Usage()
+	run := runreport.New("import-elspot", *runReportDir, flag.CommandLine)
+	warnf = run.Warnf
+	if *progressJSON {
+		events = progress.New(os.Stderr)
 	}
 
-	progress := timer{time.Now()}
-
-	var src io.ReadCloser
+	if *profile != "" {
+		os.Setenv(config.ProfileEnv, *profile)
+	}
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		run.Fatalf("ERROR %s", err)
+	}
+	if cfg.Connstring != "" && !flagSet("connstring") {
+		connstrings.Set(cfg.Connstring)
+	}
+	if len(cfg.Elspot.Areas) > 0 && !flagSet("areas") {
+		*areaList = strings.Join(cfg.Elspot.Areas, ",")
+	}
+	names := flag.Args()
+	if len(names) == 0 {
+		names = cfg.Elspot.Sources
+	}
+	parser.Columns = cfg.Elspot.Columns
+	parser.Ignore = cfg.Elspot.Ignore
+	for from, to := range cfg.Elspot.Columns {
+		if strings.EqualFold(from, "FI") {
+			mainArea = to
+		}
+	}
 
-	// If ELSPOT is a URL, download it. If not, assume it's a file.
-	if u, err := url.Parse(flag.Arg(0)); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
-		resp, err := http.Get(flag.Arg(0))
+	if *presetName != "" && !flagSet("areas") {
+		p, err := preset.Lookup(*presetName)
 		if err != nil {
-			log.Fatalf("ERROR opening URL: %s", err)
+			run.Fatalf("ERROR -preset: %s", err)
 		}
-		if resp.StatusCode != http.StatusOK {
-			log.Fatalf("ERROR got HTTP status code: %d, want %d", resp.StatusCode, http.StatusOK)
+		*areaList = strings.Join(p.Areas, ",")
+	}
+	if priceColumns, err = parseAreas(*areaList); err != nil {
+		run.Fatalf("ERROR -areas: %s", err)
+	}
+
+	if parser.Location, err = zoneinfo.Load(*timeLocation); err != nil {
+		run.Fatalf("ERROR -time-location: %s", err)
+	}
+	if parser.Numbers, err = elspot.ParseNumberFormat(*numbers); err != nil {
+		run.Fatalf("ERROR -numbers: %s", err)
+	}
+	if *archiveDir != "" {
+		if rawArchive, err = archive.Open(*archiveDir, *archiveCodec); err != nil {
+			run.Fatalf("ERROR -archive: %s", err)
 		}
-		src = resp.Body
-	} else {
-		src, err = os.OpenFile(flag.Arg(0), os.O_RDONLY, 0)
-		if err != nil {
-			log.Fatalf("ERROR opening data file: %s", err)
+	}
+	var filter *elspot.Filter
+	if *filterExpr != "" {
+		if filter, err = elspot.ParseFilter(*filterExpr); err != nil {
+			run.Fatalf("ERROR -filter: %s", err)
 		}
 	}
-	defer src.Close()
 
-	progress.Track("open file")
+	var queued []spool.Item
+	if *queueDir != "" {
+		queue = &spool.Spool{Dir: *queueDir, MaxAttempts: *queueAttempts, MaxPending: *queueMax}
+		if queued, err = queue.Pending(); err != nil {
+			run.Fatalf("ERROR -queue: %s", err)
+		}
+		if len(names) == 0 && len(queued) == 0 {
+			fmt.Println("OK! queue is empty")
+			return
+		}
+	}
+	if len(names) < 1 && len(queued) == 0 {
+		flag.Usage()
+	}
+	if *ddlConnstring != "" && len(connstrings.Values) > 1 {
+		run.Fatalf("ERROR -ddl-connstring cannot be used with several -connstring targets")
+	}
+	if err := checkStorage(storage); err != nil {
+		run.Fatalf("ERROR -storage: %s", err)
+	}
 
-	tables, err := htmltable.Parse(src)
-	if err != nil {
-		log.Fatalf("ERROR parsing HTML table: %s", err)
+	tracer := telemetry.New("import-elspot", cfg.Telemetry.WithEnv())
+	root := tracer.Start("import-elspot", nil)
+	// fatalf ends the trace as failed before exiting.
+	fatalf := func(format string, args ...interface{}) {
+		root.End(fmt.Errorf(format, args...))
+		flushTrace(tracer)
+		run.Fatalf(format, args...)
 	}
 
-	progress.Track("parse html")
+	progress := newTimer()
 
-	records, err := parseTable(tables[0])
-	if err != nil {
-		log.Fatalf("ERROR parsing elspot table: %s", err)
+	// Parse all inputs before loading anything, so that overlapping files
+	// can be merged with the newest file winning. Inputs queued by earlier
+	// runs are retried along with the new ones.
+	sources := make([]input, 0, len(names)+len(queued))
+	for _, name := range names {
+		sources = append(sources, input{source: name})
+	}
+	for i := range queued {
+		sources = append(sources, input{source: queued[i].Source, item: &queued[i]})
+	}
+	var inputs []input
+	warnings := 0
+	parsing := events.Stage("parse", "", len(sources))
+	var parsed int64
+	for _, src := range sources {
+		name, path := src.source, src.source
+		if src.item != nil {
+			name += " (queued)"
+			path = queue.Path(*src.item)
+		}
+		parser.Warn = func(w elspot.Warning) {
+			warnings++
+			run.Warnf("%s: %s", name, w)
+		}
+		span := tracer.Start("parse", root)
+		span.SetAttr("input", name)
+		in, err := parseInput(path, &progress)
+		span.SetAttr("records", len(in.records))
+		span.End(err)
+		if err != nil && src.item != nil {
+			if err := queue.Bury(*src.item, err); err != nil {
+				fatalf("ERROR moving %s to dead letters: %s", name, err)
+			}
+			run.Warnf("%s: %s; moved to dead letters", name, err)
+			continue
+		}
+		if err != nil {
+			fatalf("ERROR %s: %s", name, err)
+		}
+		in.source, in.item = src.source, src.item
+		inputs = append(inputs, in)
+		parsed += int64(len(in.records))
+		parsing.Step(parsed)
+		if debugRows > 0 {
+			printRows(os.Stdout, name, in.records, debugRows)
+		}
+	}
+	if debugRows > 0 {
+		return
+	}
+	if *werror && warnings > 0 {
+		fatalf("ERROR %d parse warnings with -werror", warnings)
 	}
+	if len(inputs) == 0 {
+		root.End(nil)
+		flushTrace(tracer)
+		if err := run.Write(); err != nil {
+			log.Fatalf("ERROR writing run report: %s", err)
+		}
+		return
+	}
+	sort.SliceStable(inputs, func(i, j int) bool { return inputs[i].modTime.Before(inputs[j].modTime) })
+	sets := make([][]elspot.Record, len(inputs))
+	files := make([]ledger.File, len(inputs))
+	for i, in := range inputs {
+		sets[i] = in.records
+		files[i] = in.file
+	}
+	run.Inputs = append(run.Inputs, files...)
+	merging := events.Stage("merge", "", 1)
+	merge := tracer.Start("merge", root)
+	records := elspot.Merge(sets...)
+	merge.SetAttr("records", len(records))
+	merge.End(nil)
+	// Filter after merging, so that a newer file still replaces the
+	// prices of an older one even where only the older one matches.
+	if filter != nil {
+		n := len(records)
+		records = filter.Apply(records)
+		log.Printf("-filter kept %d of %d rows", len(records), n)
+	}
+	if anomalies := elspot.Anomalies(records, *anomalyFactor); len(anomalies) > 0 {
+		for _, a := range anomalies {
+			run.Warnf("anomaly %s", a)
+		}
+		if !*allowAnomalies {
+			fatalf("ERROR %d price anomalies, possibly misread decimals; check the files or import with -allow-anomalies", len(anomalies))
+		}
+	}
+	merging.Done(int64(len(records)))
 
-	progress.Track("parse table")
+	progress.Track("merge inputs")
 
-	rowsAffected, err := loadToPostgres(*connstring, records)
-	if err != nil {
-		log.Fatalf("ERROR importing to PostgreSQL: %s", err)
-	}
+	results := target.LoadStats(connstrings.Values, func(connstring string, stats *target.Stats) (int64, error) {
+		span := tracer.Start("load", root)
+		span.SetAttr("target", target.Redact(connstring))
+		n, err := loadToPostgres(connstring, *ddlConnstring, records, files, stats)
+		span.SetAttr("rows_affected", n)
+		span.End(err)
+		return n, err
+	})
 
 	progress.Track("load to postgres")
 
-	fmt.Printf("OK! %d rows affected\n", rowsAffected)
+	run.SetTargets(results)
+	if err := target.Summarize(os.Stdout, results); err != nil {
+		if queue == nil {
+			fatalf("ERROR importing to PostgreSQL: %s", err)
+		}
+		// The first failure says more than the count of failed targets.
+		for _, res := range results {
+			if res.Err != nil {
+				err = fmt.Errorf("%s: %s", res.Target, res.Err)
+				break
+			}
+		}
+		if err := requeue(inputs, err, run.Warnf); err != nil {
+			fatalf("ERROR queueing inputs for retry: %s", err)
+		}
+		root.End(err)
+		flushTrace(tracer)
+		run.Error = fmt.Sprintf("inputs queued for retry: %s", err)
+		if err := run.Write(); err != nil {
+			log.Fatalf("ERROR writing run report: %s", err)
+		}
+		log.Printf("ERROR %s", run.Error)
+		os.Exit(exitQueued)
+	}
+	for _, in := range inputs {
+		if in.item != nil {
+			if err := queue.Done(*in.item); err != nil {
+				run.Warnf("removing %s from -queue: %s", in.source, err)
+			}
+		}
+	}
+	if *redisURL != "" {
+		span := tracer.Start("cache", root)
+		hours, err := cachePrices(*redisURL, records, time.Now())
+		span.End(err)
+		if err != nil {
+			fatalf("ERROR caching prices in Redis: %s", err)
+		}
+		fmt.Printf("OK! redis: %d hours cached\n", hours)
+	}
+	root.End(nil)
+	flushTrace(tracer)
+	var loaded int64
+	for _, res := range results {
+		loaded += res.RowsAffected
+	}
+	events.Done(loaded)
+	if err := run.Finish(cfg.Hooks); err != nil {
+		log.Fatalf("ERROR writing run report: %s", err)
+	}
 }
 
-type record struct {
-	Timestamp time.Time
-	Prices    map[string]string
+// flagSet reports whether flag name was given on the command line.
+func flagSet(name string) (set bool) {
+	flag.Visit(func(f *flag.Flag) { set = set || f.Name == name })
+	return set
 }
 
-// records implements notz.Interface for notz.FixDST.
-type records []record
-
-func (r records) Len() int                     { return len(r) }
-func (r records) Time(i int) time.Time         { return r[i].Timestamp }
-func (r records) SetTime(i int, new time.Time) { r[i].Timestamp = new }
-
-func parseTable(table htmltable.Table) (data []record, err error) {
-	var loc *time.Location
-	loc, err = time.LoadLocation("Europe/Paris")
+// cachePrices writes the upcoming prices of the -areas columns to Redis.
+func cachePrices(redisURL string, records []elspot.Record, now time.Time) (int, error) {
+	c, err := pricecache.Dial(redisURL)
 	if err != nil {
-		return nil, err
+		return 0, err
+	}
+	defer c.Close()
+	areas := make([]pricecache.Area, len(priceColumns))
+	for i, pc := range priceColumns {
+		areas[i] = pricecache.Area{Area: pc.Area, Column: pc.Column}
+	}
+	return pricecache.Write(c, records, areas, now)
+}
 
+// flushTrace exports the spans of the run. Telemetry is best effort: an
+// unreachable collector does not fail the import.
+func flushTrace(t *telemetry.Tracer) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := t.Flush(ctx); err != nil {
+		log.Printf("ERROR exporting telemetry: %s", err)
 	}
-	header := table.Headers[2]
+}
 
-	commaToPeriod := strings.NewReplacer(",", ".")
+// input is a parsed elspot file.
+type input struct {
+	records []elspot.Record
 
-	for _, t := range table.Rows {
-		prices := make(map[string]string, len(header)-1)
-		for i, k := range header {
-			prices[k] = commaToPeriod.Replace(t[i])
-		}
-		if prices["SYS"] == "" {
+	// modTime is the file modification time, or the Last-Modified time
+	// of a URL.
+	modTime time.Time
+
+	// file is the digest of the input recorded in the import ledger.
+	file ledger.File
+
+	// source is the file name or URL of the input, and item its entry in
+	// -queue if it was queued by an earlier run.
+	source string
+	item   *spool.Item
+
+	// doc is the contents, kept for -queue.
+	doc []byte
+}
+
+// requeue queues new inputs for the next run after loading failed with
+// cause, and records another failure of the inputs already queued.
+func requeue(inputs []input, cause error, warnf func(string, ...interface{})) error {
+	for _, in := range inputs {
+		if in.item == nil {
+			if _, err := queue.Put(in.source, in.doc, in.modTime); err != nil {
+				return fmt.Errorf("%s: %s", in.source, err)
+			}
+			warnf("%s: queued for retry: %s", in.source, cause)
 			continue
 		}
+		dead, err := queue.Fail(*in.item, cause)
+		if err != nil {
+			return fmt.Errorf("%s: %s", in.source, err)
+		}
+		if dead {
+			warnf("%s: moved to dead letters after %d attempts: %s", in.source, in.item.Attempts+1, cause)
+		} else {
+			warnf("%s: still queued after %d attempts: %s", in.source, in.item.Attempts+1, cause)
+		}
+	}
+	return nil
+}
+
+// parseInput opens and parses the elspot file or URL name.
+func parseInput(name string, progress *timer) (in input, err error) {
+	var src io.ReadCloser
+	var mapped *mmap.File
+	fileName := name
 
-		// Date is t[0], and hour is first two bytes of t[1]
-		ts, err := time.ParseInLocation(timeLayout, fmt.Sprintf("%s %s", t[0], t[1][0:2]), loc)
+	// If name is a URL, download it. If not, assume it's a file.
+	if u, err := url.Parse(name); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		resp, err := http.Get(name)
+		if err != nil {
+			return in, fmt.Errorf("opening URL: %s", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return in, fmt.Errorf("got HTTP status code: %d, want %d", resp.StatusCode, http.StatusOK)
+		}
+		in.modTime, _ = http.ParseTime(resp.Header.Get("Last-Modified"))
+		src = resp.Body
+	} else if mmapFiles {
+		mapped, err = mmap.Open(name)
 		if err != nil {
-			return nil, fmt.Errorf("parsing timestamp: %s", err)
+			return in, fmt.Errorf("mapping data file: %s", err)
 		}
+		defer mapped.Close()
+		if fi, err := os.Stat(name); err == nil {
+			in.modTime = fi.ModTime()
+		}
+		if abs, err := filepath.Abs(name); err == nil {
+			fileName = abs
+		}
+		src = ioutil.NopCloser(bytes.NewReader(mapped.Bytes()))
+	} else {
+		f, err := os.Open(name)
+		if err != nil {
+			return in, fmt.Errorf("opening data file: %s", err)
+		}
+		if fi, err := f.Stat(); err == nil {
+			in.modTime = fi.ModTime()
+		}
+		if abs, err := filepath.Abs(name); err == nil {
+			fileName = abs
+		}
+		src = f
+	}
+	defer src.Close()
 
-		data = append(data, record{
-			Timestamp: ts,
-			Prices:    prices,
-		})
+	progress.Track("open file")
+
+	h := ledger.NewHash()
+	var buf bytes.Buffer
+	copies := io.MultiWriter(h, &buf)
+	if mapped != nil {
+		copies = h
+	}
+	tables, err := parser.Limits.Parse(io.TeeReader(src, copies))
+	if err != nil {
+		return in, fmt.Errorf("parsing HTML table: %s", err)
+	}
+	in.file = h.File(fileName)
+	doc := buf.Bytes()
+	if mapped != nil {
+		doc = mapped.Bytes()
+	}
+	if rawArchive != nil {
+		if _, err := rawArchive.Put(doc); err != nil {
+			return in, fmt.Errorf("archiving input: %s", err)
+		}
+	}
+	if queue != nil {
+		in.doc = doc
+		if mapped != nil {
+			// The mapping ends when parseInput returns.
+			in.doc = append([]byte(nil), doc...)
+		}
 	}
-	notz.FixDST(records(data))
-	return
-}
 
-type timer struct{ time.Time }
+	progress.Track("parse html")
 
-func (t *timer) Track(msg string) {
-	if !traceTimings {
-		return
+	if len(tables) == 0 {
+		return in, elspot.ErrNoTable
+	}
+
+	p := parser
+	if loc, marker := elspot.DetectLocation(doc); loc != nil {
+		log.Printf("%s: timestamps in %s (file says %s)", name, loc, marker)
+		p.Location = loc
+	} else {
+		log.Printf("%s: timestamps in %s (-time-location)", name, p.Location)
+	}
+	in.records, err = p.ParseTable(tables[0])
+	if err != nil {
+		return in, fmt.Errorf("parsing elspot table: %s", err)
+	}
+
+	progress.Track("parse table")
+
+	return in, nil
+}
+
+// printRows prints the first n records of file name for checking that the
+// time layout and location are right.
+func printRows(w io.Writer, name string, records []elspot.Record, n int) {
+	fmt.Fprintf(w, "%s: %d rows\n", name, len(records))
+	if n > len(records) {
+		n = len(records)
 	}
-	if t.IsZero() {
-		t.Time = time.Now()
+	for _, r := range records[:n] {
+		areas := make([]string, 0, len(r.Prices))
+		for area := range r.Prices {
+			areas = append(areas, area)
+		}
+		sort.Strings(areas)
+		prices := make([]string, len(areas))
+		for i, area := range areas {
+			prices[i] = area + "=" + r.Prices[area]
+		}
+		status := rowStatus(r, priceColumns)
+		fmt.Fprintf(w, "  %s %s %s\n", r.Timestamp.Format(time.RFC3339), status, strings.Join(prices, " "))
 	}
-	fmt.Println(msg, "took", time.Now().Sub(t.Time))
-	t.Time = time.Now()
 }
 
-func loadToPostgres(connstring string, records []record) (rowsAffected int64, err error) {
-	progress := timer{time.Now()}
+func loadToPostgres(connstring, ddlConnstring string, records []elspot.Record, files []ledger.File, stats *target.Stats) (rowsAffected int64, err error) {
+	progress := newTimer()
 
 	db, err := sql.Open("postgres", connstring)
 	if err != nil {
@@ -163,22 +611,99 @@ func loadToPostgres(connstring string, records []record) (rowsAffected int64, er
 
 	progress.Track("connect to database")
 
+	types, err := columnTypes(db, targetTable)
+	if err != nil {
+		return 0, fmt.Errorf("read columns of %s: %s", targetTable, err)
+	}
+	existing := make(map[string]bool, len(types))
+	for name := range types {
+		existing[name] = true
+	}
+	columnType := "REAL"
+	if storage != "" {
+		columnType = storageTypes[storage]
+	}
+	columns, addColumns, skipped := selectColumns(priceColumns, existing, autoAddColumns, columnType)
+	for _, c := range skipped {
+		warnf("%s has no column %s, area %s not loaded; use -auto-add-columns to add it", targetTable, c.Column, c.Area)
+	}
+	if areaReport {
+		var report bytes.Buffer
+		writeAreaReport(&report, target.Redact(connstring), areas(records), priceColumns, columns, existing, columnType)
+		os.Stdout.Write(report.Bytes())
+	}
+
 	// Ensure table exists
-	_, err = db.Exec(createTableSQL)
+	var (
+		ddl         []string
+		first, last time.Time
+	)
+	if len(records) > 0 {
+		first, last = records[0].Timestamp, records[len(records)-1].Timestamp
+	}
+	if partitionMonthly && len(records) > 0 {
+		ddl = partition.MonthlySQL(targetTable, first, last)
+	}
+	if perArea {
+		ddl = append(ddl, areaTableSQL(areas(records), first, last)...)
+	}
+	var areaTypes map[string]string
+	if perArea && storage != "" {
+		if areaTypes, err = columnTypes(db, areaTable); err != nil {
+			return 0, fmt.Errorf("read columns of %s: %s", areaTable, err)
+		}
+	}
+	convert, err := storageSQL(storage, columns, types, areaTypes, perArea)
+	if err != nil {
+		return 0, err
+	}
+	ddl = append(ddl, convert...)
+	ddl = append(ddl, addColumns...)
+	if recordCorrections {
+		ddl = append(ddl, correctionsSQL)
+	}
+	err = ensureTable(db, ddlConnstring, ddl...)
 	if err != nil {
 		return 0, fmt.Errorf("ensure table exists: %s", err)
 	}
+	for _, t := range expectedColumns(columns, columnType) {
+		if err := drift.Check(db, t.Table, t.Columns); err != nil {
+			return 0, err
+		}
+	}
 
 	progress.Track("table exists")
 
+	months := monthChunks(records)
+	if !chunkMonthly || len(months) == 0 {
+		stage := events.Stage("load", target.Redact(connstring), 1)
+		n, err := loadChunk(db, columns, records, &progress, stats, func(txn *sql.Tx, n int64) error {
+			return ledger.Record(txn, ledger.SourceElspot, n, files...)
+		})
+		if err == nil {
+			stage.Done(n)
+		}
+		return n, err
+	}
+	stage := events.Stage("load", target.Redact(connstring), len(months))
+	return loadMonths(db, columns, months, files, &progress, stats, stage)
+}
+
+// loadChunk loads records in one transaction, calling commit in the
+// transaction with the number of rows affected before committing it. If
+// stats is not nil, the rows of the table elspot are counted in it.
+func loadChunk(db *sql.DB, columns []areaColumn, records []elspot.Record, progress *timer, stats *target.Stats, commit func(txn *sql.Tx, rowsAffected int64) error) (rowsAffected int64, err error) {
 	txn, err := db.Begin()
 	if err != nil {
 		return 0, fmt.Errorf("begin transaction: %s", err)
 	}
+	defer txn.Rollback()
 
 	progress.Track("begin transaction")
 
-	// Create an empty temporary table identical to target
+	// Create an empty temporary table identical to target. Temporary tables
+	// are private to the session, so concurrent imports do not collide as
+	// long as every reference is qualified with pg_temp.
 	_, err = txn.Exec(fmt.Sprintf("CREATE TEMP TABLE %s ON COMMIT DROP AS SELECT * FROM %s WITH NO DATA", pq.QuoteIdentifier(tmpTable), pq.QuoteIdentifier(targetTable)))
 	if err != nil {
 		return 0, fmt.Errorf("create temporary table: %s", err)
@@ -187,15 +712,48 @@ func loadToPostgres(connstring string, records []record) (rowsAffected int64, er
 	progress.Track("create temp table")
 
 	// Load data into temporary table
-	stmt, err := txn.Prepare(pq.CopyIn(tmpTable, "ts", "fi"))
+	copyColumns := []string{"ts"}
+	for _, c := range columns {
+		copyColumns = append(copyColumns, c.Column)
+	}
+	var extra []string
+	if recordCorrections {
+		extra = correctionColumns
+	}
+	copyColumns = append(copyColumns, extra...)
+	copyColumns = append(copyColumns, "status")
+	stmt, err := txn.Prepare(pq.CopyInSchema("pg_temp", tmpTable, copyColumns...))
 	if err != nil {
 		return 0, fmt.Errorf("copy data into temporary table: %s", err)
 	}
+	values := make([]interface{}, len(copyColumns))
 	for _, r := range records {
-		if r.Prices["FI"] == "" {
+		values[0] = r.Timestamp
+		empty := true
+		for i, c := range columns {
+			values[i+1] = nil
+			if r.Prices[c.Area] == "" {
+				continue
+			}
+			price, err := encodePrice(storage, r.Prices[c.Area])
+			if err != nil {
+				return 0, fmt.Errorf("%s %s: %s", r.Timestamp.Format(time.RFC3339), c.Area, err)
+			}
+			values[i+1] = price
+			empty = false
+		}
+		if empty {
 			continue
 		}
-		_, err = stmt.Exec(r.Timestamp, r.Prices["FI"])
+		if recordCorrections {
+			values[len(columns)+1] = r.Corrected
+			values[len(columns)+2] = nil
+			if r.Corrected {
+				values[len(columns)+2] = r.OriginalLocal
+			}
+		}
+		values[len(values)-1] = rowStatus(r, columns)
+		_, err = stmt.Exec(values...)
 		if err != nil {
 			return 0, fmt.Errorf("insert data into temporary table: %s", err)
 		}
@@ -211,8 +769,19 @@ func loadToPostgres(connstring string, records []record) (rowsAffected int64, er
 
 	progress.Track("load data into temp table")
 
-	// Copy data from temporary table into target
-	res, err := txn.Exec(fmt.Sprintf("INSERT INTO %s (ts, FI) SELECT ts, FI FROM %s ON CONFLICT DO NOTHING", pq.QuoteIdentifier(targetTable), pq.QuoteIdentifier(tmpTable)))
+	var chunk target.Stats
+	if stats != nil {
+		err = txn.QueryRow(fmt.Sprintf(statsSQL(columns, extra...), pq.QuoteIdentifier(targetTable), "pg_temp."+pq.QuoteIdentifier(tmpTable))).Scan(&chunk.New, &chunk.Identical, &chunk.Conflicting)
+		if err != nil {
+			return 0, fmt.Errorf("compare with stored rows: %s", err)
+		}
+
+		progress.Track("compare with stored rows")
+	}
+
+	// Copy data from temporary table into target. Provisional rows already
+	// in the target are replaced; final rows are never overwritten.
+	res, err := txn.Exec(fmt.Sprintf(upsertSQL(columns, extra...), pq.QuoteIdentifier(targetTable), "pg_temp."+pq.QuoteIdentifier(tmpTable)))
 	if err != nil {
 		return 0, fmt.Errorf("load data from temporary table: %s", err)
 	}
@@ -223,6 +792,33 @@ func loadToPostgres(connstring string, records []record) (rowsAffected int64, er
 
 	progress.Track("copy data to target table")
 
+	if perArea {
+		n, err := loadAreas(txn, records)
+		if err != nil {
+			return 0, err
+		}
+		rowsAffected += n
+
+		progress.Track("load areas")
+	}
+
+	err = commit(txn, rowsAffected)
+	if err != nil {
+		return 0, fmt.Errorf("record import: %s", err)
+	}
+
+	if notify && rowsAffected > 0 {
+		n := ledger.Notification{
+			Source:       ledger.SourceElspot,
+			From:         records[0].Timestamp.UTC(),
+			To:           records[len(records)-1].Timestamp.Add(time.Hour).UTC(),
+			RowsAffected: rowsAffected,
+		}
+		if err = ledger.Notify(txn, n); err != nil {
+			return 0, fmt.Errorf("notify %s: %s", ledger.NotifyChannel, err)
+		}
+	}
+
 	err = txn.Commit()
 	if err != nil {
 		return 0, fmt.Errorf("commit transaction: %s", err)
@@ -230,5 +826,200 @@ func loadToPostgres(connstring string, records []record) (rowsAffected int64, er
 
 	progress.Track("commit transaction")
 
+	if stats != nil {
+		stats.Add(chunk)
+	}
 	return
 }
+
+// loadMonths loads the months of -chunk-monthly one transaction at a time.
+// Each transaction moves a cursor keyed by the inputs, so that a run that
+// is interrupted and started again with the same files continues after
+// the last committed month. The last month records the import in the
+// ledger and removes the cursor.
+func loadMonths(db *sql.DB, columns []areaColumn, months [][]elspot.Record, files []ledger.File, progress *timer, stats *target.Stats, stage *progress.Stage) (total int64, err error) {
+	key := resumeKey(files)
+	cursor, err := ledger.Cursor(db, ledger.SourceElspot, key)
+	if err != nil {
+		return 0, fmt.Errorf("read resume marker: %s", err)
+	}
+	for i, m := range months {
+		last := m[len(m)-1].Timestamp
+		final := i == len(months)-1
+		if !final && !last.After(cursor) {
+			stage.Step(total)
+			continue
+		}
+		n, err := loadChunk(db, columns, m, progress, stats, func(txn *sql.Tx, n int64) error {
+			if !final {
+				return ledger.SetCursor(txn, ledger.SourceElspot, key, last)
+			}
+			if err := ledger.Record(txn, ledger.SourceElspot, total+n, files...); err != nil {
+				return err
+			}
+			return ledger.ClearCursor(txn, ledger.SourceElspot, key)
+		})
+		if err != nil {
+			return total, fmt.Errorf("month %s: %s (run again to resume)", m[0].Timestamp.UTC().Format("2006-01"), err)
+		}
+		total += n
+		stage.Step(total)
+	}
+	return total, nil
+}
+
+// monthChunks splits records sorted by time into UTC months, the ranges
+// of the -partition-monthly partitions.
+func monthChunks(records []elspot.Record) (months [][]elspot.Record) {
+	start := 0
+	for i := 1; i <= len(records); i++ {
+		if i == len(records) || !sameMonth(records[i].Timestamp, records[start].Timestamp) {
+			months = append(months, records[start:i])
+			start = i
+		}
+	}
+	return months
+}
+
+func sameMonth(a, b time.Time) bool {
+	a, b = a.UTC(), b.UTC()
+	return a.Year() == b.Year() && a.Month() == b.Month()
+}
+
+// resumeKey identifies a -chunk-monthly load by the contents of its
+// inputs, so that the same files resume and changed files start over.
+func resumeKey(files []ledger.File) string {
+	h := sha256.New()
+	for _, f := range files {
+		fmt.Fprintln(h, f.SHA256)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ensureTable creates the target table if it does not exist, followed by
+// any extra DDL statements. If ddlConnstring is set, the tables are created
+// over a separate connection so that the import itself can run as a role
+// without CREATE privilege.
+func ensureTable(db *sql.DB, ddlConnstring string, extra ...string) error {
+	if ddlConnstring != "" {
+		ddl, err := sql.Open("postgres", ddlConnstring)
+		if err != nil {
+			return err
+		}
+		defer ddl.Close()
+		db = ddl
+	}
+	stmts := []string{createTableSQL, ledger.CreateTableSQL}
+	if partitionMonthly {
+		stmts = append([]string{createPartitionedTableSQL}, stmts...)
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	if partitionMonthly {
+		if err := partition.Check(db, targetTable); err != nil {
+			return err
+		}
+	}
+	for _, stmt := range extra {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// areas returns the sorted area codes present in records.
+func areas(records []elspot.Record) []string {
+	seen := make(map[string]bool)
+	for _, r := range records {
+		for area := range r.Prices {
+			seen[area] = true
+		}
+	}
+	list := make([]string, 0, len(seen))
+	for area := range seen {
+		list = append(list, area)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// areaCode returns the name of area in the area table.
+func areaCode(area string) string {
+	if zoneCodes {
+		return zones.Code(area)
+	}
+	return area
+}
+
+// areaTableSQL returns the DDL for the area table and one partition per
+// area. With -partition-monthly, the partition of each area is in turn
+// partitioned by month, with partitions from the month of first to that of
+// last.
+func areaTableSQL(areas []string, first, last time.Time) []string {
+	stmts := []string{createAreaTableSQL}
+	seen := make(map[string]bool)
+	for _, area := range areas {
+		if area = areaCode(area); seen[area] {
+			continue
+		}
+		seen[area] = true
+		name := areaTable + "_" + strings.ToLower(area)
+		stmt := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES IN (%s)",
+			pq.QuoteIdentifier(name), pq.QuoteIdentifier(areaTable), pq.QuoteLiteral(area))
+		if !partitionMonthly {
+			stmts = append(stmts, stmt)
+			continue
+		}
+		stmts = append(stmts, stmt+" PARTITION BY RANGE (ts)")
+		stmts = append(stmts, partition.MonthlySQL(name, first, last)...)
+	}
+	return stmts
+}
+
+// loadAreas loads the price of every area in records into the area table
+// within txn.
+func loadAreas(txn *sql.Tx, records []elspot.Record) (rowsAffected int64, err error) {
+	_, err = txn.Exec(fmt.Sprintf("CREATE TEMP TABLE %s ON COMMIT DROP AS SELECT * FROM %s WITH NO DATA", pq.QuoteIdentifier(areaTmpTable), pq.QuoteIdentifier(areaTable)))
+	if err != nil {
+		return 0, fmt.Errorf("create temporary area table: %s", err)
+	}
+	stmt, err := txn.Prepare(pq.CopyInSchema("pg_temp", areaTmpTable, "area", "ts", "price", "status"))
+	if err != nil {
+		return 0, fmt.Errorf("copy data into temporary area table: %s", err)
+	}
+	warned := make(map[string]bool)
+	for _, r := range records {
+		for area, price := range r.Prices {
+			if price == "" {
+				continue
+			}
+			if price, err = encodePrice(storage, price); err != nil {
+				return 0, fmt.Errorf("%s %s: %s", area, r.Timestamp.Format(time.RFC3339), err)
+			}
+			if zoneCodes && zones.Known(area) && !warned[area] {
+				if _, err := zones.Lookup(area, r.Timestamp); err != nil {
+					warnf("%s: %s", area, err)
+					warned[area] = true
+				}
+			}
+			if _, err = stmt.Exec(areaCode(area), r.Timestamp, price, r.Status(area)); err != nil {
+				return 0, fmt.Errorf("insert data into temporary area table: %s", err)
+			}
+		}
+	}
+	if _, err = stmt.Exec(); err != nil {
+		return 0, fmt.Errorf("flush after loading area data: %s", err)
+	}
+	if err = stmt.Close(); err != nil {
+		return 0, err
+	}
+	res, err := txn.Exec(fmt.Sprintf(upsertAreaSQL, pq.QuoteIdentifier(areaTable), "pg_temp."+pq.QuoteIdentifier(areaTmpTable)))
+	if err != nil {
+		return 0, fmt.Errorf("load data from temporary area table: %s", err)
+	}
+	return res.RowsAffected()
+}
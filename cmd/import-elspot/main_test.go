@@ -0,0 +1,317 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/joneskoo/etget/elspot"
+	"github.com/joneskoo/etget/elspot/elspottest"
+	"github.com/joneskoo/etget/htmltable"
+	"github.com/joneskoo/etget/internal/ledger"
+	"github.com/joneskoo/etget/internal/target"
+	"github.com/joneskoo/etget/pgtest"
+	"github.com/joneskoo/etget/testserver"
+)
+
+func TestMain(m *testing.M) { os.Exit(pgtest.Main(m)) }
+
+// TestLoadToPostgresParallel runs two imports at the same time to verify
+// their temporary tables do not collide.
+func TestLoadToPostgresParallel(t *testing.T) {
+	db := pgtest.New(t)
+	defer db.Close()
+	connstring := db.Connstring
+	if err := ensureTable(db.DB, ""); err != nil {
+		t.Fatalf("ensureTable: %s", err)
+	}
+
+	start := time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	batch := func(day int) (r []elspot.Record) {
+		for h := 0; h < 24; h++ {
+			r = append(r, elspot.Record{
+				Timestamp: start.AddDate(0, 0, day).Add(time.Duration(h) * time.Hour),
+				Prices:    map[string]string{"FI": "1.5"},
+			})
+		}
+		return r
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	affected := make([]int64, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			affected[i], errs[i] = loadToPostgres(connstring, "", batch(i), nil, nil)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("import #%d: %s", i, err)
+		}
+		if affected[i] != 24 {
+			t.Errorf("import #%d: %d rows affected, want 24", i, affected[i])
+		}
+	}
+}
+
+// TestImportDST runs the whole import of a file with the end of summer
+// time, checking that the repeated hour is stored as two rows.
+func TestImportDST(t *testing.T) {
+	db := pgtest.New(t)
+	defer db.Close()
+
+	var progress timer
+	in, err := parseInput("../../elspot/testdata/dst-autumn.html", &progress)
+	if err != nil {
+		t.Fatalf("parseInput: %s", err)
+	}
+	n, err := loadToPostgres(db.Connstring, "", in.records, []ledger.File{in.file}, nil)
+	if err != nil {
+		t.Fatalf("loadToPostgres: %s", err)
+	}
+	if n != 3 {
+		t.Errorf("loadToPostgres affected %d rows, want 3", n)
+	}
+
+	rows, err := db.Query("SELECT ts, fi, status FROM elspot ORDER BY ts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	want := []struct {
+		utc    string
+		fi     float64
+		status string
+	}{
+		{"2015-10-24T23:00:00Z", 20.51, statusFinal},
+		{"2015-10-25T00:00:00Z", 19.44, statusFinal},
+		{"2015-10-25T01:00:00Z", 19.10, statusProvisional},
+	}
+	i := 0
+	for ; rows.Next(); i++ {
+		var (
+			ts     time.Time
+			fi     float64
+			status string
+		)
+		if err := rows.Scan(&ts, &fi, &status); err != nil {
+			t.Fatal(err)
+		}
+		if i >= len(want) {
+			continue
+		}
+		w := want[i]
+		if ts.UTC().Format(time.RFC3339) != w.utc || math.Abs(fi-w.fi) > 1e-4 || status != w.status {
+			t.Errorf("row %d = %s %g %s, want %s %g %s", i, ts.UTC().Format(time.RFC3339), fi, status, w.utc, w.fi, w.status)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if i != len(want) {
+		t.Errorf("got %d rows, want %d", i, len(want))
+	}
+
+	// Loading the same file again changes nothing; a changed price of a
+	// final hour conflicts and one hour more is new.
+	var stats target.Stats
+	if _, err := loadToPostgres(db.Connstring, "", in.records, nil, &stats); err != nil {
+		t.Fatalf("loadToPostgres again: %s", err)
+	}
+	if want := (target.Stats{Identical: 3}); stats != want {
+		t.Errorf("reload stats = %+v, want %+v", stats, want)
+	}
+	changed := append([]elspot.Record(nil), in.records...)
+	changed[0].Prices = map[string]string{"FI": "99.00"}
+	changed = append(changed, elspot.Record{Timestamp: changed[2].Timestamp.Add(time.Hour), Prices: map[string]string{"FI": "18.00"}})
+	stats = target.Stats{}
+	if _, err := loadToPostgres(db.Connstring, "", changed, nil, &stats); err != nil {
+		t.Fatalf("loadToPostgres changed: %s", err)
+	}
+	if want := (target.Stats{New: 1, Identical: 2, Conflicting: 1}); stats != want {
+		t.Errorf("changed stats = %+v, want %+v", stats, want)
+	}
+}
+
+func TestParseInputURL(t *testing.T) {
+	srv := testserver.New()
+	defer srv.Close()
+	srv.Handle("/elspot.xls", testserver.Status(http.StatusServiceUnavailable), testserver.File("../../elspot/testdata/dst-autumn.html"))
+
+	var progress timer
+	if _, err := parseInput(srv.URL+"/elspot.xls", &progress); err == nil {
+		t.Error("parseInput did not return error for HTTP 503")
+	}
+	in, err := parseInput(srv.URL+"/elspot.xls", &progress)
+	if err != nil {
+		t.Fatalf("parseInput: %s", err)
+	}
+	if len(in.records) != 4 {
+		t.Errorf("parseInput returned %d records, want 4", len(in.records))
+	}
+	if in.file.Name != srv.URL+"/elspot.xls" || in.file.SHA256 == "" {
+		t.Errorf("parseInput file = %+v, want digest of the URL", in.file)
+	}
+}
+
+// dailyRecords returns a price at noon UTC of each day from start.
+func dailyRecords(start time.Time, days int) (r []elspot.Record) {
+	for d := 0; d < days; d++ {
+		r = append(r, elspot.Record{
+			Timestamp: start.AddDate(0, 0, d).Add(12 * time.Hour),
+			Prices:    map[string]string{"FI": "1.5"},
+		})
+	}
+	return r
+}
+
+func TestMonthChunks(t *testing.T) {
+	months := monthChunks(dailyRecords(time.Date(2019, 1, 30, 0, 0, 0, 0, time.UTC), 31))
+	var sizes []int
+	for _, m := range months {
+		sizes = append(sizes, len(m))
+	}
+	if want := []int{2, 28, 1}; !reflect.DeepEqual(sizes, want) {
+		t.Errorf("month sizes = %v, want %v", sizes, want)
+	}
+	if monthChunks(nil) != nil {
+		t.Error("monthChunks(nil) is not empty")
+	}
+}
+
+func TestAreaTableSQLZoneCodes(t *testing.T) {
+	defer func(v bool) { zoneCodes = v }(zoneCodes)
+	zoneCodes = true
+	stmts := areaTableSQL([]string{"NO1", "Oslo", "SYS"}, time.Time{}, time.Time{})
+	if len(stmts) != 3 {
+		t.Fatalf("got %d statements, want table and partitions NO1 and SYS:\n%s", len(stmts), strings.Join(stmts, "\n"))
+	}
+	for i, area := range []string{"'NO1'", "'SYS'"} {
+		if !strings.Contains(stmts[i+1], area) {
+			t.Errorf("statement %d = %s, want partition for %s", i+1, stmts[i+1], area)
+		}
+	}
+}
+
+func TestAreaTableSQLMonthly(t *testing.T) {
+	defer func(v bool) { partitionMonthly = v }(partitionMonthly)
+	partitionMonthly = true
+	first := time.Date(2025, 1, 31, 22, 0, 0, 0, time.UTC)
+	stmts := areaTableSQL([]string{"FI"}, first, first.Add(2*time.Hour))
+	want := []string{
+		createAreaTableSQL,
+		`CREATE TABLE IF NOT EXISTS "elspot_area_fi" PARTITION OF "elspot_area" FOR VALUES IN ('FI') PARTITION BY RANGE (ts)`,
+		`CREATE TABLE IF NOT EXISTS "elspot_area_fi_y2025m01" PARTITION OF "elspot_area_fi" FOR VALUES FROM ('2025-01-01T00:00:00Z') TO ('2025-02-01T00:00:00Z')`,
+		`CREATE TABLE IF NOT EXISTS "elspot_area_fi_y2025m02" PARTITION OF "elspot_area_fi" FOR VALUES FROM ('2025-02-01T00:00:00Z') TO ('2025-03-01T00:00:00Z')`,
+	}
+	if !reflect.DeepEqual(stmts, want) {
+		t.Errorf("areaTableSQL() =\n%s\nwant\n%s", strings.Join(stmts, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// TestChunkMonthlyResume checks that a -chunk-monthly load continues after
+// the month in the resume marker and removes the marker when done.
+func TestChunkMonthlyResume(t *testing.T) {
+	db := pgtest.New(t)
+	defer db.Close()
+	if err := ensureTable(db.DB, ""); err != nil {
+		t.Fatalf("ensureTable: %s", err)
+	}
+	defer func(old bool) { chunkMonthly = old }(chunkMonthly)
+	chunkMonthly = true
+
+	records := dailyRecords(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), 31+28+31)
+	files := []ledger.File{{Name: "elspot-2019.xls", SHA256: "abc", Size: 1}}
+	key := resumeKey(files)
+
+	// An earlier run committed January.
+	txn, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ledger.SetCursor(txn, ledger.SourceElspot, key, records[30].Timestamp); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := loadToPostgres(db.Connstring, "", records, files, nil)
+	if err != nil {
+		t.Fatalf("loadToPostgres: %s", err)
+	}
+	if n != 28+31 {
+		t.Errorf("loadToPostgres affected %d rows, want %d of February and March", n, 28+31)
+	}
+	if cursor, err := ledger.Cursor(db.DB, ledger.SourceElspot, key); err != nil || !cursor.IsZero() {
+		t.Errorf("resume marker after load = %s, %v; want none", cursor, err)
+	}
+}
+
+func TestParseInputMmap(t *testing.T) {
+	defer func(old bool) { mmapFiles = old }(mmapFiles)
+	var progress timer
+	const name = "../../elspot/testdata/dst-autumn.html"
+	read, err := parseInput(name, &progress)
+	if err != nil {
+		t.Fatalf("parseInput: %s", err)
+	}
+	mmapFiles = true
+	mapped, err := parseInput(name, &progress)
+	if err != nil {
+		t.Fatalf("parseInput with -mmap: %s", err)
+	}
+	if !reflect.DeepEqual(mapped.records, read.records) {
+		t.Errorf("parseInput with -mmap = %+v, want %+v", mapped.records, read.records)
+	}
+	if mapped.file != read.file || !mapped.modTime.Equal(read.modTime) {
+		t.Errorf("parseInput with -mmap file = %+v at %s, want %+v at %s", mapped.file, mapped.modTime, read.file, read.modTime)
+	}
+}
+
+// BenchmarkParseInput compares the memory allocated parsing a large file
+// read to the heap and mapped with -mmap.
+func BenchmarkParseInput(b *testing.B) {
+	f := elspottest.File{
+		From:  time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:    time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		Areas: []string{"FI", "SE1", "SE2", "SE3", "SE4", "NO1", "NO2", "DK1", "DK2", "EE"},
+	}
+	tmp, err := ioutil.TempFile("", "elspot")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(f.HTML()); err != nil {
+		b.Fatal(err)
+	}
+	tmp.Close()
+
+	defer func(old htmltable.Limits, mapped bool) { parser.Limits, mmapFiles = old, mapped }(parser.Limits, mmapFiles)
+	parser.Limits = htmltable.Limits{Bytes: -1, Rows: -1}
+	for _, mapped := range []bool{false, true} {
+		mmapFiles = mapped
+		b.Run(fmt.Sprintf("mmap=%v", mapped), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var progress timer
+				if _, err := parseInput(tmp.Name(), &progress); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
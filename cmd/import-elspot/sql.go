@@ -1,12 +1,51 @@
 package main
 
+import "github.com/joneskoo/etget/elspot"
+
+// correctionColumns are the columns of -record-corrections.
+var correctionColumns = []string{"dst_corrected", "original_local"}
+
 const (
 	targetTable = "elspot"
 	tmpTable    = "_elspot_tmp"
 
+	areaTable    = "elspot_area"
+	areaTmpTable = "_elspot_area_tmp"
+
 	createTableSQL = `CREATE TABLE IF NOT EXISTS elspot (
     id      SERIAL,
     ts      TIMESTAMPTZ UNIQUE,
     FI      REAL
-    );`
+    );
+    ALTER TABLE elspot ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'final';`
+
+	// createPartitionedTableSQL creates the table for -partition-monthly.
+	// It must run before createTableSQL, which then only adds columns.
+	createPartitionedTableSQL = `CREATE TABLE IF NOT EXISTS elspot (
+    id      SERIAL,
+    ts      TIMESTAMPTZ UNIQUE,
+    FI      REAL
+    ) PARTITION BY RANGE (ts);`
+
+	// createAreaTableSQL creates the parent table for -per-area. Partitions
+	// are added per area by areaTableSQL.
+	createAreaTableSQL = `CREATE TABLE IF NOT EXISTS elspot_area (
+    area    TEXT NOT NULL,
+    ts      TIMESTAMPTZ NOT NULL,
+    price   REAL,
+    status  TEXT NOT NULL DEFAULT 'final',
+    PRIMARY KEY (area, ts)
+    ) PARTITION BY LIST (area);`
+
+	upsertAreaSQL = `INSERT INTO %[1]s AS t (area, ts, price, status)
+    SELECT area, ts, price, status FROM %[2]s
+    ON CONFLICT (area, ts) DO UPDATE SET price = EXCLUDED.price, status = EXCLUDED.status
+    WHERE t.status <> 'final'`
+
+	// correctionsSQL adds the columns of -record-corrections.
+	correctionsSQL = `ALTER TABLE elspot ADD COLUMN IF NOT EXISTS dst_corrected BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS original_local TEXT;`
+
+	statusFinal       = elspot.StatusFinal
+	statusProvisional = elspot.StatusProvisional
 )
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+// Price storage types selected with -storage. Floats suit analytics;
+// decimals keep prices exact for billing.
+const (
+	storageFloat   = "float"
+	storageDecimal = "decimal"
+)
+
+// storageTypes maps -storage to the SQL type of the price columns.
+var storageTypes = map[string]string{
+	storageFloat:   "DOUBLE PRECISION",
+	storageDecimal: "NUMERIC(10,2)",
+}
+
+// storedTypes are the types of storageTypes as columnTypes reports them.
+var storedTypes = map[string]string{
+	storageFloat:   "double precision",
+	storageDecimal: "numeric(10,2)",
+}
+
+// checkStorage returns an error if storage is not a -storage type.
+func checkStorage(storage string) error {
+	if _, ok := storageTypes[storage]; storage != "" && !ok {
+		return fmt.Errorf("unknown storage type %q, want %s or %s", storage, storageFloat, storageDecimal)
+	}
+	return nil
+}
+
+// storageSQL returns the statements converting to storage the columns of
+// table elspot loaded into, and with perArea the price of table
+// elspot_area. have and haveArea are the column types of the tables by
+// columnTypes. As ALTER TABLE locks the table, only columns of another
+// type are converted. Missing columns are left to -auto-add-columns,
+// which creates them with the type, except fi and price, which are created
+// with their tables as REAL. An empty storage keeps the existing columns.
+func storageSQL(storage string, columns []areaColumn, have, haveArea map[string]string, perArea bool) ([]string, error) {
+	if err := checkStorage(storage); err != nil || storage == "" {
+		return nil, err
+	}
+	typ, want := storageTypes[storage], storedTypes[storage]
+	var stmts []string
+	convert := func(table, column, have string) {
+		if have != want {
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s",
+				pq.QuoteIdentifier(table), pq.QuoteIdentifier(column), typ))
+		}
+	}
+	for _, c := range columns {
+		if t, ok := have[c.Column]; ok || c.Column == "fi" {
+			convert(targetTable, c.Column, t)
+		}
+	}
+	if perArea {
+		convert(areaTable, "price", haveArea["price"])
+	}
+	return stmts, nil
+}
+
+// exactPrice matches prices that NUMERIC(10,2) stores without rounding.
+var exactPrice = regexp.MustCompile(`^-?\d{1,8}(\.\d{1,2})?$`)
+
+// encodePrice validates the price text for the column type. Decimal
+// storage rejects prices it would round, rather than change them silently.
+func encodePrice(storage, price string) (string, error) {
+	if storage == storageDecimal {
+		if !exactPrice.MatchString(price) {
+			return "", fmt.Errorf("price %q cannot be stored exactly as %s", price, storageTypes[storageDecimal])
+		}
+		return price, nil
+	}
+	if _, err := strconv.ParseFloat(price, 64); err != nil {
+		return "", fmt.Errorf("invalid price %q", price)
+	}
+	return price, nil
+}
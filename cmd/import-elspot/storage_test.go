@@ -0,0 +1,61 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncodePrice(t *testing.T) {
+	cases := []struct {
+		storage, in string
+		ok          bool
+	}{
+		{"", "16.39", true},
+		{storageFloat, "16.391", true},
+		{storageDecimal, "16.39", true},
+		{storageDecimal, "-0.5", true},
+		{storageDecimal, "16.391", false},
+		{storageDecimal, "1e3", false},
+		{storageFloat, "n/a", false},
+	}
+	for _, c := range cases {
+		got, err := encodePrice(c.storage, c.in)
+		if (err == nil) != c.ok || (c.ok && got != c.in) {
+			t.Errorf("encodePrice(%q, %q) = %q, %v, want ok %v", c.storage, c.in, got, err, c.ok)
+		}
+	}
+}
+
+func TestStorageSQL(t *testing.T) {
+	columns := []areaColumn{{"FI", "fi"}, {"SE3", "se3"}, {"EE", "ee"}, {"NO1", "no1"}}
+	have := map[string]string{"ts": "timestamp with time zone", "fi": "real", "se3": "numeric(10,2)", "ee": "numeric"}
+	stmts, err := storageSQL(storageDecimal, columns, have, map[string]string{"price": "real"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`ALTER TABLE "elspot" ALTER COLUMN "fi" TYPE NUMERIC(10,2)`,
+		`ALTER TABLE "elspot" ALTER COLUMN "ee" TYPE NUMERIC(10,2)`,
+		`ALTER TABLE "elspot_area" ALTER COLUMN "price" TYPE NUMERIC(10,2)`,
+	}
+	if !reflect.DeepEqual(stmts, want) {
+		t.Errorf("storageSQL(decimal) = %q, want %q", stmts, want)
+	}
+
+	// A new database: the tables are created with REAL prices.
+	stmts, _ = storageSQL(storageFloat, columns[:1], nil, nil, true)
+	if len(stmts) != 2 {
+		t.Errorf("storageSQL(float) of new tables = %q, want fi and price converted", stmts)
+	}
+
+	have = map[string]string{"fi": "double precision", "se3": "double precision"}
+	if stmts, _ := storageSQL(storageFloat, columns[:2], have, map[string]string{"price": "double precision"}, true); len(stmts) != 0 {
+		t.Errorf("storageSQL(float) of converted columns = %q, want none", stmts)
+	}
+	if stmts, _ := storageSQL("", columns, have, nil, true); len(stmts) != 0 {
+		t.Errorf("storageSQL(\"\") = %q, want none", stmts)
+	}
+	if _, err := storageSQL("money", columns, have, nil, false); err == nil {
+		t.Error("storageSQL(\"money\") did not return error")
+	}
+}
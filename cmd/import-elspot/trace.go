@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timer prints, with -trace, what each stage of the import took since the
+// previous one: wall-clock time, heap allocations and the peak resident
+// set size of the process so far. The targets and months are loaded one
+// after another, so the stages do not overlap, but the allocations are
+// those of the whole process, including any telemetry export running in
+// the background.
+type timer struct {
+	time.Time
+	alloc, mallocs uint64
+}
+
+func newTimer() timer {
+	var t timer
+	t.reset()
+	return t
+}
+
+func (t *timer) reset() {
+	t.Time = time.Now()
+	if !traceTimings {
+		return
+	}
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	t.alloc, t.mallocs = m.TotalAlloc, m.Mallocs
+}
+
+func (t *timer) Track(msg string) {
+	if !traceTimings {
+		return
+	}
+	if t.IsZero() {
+		t.reset()
+	}
+	took := time.Now().Sub(t.Time)
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	line := fmt.Sprintf("%s took %s, allocated %s in %d objects", msg, took, formatBytes(m.TotalAlloc-t.alloc), m.Mallocs-t.mallocs)
+	if rss, err := peakRSS(); err == nil {
+		line += ", peak RSS " + formatBytes(rss)
+	}
+	fmt.Println(line)
+	t.reset()
+}
+
+// procStatus is read for the peak RSS, which only Linux reports there.
+var procStatus = "/proc/self/status"
+
+// peakRSS returns the peak resident set size of the process in bytes.
+func peakRSS() (uint64, error) {
+	f, err := os.Open(procStatus)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return parseVmHWM(f)
+}
+
+// parseVmHWM reads the line "VmHWM:   123456 kB" of /proc/PID/status.
+func parseVmHWM(r io.Reader) (uint64, error) {
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) != 3 || fields[0] != "VmHWM:" || fields[2] != "kB" {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("VmHWM: %s", err)
+		}
+		return kb * 1024, nil
+	}
+	if err := sc.Err(); err != nil {
+		return 0, err
+	}
+	return 0, errors.New("no VmHWM")
+}
+
+// formatBytes formats n bytes in binary units, e.g. 1.5 MiB.
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit && exp < 3; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGT"[exp])
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseVmHWM(t *testing.T) {
+	status := "Name:\timport-elspot\nVmPeak:\t  812340 kB\nVmHWM:\t   45120 kB\nVmRSS:\t   40960 kB\n"
+	got, err := parseVmHWM(strings.NewReader(status))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := uint64(45120 * 1024); got != want {
+		t.Errorf("parseVmHWM = %d, want %d", got, want)
+	}
+	if _, err := parseVmHWM(strings.NewReader("Name:\tx\n")); err == nil {
+		t.Error("parseVmHWM without VmHWM succeeded")
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for _, tt := range []struct {
+		n    uint64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1536, "1.5 KiB"},
+		{45120 * 1024, "44.1 MiB"},
+		{3 << 30, "3.0 GiB"},
+	} {
+		if got := formatBytes(tt.n); got != tt.want {
+			t.Errorf("formatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/joneskoo/etget/energiatili"
+)
+
+// loadProfile is the relative consumption of a typical household in each
+// hour of the day, local time: low at night, peaking in the evening.
+var loadProfile = [24]float64{
+	30, 27, 25, 24, 24, 26, 33, 42, 45, 43, 41, 41,
+	41, 40, 40, 42, 47, 53, 56, 56, 54, 51, 45, 37,
+}
+
+// fillGaps estimates the consumption of hours missing from rows using the
+// daily totals. The part of a day's total not measured is distributed
+// across its missing hours in proportion to loadProfile, and those rows
+// are marked estimated. Only days within the measured hours are filled;
+// a day whose measured hours already exceed its total is left as is.
+// It returns the rows sorted by time and the number of hours estimated.
+func fillGaps(rows []meterRow, daily []energiatili.Record) ([]meterRow, int) {
+	var first, last time.Time
+	byHour := make(map[int64]int, len(rows))
+	for i, r := range rows {
+		byHour[r.Timestamp.Unix()] = i
+		if !r.KWh.Valid {
+			continue
+		}
+		if first.IsZero() {
+			first = r.Timestamp
+		}
+		last = r.Timestamp
+	}
+	if first.IsZero() {
+		return rows, 0
+	}
+
+	filled := 0
+	for _, d := range daily {
+		start := d.Timestamp.In(helsinki)
+		end := time.Date(start.Year(), start.Month(), start.Day()+1, 0, 0, 0, 0, helsinki)
+		if start.Before(first) || end.Add(-time.Hour).After(last) {
+			continue
+		}
+		var measured, weights float64
+		var missing []time.Time
+		for h := start; h.Before(end); h = h.Add(time.Hour) {
+			if i, ok := byHour[h.Unix()]; ok && rows[i].KWh.Valid {
+				measured += rows[i].KWh.Float64
+				continue
+			}
+			missing = append(missing, h)
+			weights += loadProfile[h.Hour()]
+		}
+		remaining := d.Value - measured
+		if len(missing) == 0 || remaining < 0 {
+			continue
+		}
+		for _, h := range missing {
+			kwh := sql.NullFloat64{Float64: remaining * loadProfile[h.Hour()] / weights, Valid: true}
+			if i, ok := byHour[h.Unix()]; ok {
+				rows[i].KWh = kwh
+				rows[i].Estimated = true
+			} else {
+				byHour[h.Unix()] = len(rows)
+				rows = append(rows, meterRow{Timestamp: h, KWh: kwh, Estimated: true})
+			}
+			filled++
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Timestamp.Before(rows[j].Timestamp) })
+	return rows, filled
+}
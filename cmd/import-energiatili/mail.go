@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/url"
+
+	"github.com/joneskoo/etget/energiatili"
+	"github.com/joneskoo/etget/internal/archive"
+	"github.com/joneskoo/etget/internal/imapfetch"
+	"github.com/joneskoo/etget/internal/ledger"
+)
+
+// mailReports fetches the consumption reports attached to the unseen
+// messages of the mailbox of o. The returned function marks the messages
+// the reports came from seen and logs out; call it once they are
+// imported, so that a failed import is retried on the next run.
+// Messages without a matching attachment are left unseen.
+func mailReports(o imapfetch.Options, rawArchive *archive.Archive) (reports []*energiatili.ConsumptionReport, files []ledger.File, closeMailbox func() error, err error) {
+	c, err := imapfetch.Dial(o)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("mailbox %s: %s", o.Addr, err)
+	}
+	uids, err := c.Unseen()
+	if err != nil {
+		c.Close()
+		return nil, nil, nil, err
+	}
+	var imported []uint32
+	for _, uid := range uids {
+		attachments, err := c.Attachments(uid)
+		if err != nil {
+			c.Close()
+			return nil, nil, nil, err
+		}
+		if len(attachments) == 0 {
+			log.Printf("Message %d has no report attached, left unseen", uid)
+			continue
+		}
+		for _, a := range attachments {
+			report, file, err := decodeReport(bytes.NewReader(a.Data), messageURL(o, uid, a.Name), rawArchive)
+			if err != nil {
+				c.Close()
+				return nil, nil, nil, fmt.Errorf("message %d: %s: %s", uid, a.Name, err)
+			}
+			log.Printf("Fetched %s from message %d", a.Name, uid)
+			reports = append(reports, report)
+			files = append(files, file)
+		}
+		imported = append(imported, uid)
+	}
+	closeMailbox = func() error {
+		for _, uid := range imported {
+			if err := c.MarkSeen(uid); err != nil {
+				c.Close()
+				return err
+			}
+		}
+		return c.Logout()
+	}
+	return reports, files, closeMailbox, nil
+}
+
+// messageURL names an attachment in the ledger, after the IMAP URLs of
+// RFC 5092.
+func messageURL(o imapfetch.Options, uid uint32, name string) string {
+	mailbox := o.Mailbox
+	if mailbox == "" {
+		mailbox = "INBOX"
+	}
+	return fmt.Sprintf("imap://%s/%s/;UID=%d/%s", o.Addr, url.PathEscape(mailbox), uid, url.PathEscape(name))
+}
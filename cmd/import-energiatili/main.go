@@ -2,26 +2,71 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"encoding/json"
 
 	"github.com/joneskoo/etget/energiatili"
+	"github.com/joneskoo/etget/internal/archive"
+	"github.com/joneskoo/etget/internal/config"
+	"github.com/joneskoo/etget/internal/drift"
+	"github.com/joneskoo/etget/internal/ledger"
+	"github.com/joneskoo/etget/internal/partition"
+	"github.com/joneskoo/etget/internal/runreport"
+	"github.com/joneskoo/etget/internal/target"
+	"github.com/joneskoo/etget/internal/zoneinfo"
 	"github.com/joneskoo/etget/keyring"
+	"github.com/joneskoo/etget/notz"
 	"github.com/lib/pq"
 )
 
 func main() {
 	credfile := flag.String("credfile", "./credentials.json", "File username/password are saved in (plaintext)")
-	connstring := flag.String("connstring", "sslmode=disable", "https://www.postgresql.org/docs/current/static/libpq-connect.html#LIBPQ-CONNSTRING")
+	sessionFile := flag.String("session", "", "keep the portal login session in this `file`, encrypted with $"+energiatili.SessionKeyEnv+", to skip logging in on later runs")
+	connstrings := target.NewList("sslmode=disable")
+	flag.Var(connstrings, "connstring", "https://www.postgresql.org/docs/current/static/libpq-connect.html#LIBPQ-CONNSTRING; repeat to load several databases")
+	ddlConnstring := flag.String("ddl-connstring", "", "connection string of the table owner role used to create tables (default -connstring)")
+	meterName := flag.String("meter", "default", "metering point the data is stored under: a name in the \"meters\" of -config or the meter_id itself")
+	consumptionReportFile := flag.String("report", "consumptionreport.json", "consumption report JSON file, downloaded if it does not exist; - reads standard input")
+	fromMail := flag.Bool("imap", false, "import the reports attached to unseen messages in the \"mailbox\" of -config instead of -report, marking the messages seen once imported")
+	partitionMonthly := flag.Bool("partition-monthly", false, "create table energiatili partitioned by month, adding missing partitions on import")
+	incremental := flag.Bool("incremental", false, "load only hours after the last hour loaded for -meter, and download the report only when newer hours can be available")
+	snapTolerance := flag.Duration("snap-tolerance", 0, "move hourly timestamps of a drifting meter clock within this `duration` of a whole hour to the hour, warning of those further off (default off)")
+	fillGapsFlag := flag.Bool("fill-gaps", false, "estimate hours missing within the data from the portal's daily totals using a typical household load profile, marking them estimated")
+	configFile := flag.String("config", config.DefaultFile, "configuration file with the hooks run after the import")
+	runReportDir := flag.String("run-report", "", "write a JSON report of the run to this `directory`")
+	archiveDir := flag.String("archive", "", "keep a compressed copy of every consumption report in this `directory`, named by its SHA-256 as in the ledger")
+	archiveCodec := flag.String("archive-compression", archive.DefaultCodec, "compression of -archive files: "+strings.Join(archive.Codecs(), ", "))
 	flag.Parse()
 
+	run := runreport.New("import-energiatili", *runReportDir, flag.CommandLine)
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		run.Fatalf("ERROR %s", err)
+	}
+	meter := cfg.MeterID(*meterName)
+	if *ddlConnstring != "" && len(connstrings.Values) > 1 {
+		run.Fatalf("ERROR -ddl-connstring cannot be used with several -connstring targets")
+	}
+	var rawArchive *archive.Archive
+	if *archiveDir != "" {
+		if rawArchive, err = archive.Open(*archiveDir, *archiveCodec); err != nil {
+			run.Fatalf("ERROR -archive: %s", err)
+		}
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
@@ -33,53 +78,252 @@ func main() {
 	client := &energiatili.Client{
 		UsernamePasswordFunc: cs.UsernamePassword,
 	}
+	if *sessionFile != "" {
+		passphrase := os.Getenv(energiatili.SessionKeyEnv)
+		if passphrase == "" {
+			run.Fatalf("ERROR -session requires the passphrase in $%s", energiatili.SessionKeyEnv)
+		}
+		client.Session = &energiatili.SessionFile{Name: *sessionFile, Passphrase: passphrase}
+	}
+
+	// The portal only serves the full report, so incremental runs save
+	// the download when every database already has yesterday's hours.
+	refresh := false
+	if *incremental && !*fromMail {
+		cursor, err := oldestCursor(connstrings.Values, meter)
+		if err != nil {
+			run.Fatalf("ERROR reading import cursor: %s", err)
+		}
+		if upToDate(cursor, time.Now()) {
+			log.Printf("Consumption of %s is up to date until %s", meter, cursor.In(helsinki).Format("2006-01-02 15:04"))
+			if err := run.Write(); err != nil {
+				log.Fatalf("ERROR writing run report: %s", err)
+			}
+			return
+		}
+		refresh = true
+	}
 
-	consumptionReportFile := "consumptionreport.json"
+	var reports []*energiatili.ConsumptionReport
+	var files []ledger.File
+	var closeMailbox func() error
+	if *fromMail {
+		if reports, files, closeMailbox, err = mailReports(cfg.Mailbox, rawArchive); err != nil {
+			run.Fatalf("ERROR fetching reports from mail: %s", err)
+		}
+		if len(reports) == 0 {
+			log.Println("No new consumption reports in the mailbox")
+			if err := closeMailbox(); err != nil {
+				log.Printf("WARNING closing mailbox: %s", err)
+			}
+			if err := run.Write(); err != nil {
+				log.Fatalf("ERROR writing run report: %s", err)
+			}
+			return
+		}
+	} else {
+		// Download data from API
+		var f *os.File
 
-	// Download data from API
-	var f *os.File
-	var err error
+		if *consumptionReportFile == "-" {
+			f = os.Stdin
+		} else if refresh {
+			err = os.ErrNotExist
+		} else {
+			f, err = os.Open(*consumptionReportFile)
+		}
+		if os.IsNotExist(err) {
+			log.Println("Downloading consumption data…")
+			f, err = os.Create(*consumptionReportFile)
+			if err != nil {
+				panic(err)
+			}
+			if err := client.ConsumptionReport(ctx, f); err != nil {
+				run.Fatalf("ERROR %s", err)
+			}
+			f.Seek(0, 0)
+		} else {
+			log.Println("Using cached consumption data:", *consumptionReportFile)
+		}
+		defer f.Close()
 
-	f, err = os.Open(consumptionReportFile)
-	if os.IsNotExist(err) {
-		log.Println("Downloading consumption data…")
-		f, err = os.Create(consumptionReportFile)
 		if err != nil {
 			panic(err)
 		}
-		if err := client.ConsumptionReport(ctx, f); err != nil {
-			log.Fatalln(err)
+
+		var name string
+		if *consumptionReportFile != "-" {
+			if name, err = filepath.Abs(*consumptionReportFile); err != nil {
+				run.Fatalf("ERROR resolving report file name: %s", err)
+			}
+		}
+		report, file, err := decodeReport(f, name, rawArchive)
+		if err != nil {
+			run.Fatalf("ERROR %s", err)
+		}
+		reports = append(reports, report)
+		if name != "" {
+			files = append(files, file)
+		}
+	}
+	var points, production []energiatili.Record
+	var daily []energiatili.Record
+	for _, consumptionreport := range reports {
+		consumptionreport.SnapTolerance = *snapTolerance
+		consumptionreport.Drift = func(d notz.Drift) {
+			run.Warnf("meter clock drift beyond -snap-tolerance: %s", d)
+		}
+		p, err := consumptionreport.Records()
+		if err != nil {
+			run.Fatalf("ERROR parsing data: %s", err)
+		}
+		pp, err := consumptionreport.ProductionRecords()
+		if err != nil {
+			run.Fatalf("ERROR parsing production data: %s", err)
+		}
+		points, production = append(points, p...), append(production, pp...)
+		daily = append(daily, consumptionreport.DailyRecords()...)
+	}
+	run.Inputs = append(run.Inputs, files...)
+	rows := meterRows(points, production)
+	if *fillGapsFlag {
+		var n int
+		rows, n = fillGaps(rows, daily)
+		if n > 0 {
+			log.Printf("Estimated %d missing hours from daily totals", n)
 		}
-		f.Seek(0, 0)
-	} else {
-		log.Println("Using cached consumption data:", consumptionReportFile)
 	}
-	defer f.Close()
 
-	if err != nil {
-		panic(err)
+	results := target.LoadStats(connstrings.Values, func(connstring string, stats *target.Stats) (int64, error) {
+		return importPoints(connstring, *ddlConnstring, meter, *partitionMonthly, *incremental, rows, files, stats)
+	})
+	run.SetTargets(results)
+	if err := target.Summarize(os.Stdout, results); err != nil {
+		run.Fatalf("ERROR importing to database: %s", err)
+	}
+	if closeMailbox != nil {
+		if err := closeMailbox(); err != nil {
+			run.Fatalf("ERROR marking imported messages seen: %s", err)
+		}
+	}
+	if err := run.Finish(cfg.Hooks); err != nil {
+		log.Fatalf("ERROR writing run report: %s", err)
 	}
+}
 
+// decodeReport decodes the consumption report read from r and returns it
+// with its ledger entry under name, keeping a copy in rawArchive if set.
+func decodeReport(r io.Reader, name string, rawArchive *archive.Archive) (*energiatili.ConsumptionReport, ledger.File, error) {
+	h := ledger.NewHash()
+	var raw bytes.Buffer
+	var copies io.Writer = h
+	if rawArchive != nil {
+		copies = io.MultiWriter(h, &raw)
+	}
+	src := io.TeeReader(r, copies)
 	var consumptionreport energiatili.ConsumptionReport
-	decoder := json.NewDecoder(f)
-	err = decoder.Decode(&consumptionreport)
-	if err != nil {
-		log.Fatalf("ERROR parsing JSON structure: %s", err)
+	if err := json.NewDecoder(src).Decode(&consumptionreport); err != nil {
+		return nil, ledger.File{}, fmt.Errorf("parsing JSON structure: %s", err)
 	}
-	points, err := consumptionreport.Records()
-	if err != nil {
-		log.Fatalf("ERROR parsing data: %s", err)
+	// Hash the whole file, not only what the decoder consumed
+	if _, err := io.Copy(ioutil.Discard, src); err != nil {
+		return nil, ledger.File{}, fmt.Errorf("reading consumption data: %s", err)
 	}
+	if rawArchive != nil {
+		if _, err := rawArchive.Put(raw.Bytes()); err != nil {
+			return nil, ledger.File{}, fmt.Errorf("archiving consumption data: %s", err)
+		}
+	}
+	return &consumptionreport, h.File(name), nil
+}
 
-	rowsAffected, err := importPoints(*connstring, points)
-	if err != nil {
-		log.Fatalf("ERROR importing to database: %s", err)
+// meterRow is the consumption and production of one hour. Either may be
+// missing.
+type meterRow struct {
+	Timestamp time.Time
+	KWh       sql.NullFloat64
+	Produced  sql.NullFloat64
+
+	// Estimated is set if KWh was not measured but estimated by fillGaps.
+	Estimated bool
+}
+
+// meterRows merges the consumption and production series by hour.
+func meterRows(consumption, production []energiatili.Record) []meterRow {
+	byHour := make(map[int64]*meterRow)
+	var rows []*meterRow
+	get := func(ts time.Time) *meterRow {
+		r, ok := byHour[ts.Unix()]
+		if !ok {
+			r = &meterRow{Timestamp: ts}
+			byHour[ts.Unix()] = r
+			rows = append(rows, r)
+		}
+		return r
 	}
+	for _, p := range consumption {
+		get(p.Timestamp).KWh = sql.NullFloat64{Float64: p.Value, Valid: true}
+	}
+	for _, p := range production {
+		get(p.Timestamp).Produced = sql.NullFloat64{Float64: p.Value, Valid: true}
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Timestamp.Before(rows[j].Timestamp) })
+	merged := make([]meterRow, len(rows))
+	for i, r := range rows {
+		merged[i] = *r
+	}
+	return merged
+}
+
+// helsinki is the time zone of the energiatili.fi reporting day.
+var helsinki = zoneinfo.MustLoad("Europe/Helsinki")
 
-	log.Printf("Loaded %d new rows", rowsAffected)
+// oldestCursor returns the earliest import cursor of meter among the
+// databases, the zero time if any database has none.
+func oldestCursor(connstrings []string, meter string) (oldest time.Time, err error) {
+	for i, connstring := range connstrings {
+		db, err := sql.Open("postgres", connstring)
+		if err != nil {
+			return time.Time{}, err
+		}
+		cursor, err := ledger.Cursor(db, ledger.SourceEnergiatili, meter)
+		db.Close()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%s: %s", target.Redact(connstring), err)
+		}
+		if i == 0 || cursor.Before(oldest) {
+			oldest = cursor
+		}
+	}
+	return oldest, nil
 }
 
-func importPoints(connstring string, points []energiatili.Record) (rowsAffected int64, err error) {
+// upToDate reports whether cursor is at the last hour of yesterday or
+// later; the portal publishes a day's consumption the next morning.
+func upToDate(cursor, now time.Time) bool {
+	now = now.In(helsinki)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, helsinki)
+	return !cursor.Before(today.Add(-time.Hour))
+}
+
+// rowsAfter returns the rows after cursor, and the last of them with
+// measured consumption, which the cursor is moved to. Hours estimated by
+// fillGaps stay after the cursor, so that a later run replaces them once
+// they are measured.
+func rowsAfter(rows []meterRow, cursor time.Time) (after []meterRow, last time.Time) {
+	for _, r := range rows {
+		if !r.Timestamp.After(cursor) {
+			continue
+		}
+		after = append(after, r)
+		if r.KWh.Valid && !r.Estimated {
+			last = r.Timestamp
+		}
+	}
+	return after, last
+}
+
+func importPoints(connstring, ddlConnstring, meter string, partitionMonthly, incremental bool, rows []meterRow, files []ledger.File, stats *target.Stats) (rowsAffected int64, err error) {
 	targetTable := "energiatili"
 	tmpTable := fmt.Sprintf("_%s_tmp", targetTable)
 
@@ -94,29 +338,42 @@ func importPoints(connstring string, points []energiatili.Record) (rowsAffected
 	}
 
 	// Ensure table exists
-	res, err := db.Exec(createTable)
+	err = ensureTable(db, ddlConnstring, partitionMonthly, rows)
 	if err != nil {
 		return 0, fmt.Errorf("ensure table exists: %s", err)
 	}
+	if err = drift.Check(db, targetTable, loadedColumns); err != nil {
+		return 0, err
+	}
+
+	var last time.Time
+	if incremental {
+		cursor, err := ledger.Cursor(db, ledger.SourceEnergiatili, meter)
+		if err != nil {
+			return 0, fmt.Errorf("read import cursor: %s", err)
+		}
+		rows, last = rowsAfter(rows, cursor)
+	}
 
 	txn, err := db.Begin()
 	if err != nil {
 		return 0, fmt.Errorf("begin transaction: %s", err)
 	}
 
-	// Create an empty temporary table identical to target
+	// Create an empty temporary table identical to target. It lives in the
+	// session's own pg_temp schema, so parallel imports cannot collide.
 	_, err = txn.Exec(fmt.Sprintf("CREATE TEMP TABLE %s ON COMMIT DROP AS SELECT * FROM %s WITH NO DATA", pq.QuoteIdentifier(tmpTable), pq.QuoteIdentifier(targetTable)))
 	if err != nil {
 		return 0, fmt.Errorf("create temporary table: %s", err)
 	}
 
 	// Load data into temporary table
-	stmt, err := txn.Prepare(pq.CopyIn(tmpTable, "ts", "kwh"))
+	stmt, err := txn.Prepare(pq.CopyInSchema("pg_temp", tmpTable, "meter_id", "ts", "kwh", "produced_kwh", "estimated"))
 	if err != nil {
 		return 0, fmt.Errorf("copy data into temporary table: %s", err)
 	}
-	for _, point := range points {
-		_, err = stmt.Exec(point.Timestamp.UTC(), point.Value)
+	for _, row := range rows {
+		_, err = stmt.Exec(meter, row.Timestamp.UTC(), row.KWh, row.Produced, row.Estimated)
 		if err != nil {
 			return 0, fmt.Errorf("insert data into temporary table: %s", err)
 		}
@@ -130,8 +387,14 @@ func importPoints(connstring string, points []energiatili.Record) (rowsAffected
 		return
 	}
 
+	var counted target.Stats
+	err = txn.QueryRow(fmt.Sprintf(statsSQL, pq.QuoteIdentifier(targetTable), "pg_temp."+pq.QuoteIdentifier(tmpTable))).Scan(&counted.New, &counted.Identical, &counted.Conflicting)
+	if err != nil {
+		return 0, fmt.Errorf("compare with stored rows: %s", err)
+	}
+
 	// Copy data from temporary table into target
-	res, err = txn.Exec(fmt.Sprintf("INSERT INTO %s (ts, kwh) SELECT ts, kwh FROM %s ON CONFLICT DO NOTHING", pq.QuoteIdentifier(targetTable), pq.QuoteIdentifier(tmpTable)))
+	res, err := txn.Exec(fmt.Sprintf(insertSQL, pq.QuoteIdentifier(targetTable), "pg_temp."+pq.QuoteIdentifier(tmpTable)))
 	if err != nil {
 		return 0, fmt.Errorf("load data from temporary table: %s", err)
 	}
@@ -140,9 +403,66 @@ func importPoints(connstring string, points []energiatili.Record) (rowsAffected
 		return
 	}
 
+	err = ledger.Record(txn, ledger.SourceEnergiatili, rowsAffected, files...)
+	if err != nil {
+		return 0, fmt.Errorf("record import: %s", err)
+	}
+	if !last.IsZero() {
+		if err = ledger.SetCursor(txn, ledger.SourceEnergiatili, meter, last); err != nil {
+			return 0, fmt.Errorf("move import cursor: %s", err)
+		}
+	}
+
 	err = txn.Commit()
 	if err != nil {
 		return 0, fmt.Errorf("commit transaction: %s", err)
 	}
+	stats.Add(counted)
 	return
 }
+
+// loadedColumns are the columns importPoints loads.
+var loadedColumns = []drift.Column{
+	{Name: "meter_id", Types: []string{"text"}},
+	{Name: "ts", Types: []string{"timestamp with time zone"}},
+	{Name: "kwh", Types: []string{"double precision", "real", "numeric"}},
+	{Name: "produced_kwh", Types: []string{"double precision", "real", "numeric"}},
+	{Name: "estimated", Types: []string{"boolean"}},
+}
+
+// ensureTable runs the table DDL, using ddlConnstring instead of db if set.
+// With partitionMonthly it also creates the monthly partitions the points
+// fall in.
+func ensureTable(db *sql.DB, ddlConnstring string, partitionMonthly bool, rows []meterRow) error {
+	if ddlConnstring != "" {
+		ddl, err := sql.Open("postgres", ddlConnstring)
+		if err != nil {
+			return err
+		}
+		defer ddl.Close()
+		db = ddl
+	}
+	if partitionMonthly {
+		if _, err := db.Exec(createPartitionedTable); err != nil {
+			return err
+		}
+	}
+	if _, err := db.Exec(createTable); err != nil {
+		return err
+	}
+	if _, err := db.Exec(ledger.CreateTableSQL); err != nil {
+		return err
+	}
+	if !partitionMonthly || len(rows) == 0 {
+		return nil
+	}
+	if err := partition.Check(db, "energiatili"); err != nil {
+		return err
+	}
+	for _, stmt := range partition.MonthlySQL("energiatili", rows[0].Timestamp, rows[len(rows)-1].Timestamp) {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
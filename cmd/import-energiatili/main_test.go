@@ -0,0 +1,135 @@
+package main
+
+import (
+	"database/sql"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/joneskoo/etget/energiatili"
+)
+
+func TestMeterRows(t *testing.T) {
+	ts := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+	consumption := []energiatili.Record{{Timestamp: ts, Value: 1.5}, {Timestamp: ts.Add(time.Hour), Value: 0.2}}
+	production := []energiatili.Record{{Timestamp: ts.Add(time.Hour), Value: 2.5}, {Timestamp: ts.Add(-time.Hour), Value: 3}}
+
+	rows := meterRows(consumption, production)
+	if len(rows) != 3 {
+		t.Fatalf("meterRows returned %d rows, want 3: %+v", len(rows), rows)
+	}
+	if !rows[0].Timestamp.Equal(ts.Add(-time.Hour)) || rows[0].KWh.Valid || rows[0].Produced.Float64 != 3 {
+		t.Errorf("rows[0] = %+v, want production only", rows[0])
+	}
+	if rows[1].KWh.Float64 != 1.5 || rows[1].Produced.Valid {
+		t.Errorf("rows[1] = %+v, want consumption only", rows[1])
+	}
+	if rows[2].KWh.Float64 != 0.2 || rows[2].Produced.Float64 != 2.5 {
+		t.Errorf("rows[2] = %+v, want both", rows[2])
+	}
+}
+
+func TestUpToDate(t *testing.T) {
+	now := time.Date(2025, 1, 10, 9, 30, 0, 0, helsinki)
+	tests := []struct {
+		cursor time.Time
+		want   bool
+	}{
+		{time.Time{}, false},
+		{time.Date(2025, 1, 9, 22, 0, 0, 0, helsinki), false},
+		{time.Date(2025, 1, 9, 23, 0, 0, 0, helsinki), true},
+		{time.Date(2025, 1, 10, 8, 0, 0, 0, helsinki), true},
+	}
+	for _, tt := range tests {
+		if got := upToDate(tt.cursor, now); got != tt.want {
+			t.Errorf("upToDate(%s) = %v, want %v", tt.cursor, got, tt.want)
+		}
+	}
+}
+
+func TestRowsAfter(t *testing.T) {
+	start := time.Date(2025, 1, 9, 0, 0, 0, 0, time.UTC)
+	kwh := sql.NullFloat64{Float64: 1, Valid: true}
+	rows := []meterRow{
+		{Timestamp: start, KWh: kwh},
+		{Timestamp: start.Add(time.Hour), KWh: kwh},
+		{Timestamp: start.Add(2 * time.Hour), KWh: kwh},
+		{Timestamp: start.Add(3 * time.Hour), Produced: kwh},
+	}
+	after, last := rowsAfter(rows, start.Add(time.Hour))
+	if len(after) != 2 || !after[0].Timestamp.Equal(start.Add(2*time.Hour)) {
+		t.Errorf("rowsAfter = %+v, want the last two rows", after)
+	}
+	if !last.Equal(start.Add(2 * time.Hour)) {
+		t.Errorf("last = %s, want the last hour with consumption %s", last, start.Add(2*time.Hour))
+	}
+}
+
+func TestRowsAfterEstimated(t *testing.T) {
+	start := time.Date(2025, 1, 9, 0, 0, 0, 0, time.UTC)
+	kwh := sql.NullFloat64{Float64: 1, Valid: true}
+	rows := []meterRow{
+		{Timestamp: start, KWh: kwh},
+		{Timestamp: start.Add(time.Hour), KWh: kwh, Estimated: true},
+		{Timestamp: start.Add(2 * time.Hour), KWh: kwh, Estimated: true},
+	}
+	after, last := rowsAfter(rows, start.Add(-time.Hour))
+	if len(after) != 3 {
+		t.Errorf("rowsAfter = %+v, want all rows", after)
+	}
+	if !last.Equal(start) {
+		t.Errorf("last = %s, want the last measured hour %s", last, start)
+	}
+	if _, last := rowsAfter(rows, start); !last.IsZero() {
+		t.Errorf("last = %s after only estimated hours, want the cursor kept", last)
+	}
+}
+
+func TestFillGaps(t *testing.T) {
+	day := time.Date(2025, 1, 9, 0, 0, 0, 0, helsinki)
+	kwh := sql.NullFloat64{Float64: 1, Valid: true}
+	var rows []meterRow
+	for h := 0; h < 48; h++ {
+		ts := day.Add(time.Duration(h) * time.Hour)
+		switch {
+		case h == 3 || h == 18:
+			// missing
+		case h == 19:
+			rows = append(rows, meterRow{Timestamp: ts, Produced: kwh})
+		default:
+			rows = append(rows, meterRow{Timestamp: ts, KWh: kwh})
+		}
+	}
+	daily := []energiatili.Record{
+		{Timestamp: day, Value: 24 + 8},                   // 3 hours, 11 kWh missing
+		{Timestamp: day.AddDate(0, 0, 1), Value: 30},      // complete
+		{Timestamp: day.AddDate(0, 0, 2), Value: 24},      // beyond the measured hours
+		{Timestamp: day.AddDate(0, 0, -1), Value: 24 + 5}, // before them
+	}
+	filled, n := fillGaps(rows, daily)
+	if n != 3 || len(filled) != 48 {
+		t.Fatalf("fillGaps = %d rows, %d estimated; want 48, 3", len(filled), n)
+	}
+	var sum float64
+	for i, r := range filled {
+		if !r.Timestamp.Equal(day.Add(time.Duration(i) * time.Hour)) {
+			t.Fatalf("filled[%d] at %s, want sorted hours", i, r.Timestamp)
+		}
+		if i >= 24 {
+			continue
+		}
+		sum += r.KWh.Float64
+		if estimated := i == 3 || i == 18 || i == 19; r.Estimated != estimated {
+			t.Errorf("filled[%d].Estimated = %v, want %v", i, r.Estimated, estimated)
+		}
+	}
+	if math.Abs(sum-32) > 1e-9 {
+		t.Errorf("day sums to %v kWh, want the daily total 32", sum)
+	}
+	if filled[3].KWh.Float64 >= filled[18].KWh.Float64 {
+		t.Errorf("03:00 estimate %v not below 18:00 estimate %v", filled[3].KWh.Float64, filled[18].KWh.Float64)
+	}
+	if filled[19].Produced.Float64 != 1 {
+		t.Errorf("filled[19] = %+v, production lost", filled[19])
+	}
+}
@@ -3,9 +3,45 @@ package main
 const (
 	dropTable = `DROP TABLE IF EXISTS energiatili;`
 
+	// createPartitionedTable creates the table for -partition-monthly,
+	// before createTable adds the remaining columns and indexes.
+	createPartitionedTable = `CREATE TABLE IF NOT EXISTS energiatili (
+    id SERIAL,
+    ts timestamptz,
+    kwh double precision,
+    temp real,
+    meter_id TEXT NOT NULL DEFAULT 'default',
+    produced_kwh double precision
+    ) PARTITION BY RANGE (ts);`
+
 	createTable = `CREATE TABLE IF NOT EXISTS energiatili (
     id SERIAL,
     ts timestamptz unique,
     kwh double precision,
-    temp real);`
+    temp real);
+    ALTER TABLE energiatili ADD COLUMN IF NOT EXISTS meter_id TEXT NOT NULL DEFAULT 'default';
+    ALTER TABLE energiatili DROP CONSTRAINT IF EXISTS energiatili_ts_key;
+    CREATE UNIQUE INDEX IF NOT EXISTS energiatili_meter_id_ts_key ON energiatili (meter_id, ts);
+    ALTER TABLE energiatili ADD COLUMN IF NOT EXISTS produced_kwh double precision;
+    ALTER TABLE energiatili ADD COLUMN IF NOT EXISTS estimated boolean NOT NULL DEFAULT false;`
+
+	// insertSQL copies new hours from the temporary table %[2]s into the
+	// target table %[1]s. Stored consumption is only replaced when it was
+	// estimated and the hour has since been measured; production is filled
+	// in for hours imported before it was available.
+	insertSQL = `INSERT INTO %[1]s AS t (meter_id, ts, kwh, produced_kwh, estimated)
+    SELECT meter_id, ts, kwh, produced_kwh, estimated FROM %[2]s
+    ON CONFLICT (meter_id, ts) DO UPDATE SET
+        kwh = CASE WHEN t.estimated AND NOT EXCLUDED.estimated AND EXCLUDED.kwh IS NOT NULL THEN EXCLUDED.kwh ELSE t.kwh END,
+        estimated = t.estimated AND (EXCLUDED.estimated OR EXCLUDED.kwh IS NULL),
+        produced_kwh = COALESCE(t.produced_kwh, EXCLUDED.produced_kwh)
+    WHERE (t.produced_kwh IS NULL AND EXCLUDED.produced_kwh IS NOT NULL)
+        OR (t.estimated AND NOT EXCLUDED.estimated AND EXCLUDED.kwh IS NOT NULL)`
+
+	// statsSQL counts the hours of the temporary table %[2]s that are new,
+	// identical and conflicting in the target table %[1]s.
+	statsSQL = `SELECT count(*) FILTER (WHERE t.ts IS NULL),
+    count(*) FILTER (WHERE t.ts IS NOT NULL AND (s.kwh, s.produced_kwh, s.estimated) IS NOT DISTINCT FROM (t.kwh, t.produced_kwh, t.estimated)),
+    count(*) FILTER (WHERE t.ts IS NOT NULL AND (s.kwh, s.produced_kwh, s.estimated) IS DISTINCT FROM (t.kwh, t.produced_kwh, t.estimated))
+    FROM %[2]s s LEFT JOIN %[1]s t ON t.meter_id = s.meter_id AND t.ts = s.ts`
 )
@@ -0,0 +1,263 @@
+// The import-exchange command loads Nord Pool elspot capacity and flow
+// files, the transmission capacities offered to the day-ahead market and
+// the resulting physical flows between bidding areas, into tables
+// elspot_capacity and elspot_flow.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/joneskoo/etget/elspot"
+	"github.com/joneskoo/etget/htmltable"
+	"github.com/joneskoo/etget/importer"
+	"github.com/joneskoo/etget/internal/config"
+	"github.com/joneskoo/etget/internal/ledger"
+	"github.com/joneskoo/etget/internal/runreport"
+	"github.com/joneskoo/etget/internal/target"
+	"github.com/joneskoo/etget/internal/zoneinfo"
+	"github.com/lib/pq"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s -kind capacity|flow FILE...\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "   FILE	elspot capacities or flow 'xls' file name or URL; where files overlap, the later one wins\n\n")
+	flag.PrintDefaults()
+	os.Exit(1)
+}
+
+func main() {
+	connstrings := target.NewList("sslmode=disable")
+	flag.Var(connstrings, "connstring", "https://www.postgresql.org/docs/current/static/libpq-connect.html#LIBPQ-CONNSTRING; repeat to load several databases")
+	ddlConnstring := flag.String("ddl-connstring", "", "connection string of the table owner role used to create tables (default -connstring)")
+	kind := flag.String("kind", "", "file contents, capacity or flow")
+	var parser elspot.Parser
+	flag.Var(&parser.Limits, "html-limits", "refuse HTML files exceeding any of these `limits`: bytes=N,tables=N,rows=N,cell=N (default "+htmltable.DefaultLimits.String()+")")
+	numbers := flag.String("numbers", "auto", "number `format` of the values: comma for 1.234,56, point for 1,234.56, or auto to decide per value")
+	flag.StringVar(&parser.TimeLayout, "time-layout", elspot.DefaultTimeLayout, "Go time layout of the date and hour columns joined by a space")
+	timeLocation := flag.String("time-location", "Europe/Paris", "time zone of the timestamps")
+	configFile := flag.String("config", config.DefaultFile, "configuration file with the hooks run after the import")
+	runReportDir := flag.String("run-report", "", "write a JSON report of the run to this `directory`")
+	flag.Usage = usage
+	flag.Parse()
+
+	run := runreport.New("import-exchange", *runReportDir, flag.CommandLine)
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		run.Fatalf("ERROR %s", err)
+	}
+
+	k, ok := kinds[*kind]
+	if !ok || flag.NArg() < 1 {
+		flag.Usage()
+	}
+	if *ddlConnstring != "" && len(connstrings.Values) > 1 {
+		run.Fatalf("ERROR -ddl-connstring cannot be used with several -connstring targets")
+	}
+	if parser.Location, err = zoneinfo.Load(*timeLocation); err != nil {
+		run.Fatalf("ERROR -time-location: %s", err)
+	}
+	if parser.Numbers, err = elspot.ParseNumberFormat(*numbers); err != nil {
+		run.Fatalf("ERROR -numbers: %s", err)
+	}
+	parser.Warn = func(w elspot.Warning) { run.Warnf("%s", w) }
+
+	imp := importer.Importer{
+		Source: importer.SourceFunc(func(ctx context.Context) (*importer.Batch, error) {
+			return readExchanges(flag.Args(), parser, k.source)
+		}),
+		Sinks: target.Sinks(connstrings.Values, func(ctx context.Context, connstring string, b *importer.Batch) (int64, error) {
+			return loadExchanges(connstring, *ddlConnstring, k.table, k.source, b.Data.([]elspot.Exchange), b.Inputs)
+		}),
+		BeforeLoad: []func(context.Context, *importer.Batch) error{
+			func(ctx context.Context, b *importer.Batch) error {
+				run.Inputs = append(run.Inputs, b.Inputs...)
+				return nil
+			},
+		},
+	}
+	res, err := imp.Run(context.Background())
+	if err != nil {
+		run.Fatalf("ERROR %s", err)
+	}
+	results := target.Results(res)
+	run.SetTargets(results)
+	if err := target.Summarize(os.Stdout, results); err != nil {
+		run.Fatalf("ERROR importing to PostgreSQL: %s", err)
+	}
+	if err := run.Finish(cfg.Hooks); err != nil {
+		log.Fatalf("ERROR writing run report: %s", err)
+	}
+}
+
+// readExchanges parses the files and merges them, the later file winning
+// where they overlap.
+func readExchanges(names []string, p elspot.Parser, source string) (*importer.Batch, error) {
+	b := &importer.Batch{Source: source}
+	var sets [][]elspot.Exchange
+	for _, name := range names {
+		e, file, err := parseFile(name, p)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err)
+		}
+		sets = append(sets, e)
+		b.Inputs = append(b.Inputs, file)
+	}
+	b.Data = merge(sets...)
+	return b, nil
+}
+
+// merge returns the union of sets, sorted by time and connection. Values
+// of later sets replace those of earlier sets.
+func merge(sets ...[]elspot.Exchange) []elspot.Exchange {
+	type key struct {
+		ts       int64
+		from, to string
+	}
+	byKey := make(map[key]elspot.Exchange)
+	for _, set := range sets {
+		for _, e := range set {
+			byKey[key{e.Timestamp.Unix(), e.From, e.To}] = e
+		}
+	}
+	merged := make([]elspot.Exchange, 0, len(byKey))
+	for _, e := range byKey {
+		merged = append(merged, e)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		a, b := merged[i], merged[j]
+		if !a.Timestamp.Equal(b.Timestamp) {
+			return a.Timestamp.Before(b.Timestamp)
+		}
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+	return merged
+}
+
+// parseFile downloads or opens the file or URL name and parses its
+// exchanges.
+func parseFile(name string, p elspot.Parser) ([]elspot.Exchange, ledger.File, error) {
+	var src io.ReadCloser
+	if u, err := url.Parse(name); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		resp, err := http.Get(name)
+		if err != nil {
+			return nil, ledger.File{}, fmt.Errorf("opening URL: %s", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, ledger.File{}, fmt.Errorf("got HTTP status code: %d, want %d", resp.StatusCode, http.StatusOK)
+		}
+		src = resp.Body
+	} else {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, ledger.File{}, err
+		}
+		if abs, err := filepath.Abs(name); err == nil {
+			name = abs
+		}
+		src = f
+	}
+	defer src.Close()
+
+	h := ledger.NewHash()
+	tables, err := p.Limits.Parse(io.TeeReader(src, h))
+	if err != nil {
+		return nil, ledger.File{}, fmt.Errorf("parsing HTML table: %s", err)
+	}
+	if len(tables) == 0 {
+		return nil, ledger.File{}, elspot.ErrNoTable
+	}
+	exchanges, err := p.ParseExchanges(tables[0])
+	if err != nil {
+		return nil, ledger.File{}, err
+	}
+	return exchanges, h.File(name), nil
+}
+
+func loadExchanges(connstring, ddlConnstring, table, source string, exchanges []elspot.Exchange, files []ledger.File) (rowsAffected int64, err error) {
+	db, err := sql.Open("postgres", connstring)
+	if err != nil {
+		return 0, fmt.Errorf("connect to database: %s", err)
+	}
+	defer db.Close()
+
+	if err = ensureTable(db, ddlConnstring, table); err != nil {
+		return 0, fmt.Errorf("ensure table exists: %s", err)
+	}
+
+	txn, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %s", err)
+	}
+	defer txn.Rollback()
+
+	_, err = txn.Exec(fmt.Sprintf("CREATE TEMP TABLE %s ON COMMIT DROP AS SELECT * FROM %s WITH NO DATA", pq.QuoteIdentifier(tmpTable), pq.QuoteIdentifier(table)))
+	if err != nil {
+		return 0, fmt.Errorf("create temporary table: %s", err)
+	}
+	stmt, err := txn.Prepare(pq.CopyInSchema("pg_temp", tmpTable, "ts", "from_area", "to_area", "mw", "status"))
+	if err != nil {
+		return 0, fmt.Errorf("copy data into temporary table: %s", err)
+	}
+	for _, e := range exchanges {
+		status := statusFinal
+		if e.Provisional {
+			status = statusProvisional
+		}
+		if _, err = stmt.Exec(e.Timestamp, e.From, e.To, e.MW, status); err != nil {
+			return 0, fmt.Errorf("insert data into temporary table: %s", err)
+		}
+	}
+	if _, err = stmt.Exec(); err != nil {
+		return 0, fmt.Errorf("flush after loading data: %s", err)
+	}
+	if err = stmt.Close(); err != nil {
+		return 0, err
+	}
+
+	res, err := txn.Exec(fmt.Sprintf(upsertSQL, pq.QuoteIdentifier(table), "pg_temp."+pq.QuoteIdentifier(tmpTable)))
+	if err != nil {
+		return 0, fmt.Errorf("load data from temporary table: %s", err)
+	}
+	if rowsAffected, err = res.RowsAffected(); err != nil {
+		return 0, err
+	}
+	if err = ledger.Record(txn, source, rowsAffected, files...); err != nil {
+		return 0, fmt.Errorf("record import: %s", err)
+	}
+	if err = txn.Commit(); err != nil {
+		return 0, fmt.Errorf("commit transaction: %s", err)
+	}
+	return rowsAffected, nil
+}
+
+// ensureTable runs the table DDL, as the ddlConnstring role if one is set.
+func ensureTable(db *sql.DB, ddlConnstring, table string) error {
+	if ddlConnstring != "" {
+		ddl, err := sql.Open("postgres", ddlConnstring)
+		if err != nil {
+			return err
+		}
+		defer ddl.Close()
+		db = ddl
+	}
+	for _, stmt := range []string{createTableSQL(table), ledger.CreateTableSQL} {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joneskoo/etget/elspot"
+)
+
+func TestMerge(t *testing.T) {
+	ts := time.Date(2016, 3, 27, 0, 0, 0, 0, time.UTC)
+	older := []elspot.Exchange{
+		{Timestamp: ts, From: "FI", To: "SE1", MW: 1000, Provisional: true},
+		{Timestamp: ts.Add(time.Hour), From: "FI", To: "SE1", MW: 1100},
+	}
+	newer := []elspot.Exchange{
+		{Timestamp: ts, From: "FI", To: "SE1", MW: 1500},
+		{Timestamp: ts, From: "EE", To: "FI", MW: 300},
+	}
+	got := merge(older, newer)
+	want := []elspot.Exchange{newer[1], newer[0], older[1]}
+	if len(got) != len(want) {
+		t.Fatalf("merge = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("merge[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/joneskoo/etget/internal/ledger"
+)
+
+// kinds maps -kind to the target table and ledger source.
+var kinds = map[string]struct{ table, source string }{
+	"capacity": {"elspot_capacity", ledger.SourceCapacity},
+	"flow":     {"elspot_flow", ledger.SourceFlow},
+}
+
+const tmpTable = "_exchange_tmp"
+
+// createTableSQL returns the DDL of table, a capacity or flow table.
+func createTableSQL(table string) string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+    ts         TIMESTAMPTZ NOT NULL,
+    from_area  TEXT NOT NULL,
+    to_area    TEXT NOT NULL,
+    mw         DOUBLE PRECISION NOT NULL,
+    status     TEXT NOT NULL,
+    PRIMARY KEY (ts, from_area, to_area)
+    );`, table)
+}
+
+const (
+	// upsertSQL loads the temporary table %[2]s into the target table
+	// %[1]s. As with prices, only provisional values are updated.
+	upsertSQL = `INSERT INTO %[1]s AS t (ts, from_area, to_area, mw, status)
+    SELECT ts, from_area, to_area, mw, status FROM %[2]s
+    ON CONFLICT (ts, from_area, to_area) DO UPDATE SET mw = EXCLUDED.mw, status = EXCLUDED.status
+    WHERE t.status = 'provisional'`
+
+	statusFinal       = "final"
+	statusProvisional = "provisional"
+)
@@ -0,0 +1,202 @@
+// The import-intraday command loads Nord Pool intraday (Elbas) trade
+// exports as hourly volume-weighted prices into table intraday.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/joneskoo/etget/importer"
+	"github.com/joneskoo/etget/internal/config"
+	"github.com/joneskoo/etget/internal/ledger"
+	"github.com/joneskoo/etget/internal/runreport"
+	"github.com/joneskoo/etget/internal/target"
+	"github.com/joneskoo/etget/internal/zoneinfo"
+	"github.com/joneskoo/etget/intraday"
+	"github.com/lib/pq"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s TRADES...\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "   TRADES	intraday trade export CSV file\n\n")
+	flag.PrintDefaults()
+	os.Exit(1)
+}
+
+func main() {
+	connstrings := target.NewList("sslmode=disable")
+	flag.Var(connstrings, "connstring", "https://www.postgresql.org/docs/current/static/libpq-connect.html#LIBPQ-CONNSTRING; repeat to load several databases")
+	ddlConnstring := flag.String("ddl-connstring", "", "connection string of the table owner role used to create tables (default -connstring)")
+	area := flag.String("area", "", "delivery area of files without an area column, e.g. FI")
+	timeLocation := flag.String("time-location", "Europe/Paris", "time zone of delivery times without offset")
+	configFile := flag.String("config", config.DefaultFile, "configuration file with the hooks run after the import")
+	runReportDir := flag.String("run-report", "", "write a JSON report of the run to this `directory`")
+	flag.Usage = usage
+	flag.Parse()
+
+	run := runreport.New("import-intraday", *runReportDir, flag.CommandLine)
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		run.Fatalf("ERROR %s", err)
+	}
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+	}
+	if *ddlConnstring != "" && len(connstrings.Values) > 1 {
+		run.Fatalf("ERROR -ddl-connstring cannot be used with several -connstring targets")
+	}
+	loc, err := zoneinfo.Load(*timeLocation)
+	if err != nil {
+		run.Fatalf("ERROR -time-location: %s", err)
+	}
+
+	imp := importer.Importer{
+		Source: importer.SourceFunc(func(ctx context.Context) (*importer.Batch, error) {
+			return readHours(flag.Args(), loc, *area)
+		}),
+		Sinks: target.Sinks(connstrings.Values, func(ctx context.Context, connstring string, b *importer.Batch) (int64, error) {
+			return loadHours(connstring, *ddlConnstring, b.Data.([]intraday.Hour), b.Inputs)
+		}),
+		BeforeLoad: []func(context.Context, *importer.Batch) error{
+			func(ctx context.Context, b *importer.Batch) error {
+				run.Inputs = append(run.Inputs, b.Inputs...)
+				return nil
+			},
+		},
+	}
+	res, err := imp.Run(context.Background())
+	if err != nil {
+		run.Fatalf("ERROR %s", err)
+	}
+	results := target.Results(res)
+	run.SetTargets(results)
+	if err := target.Summarize(os.Stdout, results); err != nil {
+		run.Fatalf("ERROR importing to PostgreSQL: %s", err)
+	}
+	if err := run.Finish(cfg.Hooks); err != nil {
+		log.Fatalf("ERROR writing run report: %s", err)
+	}
+}
+
+// readHours parses the trade files and aggregates them into hours. area is
+// the delivery area of trades without one.
+func readHours(names []string, loc *time.Location, area string) (*importer.Batch, error) {
+	b := &importer.Batch{Source: ledger.SourceIntraday}
+	var trades []intraday.Trade
+	for _, name := range names {
+		t, file, err := parseFile(name, loc)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err)
+		}
+		trades = append(trades, t...)
+		b.Inputs = append(b.Inputs, file)
+	}
+	for i := range trades {
+		if trades[i].Area == "" {
+			trades[i].Area = area
+		}
+		if trades[i].Area == "" {
+			return nil, errors.New("trade without area; set -area")
+		}
+	}
+	b.Data = intraday.Aggregate(trades)
+	return b, nil
+}
+
+// parseFile reads the trades of file name and its digest.
+func parseFile(name string, loc *time.Location) ([]intraday.Trade, ledger.File, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, ledger.File{}, err
+	}
+	defer f.Close()
+	if abs, err := filepath.Abs(name); err == nil {
+		name = abs
+	}
+	h := ledger.NewHash()
+	trades, err := intraday.ParseTrades(io.TeeReader(f, h), loc)
+	if err != nil {
+		return nil, ledger.File{}, err
+	}
+	return trades, h.File(name), nil
+}
+
+func loadHours(connstring, ddlConnstring string, hours []intraday.Hour, files []ledger.File) (rowsAffected int64, err error) {
+	db, err := sql.Open("postgres", connstring)
+	if err != nil {
+		return 0, fmt.Errorf("connect to database: %s", err)
+	}
+	defer db.Close()
+
+	if err = ensureTable(db, ddlConnstring); err != nil {
+		return 0, fmt.Errorf("ensure table exists: %s", err)
+	}
+
+	txn, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %s", err)
+	}
+	defer txn.Rollback()
+
+	_, err = txn.Exec(fmt.Sprintf("CREATE TEMP TABLE %s ON COMMIT DROP AS SELECT * FROM %s WITH NO DATA", pq.QuoteIdentifier(tmpTable), pq.QuoteIdentifier(targetTable)))
+	if err != nil {
+		return 0, fmt.Errorf("create temporary table: %s", err)
+	}
+	stmt, err := txn.Prepare(pq.CopyInSchema("pg_temp", tmpTable, "area", "ts", "vwap", "volume", "trades"))
+	if err != nil {
+		return 0, fmt.Errorf("copy data into temporary table: %s", err)
+	}
+	for _, h := range hours {
+		if _, err = stmt.Exec(h.Area, h.Timestamp, h.VWAP, h.Volume, h.Trades); err != nil {
+			return 0, fmt.Errorf("insert data into temporary table: %s", err)
+		}
+	}
+	if _, err = stmt.Exec(); err != nil {
+		return 0, fmt.Errorf("flush after loading data: %s", err)
+	}
+	if err = stmt.Close(); err != nil {
+		return 0, err
+	}
+
+	res, err := txn.Exec(fmt.Sprintf(upsertSQL, pq.QuoteIdentifier(targetTable), "pg_temp."+pq.QuoteIdentifier(tmpTable)))
+	if err != nil {
+		return 0, fmt.Errorf("load data from temporary table: %s", err)
+	}
+	if rowsAffected, err = res.RowsAffected(); err != nil {
+		return 0, err
+	}
+	if err = ledger.Record(txn, ledger.SourceIntraday, rowsAffected, files...); err != nil {
+		return 0, fmt.Errorf("record import: %s", err)
+	}
+	if err = txn.Commit(); err != nil {
+		return 0, fmt.Errorf("commit transaction: %s", err)
+	}
+	return rowsAffected, nil
+}
+
+// ensureTable runs the table DDL, as the ddlConnstring role if one is set.
+func ensureTable(db *sql.DB, ddlConnstring string) error {
+	if ddlConnstring != "" {
+		ddl, err := sql.Open("postgres", ddlConnstring)
+		if err != nil {
+			return err
+		}
+		defer ddl.Close()
+		db = ddl
+	}
+	for _, stmt := range []string{createTableSQL, ledger.CreateTableSQL} {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package main
+
+const (
+	targetTable = "intraday"
+	tmpTable    = "_intraday_tmp"
+
+	createTableSQL = `CREATE TABLE IF NOT EXISTS intraday (
+    area    TEXT NOT NULL,
+    ts      TIMESTAMPTZ NOT NULL,
+    vwap    DOUBLE PRECISION NOT NULL,
+    volume  DOUBLE PRECISION NOT NULL,
+    trades  INTEGER NOT NULL,
+    PRIMARY KEY (area, ts)
+    );`
+
+	// upsertSQL replaces the hours in the target table %[1]s with those
+	// in the temporary table %[2]s. Each export is expected to hold all
+	// trades of the hours it covers.
+	upsertSQL = `INSERT INTO %[1]s (area, ts, vwap, volume, trades)
+    SELECT area, ts, vwap, volume, trades FROM %[2]s
+    ON CONFLICT (area, ts) DO UPDATE SET vwap = EXCLUDED.vwap, volume = EXCLUDED.volume, trades = EXCLUDED.trades`
+)
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// event is a block of consecutive hours.
+type event struct {
+	Summary    string
+	Start, End time.Time
+	Min, Max   float64
+}
+
+// blocks groups consecutive hourly prices matching match into events.
+func blocks(prices []price, summary string, match func(float64) bool) (events []event) {
+	var cur *event
+	for _, p := range prices {
+		if !match(p.Value) {
+			cur = nil
+			continue
+		}
+		if cur == nil || !p.Timestamp.Equal(cur.End) {
+			events = append(events, event{Summary: summary, Start: p.Timestamp, Min: p.Value, Max: p.Value})
+			cur = &events[len(events)-1]
+		}
+		cur.End = p.Timestamp.Add(time.Hour)
+		if p.Value < cur.Min {
+			cur.Min = p.Value
+		}
+		if p.Value > cur.Max {
+			cur.Max = p.Value
+		}
+	}
+	return events
+}
+
+const icalTime = "20060102T150405Z"
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+// writeCalendar writes events as an RFC 5545 calendar.
+func writeCalendar(w io.Writer, events []event, now time.Time) error {
+	bw := bufio.NewWriter(w)
+	line := func(format string, a ...interface{}) {
+		fmt.Fprintf(bw, format+"\r\n", a...)
+	}
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//etget//price-calendar//EN")
+	line("X-WR-CALNAME:Electricity prices")
+	for _, e := range events {
+		summary := fmt.Sprintf("%s %.2f–%.2f EUR/MWh", e.Summary, e.Min, e.Max)
+		line("BEGIN:VEVENT")
+		line("UID:%s-%s@etget", e.Start.UTC().Format(icalTime), strings.ToLower(strings.Fields(e.Summary)[0]))
+		line("DTSTAMP:%s", now.UTC().Format(icalTime))
+		line("DTSTART:%s", e.Start.UTC().Format(icalTime))
+		line("DTEND:%s", e.End.UTC().Format(icalTime))
+		line("SUMMARY:%s", icalEscaper.Replace(summary))
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return bw.Flush()
+}
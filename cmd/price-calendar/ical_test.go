@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBlocks(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	var prices []price
+	for h, v := range []float64{10, 12, 50, 8, 9, 9, 60} {
+		prices = append(prices, price{Timestamp: start.Add(time.Duration(h) * time.Hour), Value: v})
+	}
+	// Hour 4 missing: the cheap block must be split around it.
+	prices = append(prices[:4], prices[5:]...)
+
+	got := blocks(prices, "Cheap", func(p float64) bool { return p <= 12 })
+	want := []event{
+		{Summary: "Cheap", Start: start, End: start.Add(2 * time.Hour), Min: 10, Max: 12},
+		{Summary: "Cheap", Start: start.Add(3 * time.Hour), End: start.Add(4 * time.Hour), Min: 8, Max: 8},
+		{Summary: "Cheap", Start: start.Add(5 * time.Hour), End: start.Add(6 * time.Hour), Min: 9, Max: 9},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("blocks() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("blocks()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestWriteCalendar(t *testing.T) {
+	start := time.Date(2016, 1, 1, 22, 0, 0, 0, time.UTC)
+	events := []event{{Summary: "Cheap electricity", Start: start, End: start.Add(2 * time.Hour), Min: 1, Max: 2.5}}
+
+	var buf bytes.Buffer
+	if err := writeCalendar(&buf, events, start); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"DTSTART:20160101T220000Z\r\n",
+		"DTEND:20160102T000000Z\r\n",
+		"SUMMARY:Cheap electricity 1.00–2.50 EUR/MWh\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("writeCalendar() output missing %q:\n%s", want, buf.String())
+		}
+	}
+}
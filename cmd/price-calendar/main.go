@@ -0,0 +1,146 @@
+// The price-calendar command publishes cheap and expensive electricity
+// hours from the elspot table as an iCalendar feed.
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joneskoo/etget/internal/dbpool"
+	"github.com/joneskoo/etget/internal/preset"
+	"github.com/joneskoo/etget/internal/server"
+	"github.com/joneskoo/etget/internal/zoneinfo"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [flags]\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Writes the next day's cheap and expensive hours as an .ics feed.\n\n")
+	flag.PrintDefaults()
+	os.Exit(1)
+}
+
+func main() {
+	connstring := flag.String("connstring", "sslmode=disable", "https://www.postgresql.org/docs/current/static/libpq-connect.html#LIBPQ-CONNSTRING")
+	cheap := flag.Float64("cheap", 20, "price in EUR/MWh at or below which an hour is cheap")
+	expensive := flag.Float64("expensive", 100, "price in EUR/MWh at or above which an hour is expensive")
+	output := flag.String("o", "-", "output file, - for standard output")
+	presetName := flag.String("preset", "fi", "country `preset` ("+strings.Join(preset.Names(), ", ")+") giving the time zone of days and the price area")
+	listen := flag.String("listen", "", "serve the feed over HTTP on this address instead of writing a file")
+	var opts server.Options
+	flag.StringVar(&opts.CertFile, "tls-cert", "", "TLS certificate file for -listen")
+	flag.StringVar(&opts.KeyFile, "tls-key", "", "TLS key file for -listen")
+	flag.StringVar(&opts.ClientCAFile, "client-ca", "", "require client certificates signed by a CA in this file")
+	var pool dbpool.Options
+	pool.RegisterFlags(flag.CommandLine)
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() != 0 {
+		flag.Usage()
+	}
+	p, err := preset.Lookup(*presetName)
+	if err != nil {
+		log.Fatalf("ERROR -preset: %s", err)
+	}
+	if local, err = p.LoadLocation(); err != nil {
+		log.Fatalf("ERROR -preset: %s", err)
+	}
+	priceColumn = p.Column()
+
+	db, err := pool.Open(*connstring)
+	if err != nil {
+		log.Fatalf("ERROR connecting to database: %s", err)
+	}
+	defer db.Close()
+
+	feed := func(w io.Writer) error {
+		day := tomorrow(time.Now())
+		prices, err := queryPrices(db, day, day.AddDate(0, 0, 1))
+		if err != nil {
+			return err
+		}
+		events := append(
+			blocks(prices, "Cheap electricity", func(p float64) bool { return p <= *cheap }),
+			blocks(prices, "Expensive electricity", func(p float64) bool { return p >= *expensive })...,
+		)
+		return writeCalendar(w, events, time.Now())
+	}
+
+	if *listen != "" {
+		// Tokens are read from the environment; calendar apps pass them
+		// as ?access_token=.
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+			if err := feed(w); err != nil {
+				log.Printf("ERROR generating feed: %s", err)
+				http.Error(w, "internal error", http.StatusInternalServerError)
+			}
+		})
+		log.Fatal(server.ListenAndServe(*listen, h, opts.WithEnv()))
+	}
+
+	w := os.Stdout
+	if *output != "-" {
+		if w, err = os.Create(*output); err != nil {
+			log.Fatalf("ERROR creating output file: %s", err)
+		}
+	}
+	if err := feed(w); err != nil {
+		log.Fatalf("ERROR generating feed: %s", err)
+	}
+	if err := w.Close(); err != nil {
+		log.Fatalf("ERROR writing output file: %s", err)
+	}
+}
+
+// tomorrow returns the start of the next day in the -preset time zone.
+func tomorrow(now time.Time) time.Time {
+	now = now.In(local)
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, local)
+}
+
+var (
+	// local is the time zone of the -preset, Finnish time by default.
+	local *time.Location
+
+	// priceColumn is the column of table elspot with the -preset area's
+	// price.
+	priceColumn = "fi"
+)
+
+func init() {
+	var err error
+	local, err = zoneinfo.Load("Europe/Helsinki")
+	if err != nil {
+		panic(err)
+	}
+}
+
+// price is the spot price of the hour starting at Timestamp.
+type price struct {
+	Timestamp time.Time
+	Value     float64
+}
+
+func queryPrices(db *sql.DB, start, end time.Time) (prices []price, err error) {
+	rows, err := db.Query("SELECT ts, "+priceColumn+" FROM elspot WHERE ts >= $1 AND ts < $2 AND "+priceColumn+" IS NOT NULL ORDER BY ts", start, end)
+	if err != nil {
+		return nil, fmt.Errorf("query prices: %s", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p price
+		if err = rows.Scan(&p.Timestamp, &p.Value); err != nil {
+			return nil, fmt.Errorf("query prices: %s", err)
+		}
+		prices = append(prices, p)
+	}
+	return prices, rows.Err()
+}
@@ -0,0 +1,89 @@
+package elspot
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// anomalyFloor is the price magnitude, in EUR/MWh, below which prices are
+// compared as if they were this large. Near zero any change is a large
+// factor.
+const anomalyFloor = 10
+
+// Anomaly is an hour whose price differs from the hours before and after
+// it by an implausible factor, as when a decimal separator was misread.
+type Anomaly struct {
+	Timestamp time.Time
+	Area      string
+	Price     float64
+
+	// Before and After are the prices of the neighbouring hours.
+	Before, After float64
+}
+
+func (a Anomaly) String() string {
+	return fmt.Sprintf("%s %s: price %g between %g and %g", a.Timestamp.Format(time.RFC3339), a.Area, a.Price, a.Before, a.After)
+}
+
+// Anomalies returns the prices of records, which must be sorted by time,
+// that are at least factor times larger or smaller than the price of the
+// same area in both the previous and the next hour. Hours without a price
+// on both sides are not checked.
+func Anomalies(records []Record, factor float64) []Anomaly {
+	var found []Anomaly
+	for i, r := range records {
+		areas := make([]string, 0, len(r.Prices))
+		for area := range r.Prices {
+			areas = append(areas, area)
+		}
+		sort.Strings(areas)
+		for _, area := range areas {
+			p, ok := parsePrice(r.Prices[area])
+			if !ok {
+				continue
+			}
+			a := Anomaly{Timestamp: r.Timestamp, Area: area, Price: p}
+			neighbours := 0
+			outliers := 0
+			if i > 0 && records[i-1].Timestamp.Add(time.Hour).Equal(r.Timestamp) {
+				if a.Before, ok = parsePrice(records[i-1].Prices[area]); ok {
+					neighbours++
+					if differs(p, a.Before, factor) {
+						outliers++
+					}
+				}
+			}
+			if i+1 < len(records) && r.Timestamp.Add(time.Hour).Equal(records[i+1].Timestamp) {
+				if a.After, ok = parsePrice(records[i+1].Prices[area]); ok {
+					neighbours++
+					if differs(p, a.After, factor) {
+						outliers++
+					}
+				}
+			}
+			if neighbours == 2 && outliers == 2 {
+				found = append(found, a)
+			}
+		}
+	}
+	return found
+}
+
+func parsePrice(s string) (float64, bool) {
+	if s == "" {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	return v, err == nil
+}
+
+// differs reports whether the magnitudes of a and b, raised to
+// anomalyFloor, differ by at least factor.
+func differs(a, b, factor float64) bool {
+	x := math.Max(math.Abs(a), anomalyFloor)
+	y := math.Max(math.Abs(b), anomalyFloor)
+	return x/y >= factor || y/x >= factor
+}
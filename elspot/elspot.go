@@ -0,0 +1,512 @@
+// Package elspot parses Nordpool Elspot market data files: prices, and the
+// capacities and flows between bidding areas.
+//
+// The files are HTML tables (served with an .xls extension) with one row
+// per hour and one price column per area. Timestamps in the file are local
+// CET/CEST time without offset; the parser repairs the DST transitions
+// with package notz.
+package elspot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joneskoo/etget/htmltable"
+	"github.com/joneskoo/etget/internal/zoneinfo"
+	"github.com/joneskoo/etget/notz"
+)
+
+// DefaultTimeLayout is the layout of the date and hour columns joined by a
+// space, e.g. "27-10-2019 02".
+const DefaultTimeLayout = "02-01-2006 15"
+
+// defaultLocation is the time zone of the timestamps in Nordpool files.
+const defaultLocation = "Europe/Paris"
+
+// provisionalMark is appended to prices that Nordpool has not yet
+// confirmed, e.g. "16,39*". estimatedMark is appended to prices that some
+// exports estimate, e.g. "16,39 (e)".
+const (
+	provisionalMark = "*"
+	estimatedMark   = "(e)"
+)
+
+// Statuses of a price, from Record.Status.
+const (
+	StatusFinal       = "final"
+	StatusProvisional = "provisional"
+	StatusEstimated   = "estimated"
+)
+
+// Record is the prices of one hour.
+type Record struct {
+	Timestamp time.Time
+
+	// Prices maps area code (column header) to price.
+	Prices map[string]string
+
+	// Provisional is set if any price on the row was marked provisional
+	// or estimated.
+	Provisional bool
+
+	// Quality maps the keys of Prices marked provisional or estimated to
+	// StatusProvisional or StatusEstimated. It is nil if none are.
+	Quality map[string]string
+
+	// Corrected is set if the hour is a local time repeated at the end of
+	// summer time, whose offset notz.CheckedFixDST inferred from the order
+	// of the rows. OriginalLocal is then the local time read from the
+	// file, formatted as "2006-01-02 15:04".
+	Corrected     bool
+	OriginalLocal string
+}
+
+// Status returns the status of the price of area: StatusFinal unless it
+// was marked provisional or estimated.
+func (r Record) Status(area string) string {
+	if q := r.Quality[area]; q != "" {
+		return q
+	}
+	return StatusFinal
+}
+
+// records implements notz.Interface for notz.CheckedFixDST.
+type records []Record
+
+func (r records) Len() int             { return len(r) }
+func (r records) Time(i int) time.Time { return r[i].Timestamp }
+
+func (r records) SetTime(i int, new time.Time) {
+	if !r[i].Corrected {
+		r[i].Corrected = true
+		r[i].OriginalLocal = r[i].Timestamp.Format("2006-01-02 15:04")
+	}
+	r[i].Timestamp = new
+}
+
+// ErrNoTable is returned by Parse if the document contains no table.
+var ErrNoTable = errors.New("elspot: no table in document")
+
+// Parser parses elspot files with overrides for export variants that the
+// defaults do not handle. The zero value parses standard Nordpool files.
+type Parser struct {
+	// TimeLayout is the time.Parse layout of the date column and the
+	// first two characters of the hour column, joined by a space. The
+	// default is DefaultTimeLayout.
+	TimeLayout string
+
+	// Location is the time zone of the timestamps (default Europe/Paris).
+	Location *time.Location
+
+	// Warn, if set, is called for each recoverable problem in the file.
+	Warn func(Warning)
+
+	// Columns renames price columns. Keys are headers in the file,
+	// compared case-insensitively; values are the keys used in
+	// Record.Prices instead.
+	Columns map[string]string
+
+	// Ignore lists headers of price columns left out of Record.Prices.
+	Ignore []string
+
+	// Numbers is the notation of the prices (default NumbersAuto).
+	Numbers NumberFormat
+
+	// Limits bound the HTML documents Parse accepts (default
+	// htmltable.DefaultLimits).
+	Limits htmltable.Limits
+}
+
+// Warning is a recoverable problem in an elspot file. Values that cannot
+// be parsed are left out of the result; the rest of the row is kept.
+type Warning struct {
+	// Row is the 1-based data row.
+	Row int
+	Msg string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("row %d: %s", w.Row, w.Msg)
+}
+
+func (p Parser) warnf(row int, format string, args ...interface{}) {
+	if p.Warn != nil {
+		p.Warn(Warning{Row: row, Msg: fmt.Sprintf(format, args...)})
+	}
+}
+
+// Parse parses an elspot file from r.
+func Parse(r io.Reader) ([]Record, error) {
+	return Parser{}.Parse(r)
+}
+
+// ParseTable parses the price table of an elspot file.
+func ParseTable(table htmltable.Table) ([]Record, error) {
+	return Parser{}.ParseTable(table)
+}
+
+// Parse parses an elspot file from r.
+func (p Parser) Parse(r io.Reader) ([]Record, error) {
+	tables, err := p.Limits.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(tables) == 0 {
+		return nil, ErrNoTable
+	}
+	return p.ParseTable(tables[0])
+}
+
+// ParseTable parses the price table of an elspot file.
+func (p Parser) ParseTable(table htmltable.Table) (data []Record, err error) {
+	return p.parseRows(table, p.keepPrices)
+}
+
+// keepPrices tells whether a row of the price table is an hour: the hour
+// skipped at the start of DST is an empty row.
+func (p Parser) keepPrices(row int, prices map[string]string) bool {
+	if prices["SYS"] != "" {
+		return true
+	}
+	for k, v := range prices {
+		if v != "" {
+			p.warnf(row, "no SYS price, row skipped although %s has a price", k)
+			break
+		}
+	}
+	return false
+}
+
+// parseRows parses the rows of table for which keep returns true, keyed
+// by the headers before column mapping.
+func (p Parser) parseRows(table htmltable.Table, keep func(row int, values map[string]string) bool) (data []Record, err error) {
+	rp, err := p.rowParser(table.Headers, keep)
+	if err != nil {
+		return nil, err
+	}
+	for n, t := range table.Rows {
+		r, ok, err := rp.parse(n+1, t)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			data = append(data, r)
+		}
+	}
+	if err = notz.CheckedFixDST(records(data)); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// rowParser parses the rows of a table with the given headers into
+// records whose DST transitions are still to be fixed.
+type rowParser struct {
+	p                Parser
+	layout           string
+	loc              *time.Location
+	header           []string
+	dateCol, hourCol int
+	columns          map[string]string
+	keep             func(row int, values map[string]string) bool
+}
+
+func (p Parser) rowParser(headers [][]string, keep func(row int, values map[string]string) bool) (*rowParser, error) {
+	rp := &rowParser{p: p, layout: p.TimeLayout, loc: p.Location, columns: p.columnMap(), keep: keep}
+	if rp.layout == "" {
+		rp.layout = DefaultTimeLayout
+	}
+	if rp.loc == nil {
+		var err error
+		if rp.loc, err = zoneinfo.Load(defaultLocation); err != nil {
+			return nil, err
+		}
+	}
+	headerRow, dateCol, hourCol, err := findHeader(headers)
+	if err != nil {
+		return nil, err
+	}
+	rp.header, rp.dateCol, rp.hourCol = headers[headerRow], dateCol, hourCol
+	return rp, nil
+}
+
+// parse parses data row t, numbered row from 1. It reports false for rows
+// that are skipped.
+func (rp *rowParser) parse(row int, t []string) (Record, bool, error) {
+	p := rp.p
+	prices := make(map[string]string, len(rp.header)-2)
+	var quality map[string]string
+	for i, k := range rp.header {
+		if i == rp.dateCol || i == rp.hourCol || i >= len(t) {
+			continue
+		}
+		v := strings.TrimSpace(t[i])
+		if k == "" {
+			if v != "" {
+				p.warnf(row, "value %q in column %d without header ignored", v, i+1)
+			}
+			continue
+		}
+		if status, trimmed := priceStatus(v); status != StatusFinal {
+			if quality == nil {
+				quality = make(map[string]string)
+			}
+			v, quality[k] = trimmed, status
+		}
+		price, normalized, ok := p.Numbers.normalize(v)
+		if !ok {
+			p.warnf(row, "%s price %q is not a number", k, v)
+		} else if normalized {
+			p.warnf(row, "%s price %q normalized to %s", k, v, price)
+		}
+		prices[k] = price
+	}
+	if !rp.keep(row, prices) {
+		return Record{}, false, nil
+	}
+
+	// Hour is the first two bytes of the hour column, e.g. "02 - 03"
+	if rp.dateCol >= len(t) || rp.hourCol >= len(t) || len(t[rp.hourCol]) < 2 {
+		p.warnf(row, "no date or hour, row skipped")
+		return Record{}, false, nil
+	}
+	ts, err := time.ParseInLocation(rp.layout, fmt.Sprintf("%s %s", t[rp.dateCol], t[rp.hourCol][0:2]), rp.loc)
+	if err != nil {
+		return Record{}, false, fmt.Errorf("parsing timestamp: %s", err)
+	}
+	return Record{
+		Timestamp:   ts,
+		Prices:      mapColumns(prices, rp.columns),
+		Provisional: quality != nil,
+		Quality:     mapColumns(quality, rp.columns),
+	}, true, nil
+}
+
+// priceStatus returns the status marked on the price text v and v
+// without the mark.
+func priceStatus(v string) (status, price string) {
+	switch {
+	case strings.HasSuffix(v, provisionalMark):
+		return StatusProvisional, strings.TrimSpace(strings.TrimSuffix(v, provisionalMark))
+	case strings.HasSuffix(v, estimatedMark):
+		return StatusEstimated, strings.TrimSpace(strings.TrimSuffix(v, estimatedMark))
+	}
+	return StatusFinal, v
+}
+
+// columnMap returns the Columns and Ignore settings keyed by normalized
+// header, mapping ignored columns to the empty string. It returns nil if
+// no column is renamed or ignored.
+func (p Parser) columnMap() map[string]string {
+	if len(p.Columns) == 0 && len(p.Ignore) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(p.Columns)+len(p.Ignore))
+	for from, to := range p.Columns {
+		m[normalizeHeader(from)] = to
+	}
+	for _, h := range p.Ignore {
+		m[normalizeHeader(h)] = ""
+	}
+	return m
+}
+
+// mapColumns renames and drops the prices of a row, or their Quality, as
+// given by columnMap.
+func mapColumns(prices, columns map[string]string) map[string]string {
+	if columns == nil || prices == nil {
+		return prices
+	}
+	mapped := make(map[string]string, len(prices))
+	for k, v := range prices {
+		to, ok := columns[normalizeHeader(k)]
+		switch {
+		case !ok:
+			mapped[k] = v
+		case to != "":
+			mapped[to] = v
+		}
+	}
+	return mapped
+}
+
+// Stream parses an elspot file from r and sends the records on the
+// returned channel, which is closed when parsing ends. The error channel
+// receives at most one error, either from parsing or ctx.Err() if ctx is
+// done before all records were received, and is then closed.
+func Stream(ctx context.Context, r io.Reader) (<-chan Record, <-chan error) {
+	return Parser{}.Stream(ctx, r)
+}
+
+// Stream is like the package-level Stream but uses the settings of p.
+//
+// The records are sent as the rows are read, without reading the whole
+// document first. Around the DST transitions, where the repair needs the
+// hours on both sides, and for the latest hour, which the next may still
+// correct, records are held back until the following rows are read. If
+// parsing fails, the records sent before the error are valid.
+func (p Parser) Stream(ctx context.Context, r io.Reader) (<-chan Record, <-chan error) {
+	out := make(chan Record)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(errc)
+		defer close(out)
+		if err := p.stream(ctx, r, out); err != nil {
+			errc <- err
+		}
+	}()
+	return out, errc
+}
+
+func (p Parser) stream(ctx context.Context, r io.Reader, out chan<- Record) error {
+	send := func(rs []Record) error {
+		for _, rec := range rs {
+			select {
+			case out <- rec:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	}
+	var (
+		headers [][]string
+		rp      *rowParser
+		pending records
+		n       int
+	)
+	err := p.Limits.Scan(r, func(cells []string) error {
+		headers = append(headers, cells)
+		return nil
+	}, func(cells []string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if rp == nil {
+			var err error
+			if rp, err = p.rowParser(headers, p.keepPrices); err != nil {
+				return err
+			}
+		}
+		n++
+		rec, ok, err := rp.parse(n, cells)
+		if err != nil || !ok {
+			return err
+		}
+		pending = append(pending, rec)
+		if !settled(pending) {
+			return nil
+		}
+		if err := notz.CheckedFixDST(pending); err != nil {
+			return err
+		}
+		last := pending[len(pending)-1]
+		if err := send(pending[:len(pending)-1]); err != nil {
+			return err
+		}
+		pending = append(pending[:0], last)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if rp == nil {
+		// No data rows: fail as Parse would on the table, if any.
+		if headers == nil {
+			return ErrNoTable
+		}
+		if _, err := p.rowParser(headers, p.keepPrices); err != nil {
+			return err
+		}
+		return ctx.Err()
+	}
+	if err := notz.CheckedFixDST(pending); err != nil {
+		return err
+	}
+	if err := send(pending); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// settled reports whether the records held back before the last one can
+// be sent: the last two hours follow each other away from any DST
+// transition, so that no later row can change them.
+func settled(rs records) bool {
+	if len(rs) < 2 {
+		return false
+	}
+	prev, last := rs[len(rs)-2].Timestamp, rs[len(rs)-1].Timestamp
+	return last.After(prev) && !nearTransition(prev) && !nearTransition(last)
+}
+
+// nearTransition reports whether the UTC offset of the location of t
+// changes within three hours of t.
+func nearTransition(t time.Time) bool {
+	_, before := t.Add(-3 * time.Hour).Zone()
+	_, after := t.Add(3 * time.Hour).Zone()
+	return before != after
+}
+
+// Merge combines record sets that may overlap in time, such as a yearly
+// and a weekly file. Where several sets have a record for the same hour,
+// the one in the latest set wins. The result is sorted by time.
+func Merge(sets ...[]Record) []Record {
+	byHour := make(map[int64]Record)
+	for _, set := range sets {
+		for _, r := range set {
+			byHour[r.Timestamp.Unix()] = r
+		}
+	}
+	merged := make([]Record, 0, len(byHour))
+	for _, r := range byHour {
+		merged = append(merged, r)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Timestamp.Before(merged[j].Timestamp) })
+	return merged
+}
+
+// Header synonyms of the date and hour columns in the export languages,
+// in normalized form.
+var (
+	dateHeaders = map[string]bool{"date": true, "päivä": true, "pvm": true, "datum": true, "dato": true, "kuupäev": true}
+	hourHeaders = map[string]bool{"hours": true, "hour": true, "h": true, "tunti": true, "tunnit": true, "timme": true, "timmar": true, "time": true, "timer": true, "tund": true}
+)
+
+// normalizeHeader lowercases s and collapses whitespace.
+func normalizeHeader(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// findHeader locates the header row with the date and hour columns. Older
+// files leave the date column header empty; it is then taken to be the
+// column before the hour.
+func findHeader(headers [][]string) (row, dateCol, hourCol int, err error) {
+	for row = len(headers) - 1; row >= 0; row-- {
+		dateCol, hourCol = -1, -1
+		for i, h := range headers[row] {
+			h = normalizeHeader(h)
+			if dateHeaders[h] && dateCol == -1 {
+				dateCol = i
+			}
+			if hourHeaders[h] && hourCol == -1 {
+				hourCol = i
+			}
+		}
+		if hourCol == -1 {
+			continue
+		}
+		if dateCol == -1 && hourCol > 0 && normalizeHeader(headers[row][hourCol-1]) == "" {
+			dateCol = hourCol - 1
+		}
+		if dateCol != -1 {
+			return row, dateCol, hourCol, nil
+		}
+	}
+	return 0, 0, 0, errors.New("elspot: no header row with date and hour columns")
+}
@@ -0,0 +1,484 @@
+package elspot_test
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/joneskoo/etget/elspot"
+	"github.com/joneskoo/etget/elspot/elspottest"
+	"github.com/joneskoo/etget/htmltable"
+	"github.com/joneskoo/etget/htmltable/htmltabletest"
+)
+
+func TestParse(t *testing.T) {
+	f, err := os.Open("testdata/dst-autumn.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	got, err := elspot.Parse(f)
+	if err != nil {
+		t.Fatalf("elspot.Parse: %s", err)
+	}
+
+	want := []struct {
+		utc           string
+		prices        map[string]string
+		provisional   bool
+		originalLocal string
+	}{
+		{"2015-10-24T23:00:00Z", map[string]string{"SYS": "21.03", "FI": "20.51"}, false, ""},
+		{"2015-10-25T00:00:00Z", map[string]string{"SYS": "20.08", "FI": "19.44"}, false, "2015-10-25 02:00"},
+		{"2015-10-25T01:00:00Z", map[string]string{"SYS": "19.96", "FI": "19.10"}, true, "2015-10-25 02:00"},
+		{"2015-10-25T02:00:00Z", map[string]string{"SYS": "19.50", "FI": ""}, false, ""},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("elspot.Parse returned %d records, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		ts, _ := time.Parse(time.RFC3339, w.utc)
+		if !got[i].Timestamp.Equal(ts) {
+			t.Errorf("record %d: Timestamp = %s, want %s", i, got[i].Timestamp.UTC(), ts)
+		}
+		if !reflect.DeepEqual(got[i].Prices, w.prices) {
+			t.Errorf("record %d: Prices = %v, want %v", i, got[i].Prices, w.prices)
+		}
+		if got[i].Provisional != w.provisional {
+			t.Errorf("record %d: Provisional = %v, want %v", i, got[i].Provisional, w.provisional)
+		}
+		if got[i].Corrected != (w.originalLocal != "") || got[i].OriginalLocal != w.originalLocal {
+			t.Errorf("record %d: Corrected = %v, OriginalLocal = %q; want %q", i, got[i].Corrected, got[i].OriginalLocal, w.originalLocal)
+		}
+	}
+}
+
+func TestGolden(t *testing.T) {
+	htmltabletest.RunParser(t, "testdata", elspottest.Golden(elspot.Parser{}))
+}
+
+func TestParseTableLocalizedHeaders(t *testing.T) {
+	table := htmltable.Table{
+		Headers: [][]string{
+			{"Elspot-hinnat EUR/MWh"},
+			{" PÄIVÄ ", "Tunti", "SYS", "FI"},
+		},
+		Rows: [][]string{
+			{"01-01-2016", "00 - 01", "16,39", "16,39"},
+		},
+	}
+	got, err := elspot.ParseTable(table)
+	if err != nil {
+		t.Fatalf("elspot.ParseTable: %s", err)
+	}
+	want := map[string]string{"SYS": "16.39", "FI": "16.39"}
+	if len(got) != 1 || !reflect.DeepEqual(got[0].Prices, want) {
+		t.Fatalf("elspot.ParseTable = %+v, want one record with prices %v", got, want)
+	}
+	if ts := time.Date(2015, 12, 31, 23, 0, 0, 0, time.UTC); !got[0].Timestamp.Equal(ts) {
+		t.Errorf("Timestamp = %s, want %s", got[0].Timestamp.UTC(), ts)
+	}
+}
+
+func TestParseTableNoHeader(t *testing.T) {
+	table := htmltable.Table{Headers: [][]string{{"Area", "Price"}}}
+	if _, err := elspot.ParseTable(table); err == nil {
+		t.Error("elspot.ParseTable did not return error for table without date and hour columns")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	hour := func(h int, price string) elspot.Record {
+		return elspot.Record{
+			Timestamp: time.Date(2016, 1, 1, h, 0, 0, 0, time.UTC),
+			Prices:    map[string]string{"FI": price},
+		}
+	}
+	yearly := []elspot.Record{hour(0, "1"), hour(1, "1"), hour(2, "1")}
+	weekly := []elspot.Record{hour(3, "2"), hour(1, "2")}
+
+	got := elspot.Merge(yearly, weekly)
+	want := []string{"1", "2", "1", "2"}
+	if len(got) != len(want) {
+		t.Fatalf("Merge returned %d records, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].Timestamp.Hour() != i || got[i].Prices["FI"] != w {
+			t.Errorf("Merge()[%d] = %s %s, want hour %d price %s", i, got[i].Timestamp, got[i].Prices["FI"], i, w)
+		}
+	}
+}
+
+func TestParserOverrides(t *testing.T) {
+	table := htmltable.Table{
+		Headers: [][]string{{"Date", "Hours", "SYS", "FI"}},
+		Rows: [][]string{
+			{"2016/01/01", "00 - 01", "16,39", "16,39"},
+		},
+	}
+	p := elspot.Parser{TimeLayout: "2006/01/02 15", Location: time.UTC}
+	got, err := p.ParseTable(table)
+	if err != nil {
+		t.Fatalf("Parser.ParseTable: %s", err)
+	}
+	if ts := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC); len(got) != 1 || !got[0].Timestamp.Equal(ts) {
+		t.Fatalf("Parser.ParseTable = %+v, want one record at %s", got, ts)
+	}
+	if _, err := elspot.ParseTable(table); err == nil {
+		t.Error("elspot.ParseTable accepted a date in a non-default layout")
+	}
+}
+
+func TestStream(t *testing.T) {
+	f, err := os.Open("testdata/dst-autumn.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	records, errc := elspot.Stream(context.Background(), f)
+	var n int
+	for range records {
+		n++
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("elspot.Stream: %s", err)
+	}
+	if n != 4 {
+		t.Errorf("elspot.Stream sent %d records, want 4", n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	f.Seek(0, 0)
+	records, errc = elspot.Stream(ctx, f)
+	for range records {
+	}
+	if err := <-errc; err != context.Canceled {
+		t.Errorf("elspot.Stream with canceled context returned %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestParserWarnings(t *testing.T) {
+	table := htmltable.Table{
+		Headers: [][]string{{"Date", "Hours", "SYS", "FI", ""}},
+		Rows: [][]string{
+			{"01-01-2016", "00 - 01", "16,39", "1 016,39", ""},
+			{"01-01-2016", "01 - 02", "16,39", "n/a", "x"},
+			{"01-01-2016", "02 - 03", "", "16,39", ""},
+			{"01-01-2016", "03 - 04", "", "", ""},
+		},
+	}
+	var warnings []string
+	p := elspot.Parser{Warn: func(w elspot.Warning) { warnings = append(warnings, w.String()) }}
+	got, err := p.ParseTable(table)
+	if err != nil {
+		t.Fatalf("Parser.ParseTable: %s", err)
+	}
+	want := []string{
+		`row 1: FI price "1 016,39" normalized to 1016.39`,
+		`row 2: FI price "n/a" is not a number`,
+		`row 2: value "x" in column 5 without header ignored`,
+		`row 3: no SYS price, row skipped although FI has a price`,
+	}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Errorf("warnings =\n%q\nwant\n%q", warnings, want)
+	}
+	if len(got) != 2 || got[0].Prices["FI"] != "1016.39" || got[1].Prices["FI"] != "" {
+		t.Errorf("Parser.ParseTable = %+v, want two records with FI 1016.39 and empty", got)
+	}
+}
+
+// TestParseQuality checks that the provisional and estimated marks apply
+// to the price they are on, not to the rest of the row.
+func TestParseQuality(t *testing.T) {
+	table := htmltable.Table{
+		Headers: [][]string{{"Date", "Hours", "SYS", "FI", "SE3"}},
+		Rows: [][]string{
+			{"01-01-2016", "00 - 01", "16,39*", "16,00", "15,00"},
+			{"01-01-2016", "01 - 02", "16,39", "17,00 (e)", "15,00*"},
+			{"01-01-2016", "02 - 03", "16,39", "18,00", "15,00"},
+		},
+	}
+	p := elspot.Parser{Columns: map[string]string{"se3": "stockholm"}}
+	got, err := p.ParseTable(table)
+	if err != nil {
+		t.Fatalf("Parser.ParseTable: %s", err)
+	}
+	want := []struct {
+		fi, fiStatus, stockholmStatus string
+		provisional                   bool
+	}{
+		{"16.00", elspot.StatusFinal, elspot.StatusFinal, true},
+		{"17.00", elspot.StatusEstimated, elspot.StatusProvisional, true},
+		{"18.00", elspot.StatusFinal, elspot.StatusFinal, false},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Parser.ParseTable returned %d records, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		r := got[i]
+		if r.Prices["FI"] != w.fi || r.Status("FI") != w.fiStatus || r.Status("stockholm") != w.stockholmStatus || r.Provisional != w.provisional {
+			t.Errorf("record %d: FI %s %s, stockholm %s, Provisional %v; want FI %s %s, stockholm %s, Provisional %v",
+				i, r.Prices["FI"], r.Status("FI"), r.Status("stockholm"), r.Provisional, w.fi, w.fiStatus, w.stockholmStatus, w.provisional)
+		}
+	}
+}
+
+// TestParserMalformedRows checks that rows too short for their date and
+// hour are skipped with a warning rather than panicking.
+func TestParserMalformedRows(t *testing.T) {
+	table := htmltable.Table{
+		Headers: [][]string{{"SYS", "FI", "Date", "Hours"}},
+		Rows: [][]string{
+			{"16,39", "16,39", "01-01-2016", "00 - 01"},
+			{"16,39", "16,39", "01-01-2016", "1"},
+			{"16,39", "16,39", "01-01-2016"},
+			{"16,39", "16,39"},
+		},
+	}
+	var warnings []string
+	p := elspot.Parser{Warn: func(w elspot.Warning) { warnings = append(warnings, w.String()) }}
+	got, err := p.ParseTable(table)
+	if err != nil {
+		t.Fatalf("Parser.ParseTable: %s", err)
+	}
+	want := []string{
+		"row 2: no date or hour, row skipped",
+		"row 3: no date or hour, row skipped",
+		"row 4: no date or hour, row skipped",
+	}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Errorf("warnings =\n%q\nwant\n%q", warnings, want)
+	}
+	if len(got) != 1 {
+		t.Errorf("Parser.ParseTable = %+v, want the first row only", got)
+	}
+}
+
+func TestDetectLocation(t *testing.T) {
+	tests := []struct {
+		doc  string
+		want string
+	}{
+		{`<p>Prices in EUR/MWh, times in CET/CEST</p><table></table>`, "Europe/Paris"},
+		{`<table></table><p>All hours are EET</p>`, "Europe/Helsinki"},
+		{`<div class="EET">Hours in CET</div>`, "Europe/Paris"},
+		{`<p>CET</p><p>EET</p>`, ""},
+		{`<p>SECETARY</p>`, ""},
+		{`<table></table>`, ""},
+	}
+	for _, tt := range tests {
+		loc, _ := elspot.DetectLocation([]byte(tt.doc))
+		got := ""
+		if loc != nil {
+			got = loc.String()
+		}
+		if got != tt.want {
+			t.Errorf("DetectLocation(%q) = %q, want %q", tt.doc, got, tt.want)
+		}
+	}
+}
+
+func TestParserColumns(t *testing.T) {
+	table := htmltable.Table{
+		Headers: [][]string{{"Date", "Hours", "SYS", "FI", "Oslo", "Kr.sand"}},
+		Rows:    [][]string{{"01-01-2016", "00 - 01", "16,39", "16,40", "20,00", "21,00"}},
+	}
+	p := elspot.Parser{
+		Columns: map[string]string{"fi": "finland", "SYS": "system"},
+		Ignore:  []string{"oslo", "KR.SAND"},
+	}
+	got, err := p.ParseTable(table)
+	if err != nil {
+		t.Fatalf("Parser.ParseTable: %s", err)
+	}
+	want := map[string]string{"system": "16.39", "finland": "16.40"}
+	if len(got) != 1 || !reflect.DeepEqual(got[0].Prices, want) {
+		t.Errorf("Parser.ParseTable = %+v, want prices %v", got, want)
+	}
+}
+
+func TestAnomalies(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	prices := []map[string]string{
+		{"FI": "16.39", "SE3": "15.00"},
+		{"FI": "1639", "SE3": "15.20"}, // decimal comma misread
+		{"FI": "17.01", "SE3": "2.50"}, // low, but near zero
+		{"FI": "150.00", "SE3": "14.00"},
+		{"FI": "-5.00", "SE3": "14.00"},
+	}
+	records := make([]elspot.Record, len(prices))
+	for i, p := range prices {
+		records[i] = elspot.Record{Timestamp: start.Add(time.Duration(i) * time.Hour), Prices: p}
+	}
+	got := elspot.Anomalies(records, 10)
+	if len(got) != 1 || got[0].Area != "FI" || got[0].Price != 1639 || got[0].Before != 16.39 || got[0].After != 17.01 {
+		t.Errorf("Anomalies = %v, want FI 1639 at 01:00", got)
+	}
+}
+
+func TestParseExchanges(t *testing.T) {
+	table := htmltable.Table{
+		Headers: [][]string{{"Date", "Hours", "FI > SE1", "SE3 < FI", "EE-FI", "FI total"}},
+		Rows: [][]string{
+			{"27-03-2016", "01 - 02", "1 500", "1200", "-358,0", "2300"},
+			{"27-03-2016", "02 - 03", "", "", "", ""},
+			{"27-03-2016", "03 - 04", "1500", "1200*", "", "2700"},
+		},
+	}
+	var warnings []string
+	p := elspot.Parser{Warn: func(w elspot.Warning) { warnings = append(warnings, w.String()) }}
+	got, err := p.ParseExchanges(table)
+	if err != nil {
+		t.Fatalf("ParseExchanges: %s", err)
+	}
+	first := time.Date(2016, 3, 27, 0, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+	want := []elspot.Exchange{
+		{Timestamp: first, From: "EE", To: "FI", MW: -358},
+		{Timestamp: first, From: "FI", To: "SE1", MW: 1500},
+		{Timestamp: first, From: "FI", To: "SE3", MW: 1200},
+		{Timestamp: second, From: "FI", To: "SE1", MW: 1500},
+		{Timestamp: second, From: "FI", To: "SE3", MW: 1200, Provisional: true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d exchanges, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if !got[i].Timestamp.Equal(want[i].Timestamp) || got[i].From != want[i].From || got[i].To != want[i].To || got[i].MW != want[i].MW || got[i].Provisional != want[i].Provisional {
+			t.Errorf("exchange %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	found := false
+	for _, w := range warnings {
+		found = found || w == `row 1: column "FI total" is not a connection, ignored`
+	}
+	if !found {
+		t.Errorf("no warning about the total column in %q", warnings)
+	}
+}
+
+func TestNumberFormats(t *testing.T) {
+	cases := []struct {
+		format elspot.NumberFormat
+		in     string
+		want   string
+	}{
+		{elspot.NumbersAuto, "16,39", "16.39"},
+		{elspot.NumbersAuto, "16.39", "16.39"},
+		{elspot.NumbersAuto, "1 234,56", "1234.56"},
+		{elspot.NumbersAuto, "1.234,56", "1234.56"},
+		{elspot.NumbersAuto, "1,234.56", "1234.56"},
+		{elspot.NumbersAuto, "1,234,567", "1234567"},
+		{elspot.NumbersAuto, "1 234,5", "1234.5"},
+		{elspot.NumbersDecimalComma, "1.234", "1234"},
+		{elspot.NumbersDecimalPoint, "1,234", "1234"},
+		{elspot.NumbersDecimalPoint, "-3.5", "-3.5"},
+		{elspot.NumbersDecimalComma, "1,2,3", ""},
+	}
+	for _, c := range cases {
+		table := htmltable.Table{
+			Headers: [][]string{{"Date", "Hours", "SYS", "FI"}},
+			Rows:    [][]string{{"01-01-2016", "00 - 01", "1", c.in}},
+		}
+		got, err := elspot.Parser{Numbers: c.format}.ParseTable(table)
+		if err != nil {
+			t.Errorf("%s %q: %s", c.format, c.in, err)
+			continue
+		}
+		if got[0].Prices["FI"] != c.want {
+			t.Errorf("%s %q = %q, want %q", c.format, c.in, got[0].Prices["FI"], c.want)
+		}
+	}
+
+	for _, name := range []string{"auto", "comma", "point"} {
+		if f, err := elspot.ParseNumberFormat(name); err != nil || f.String() != name {
+			t.Errorf("ParseNumberFormat(%s) = %s, %v", name, f, err)
+		}
+	}
+	if _, err := elspot.ParseNumberFormat("fi"); err == nil {
+		t.Error("ParseNumberFormat(fi) did not return error")
+	}
+}
+
+func TestFilter(t *testing.T) {
+	helsinki, err := time.LoadLocation("Europe/Helsinki")
+	if err != nil {
+		t.Skip(err)
+	}
+	r := elspot.Record{
+		Timestamp:   time.Date(2020, 1, 2, 8, 0, 0, 0, helsinki),
+		Prices:      map[string]string{"FI": "120.5", "SE3": "30", "EE": ""},
+		Provisional: true,
+	}
+	tests := []struct {
+		expr string
+		want []string // matching areas
+	}{
+		{"area==FI", []string{"FI"}},
+		{"area==fi && price>100", []string{"FI"}},
+		{"price>100", []string{"FI"}},
+		{"price<=30", []string{"SE3"}},
+		{"!(price>100)", []string{"EE", "SE3"}},
+		{"area=='SE3' || area==\"EE\"", []string{"EE", "SE3"}},
+		{"area!=FI && price>0", []string{"SE3"}},
+		{"hour==8 && date==2020-01-02", []string{"EE", "FI", "SE3"}},
+		{"time>=2020-01-02T06:00:00Z", []string{"EE", "FI", "SE3"}},
+		{"time<2020-01-02T06:00:00Z", nil},
+		{"provisional", []string{"EE", "FI", "SE3"}},
+		{"!provisional || area==FI", []string{"FI"}},
+		{"provisional==false", nil},
+		{"date<2020-01-01 || price>-5 && area==SE3", []string{"SE3"}},
+	}
+	for _, tt := range tests {
+		f, err := elspot.ParseFilter(tt.expr)
+		if err != nil {
+			t.Errorf("ParseFilter(%s): %s", tt.expr, err)
+			continue
+		}
+		var got []string
+		for _, area := range []string{"EE", "FI", "SE3"} {
+			if f.Match(r, area) {
+				got = append(got, area)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s matches %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, expr := range []string{"", "area", "area==", "spam>1", "price>x", "(area==FI", "area==FI &&", "date>2020-13-01", "provisional>true", "area==FI)", "area=='FI", "price>100 # comment"} {
+		if _, err := elspot.ParseFilter(expr); err == nil {
+			t.Errorf("ParseFilter(%q) did not return error", expr)
+		}
+	}
+}
+
+func TestFilterApply(t *testing.T) {
+	records := []elspot.Record{
+		{Timestamp: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), Prices: map[string]string{"FI": "10", "SE3": "200"}},
+		{Timestamp: time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC), Prices: map[string]string{"FI": "150", "SE3": "20"}},
+		{Timestamp: time.Date(2020, 1, 1, 2, 0, 0, 0, time.UTC), Prices: map[string]string{"FI": "10", "SE3": "20"}},
+	}
+	f, err := elspot.ParseFilter("price>100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := f.Apply(records)
+	want := []map[string]string{{"SE3": "200"}, {"FI": "150"}}
+	if len(got) != len(want) {
+		t.Fatalf("Apply kept %d records, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i].Prices, want[i]) {
+			t.Errorf("record %d prices = %v, want %v", i, got[i].Prices, want[i])
+		}
+	}
+	if len(records[0].Prices) != 2 {
+		t.Error("Apply modified its input")
+	}
+}
@@ -0,0 +1,184 @@
+// Package elspottest generates synthetic elspot files, so that parser and
+// loader tests can cover any date range, set of areas or DST transition
+// without real fixtures.
+//
+// A File describes the file; HTML renders it in the layout of the Nordpool
+// exports and Records returns what elspot.Parser is expected to parse from
+// it.
+package elspottest
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joneskoo/etget/elspot"
+	"github.com/joneskoo/etget/htmltable/htmltabletest"
+	"github.com/joneskoo/etget/internal/zoneinfo"
+)
+
+// Golden adapts p to htmltabletest.RunParser, so that real elspot files can
+// be added as fixtures with the records p parses from them as golden files.
+func Golden(p elspot.Parser) htmltabletest.Parser {
+	return golden{p}
+}
+
+type golden struct {
+	p elspot.Parser
+}
+
+func (g golden) Parse(r io.Reader) (interface{}, error) { return g.p.Parse(r) }
+func (golden) New() interface{}                         { return new([]elspot.Record) }
+
+// File is a synthetic elspot price file.
+type File struct {
+	// From and To are the first hour and the end of the last hour. They
+	// should not fall between the two passes through the hour repeated at
+	// the end of summer time, which the parser cannot tell apart alone.
+	From, To time.Time
+
+	// Areas are the price columns after SYS (default FI).
+	Areas []string
+
+	// Location is the time zone of the timestamps (default Europe/Paris,
+	// as in the Nordpool files).
+	Location *time.Location
+
+	// Price returns the price of area in the hour at ts (default a
+	// pattern that differs by hour and area).
+	Price func(ts time.Time, area string) float64
+
+	// Provisional, if set, marks the prices of the hours it returns true
+	// for as provisional.
+	Provisional func(ts time.Time) bool
+
+	// Numbers is the notation of the prices: the decimal comma of the
+	// Nordpool files unless NumbersDecimalPoint.
+	Numbers elspot.NumberFormat
+}
+
+func (f File) areas() []string {
+	if len(f.Areas) == 0 {
+		return []string{"FI"}
+	}
+	return f.Areas
+}
+
+func (f File) location() *time.Location {
+	if f.Location == nil {
+		return zoneinfo.MustLoad("Europe/Paris")
+	}
+	return f.Location
+}
+
+func (f File) price(ts time.Time, area string) float64 {
+	if f.Price != nil {
+		return f.Price(ts, area)
+	}
+	return float64(20+ts.UTC().Hour()) + float64(len(area))/4
+}
+
+func (f File) provisional(ts time.Time) bool {
+	return f.Provisional != nil && f.Provisional(ts)
+}
+
+// hours returns the hours of the file in order.
+func (f File) hours() (hours []time.Time) {
+	loc := f.location()
+	for t := f.From.Truncate(time.Hour); t.Before(f.To); t = t.Add(time.Hour) {
+		hours = append(hours, t.In(loc))
+	}
+	return hours
+}
+
+// Records returns the records elspot.Parser parses from the file, with
+// the timestamps in Location.
+func (f File) Records() []elspot.Record {
+	hours := f.hours()
+	records := make([]elspot.Record, len(hours))
+	for i, ts := range hours {
+		prices := map[string]string{"SYS": formatPrice(f.price(ts, "SYS"), elspot.NumbersDecimalPoint)}
+		for _, area := range f.areas() {
+			prices[area] = formatPrice(f.price(ts, area), elspot.NumbersDecimalPoint)
+		}
+		records[i] = elspot.Record{Timestamp: ts, Prices: prices}
+		if f.provisional(ts) {
+			records[i].Provisional = true
+			records[i].Quality = make(map[string]string, len(prices))
+			for area := range prices {
+				records[i].Quality[area] = elspot.StatusProvisional
+			}
+		}
+		if repeated(ts) {
+			records[i].Corrected = true
+			records[i].OriginalLocal = ts.Format("2006-01-02 15:04")
+		}
+	}
+	return records
+}
+
+// repeated reports whether the wall clock time of ts occurs twice, an
+// hour earlier or later, as at the end of summer time.
+func repeated(ts time.Time) bool {
+	wall := ts.Format("2006-01-02 15")
+	return ts.Add(-time.Hour).Format("2006-01-02 15") == wall || ts.Add(time.Hour).Format("2006-01-02 15") == wall
+}
+
+// HTML renders the file in the layout of the Nordpool exports. The hour
+// skipped at the start of summer time is an empty row.
+func (f File) HTML() []byte {
+	areas := f.areas()
+	columns := len(areas) + 3
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<html>\n<body>\n<table>\n<thead>\n")
+	fmt.Fprintf(&b, "<tr><td colspan=\"%d\">Elspot Prices in EUR/MWh</td></tr>\n", columns)
+	fmt.Fprintf(&b, "<tr><td colspan=\"%d\">Synthetic data generated by elspottest</td></tr>\n", columns)
+	fmt.Fprintf(&b, "<tr><td></td><td>Hours</td><td>SYS</td>")
+	for _, area := range areas {
+		fmt.Fprintf(&b, "<td>%s</td>", html.EscapeString(area))
+	}
+	fmt.Fprintf(&b, "</tr>\n</thead>\n<tbody>\n")
+
+	var prev time.Time
+	for _, ts := range f.hours() {
+		if !prev.IsZero() && ts.Sub(prev) == time.Hour && ts.Hour() == (prev.Hour()+2)%24 {
+			writeRow(&b, prev.Format("02-01-2006"), prev.Hour()+1, make([]string, len(areas)+1))
+		}
+		prev = ts
+		prices := []string{formatPrice(f.price(ts, "SYS"), f.Numbers)}
+		for _, area := range areas {
+			prices = append(prices, formatPrice(f.price(ts, area), f.Numbers))
+		}
+		if f.provisional(ts) {
+			for i := range prices {
+				prices[i] += "*"
+			}
+		}
+		writeRow(&b, ts.Format("02-01-2006"), ts.Hour(), prices)
+	}
+	fmt.Fprintf(&b, "</tbody>\n</table>\n</body>\n</html>\n")
+	return b.Bytes()
+}
+
+// writeRow writes the row of the hour starting at hour on date.
+func writeRow(b *bytes.Buffer, date string, hour int, values []string) {
+	fmt.Fprintf(b, "<tr><td>%s</td><td>%02d&nbsp;-&nbsp;%02d</td>", date, hour, (hour+1)%24)
+	for _, v := range values {
+		fmt.Fprintf(b, "<td>%s</td>", v)
+	}
+	b.WriteString("</tr>\n")
+}
+
+// formatPrice formats v with two decimals, with a decimal comma unless
+// numbers is NumbersDecimalPoint.
+func formatPrice(v float64, numbers elspot.NumberFormat) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	if numbers != elspot.NumbersDecimalPoint {
+		s = strings.Replace(s, ".", ",", 1)
+	}
+	return s
+}
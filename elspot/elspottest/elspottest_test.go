@@ -0,0 +1,76 @@
+package elspottest_test
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/joneskoo/etget/elspot"
+	"github.com/joneskoo/etget/elspot/elspottest"
+	"github.com/joneskoo/etget/internal/zoneinfo"
+)
+
+func TestRoundTrip(t *testing.T) {
+	paris := zoneinfo.MustLoad("Europe/Paris")
+	helsinki := zoneinfo.MustLoad("Europe/Helsinki")
+	tests := []struct {
+		name string
+		file elspottest.File
+	}{
+		{"year", elspottest.File{
+			From: time.Date(2015, 1, 1, 0, 0, 0, 0, paris),
+			To:   time.Date(2016, 1, 1, 0, 0, 0, 0, paris),
+		}},
+		{"spring", elspottest.File{
+			From:     time.Date(2016, 3, 26, 0, 0, 0, 0, helsinki),
+			To:       time.Date(2016, 3, 28, 0, 0, 0, 0, helsinki),
+			Areas:    []string{"FI", "EE", "SE1"},
+			Location: helsinki,
+		}},
+		{"autumn provisional", elspottest.File{
+			From:        time.Date(2016, 10, 29, 0, 0, 0, 0, paris),
+			To:          time.Date(2016, 10, 31, 0, 0, 0, 0, paris),
+			Provisional: func(ts time.Time) bool { return ts.Day() == 30 },
+			Numbers:     elspot.NumbersDecimalPoint,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := elspot.Parser{Location: tt.file.Location}
+			got, err := p.Parse(bytes.NewReader(tt.file.HTML()))
+			if err != nil {
+				t.Fatalf("Parse: %s", err)
+			}
+			want := tt.file.Records()
+			checkRecords(t, "Parse", got, want)
+
+			var streamed []elspot.Record
+			records, errc := p.Stream(context.Background(), bytes.NewReader(tt.file.HTML()))
+			for r := range records {
+				streamed = append(streamed, r)
+			}
+			if err := <-errc; err != nil {
+				t.Fatalf("Stream: %s", err)
+			}
+			checkRecords(t, "Stream", streamed, want)
+		})
+	}
+}
+
+func checkRecords(t *testing.T, name string, got, want []elspot.Record) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s returned %d records, want %d", name, len(got), len(want))
+	}
+	for i := range want {
+		if !got[i].Timestamp.Equal(want[i].Timestamp) {
+			t.Fatalf("%s record %d: Timestamp = %s, want %s", name, i, got[i].Timestamp, want[i].Timestamp)
+		}
+		got[i].Timestamp = want[i].Timestamp
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Fatalf("%s record %d = %+v, want %+v", name, i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package elspot
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joneskoo/etget/htmltable"
+)
+
+// Exchange is the transmission capacity or the flow of one hour on a
+// connection between two bidding areas, in MW from From to To.
+type Exchange struct {
+	Timestamp time.Time
+	From, To  string
+	MW        float64
+
+	// Provisional is set if the value was marked provisional.
+	Provisional bool
+}
+
+// connectionSeparators split a capacity or flow column header into the
+// areas, e.g. "FI > SE1" or "SE1-FI". With "<" the direction is reversed.
+var connectionSeparators = []string{">", "<", "–", "-"}
+
+// parseConnection returns the areas of a column header, or ok false if
+// the column is not a connection, e.g. a total.
+func parseConnection(header string) (from, to string, ok bool) {
+	for _, sep := range connectionSeparators {
+		parts := strings.Split(header, sep)
+		if len(parts) != 2 {
+			continue
+		}
+		from, to = strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if from == "" || to == "" || strings.ContainsAny(from+to, " ") {
+			return "", "", false
+		}
+		if sep == "<" {
+			from, to = to, from
+		}
+		return from, to, true
+	}
+	return "", "", false
+}
+
+// ParseExchanges parses the table of an elspot capacities or flow file,
+// which have the date and hour columns of price files and a column per
+// connection instead of a price per area. Columns that do not name a
+// connection are ignored with a warning. The result is sorted by time and
+// connection.
+func (p Parser) ParseExchanges(table htmltable.Table) ([]Exchange, error) {
+	data, err := p.parseRows(table, func(row int, values map[string]string) bool {
+		for _, v := range values {
+			if v != "" {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	var exchanges []Exchange
+	skipped := make(map[string]bool)
+	for i, r := range data {
+		for header, v := range r.Prices {
+			from, to, ok := parseConnection(header)
+			if !ok {
+				if !skipped[header] {
+					p.warnf(i+1, "column %q is not a connection, ignored", header)
+					skipped[header] = true
+				}
+				continue
+			}
+			if v == "" {
+				continue
+			}
+			mw, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue // already warned about by parseRows
+			}
+			exchanges = append(exchanges, Exchange{Timestamp: r.Timestamp, From: from, To: to, MW: mw, Provisional: r.Status(header) != StatusFinal})
+		}
+	}
+	sort.Slice(exchanges, func(i, j int) bool {
+		a, b := exchanges[i], exchanges[j]
+		if !a.Timestamp.Equal(b.Timestamp) {
+			return a.Timestamp.Before(b.Timestamp)
+		}
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+	return exchanges, nil
+}
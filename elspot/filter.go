@@ -0,0 +1,303 @@
+package elspot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Filter selects the prices of records by an expression such as
+//
+//	area==FI && price>100
+//	(area==SE3 || area==SE4) && date>=2020-01-01 && !provisional
+//
+// Comparisons are joined with && and ||, negated with ! and grouped with
+// parentheses. The fields are:
+//
+//	area         area code, compared case-insensitively
+//	price        price; comparisons of missing prices are false
+//	hour         hour of the day, 0–23
+//	date         day as 2006-01-02
+//	time         timestamp, compared with an RFC 3339 time
+//	provisional  whether the row is provisional; alone it means ==true
+//
+// hour and date are in the time zone of the record timestamps. Values
+// with spaces or operators are quoted with ' or ".
+type Filter struct {
+	expr string
+	root filterNode
+}
+
+// ParseFilter parses the filter expression expr.
+func ParseFilter(expr string) (*Filter, error) {
+	p := &filterParser{expr: expr}
+	if err := p.tokenize(); err != nil {
+		return nil, fmt.Errorf("filter: %s", err)
+	}
+	root, err := p.or()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q at offset %d", p.tokens[p.pos].text, p.tokens[p.pos].offset)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("filter: %s", err)
+	}
+	return &Filter{expr: expr, root: root}, nil
+}
+
+func (f *Filter) String() string { return f.expr }
+
+// Match reports whether the price of area in r is selected.
+func (f *Filter) Match(r Record, area string) bool { return f.root.match(&r, area) }
+
+// Apply returns records with only the selected prices, leaving out records
+// with none left. records are not modified.
+func (f *Filter) Apply(records []Record) []Record {
+	var out []Record
+	for _, r := range records {
+		prices := make(map[string]string, len(r.Prices))
+		for area, price := range r.Prices {
+			if f.root.match(&r, area) {
+				prices[area] = price
+			}
+		}
+		if len(prices) == 0 {
+			continue
+		}
+		r.Prices = prices
+		out = append(out, r)
+	}
+	return out
+}
+
+type filterNode interface {
+	match(r *Record, area string) bool
+}
+
+type andNode struct{ a, b filterNode }
+type orNode struct{ a, b filterNode }
+type notNode struct{ a filterNode }
+
+func (n andNode) match(r *Record, area string) bool { return n.a.match(r, area) && n.b.match(r, area) }
+func (n orNode) match(r *Record, area string) bool  { return n.a.match(r, area) || n.b.match(r, area) }
+func (n notNode) match(r *Record, area string) bool { return !n.a.match(r, area) }
+
+// comparison compares a field with a constant. Only the constant of the
+// field's type is set.
+type comparison struct {
+	field, op string
+	str       string
+	num       float64
+	t         time.Time
+	b         bool
+}
+
+func (c comparison) match(r *Record, area string) bool {
+	switch c.field {
+	case "area":
+		return compareStrings(strings.ToUpper(area), c.op, strings.ToUpper(c.str))
+	case "price":
+		p, ok := parsePrice(r.Prices[area])
+		return ok && compareNumbers(p, c.op, c.num)
+	case "hour":
+		return compareNumbers(float64(r.Timestamp.Hour()), c.op, c.num)
+	case "date":
+		return compareStrings(r.Timestamp.Format("2006-01-02"), c.op, c.str)
+	case "time":
+		d := r.Timestamp.Sub(c.t)
+		return compareNumbers(float64(d), c.op, 0)
+	case "provisional":
+		return (r.Provisional == c.b) == (c.op == "==")
+	}
+	return false
+}
+
+func compareStrings(a, op, b string) bool {
+	return compareNumbers(float64(strings.Compare(a, b)), op, 0)
+}
+
+func compareNumbers(a float64, op string, b float64) bool {
+	switch op {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+type filterToken struct {
+	text   string
+	offset int
+	quoted bool
+}
+
+type filterParser struct {
+	expr   string
+	tokens []filterToken
+	pos    int
+}
+
+func isFilterWord(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("._:+-", r)
+}
+
+func (p *filterParser) tokenize() error {
+	s := p.expr
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(s[i+1:], c)
+			if end < 0 {
+				return fmt.Errorf("unterminated string at offset %d", i)
+			}
+			p.tokens = append(p.tokens, filterToken{s[i+1 : i+1+end], i, true})
+			i += end + 2
+		case strings.HasPrefix(s[i:], "&&"), strings.HasPrefix(s[i:], "||"),
+			strings.HasPrefix(s[i:], "=="), strings.HasPrefix(s[i:], "!="),
+			strings.HasPrefix(s[i:], "<="), strings.HasPrefix(s[i:], ">="):
+			p.tokens = append(p.tokens, filterToken{s[i : i+2], i, false})
+			i += 2
+		case strings.IndexByte("()!<>", c) >= 0:
+			p.tokens = append(p.tokens, filterToken{s[i : i+1], i, false})
+			i++
+		default:
+			j := i
+			for _, r := range s[i:] {
+				if !isFilterWord(r) {
+					break
+				}
+				j += len(string(r))
+			}
+			if j == i {
+				return fmt.Errorf("unexpected %q at offset %d", s[i:i+1], i)
+			}
+			p.tokens = append(p.tokens, filterToken{s[i:j], i, false})
+			i = j
+		}
+	}
+	return nil
+}
+
+// peek returns the next operator or punctuation token, or "".
+func (p *filterParser) peek() string {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].quoted {
+		return ""
+	}
+	return p.tokens[p.pos].text
+}
+
+func (p *filterParser) next() (filterToken, error) {
+	if p.pos >= len(p.tokens) {
+		return filterToken{}, fmt.Errorf("unexpected end of %q", p.expr)
+	}
+	p.pos++
+	return p.tokens[p.pos-1], nil
+}
+
+func (p *filterParser) or() (filterNode, error) {
+	n, err := p.and()
+	for err == nil && p.peek() == "||" {
+		p.pos++
+		var b filterNode
+		if b, err = p.and(); err == nil {
+			n = orNode{n, b}
+		}
+	}
+	return n, err
+}
+
+func (p *filterParser) and() (filterNode, error) {
+	n, err := p.unary()
+	for err == nil && p.peek() == "&&" {
+		p.pos++
+		var b filterNode
+		if b, err = p.unary(); err == nil {
+			n = andNode{n, b}
+		}
+	}
+	return n, err
+}
+
+func (p *filterParser) unary() (filterNode, error) {
+	switch p.peek() {
+	case "!":
+		p.pos++
+		n, err := p.unary()
+		return notNode{n}, err
+	case "(":
+		p.pos++
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing ) in %q", p.expr)
+		}
+		p.pos++
+		return n, nil
+	}
+	return p.comparison()
+}
+
+func (p *filterParser) comparison() (filterNode, error) {
+	field, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	c := comparison{field: strings.ToLower(field.text)}
+	switch c.field {
+	case "area", "price", "hour", "date", "time", "provisional":
+	default:
+		return nil, fmt.Errorf("unknown field %q at offset %d, want area, price, hour, date, time or provisional", field.text, field.offset)
+	}
+	op := p.peek()
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		p.pos++
+	default:
+		if c.field == "provisional" {
+			return comparison{field: c.field, op: "==", b: true}, nil
+		}
+		return nil, fmt.Errorf("missing comparison after %s at offset %d", field.text, field.offset)
+	}
+	c.op = op
+	value, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if !value.quoted && !isFilterWord([]rune(value.text)[0]) {
+		return nil, fmt.Errorf("missing value after %s%s at offset %d", field.text, op, value.offset)
+	}
+	switch c.field {
+	case "area", "date":
+		c.str = value.text
+		if c.field == "date" {
+			_, err = time.Parse("2006-01-02", value.text)
+		}
+	case "price", "hour":
+		c.num, err = strconv.ParseFloat(value.text, 64)
+	case "time":
+		c.t, err = time.Parse(time.RFC3339, value.text)
+	case "provisional":
+		if op != "==" && op != "!=" {
+			return nil, fmt.Errorf("provisional can only be compared with == or !=")
+		}
+		c.b, err = strconv.ParseBool(value.text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q at offset %d", c.field, value.text, value.offset)
+	}
+	return c, nil
+}
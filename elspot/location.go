@@ -0,0 +1,47 @@
+package elspot
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/joneskoo/etget/internal/zoneinfo"
+)
+
+// zoneMarkers maps time zone abbreviations found in the text around the
+// table to the location of the timestamps. Exports from the Nordpool
+// portal are in CET or, depending on the portal settings, EET.
+var zoneMarkers = map[string]string{
+	"CET":  defaultLocation,
+	"CEST": defaultLocation,
+	"EET":  "Europe/Helsinki",
+	"EEST": "Europe/Helsinki",
+	"UTC":  "UTC",
+}
+
+var (
+	tagRE    = regexp.MustCompile(`<[^>]*>`)
+	markerRE = regexp.MustCompile(`\b(CEST|CET|EEST|EET|UTC)\b`)
+)
+
+// DetectLocation returns the time zone named in the text of the elspot
+// document doc, and the abbreviation it was found by. It returns nil if
+// the document names no time zone or names several.
+func DetectLocation(doc []byte) (loc *time.Location, marker string) {
+	text := tagRE.ReplaceAll(doc, []byte(" "))
+	var name string
+	for _, m := range markerRE.FindAll(text, -1) {
+		n := zoneMarkers[string(m)]
+		if name != "" && n != name {
+			return nil, ""
+		}
+		name, marker = n, string(m)
+	}
+	if name == "" {
+		return nil, ""
+	}
+	loc, err := zoneinfo.Load(name)
+	if err != nil {
+		return nil, ""
+	}
+	return loc, marker
+}
@@ -0,0 +1,105 @@
+package elspot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// NumberFormat is the notation of the numbers in a file, which depends on
+// the locale of the export.
+type NumberFormat int
+
+const (
+	// NumbersAuto decides per value: of a period and a comma the last is
+	// the decimal separator, and a separator that occurs more than once
+	// groups digits. A lone separator is the decimal separator, so
+	// "1,234" is read as 1.234; choose a format for exports with
+	// grouped thousands and no decimals.
+	NumbersAuto NumberFormat = iota
+
+	// NumbersDecimalComma reads "1 234,56" and "1.234,56", as in the
+	// Nordic and German exports.
+	NumbersDecimalComma
+
+	// NumbersDecimalPoint reads "1,234.56" and "1 234.56", as in the
+	// English exports.
+	NumbersDecimalPoint
+)
+
+var numberFormats = []string{"auto", "comma", "point"}
+
+// ParseNumberFormat returns the format named by s: auto, comma or point.
+func ParseNumberFormat(s string) (NumberFormat, error) {
+	for i, name := range numberFormats {
+		if s == name {
+			return NumberFormat(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown number format %q, want one of %s", s, strings.Join(numberFormats, ", "))
+}
+
+func (f NumberFormat) String() string {
+	if int(f) < len(numberFormats) {
+		return numberFormats[f]
+	}
+	return fmt.Sprintf("NumberFormat(%d)", int(f))
+}
+
+// normalize converts a price to the period-decimal form Postgres accepts.
+// The decimal separator is expected; other changes, such as removing digit
+// group separators, are reported as normalized. Prices that are not
+// numbers are returned empty with ok false.
+func (f NumberFormat) normalize(v string) (price string, normalized, ok bool) {
+	if v == "" {
+		return "", false, true
+	}
+	price = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '\'' {
+			return -1
+		}
+		return r
+	}, v)
+	normalized = price != v
+
+	decimal := f.decimal(price)
+	group := "."
+	if decimal == '.' {
+		group = ","
+	}
+	if strings.Contains(price, group) {
+		price = strings.Replace(price, group, "", -1)
+		normalized = true
+	}
+	price = strings.Replace(price, ",", ".", 1)
+	if _, err := strconv.ParseFloat(price, 64); err != nil {
+		return "", false, false
+	}
+	return price, normalized, true
+}
+
+// decimal returns the decimal separator of number s.
+func (f NumberFormat) decimal(s string) rune {
+	switch f {
+	case NumbersDecimalComma:
+		return ','
+	case NumbersDecimalPoint:
+		return '.'
+	}
+	comma, period := strings.LastIndex(s, ","), strings.LastIndex(s, ".")
+	switch {
+	case comma >= 0 && period >= 0:
+		if comma > period {
+			return ','
+		}
+		return '.'
+	case strings.Count(s, ",") > 1:
+		return '.'
+	case strings.Count(s, ".") > 1:
+		return ','
+	case period >= 0:
+		return '.'
+	}
+	return ','
+}
@@ -27,6 +27,11 @@ type Client struct {
 	// Transport is a roundtripper the client uses to make HTTP requests.
 	Transport http.RoundTripper
 
+	// Session, if set, keeps the login session between runs. A saved
+	// session is tried first and the client logs in again only when the
+	// portal no longer accepts it.
+	Session *SessionFile
+
 	// unexported
 	initOnce sync.Once
 	cl       http.Client
@@ -47,10 +52,55 @@ func (c *Client) init() {
 func (c *Client) ConsumptionReport(ctx context.Context, w io.Writer) error {
 	c.init()
 
+	if c.resumeSession() {
+		if err := c.consumptionReport(ctx, w); err == nil {
+			return nil
+		}
+		// The saved session has expired; the report is only written
+		// on success, so it is safe to log in and try again. The stale
+		// cookies must go or login would find the old .ASPXAUTH.
+		c.cl.Jar, _ = cookiejar.New(nil)
+	}
 	if err := c.login(ctx); err != nil {
 		return err
 	}
+	if c.Session != nil {
+		if err := c.Session.Save(c.cl.Jar.Cookies(loginURL)); err != nil {
+			return fmt.Errorf("saving session: %s", err)
+		}
+	}
+	return c.consumptionReport(ctx, w)
+}
+
+var (
+	portalURL = &url.URL{Scheme: "https", Host: "www.energiatili.fi", Path: "/"}
+	loginURL  = mustParse(endpointLogin)
+)
 
+func mustParse(s string) *url.URL {
+	u, err := url.Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+// resumeSession restores the saved session cookies, reporting whether there
+// were any. An unreadable session file is treated as no session: it is
+// replaced after the next login.
+func (c *Client) resumeSession() bool {
+	if c.Session == nil {
+		return false
+	}
+	cookies, err := c.Session.Load()
+	if err != nil || len(cookies) == 0 {
+		return false
+	}
+	c.cl.Jar.SetCookies(portalURL, cookies)
+	return true
+}
+
+func (c *Client) consumptionReport(ctx context.Context, w io.Writer) error {
 	// Dummy request - without this the real request will return empty.
 	req, err := http.NewRequestWithContext(ctx, "GET", endpointConsumptionReport, nil)
 	if err != nil {
@@ -108,11 +158,7 @@ func (c *Client) login(ctx context.Context) (err error) {
 	defer resp.Body.Close()
 	io.Copy(ioutil.Discard, resp.Body)
 
-	u, err := url.Parse(endpointLogin)
-	if err != nil {
-		panic(err)
-	}
-	for _, cookie := range c.cl.Jar.Cookies(u) {
+	for _, cookie := range c.cl.Jar.Cookies(loginURL) {
 		if cookie.Name == ".ASPXAUTH" {
 			return nil
 		}
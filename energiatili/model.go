@@ -7,11 +7,21 @@ import (
 	"sort"
 	"time"
 
+	"github.com/joneskoo/etget/internal/zoneinfo"
 	"github.com/joneskoo/etget/notz"
 )
 
 // ConsumptionReport is the structure in 'var model' of Energiatili
 type ConsumptionReport struct {
+	// SnapTolerance, if positive, moves hourly timestamps within it of a
+	// whole hour to the hour in Records and ProductionRecords, for meters
+	// whose clock drifts.
+	SnapTolerance time.Duration `json:"-"`
+
+	// Drift, if set, is called for each hourly timestamp further than
+	// SnapTolerance from a whole hour. Such points are kept as read.
+	Drift func(notz.Drift) `json:"-"`
+
 	IsValid                bool   `json:"IsValid"`
 	HasTemperatureSeries   bool   `json:"HasTemperatureSeries"`
 	HasReactivePowerSeries bool   `json:"HasReactivePowerSeries"`
@@ -62,8 +72,42 @@ type ConsumptionReport struct {
 	HasWaterHourlyValues                          bool    `json:"HasWaterHourlyValues"`
 }
 
+// Records returns the hourly consumption.
 func (c *ConsumptionReport) Records() (points []Record, err error) {
-	for _, cons := range c.Hours.Consumptions {
+	return c.hourlyRecords(c.Hours.Consumptions)
+}
+
+// ProductionRecords returns the hourly production sold to the grid, e.g.
+// by solar panels. It is empty if the metering point has no production.
+func (c *ConsumptionReport) ProductionRecords() (points []Record, err error) {
+	return c.hourlyRecords(c.Hours.Productions)
+}
+
+// DailyRecords returns the daily consumption totals, timestamped at the
+// start of the day in Helsinki. The portal includes its estimate for days
+// the meter could not be read, so they can be more complete than Records.
+func (c *ConsumptionReport) DailyRecords() []Record {
+	byDay := make(map[int64]int)
+	var points []Record
+	for _, cons := range c.Days.Consumptions {
+		for _, p := range cons.Series.Data {
+			if i, ok := byDay[p.Timestamp.Unix()]; ok {
+				points[i].Value += p.Value
+				continue
+			}
+			byDay[p.Timestamp.Unix()] = len(points)
+			points = append(points, p)
+		}
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Timestamp.Before(points[j].Timestamp)
+	})
+	return points
+}
+
+// hourlyRecords merges the tariff time zone series into one sorted series.
+func (c *ConsumptionReport) hourlyRecords(series []Consumption) (points []Record, err error) {
+	for _, cons := range series {
 		for _, p := range cons.Series.Data {
 			points = append(points, p)
 		}
@@ -71,7 +115,16 @@ func (c *ConsumptionReport) Records() (points []Record, err error) {
 	sort.Slice(points, func(i, j int) bool {
 		return points[i].Timestamp.Before(points[j].Timestamp)
 	})
-	notz.FixDST(records(points))
+	if c.SnapTolerance > 0 {
+		for _, d := range notz.SnapHours(records(points), c.SnapTolerance) {
+			if c.Drift != nil {
+				c.Drift(d)
+			}
+		}
+	}
+	if err := notz.CheckedFixDST(records(points)); err != nil {
+		return nil, err
+	}
 	points = trimTrailingZeros(points)
 	return points, nil
 }
@@ -232,22 +285,18 @@ func trimTrailingZeros(p []Record) []Record {
 
 // parseEnergiatiliTime decodes "unixMillis" ignoring time zone and cast to Helsinki time
 func parseEnergiatiliTime(t float64) time.Time {
-	ts := time.Unix(int64(t/1000), 0).UTC()
-	year, _, day := ts.Date()
-	month := ts.Month()
-	hour, min, sec := ts.Clock()
-	return time.Date(year, month, day, hour, min, sec, 0, helsinki)
+	return notz.WallClock(time.Unix(int64(t/1000), 0).UTC(), helsinki)
 }
 
 var utc, helsinki *time.Location
 
 func init() {
 	var err error
-	helsinki, err = time.LoadLocation("Europe/Helsinki")
+	helsinki, err = zoneinfo.Load("Europe/Helsinki")
 	if err != nil {
 		panic(err)
 	}
-	utc, err = time.LoadLocation("UTC")
+	utc, err = zoneinfo.Load("UTC")
 	if err != nil {
 		panic(err)
 	}
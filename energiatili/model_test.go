@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/joneskoo/etget/energiatili"
+	"github.com/joneskoo/etget/notz"
 )
 
 func mustTime(t time.Time, err error) time.Time {
@@ -64,6 +65,88 @@ func TestModel(t *testing.T) {
 	}
 }
 
+func TestProductionRecords(t *testing.T) {
+	var report energiatili.ConsumptionReport
+	data := `{"Hours": {"Productions": [{"Series": {"Data": [[1409706000000, 0.5], [1409702400000, 1.5]]}}]}}`
+	if err := json.Unmarshal([]byte(data), &report); err != nil {
+		t.Fatal(err)
+	}
+	points, err := report.ProductionRecords()
+	if err != nil {
+		t.Fatalf("report.ProductionRecords() returned error: %v", err)
+	}
+	want := []energiatili.Record{
+		{Value: 1.5, Timestamp: mustTime(time.Parse(time.RFC3339, "2014-09-02T21:00:00Z"))},
+		{Value: 0.5, Timestamp: mustTime(time.Parse(time.RFC3339, "2014-09-02T22:00:00Z"))},
+	}
+	if len(points) != len(want) {
+		t.Fatalf("report.ProductionRecords() = %v, want %v", points, want)
+	}
+	for i := range want {
+		if points[i].Value != want[i].Value || !points[i].Timestamp.Equal(want[i].Timestamp) {
+			t.Errorf("points[%d] = %v, want %v", i, points[i], want[i])
+		}
+	}
+	if points, _ := report.Records(); len(points) != 0 {
+		t.Errorf("report.Records() = %v, want no consumption", points)
+	}
+}
+
+func TestSnapTolerance(t *testing.T) {
+	var report energiatili.ConsumptionReport
+	// 21:00:30 and 22:59:50 drifted by seconds, 00:20 by 20 minutes
+	data := `{"Hours": {"Consumptions": [{"Series": {"Data": [[1409702430000, 1], [1409709590000, 2], [1409714400000, 3]]}}]}}`
+	if err := json.Unmarshal([]byte(data), &report); err != nil {
+		t.Fatal(err)
+	}
+	report.SnapTolerance = time.Minute
+	var drifts []notz.Drift
+	report.Drift = func(d notz.Drift) { drifts = append(drifts, d) }
+	points, err := report.Records()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"2014-09-02T21:00:00Z", "2014-09-02T23:00:00Z", "2014-09-03T00:20:00Z"}
+	if len(points) != len(want) {
+		t.Fatalf("report.Records() = %v, want %d points", points, len(want))
+	}
+	for i, w := range want {
+		if got := points[i].Timestamp.UTC().Format(time.RFC3339); got != w {
+			t.Errorf("points[%d] at %s, want %s", i, got, w)
+		}
+	}
+	if len(drifts) != 1 || drifts[0].Index != 2 || drifts[0].Offset != 20*time.Minute {
+		t.Errorf("drifts = %v, want point 2 20 minutes off", drifts)
+	}
+}
+
+func TestDailyRecords(t *testing.T) {
+	var report energiatili.ConsumptionReport
+	// Day and night tariff series of 2014-09-03 and 2014-09-04
+	data := `{"Days": {"Consumptions": [
+		{"Series": {"Data": [[1409788800000, 4], [1409702400000, 10]]}},
+		{"Series": {"Data": [[1409702400000, 2.5]]}}]}}`
+	if err := json.Unmarshal([]byte(data), &report); err != nil {
+		t.Fatal(err)
+	}
+	points := report.DailyRecords()
+	want := []struct {
+		ts    string
+		value float64
+	}{
+		{"2014-09-02T21:00:00Z", 12.5},
+		{"2014-09-03T21:00:00Z", 4},
+	}
+	if len(points) != len(want) {
+		t.Fatalf("report.DailyRecords() = %v, want %d days", points, len(want))
+	}
+	for i, w := range want {
+		if got := points[i].Timestamp.UTC().Format(time.RFC3339); got != w.ts || points[i].Value != w.value {
+			t.Errorf("points[%d] = %s %v, want %s %v", i, got, points[i].Value, w.ts, w.value)
+		}
+	}
+}
+
 var sampleJSONData = `
 {
   "IsValid": true,
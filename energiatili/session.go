@@ -0,0 +1,157 @@
+package energiatili
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// SessionKeyEnv names the environment variable holding the passphrase the
+// session file is encrypted with.
+const SessionKeyEnv = "ETGET_SESSION_KEY"
+
+// SessionFile keeps the portal's session cookies between runs, encrypted
+// with AES-256-GCM under a key derived from Passphrase with scrypt.
+//
+// The file starts with a header of sessionMagic, the scrypt cost as log2 N
+// and the random salt, followed by the GCM nonce and the ciphertext. The
+// header is authenticated as additional data. Files from before the header
+// fail to load, and the client replaces them after logging in.
+type SessionFile struct {
+	Name       string
+	Passphrase string
+}
+
+type savedSession struct {
+	Saved   time.Time     `json:"saved"`
+	Cookies []savedCookie `json:"cookies"`
+}
+
+type savedCookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+const (
+	sessionMagic = "etgs1"
+	sessionLogN  = 15 // scrypt N = 32768, r = 8, p = 1: 32 MiB
+	saltSize     = 16
+	headerSize   = len(sessionMagic) + 1 + saltSize
+)
+
+// aead returns the cipher keyed by the passphrase and the salt and cost of
+// header.
+func (s *SessionFile) aead(header []byte) (cipher.AEAD, error) {
+	if s.Passphrase == "" {
+		return nil, fmt.Errorf("session file %s: empty passphrase", s.Name)
+	}
+	logN := int(header[len(sessionMagic)])
+	if logN < 10 || logN > 20 {
+		return nil, fmt.Errorf("session file %s: scrypt cost 2^%d out of range", s.Name, logN)
+	}
+	salt := header[len(sessionMagic)+1:]
+	key, err := scrypt.Key([]byte(s.Passphrase), salt, 1<<uint(logN), 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Load returns the cookies saved in the file, or none if it does not exist.
+func (s *SessionFile) Load() ([]*http.Cookie, error) {
+	b, err := ioutil.ReadFile(s.Name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < headerSize || string(b[:len(sessionMagic)]) != sessionMagic {
+		return nil, fmt.Errorf("session file %s: unknown format", s.Name)
+	}
+	header, b := b[:headerSize], b[headerSize:]
+	aead, err := s.aead(header)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < aead.NonceSize() {
+		return nil, fmt.Errorf("session file %s: truncated", s.Name)
+	}
+	plain, err := aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], header)
+	if err != nil {
+		return nil, fmt.Errorf("session file %s: wrong key or corrupted", s.Name)
+	}
+	var saved savedSession
+	if err := json.Unmarshal(plain, &saved); err != nil {
+		return nil, fmt.Errorf("session file %s: %s", s.Name, err)
+	}
+	cookies := make([]*http.Cookie, 0, len(saved.Cookies))
+	for _, c := range saved.Cookies {
+		// The jar hands out cookies without their scope; restore them
+		// for the whole site.
+		cookies = append(cookies, &http.Cookie{Name: c.Name, Value: c.Value, Path: "/"})
+	}
+	return cookies, nil
+}
+
+// Save replaces the file with the cookies, under a new salt. The file is
+// written to a temporary name first so a crash never leaves a truncated
+// session behind.
+func (s *SessionFile) Save(cookies []*http.Cookie) error {
+	saved := savedSession{Saved: time.Now().UTC()}
+	for _, c := range cookies {
+		saved.Cookies = append(saved.Cookies, savedCookie{Name: c.Name, Value: c.Value})
+	}
+	plain, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+	header := make([]byte, headerSize)
+	copy(header, sessionMagic)
+	header[len(sessionMagic)] = sessionLogN
+	if _, err := io.ReadFull(rand.Reader, header[len(sessionMagic)+1:]); err != nil {
+		return err
+	}
+	aead, err := s.aead(header)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	b := aead.Seal(append(header, nonce...), nonce, plain, header)
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.Name), filepath.Base(s.Name)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.Name); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package energiatili_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/joneskoo/etget/energiatili"
+)
+
+const testReportBody = `var model = {"first": "value"};`
+
+func TestSessionReuse(t *testing.T) {
+	dir, err := ioutil.TempDir("", "etget-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	session := &energiatili.SessionFile{Name: filepath.Join(dir, "session"), Passphrase: "secret"}
+
+	ts := &testServer{statusCode: 200, body: testReportBody}
+	first := energiatili.Client{UsernamePasswordFunc: mockUsernamePasswordFunc, Transport: ts, Session: session}
+	if err := first.ConsumptionReport(context.TODO(), ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if len(ts.requests) != 2 {
+		t.Fatalf("first run: want login and report requests, got %d", len(ts.requests))
+	}
+	b, err := ioutil.ReadFile(session.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(b, []byte("test_auth_value")) {
+		t.Error("session file stores the cookie in plaintext")
+	}
+
+	ts = &testServer{statusCode: 200, body: testReportBody}
+	second := energiatili.Client{UsernamePasswordFunc: mockUsernamePasswordFunc, Transport: ts, Session: session}
+	buf := &bytes.Buffer{}
+	if err := second.ConsumptionReport(context.TODO(), buf); err != nil {
+		t.Fatal(err)
+	}
+	if len(ts.requests) != 1 {
+		t.Fatalf("second run: want only the report request, got %d", len(ts.requests))
+	}
+	if c, err := ts.requests[0].Cookie(".ASPXAUTH"); err != nil || c.Value != "test_auth_value" {
+		t.Errorf("second run did not send the saved cookie: %v %v", c, err)
+	}
+	if want := `{"first": "value"}`; buf.String() != want {
+		t.Errorf("got report %q, want %q", buf.String(), want)
+	}
+}
+
+func TestSessionExpired(t *testing.T) {
+	dir, err := ioutil.TempDir("", "etget-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	session := &energiatili.SessionFile{Name: filepath.Join(dir, "session"), Passphrase: "secret"}
+	if err := session.Save([]*http.Cookie{{Name: ".ASPXAUTH", Value: "expired"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The portal answers an expired session with its login page.
+	ts := &testServer{statusCode: 200, body: "<form>log in</form>"}
+	client := energiatili.Client{UsernamePasswordFunc: mockUsernamePasswordFunc, Transport: ts, Session: session}
+	if err := client.ConsumptionReport(context.TODO(), ioutil.Discard); err == nil {
+		t.Error("want error when the report is missing after login")
+	}
+	var methods []string
+	for _, req := range ts.requests {
+		methods = append(methods, req.Method)
+	}
+	if len(methods) != 3 || methods[0] != "GET" || methods[1] != "POST" || methods[2] != "GET" {
+		t.Errorf("want report, login, report requests, got %v", methods)
+	}
+	cookies, err := session.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cookies) != 1 || cookies[0].Value != "test_auth_value" {
+		t.Errorf("session not replaced after login: %v", cookies)
+	}
+}
+
+func TestSessionWrongPassphrase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "etget-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "session")
+	saved := &energiatili.SessionFile{Name: name, Passphrase: "secret"}
+	if err := saved.Save([]*http.Cookie{{Name: ".ASPXAUTH", Value: "v"}}); err != nil {
+		t.Fatal(err)
+	}
+	other := &energiatili.SessionFile{Name: name, Passphrase: "other"}
+	if _, err := other.Load(); err == nil {
+		t.Error("Load with the wrong passphrase succeeded")
+	}
+	missing := &energiatili.SessionFile{Name: filepath.Join(dir, "missing"), Passphrase: "secret"}
+	if cookies, err := missing.Load(); err != nil || cookies != nil {
+		t.Errorf("missing file: got %v, %v; want no cookies and no error", cookies, err)
+	}
+}
+
+func TestSessionSalt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "etget-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	session := &energiatili.SessionFile{Name: filepath.Join(dir, "session"), Passphrase: "secret"}
+	var headers [][]byte
+	for i := 0; i < 2; i++ {
+		if err := session.Save([]*http.Cookie{{Name: ".ASPXAUTH", Value: "v"}}); err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadFile(session.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) < 22 || string(b[:5]) != "etgs1" {
+			t.Fatalf("session file has no header: %x", b)
+		}
+		headers = append(headers, b[:22])
+	}
+	if bytes.Equal(headers[0], headers[1]) {
+		t.Error("two saves used the same salt")
+	}
+
+	// A file without the header, as written before the key was derived
+	// with scrypt, does not load.
+	if err := ioutil.WriteFile(session.Name, make([]byte, 64), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := session.Load(); err == nil {
+		t.Error("Load of a file without a header succeeded")
+	}
+}
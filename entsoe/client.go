@@ -0,0 +1,371 @@
+// Package entsoe downloads day-ahead prices from the ENTSO-E Transparency
+// Platform RESTful API.
+//
+// The API accepts at most one year per request, so long ranges are split
+// into several requests that are fetched concurrently and reassembled into
+// one series.
+package entsoe
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	endpoint = "https://web-api.tp.entsoe.eu/api"
+
+	// documentTypePrices is the document type of day-ahead prices.
+	documentTypePrices = "A44"
+
+	apiTimeLayout = "200601021504"
+)
+
+// Client retrieves data from the ENTSO-E Transparency Platform.
+type Client struct {
+	// Token is the security token of the API user.
+	Token string
+
+	// Transport is a roundtripper the client uses to make HTTP requests.
+	Transport http.RoundTripper
+
+	// Endpoint overrides the API URL, for testing.
+	Endpoint string
+
+	// MaxConcurrent limits the number of requests in flight (default 4).
+	MaxConcurrent int
+
+	// RequestsPerMinute limits the request rate (default 400, the limit
+	// of the API).
+	RequestsPerMinute int
+
+	// unexported
+	initOnce sync.Once
+	cl       http.Client
+	throttle limiter
+}
+
+func (c *Client) init() {
+	c.initOnce.Do(func() {
+		c.cl = http.Client{Transport: c.Transport}
+		if c.Endpoint == "" {
+			c.Endpoint = endpoint
+		}
+		if c.MaxConcurrent <= 0 {
+			c.MaxConcurrent = 4
+		}
+		if c.RequestsPerMinute <= 0 {
+			c.RequestsPerMinute = 400
+		}
+		c.throttle.interval = time.Minute / time.Duration(c.RequestsPerMinute)
+	})
+}
+
+// limiter spaces requests interval apart. Unlike a time.Ticker, it needs
+// no stopping when the Client is no longer used.
+type limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// wait reserves the next free slot and waits for it, or for ctx to end.
+func (l *limiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	at := l.next
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	d := at.Sub(now)
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Point is the price of one interval.
+type Point struct {
+	Start      time.Time
+	Resolution time.Duration
+	Price      float64
+}
+
+// Range is the interval from Start until End.
+type Range struct {
+	Start, End time.Time
+}
+
+// MissingError reports the intervals of a request that have no prices,
+// e.g. because the API had no data for one of the sub-ranges fetched or
+// the prices of tomorrow are not published yet.
+type MissingError struct {
+	Missing []Range
+}
+
+func (e *MissingError) Error() string {
+	ranges := make([]string, len(e.Missing))
+	for i, r := range e.Missing {
+		ranges[i] = r.Start.UTC().Format("2006-01-02T15:04Z") + ".." + r.End.UTC().Format("2006-01-02T15:04Z")
+	}
+	return "entsoe: no prices for " + strings.Join(ranges, ", ")
+}
+
+// DayAheadPrices fetches the day-ahead prices of bidding zone domain (an
+// EIC code such as "10YFI-1--------U") between start and end.
+//
+// If some of the intervals between start and end have no price, it
+// returns the points it got with a *MissingError listing the others.
+func (c *Client) DayAheadPrices(ctx context.Context, domain string, start, end time.Time) ([]Point, error) {
+	c.init()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ranges := splitRange(start, end)
+	results := make([][]Point, len(ranges))
+	sem := make(chan struct{}, c.MaxConcurrent)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for i, r := range ranges {
+		wg.Add(1)
+		go func(i int, r [2]time.Time) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			points, err := c.fetchPrices(ctx, domain, r[0], r[1])
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				mu.Unlock()
+				return
+			}
+			results[i] = points
+		}(i, r)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	points := merge(results, start, end)
+	if missing := gaps(points, start, end); len(missing) > 0 {
+		return points, &MissingError{missing}
+	}
+	return points, nil
+}
+
+// splitRange splits [start, end) into ranges of at most one year.
+func splitRange(start, end time.Time) (ranges [][2]time.Time) {
+	for s := start; s.Before(end); {
+		e := s.AddDate(1, 0, 0)
+		if e.After(end) {
+			e = end
+		}
+		ranges = append(ranges, [2]time.Time{s, e})
+		s = e
+	}
+	return ranges
+}
+
+// merge concatenates the partial series, dropping points outside
+// [start, end) and duplicates where responses overlap.
+func merge(parts [][]Point, start, end time.Time) []Point {
+	var all []Point
+	for _, p := range parts {
+		all = append(all, p...)
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Start.Before(all[j].Start) })
+	out := all[:0]
+	for _, p := range all {
+		if p.Start.Before(start) || !p.Start.Before(end) {
+			continue
+		}
+		if len(out) > 0 && out[len(out)-1].Start.Equal(p.Start) {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// gaps returns the intervals of [start, end) covered by none of points,
+// which are in order.
+func gaps(points []Point, start, end time.Time) (missing []Range) {
+	covered := start
+	for _, p := range points {
+		if p.Start.After(covered) {
+			missing = append(missing, Range{covered, p.Start})
+		}
+		if e := p.Start.Add(p.Resolution); e.After(covered) {
+			covered = e
+		}
+	}
+	if covered.Before(end) {
+		missing = append(missing, Range{covered, end})
+	}
+	return missing
+}
+
+func (c *Client) fetchPrices(ctx context.Context, domain string, start, end time.Time) ([]Point, error) {
+	if err := c.throttle.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	q := url.Values{
+		"securityToken": {c.Token},
+		"documentType":  {documentTypePrices},
+		"in_Domain":     {domain},
+		"out_Domain":    {domain},
+		"periodStart":   {start.UTC().Format(apiTimeLayout)},
+		"periodEnd":     {end.UTC().Format(apiTimeLayout)},
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", c.Endpoint+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.cl.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var ack acknowledgement
+	if xml.Unmarshal(body, &ack) == nil && ack.XMLName.Local == "Acknowledgement_MarketDocument" {
+		if ack.Reason.Code == reasonNoData {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("entsoe: %s: %s", ack.Reason.Code, strings.TrimSpace(ack.Reason.Text))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("want HTTP status code 200, got %d", resp.StatusCode)
+	}
+	return parseDocument(bytes.NewReader(body))
+}
+
+// reasonNoData is the acknowledgement reason when the range has no data.
+const reasonNoData = "999"
+
+type acknowledgement struct {
+	XMLName xml.Name
+	Reason  struct {
+		Code string `xml:"code"`
+		Text string `xml:"text"`
+	} `xml:"Reason"`
+}
+
+type document struct {
+	TimeSeries []struct {
+		CurveType string `xml:"curveType"`
+		Period    []struct {
+			TimeInterval struct {
+				Start string `xml:"start"`
+				End   string `xml:"end"`
+			} `xml:"timeInterval"`
+			Resolution string `xml:"resolution"`
+			Points     []struct {
+				Position int     `xml:"position"`
+				Price    float64 `xml:"price.amount"`
+			} `xml:"Point"`
+		} `xml:"Period"`
+	} `xml:"TimeSeries"`
+}
+
+// curveVariableBlocks is the curve type A03, in which a point is left out
+// when its price is that of the point before.
+const curveVariableBlocks = "A03"
+
+// parseDocument parses a Publication_MarketDocument into points. The
+// points left out of A03 curves are filled in, so that every interval of
+// a period has a point.
+func parseDocument(r io.Reader) (points []Point, err error) {
+	var doc document
+	if err = xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("entsoe: parse document: %s", err)
+	}
+	for _, ts := range doc.TimeSeries {
+		for _, p := range ts.Period {
+			start, err := time.Parse("2006-01-02T15:04Z", p.TimeInterval.Start)
+			if err != nil {
+				return nil, fmt.Errorf("entsoe: period start: %s", err)
+			}
+			res, err := parseResolution(p.Resolution)
+			if err != nil {
+				return nil, err
+			}
+			if ts.CurveType != curveVariableBlocks {
+				for _, pt := range p.Points {
+					points = append(points, Point{
+						Start:      start.Add(time.Duration(pt.Position-1) * res),
+						Resolution: res,
+						Price:      pt.Price,
+					})
+				}
+				continue
+			}
+			end, err := time.Parse("2006-01-02T15:04Z", p.TimeInterval.End)
+			if err != nil {
+				return nil, fmt.Errorf("entsoe: period end: %s", err)
+			}
+			pts := p.Points
+			sort.Slice(pts, func(i, j int) bool { return pts[i].Position < pts[j].Position })
+			last := int(end.Sub(start) / res)
+			for i, pt := range pts {
+				until := last
+				if i+1 < len(pts) {
+					until = pts[i+1].Position - 1
+				}
+				for pos := pt.Position; pos <= until; pos++ {
+					points = append(points, Point{
+						Start:      start.Add(time.Duration(pos-1) * res),
+						Resolution: res,
+						Price:      pt.Price,
+					})
+				}
+			}
+		}
+	}
+	return points, nil
+}
+
+// parseResolution parses the ISO 8601 durations used by the API.
+func parseResolution(s string) (time.Duration, error) {
+	switch s {
+	case "PT15M":
+		return 15 * time.Minute, nil
+	case "PT30M":
+		return 30 * time.Minute, nil
+	case "PT60M", "PT1H":
+		return time.Hour, nil
+	}
+	return 0, fmt.Errorf("entsoe: unsupported resolution %q", s)
+}
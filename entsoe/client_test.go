@@ -0,0 +1,160 @@
+package entsoe_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/joneskoo/etget/entsoe"
+)
+
+// fakeAPI answers every request with an A03 curve of hourly prices from
+// periodStart to periodEnd, 10.5 in the first hour and 11.5 after, and
+// records the requested ranges.
+type fakeAPI struct {
+	mu     sync.Mutex
+	ranges []string
+	ack    string
+
+	// ackStart, if set, limits the ack answer to the request of the
+	// range starting at ackStart, in the API layout.
+	ackStart string
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f.mu.Lock()
+	f.ranges = append(f.ranges, q.Get("periodStart")+"-"+q.Get("periodEnd"))
+	f.mu.Unlock()
+	if f.ack != "" && (f.ackStart == "" || f.ackStart == q.Get("periodStart")) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `<Acknowledgement_MarketDocument><Reason><code>999</code><text>%s</text></Reason></Acknowledgement_MarketDocument>`, f.ack)
+		return
+	}
+	start, _ := time.Parse("200601021504", q.Get("periodStart"))
+	end, _ := time.Parse("200601021504", q.Get("periodEnd"))
+	fmt.Fprintf(w, `<Publication_MarketDocument>
+  <TimeSeries><curveType>A03</curveType><Period>
+    <timeInterval><start>%s</start><end>%s</end></timeInterval>
+    <resolution>PT60M</resolution>
+    <Point><position>1</position><price.amount>10.5</price.amount></Point>
+    <Point><position>2</position><price.amount>11.5</price.amount></Point>
+  </Period></TimeSeries>
+</Publication_MarketDocument>`, start.Format("2006-01-02T15:04Z"), end.Format("2006-01-02T15:04Z"))
+}
+
+func TestDayAheadPricesSplitsRange(t *testing.T) {
+	api := &fakeAPI{}
+	ts := httptest.NewServer(api)
+	defer ts.Close()
+
+	c := &entsoe.Client{Token: "t", Endpoint: ts.URL, RequestsPerMinute: 60000}
+	start := time.Date(2015, 12, 31, 23, 0, 0, 0, time.UTC)
+	end := start.AddDate(2, 6, 0)
+	points, err := c.DayAheadPrices(context.Background(), "10YFI-1--------U", start, end)
+	if err != nil {
+		t.Fatalf("DayAheadPrices: %s", err)
+	}
+	if len(api.ranges) != 3 {
+		t.Errorf("got %d requests, want 3: %v", len(api.ranges), api.ranges)
+	}
+	if want := int(end.Sub(start) / time.Hour); len(points) != want {
+		t.Fatalf("got %d points, want %d", len(points), want)
+	}
+	for i := 1; i < len(points); i++ {
+		if !points[i-1].Start.Before(points[i].Start) {
+			t.Errorf("points not in order at %d: %s, %s", i, points[i-1].Start, points[i].Start)
+		}
+	}
+	if want := start.Add(time.Hour); !points[1].Start.Equal(want) || points[1].Price != 11.5 {
+		t.Errorf("points[1] = %+v, want 11.5 at %s", points[1], want)
+	}
+}
+
+func TestDayAheadPricesNoData(t *testing.T) {
+	ts := httptest.NewServer(&fakeAPI{ack: "No matching data found"})
+	defer ts.Close()
+
+	c := &entsoe.Client{Token: "t", Endpoint: ts.URL, RequestsPerMinute: 60000}
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 1)
+	points, err := c.DayAheadPrices(context.Background(), "10YFI-1--------U", start, end)
+	missing, ok := err.(*entsoe.MissingError)
+	if !ok {
+		t.Fatalf("DayAheadPrices returned %v, want a *MissingError", err)
+	}
+	if want := []entsoe.Range{{start, end}}; !reflect.DeepEqual(missing.Missing, want) {
+		t.Errorf("missing %v, want %v", missing.Missing, want)
+	}
+	if len(points) != 0 {
+		t.Errorf("got %d points, want none", len(points))
+	}
+}
+
+func TestDayAheadPricesMissingSubRange(t *testing.T) {
+	// The second of three one-year requests has no data.
+	start := time.Date(2015, 12, 31, 23, 0, 0, 0, time.UTC)
+	gapStart, gapEnd := start.AddDate(1, 0, 0), start.AddDate(2, 0, 0)
+	api := &fakeAPI{ack: "No matching data found", ackStart: gapStart.Format("200601021504")}
+	ts := httptest.NewServer(api)
+	defer ts.Close()
+
+	c := &entsoe.Client{Token: "t", Endpoint: ts.URL, RequestsPerMinute: 60000}
+	end := start.AddDate(2, 6, 0)
+	points, err := c.DayAheadPrices(context.Background(), "10YFI-1--------U", start, end)
+	missing, ok := err.(*entsoe.MissingError)
+	if !ok {
+		t.Fatalf("DayAheadPrices returned %v, want a *MissingError", err)
+	}
+	if want := []entsoe.Range{{gapStart, gapEnd}}; !reflect.DeepEqual(missing.Missing, want) {
+		t.Errorf("missing %v, want %v", missing.Missing, want)
+	}
+	if want := "entsoe: no prices for 2016-12-31T23:00Z..2017-12-31T23:00Z"; err.Error() != want {
+		t.Errorf("error %q, want %q", err, want)
+	}
+	if want := int((end.Sub(start) - gapEnd.Sub(gapStart)) / time.Hour); len(points) != want {
+		t.Errorf("got %d points, want the %d outside the gap", len(points), want)
+	}
+}
+
+// sparseAPI answers with an A03 curve of six hours in which the price of
+// hours 2, 3 and 6 repeats the one before.
+type sparseAPI struct{}
+
+func (sparseAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprint(w, `<Publication_MarketDocument>
+  <TimeSeries><curveType>A03</curveType><Period>
+    <timeInterval><start>2025-01-01T00:00Z</start><end>2025-01-01T06:00Z</end></timeInterval>
+    <resolution>PT60M</resolution>
+    <Point><position>1</position><price.amount>10</price.amount></Point>
+    <Point><position>4</position><price.amount>12</price.amount></Point>
+    <Point><position>5</position><price.amount>8</price.amount></Point>
+  </Period></TimeSeries>
+</Publication_MarketDocument>`)
+}
+
+func TestDayAheadPricesSparseCurve(t *testing.T) {
+	ts := httptest.NewServer(sparseAPI{})
+	defer ts.Close()
+
+	c := &entsoe.Client{Token: "t", Endpoint: ts.URL, RequestsPerMinute: 60000}
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	points, err := c.DayAheadPrices(context.Background(), "10YFI-1--------U", start, start.Add(6*time.Hour))
+	if err != nil {
+		t.Fatalf("DayAheadPrices: %s", err)
+	}
+	want := []float64{10, 10, 10, 12, 8, 8}
+	if len(points) != len(want) {
+		t.Fatalf("got %d points, want %d: %+v", len(points), len(want), points)
+	}
+	for i, p := range points {
+		if at := start.Add(time.Duration(i) * time.Hour); !p.Start.Equal(at) || p.Price != want[i] {
+			t.Errorf("points[%d] = %+v, want %v at %s", i, p, want[i], at)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Package fingrid downloads time series of the Finnish power system, such
+// as total consumption and wind production, from the Fingrid open data
+// API at https://data.fingrid.fi.
+package fingrid
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const endpoint = "https://data.fingrid.fi/api"
+
+// Datasets are the IDs of commonly used hourly datasets by short name.
+var Datasets = map[string]int{
+	"consumption": 124, // electricity consumption in Finland
+	"production":  74,  // electricity production in Finland
+	"wind":        75,  // wind power production
+	"net-import":  194, // net import to Finland
+}
+
+// Client retrieves data from the Fingrid open data API.
+type Client struct {
+	// APIKey is the key of the registered API user.
+	APIKey string
+
+	// Transport is a roundtripper the client uses to make HTTP requests.
+	Transport http.RoundTripper
+
+	// Endpoint overrides the API URL, for testing.
+	Endpoint string
+
+	// PageSize is the number of points requested per page (default
+	// 20000, the limit of the API).
+	PageSize int
+
+	// unexported
+	initOnce sync.Once
+	cl       http.Client
+}
+
+func (c *Client) init() {
+	c.initOnce.Do(func() {
+		c.cl = http.Client{Transport: c.Transport}
+		if c.Endpoint == "" {
+			c.Endpoint = endpoint
+		}
+		if c.PageSize <= 0 {
+			c.PageSize = 20000
+		}
+	})
+}
+
+// Point is the value of a dataset over [Start, End).
+type Point struct {
+	Start time.Time
+	End   time.Time
+	Value float64
+}
+
+type page struct {
+	Data []struct {
+		StartTime time.Time `json:"startTime"`
+		EndTime   time.Time `json:"endTime"`
+		Value     float64   `json:"value"`
+	} `json:"data"`
+	Pagination struct {
+		CurrentPage int `json:"currentPage"`
+		LastPage    int `json:"lastPage"`
+	} `json:"pagination"`
+}
+
+// Data fetches the points of dataset between start and end, following
+// the pages of the response.
+func (c *Client) Data(ctx context.Context, dataset int, start, end time.Time) ([]Point, error) {
+	c.init()
+	var points []Point
+	for n := 1; ; n++ {
+		p, err := c.fetchPage(ctx, dataset, start, end, n)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range p.Data {
+			points = append(points, Point{Start: d.StartTime, End: d.EndTime, Value: d.Value})
+		}
+		if p.Pagination.CurrentPage >= p.Pagination.LastPage || len(p.Data) == 0 {
+			return points, nil
+		}
+	}
+}
+
+func (c *Client) fetchPage(ctx context.Context, dataset int, start, end time.Time, n int) (*page, error) {
+	q := url.Values{
+		"startTime": {start.UTC().Format(time.RFC3339)},
+		"endTime":   {end.UTC().Format(time.RFC3339)},
+		"format":    {"json"},
+		"page":      {strconv.Itoa(n)},
+		"pageSize":  {strconv.Itoa(c.PageSize)},
+		"sortBy":    {"startTime"},
+		"sortOrder": {"asc"},
+	}
+	u := fmt.Sprintf("%s/datasets/%d/data?%s", c.Endpoint, dataset, q.Encode())
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-api-key", c.APIKey)
+	resp, err := c.cl.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fingrid: dataset %d: want HTTP status code 200, got %d", dataset, resp.StatusCode)
+	}
+	var p page
+	if err = json.Unmarshal(body, &p); err != nil {
+		return nil, fmt.Errorf("fingrid: dataset %d: %s", dataset, err)
+	}
+	return &p, nil
+}
@@ -0,0 +1,49 @@
+package fingrid_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/joneskoo/etget/fingrid"
+)
+
+func TestDataPagination(t *testing.T) {
+	var pages []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/datasets/124/data" || r.Header.Get("x-api-key") != "key" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		page := r.URL.Query().Get("page")
+		pages = append(pages, page)
+		start := "2019-01-01T00:00:00.000Z"
+		if page == "2" {
+			start = "2019-01-01T01:00:00.000Z"
+		}
+		fmt.Fprintf(w, `{"data":[{"datasetId":124,"startTime":%q,"endTime":%q,"value":9000.5}],
+			"pagination":{"currentPage":%s,"lastPage":2}}`, start, start, page)
+	}))
+	defer ts.Close()
+
+	c := &fingrid.Client{APIKey: "key", Endpoint: ts.URL, PageSize: 1}
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	points, err := c.Data(context.Background(), fingrid.Datasets["consumption"], start, start.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("Data: %s", err)
+	}
+	if len(pages) != 2 || len(points) != 2 {
+		t.Fatalf("got %d points from pages %v, want 2 from 2 pages", len(points), pages)
+	}
+	if !points[1].Start.Equal(start.Add(time.Hour)) || points[1].Value != 9000.5 {
+		t.Errorf("points[1] = %+v", points[1])
+	}
+
+	c = &fingrid.Client{APIKey: "wrong", Endpoint: ts.URL}
+	if _, err := c.Data(context.Background(), 124, start, start.Add(time.Hour)); err == nil {
+		t.Error("Data with wrong key did not return error")
+	}
+}
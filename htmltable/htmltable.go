@@ -3,8 +3,11 @@ package htmltable
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
 
 	"golang.org/x/net/html"
 )
@@ -15,37 +18,183 @@ type Table struct {
 	Rows    [][]string
 }
 
-// Parse parses HTML from r
+// Limits bound the documents Parse accepts, so that a huge or unexpected
+// file fails instead of exhausting memory. A zero field takes its value
+// from DefaultLimits and a negative one disables the limit.
+//
+// Limits implements flag.Value in the form bytes=N,tables=N,rows=N,cell=N,
+// where the fields left out keep their value.
+type Limits struct {
+	// Bytes is the size of the document.
+	Bytes int64
+
+	// Tables is the number of tables in the document.
+	Tables int
+
+	// Rows is the number of header and body rows of a table.
+	Rows int
+
+	// Cell is the length of the text of a cell in bytes.
+	Cell int
+}
+
+// DefaultLimits are the limits of Parse, ample for a year of hourly
+// prices.
+var DefaultLimits = Limits{
+	Bytes:  64 << 20,
+	Tables: 100,
+	Rows:   100000,
+	Cell:   4096,
+}
+
+// effective returns l with zero fields set from DefaultLimits.
+func (l Limits) effective() Limits {
+	if l.Bytes == 0 {
+		l.Bytes = DefaultLimits.Bytes
+	}
+	if l.Tables == 0 {
+		l.Tables = DefaultLimits.Tables
+	}
+	if l.Rows == 0 {
+		l.Rows = DefaultLimits.Rows
+	}
+	if l.Cell == 0 {
+		l.Cell = DefaultLimits.Cell
+	}
+	return l
+}
+
+func (l *Limits) String() string {
+	if l == nil {
+		return ""
+	}
+	e := l.effective()
+	return fmt.Sprintf("bytes=%d,tables=%d,rows=%d,cell=%d", e.Bytes, e.Tables, e.Rows, e.Cell)
+}
+
+// Set sets the limits named in s.
+func (l *Limits) Set(s string) error {
+	for _, kv := range strings.Split(s, ",") {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("%q is not limit=N", kv)
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64)
+		if err != nil {
+			return fmt.Errorf("limit %s: %s", parts[0], err)
+		}
+		switch strings.TrimSpace(parts[0]) {
+		case "bytes":
+			l.Bytes = n
+		case "tables":
+			l.Tables = int(n)
+		case "rows":
+			l.Rows = int(n)
+		case "cell":
+			l.Cell = int(n)
+		default:
+			return fmt.Errorf("unknown limit %q, want bytes, tables, rows or cell", parts[0])
+		}
+	}
+	return nil
+}
+
+// LimitError is returned by Parse if the document exceeds a limit.
+type LimitError struct {
+	Limit string // bytes, tables, rows or cell
+	Max   int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("htmltable: document exceeds the limit of %d %s", e.Max, e.Limit)
+}
+
+// Parse parses HTML from r with DefaultLimits.
 func Parse(r io.Reader) (page []Table, err error) {
+	return Limits{}.Parse(r)
+}
+
+// Parse parses HTML from r, failing with a *LimitError if it exceeds l.
+func (l Limits) Parse(r io.Reader) (page []Table, err error) {
+	l = l.effective()
+	if l.Bytes > 0 {
+		r = &limitedReader{r: r, n: l.Bytes}
+	}
 	n, err := html.Parse(r)
+	if lr, ok := r.(*limitedReader); ok && lr.n < 0 {
+		return nil, &LimitError{"bytes", l.Bytes}
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse HTML: %s", err)
 	}
 	tables := getElementsByName(n, "table")
+	if l.Tables > 0 && len(tables) > l.Tables {
+		return nil, &LimitError{"tables", int64(l.Tables)}
+	}
 	for _, t := range tables {
-		table := parseTable(t)
+		table, err := l.parseTable(t)
+		if err != nil {
+			return nil, err
+		}
 		page = append(page, table)
 	}
 	return
 }
 
-func parseTable(n *html.Node) (table Table) {
+// limitedReader reads from r until n bytes are left, then fails. A
+// negative n after the failure tells the limit was hit.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+var errTooLarge = errors.New("document too large")
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	if lr.n < 0 {
+		return 0, errTooLarge
+	}
+	if int64(len(p)) > lr.n+1 {
+		p = p[:lr.n+1]
+	}
+	n, err := lr.r.Read(p)
+	lr.n -= int64(n)
+	if lr.n < 0 {
+		return 0, errTooLarge
+	}
+	return n, err
+}
+
+func (l Limits) parseTable(n *html.Node) (table Table, err error) {
+	rows := 0
 	theads := getElementsByName(n, "thead")
 	if len(theads) > 0 {
-		table.Headers = parseRows(theads[0])
+		if table.Headers, err = l.parseRows(theads[0], &rows); err != nil {
+			return table, err
+		}
 	}
 	tbodies := getElementsByName(n, "tbody")
 	if len(tbodies) > 0 {
-		table.Rows = parseRows(tbodies[0])
+		if table.Rows, err = l.parseRows(tbodies[0], &rows); err != nil {
+			return table, err
+		}
 	}
 	return
 }
 
-func parseRows(n *html.Node) (rows [][]string) {
+// parseRows parses the rows under n, adding their number to count.
+func (l Limits) parseRows(n *html.Node, count *int) (rows [][]string, err error) {
 	for _, tr := range getElementsByName(n, "tr") {
+		if *count++; l.Rows > 0 && *count > l.Rows {
+			return nil, &LimitError{"rows", int64(l.Rows)}
+		}
 		elems := []string{}
 		for _, td := range getElementsByName(tr, "td") {
-			elems = append(elems, getTextContent(td))
+			text := getTextContent(td)
+			if l.Cell > 0 && len(text) > l.Cell {
+				return nil, &LimitError{"cell", int64(l.Cell)}
+			}
+			elems = append(elems, text)
 		}
 		rows = append(rows, elems)
 	}
@@ -71,9 +220,9 @@ func getTextContent(n *html.Node) string {
 	buf := new(bytes.Buffer)
 	for c := n.FirstChild; c != nil; c = c.NextSibling {
 		if c.Type == html.TextNode {
-			fmt.Fprintf(buf, c.Data)
+			buf.WriteString(c.Data)
 		}
-		fmt.Fprintf(buf, getTextContent(c))
+		buf.WriteString(getTextContent(c))
 	}
 	return buf.String()
 }
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/joneskoo/etget/htmltable"
+	"github.com/joneskoo/etget/htmltable/htmltabletest"
 )
 
 func TestParse(t *testing.T) {
@@ -76,3 +77,103 @@ func TestParse(t *testing.T) {
 		t.Fatalf("htmltable.Parse(...) = %#v, want %#v", tables, want)
 	}
 }
+
+func TestParseGolden(t *testing.T) {
+	htmltabletest.Run(t, "testdata", htmltable.Parse)
+}
+
+func TestLimits(t *testing.T) {
+	doc := "<table><tbody><tr><td>1</td><td>22</td></tr><tr><td>333</td></tr></tbody></table><table></table>"
+	cases := []struct {
+		limits htmltable.Limits
+		limit  string // "" if the document is accepted
+	}{
+		{htmltable.Limits{}, ""},
+		{htmltable.Limits{Bytes: int64(len(doc))}, ""},
+		{htmltable.Limits{Bytes: int64(len(doc)) - 1}, "bytes"},
+		{htmltable.Limits{Tables: 1}, "tables"},
+		{htmltable.Limits{Rows: 1}, "rows"},
+		{htmltable.Limits{Cell: 2}, "cell"},
+		{htmltable.Limits{Bytes: -1, Tables: -1, Rows: -1, Cell: -1}, ""},
+	}
+	for _, c := range cases {
+		_, err := c.limits.Parse(strings.NewReader(doc))
+		limitErr, _ := err.(*htmltable.LimitError)
+		switch {
+		case c.limit == "" && err != nil:
+			t.Errorf("%+v: Parse: %s", c.limits, err)
+		case c.limit != "" && (limitErr == nil || limitErr.Limit != c.limit):
+			t.Errorf("%+v: Parse error = %v, want %s limit", c.limits, err, c.limit)
+		}
+	}
+}
+
+func TestLimitsSet(t *testing.T) {
+	var l htmltable.Limits
+	if err := l.Set("rows=10, cell=-1"); err != nil {
+		t.Fatal(err)
+	}
+	if want := (htmltable.Limits{Rows: 10, Cell: -1}); l != want {
+		t.Errorf("Set = %+v, want %+v", l, want)
+	}
+	if got, want := l.String(), "bytes=67108864,tables=100,rows=10,cell=-1"; got != want {
+		t.Errorf("String = %q, want %q", got, want)
+	}
+	for _, bad := range []string{"rows", "rows=x", "columns=1"} {
+		if err := l.Set(bad); err == nil {
+			t.Errorf("Set(%q) did not return error", bad)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		input string
+		want  htmltable.Table
+	}{
+		{
+			`<table><thead><tr><td>Date</td><td>FI</td></tr></thead>
+<tbody><tr><td>01-01-2016</td><td>16,<b>39</b></td></tr><tr><td>02-01-2016</td><td>1&nbsp;6</td></tr></tbody>
+<tbody><tr><td>ignored</td></tr></tbody></table><table><tr><td>second</td></tr></table>`,
+			htmltable.Table{
+				Headers: [][]string{{"Date", "FI"}},
+				Rows:    [][]string{{"01-01-2016", "16,39"}, {"02-01-2016", "1\u00A06"}},
+			},
+		},
+		{
+			// Implied tbody, end tags left out.
+			`<table><thead><tr><td>Date<td>FI</thead><tr><td>01-01-2016<td>16,39<tr><td>02-01-2016<td>`,
+			htmltable.Table{
+				Headers: [][]string{{"Date", "FI"}},
+				Rows:    [][]string{{"01-01-2016", "16,39"}, {"02-01-2016", ""}},
+			},
+		},
+		{
+			// A table in a cell is text of the cell.
+			`<table><tr><td>a<table><tr><td>b</td></tr></table></td><td>c</td></tr></table>`,
+			htmltable.Table{Rows: [][]string{{"ab", "c"}}},
+		},
+	}
+	for _, tt := range tests {
+		var got htmltable.Table
+		err := htmltable.Limits{}.Scan(strings.NewReader(tt.input),
+			func(cells []string) error { got.Headers = append(got.Headers, cells); return nil },
+			func(cells []string) error { got.Rows = append(got.Rows, cells); return nil })
+		if err != nil {
+			t.Fatalf("Scan: %s", err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Scan(%q) =\n%q\nwant\n%q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestScanLimits(t *testing.T) {
+	input := `<table><tr><td>1</td></tr><tr><td>2</td></tr><tr><td>333</td></tr></table>`
+	nop := func([]string) error { return nil }
+	for _, l := range []htmltable.Limits{{Rows: 2}, {Cell: 2}, {Bytes: 20}} {
+		if _, ok := l.Scan(strings.NewReader(input), nop, nop).(*htmltable.LimitError); !ok {
+			t.Errorf("Scan with %+v did not return a *LimitError", l)
+		}
+	}
+}
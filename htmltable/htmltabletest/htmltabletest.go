@@ -0,0 +1,160 @@
+// Package htmltabletest loads golden HTML fixtures for htmltable parsers.
+//
+// A fixture is a pair of files in the same directory: NAME.html is the
+// input document and NAME.golden.json is the expected []htmltable.Table
+// encoded as JSON. Run the tests with HTMLTABLE_UPDATE=1 in the environment
+// to rewrite the golden files from the current parser output after
+// verifying the change is intended.
+//
+// Parsers of other results, such as the records of package elspot, are
+// tested with RunParser and an adapter implementing Parser; the golden
+// files then hold their result.
+package htmltabletest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/joneskoo/etget/htmltable"
+)
+
+// UpdateEnv is the environment variable that, if not empty, sets Update.
+const UpdateEnv = "HTMLTABLE_UPDATE"
+
+// Update makes Run rewrite the golden files with the current output
+// instead of comparing to them. It is not a flag so that the test
+// packages using Run can define their own.
+var Update = os.Getenv(UpdateEnv) != ""
+
+// Case is a single golden fixture.
+type Case struct {
+	// Name is the fixture file name without extension.
+	Name string
+
+	// Input is the HTML document.
+	Input []byte
+
+	// Want is the expected parse result.
+	Want []htmltable.Table
+
+	// GoldenFile is the path of the expected result file.
+	GoldenFile string
+}
+
+// Load reads all fixtures in dir, sorted by name.
+func Load(dir string) (cases []Case, err error) {
+	if cases, err = loadInputs(dir); err != nil {
+		return nil, err
+	}
+	for i := range cases {
+		b, err := ioutil.ReadFile(cases[i].GoldenFile)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(b, &cases[i].Want); err != nil {
+			return nil, fmt.Errorf("parse %s: %s", cases[i].GoldenFile, err)
+		}
+	}
+	return cases, nil
+}
+
+// Parser adapts a parser to RunParser.
+type Parser interface {
+	// Parse parses the document from r.
+	Parse(r io.Reader) (interface{}, error)
+
+	// New returns a pointer to a new value of the type Parse returns,
+	// to decode golden files into.
+	New() interface{}
+}
+
+// Tables adapts a parser of tables, such as htmltable.Parse, to Parser.
+type Tables func(io.Reader) ([]htmltable.Table, error)
+
+// Parse implements Parser.
+func (f Tables) Parse(r io.Reader) (interface{}, error) { return f(r) }
+
+// New implements Parser.
+func (Tables) New() interface{} { return new([]htmltable.Table) }
+
+// Run parses each fixture in dir with parse and compares the result to the
+// golden file in a subtest named after the fixture.
+func Run(t *testing.T, dir string, parse func(io.Reader) ([]htmltable.Table, error)) {
+	t.Helper()
+	RunParser(t, dir, Tables(parse))
+}
+
+// RunParser is Run for a parser of any result. The result is compared to
+// the golden file as JSON, so that for example times in different
+// locations but with the same offset are equal.
+func RunParser(t *testing.T, dir string, p Parser) {
+	t.Helper()
+	cases, err := loadInputs(dir)
+	if err != nil {
+		t.Fatalf("htmltabletest: %s", err)
+	}
+	if len(cases) == 0 {
+		t.Fatalf("htmltabletest: no fixtures in %s", dir)
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			got, err := p.Parse(bytes.NewReader(c.Input))
+			if err != nil {
+				t.Fatalf("parse: %s", err)
+			}
+			gotJSON, err := json.MarshalIndent(got, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if Update {
+				if err = ioutil.WriteFile(c.GoldenFile, append(gotJSON, '\n'), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			b, err := ioutil.ReadFile(c.GoldenFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := p.New()
+			if err = json.Unmarshal(b, want); err != nil {
+				t.Fatalf("parse %s: %s", c.GoldenFile, err)
+			}
+			wantJSON, err := json.MarshalIndent(want, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(gotJSON, wantJSON) {
+				t.Errorf("parse(%s) =\n%s\nwant\n%s", c.Name, gotJSON, wantJSON)
+			}
+		})
+	}
+}
+
+// loadInputs finds the fixture inputs in dir without reading golden files.
+func loadInputs(dir string) (cases []Case, err error) {
+	inputs, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	for _, in := range inputs {
+		name := strings.TrimSuffix(filepath.Base(in), ".html")
+		c := Case{
+			Name:       name,
+			GoldenFile: filepath.Join(dir, name+".golden.json"),
+		}
+		if c.Input, err = ioutil.ReadFile(in); err != nil {
+			return nil, err
+		}
+		cases = append(cases, c)
+	}
+	return cases, nil
+}
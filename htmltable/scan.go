@@ -0,0 +1,181 @@
+package htmltable
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"golang.org/x/net/html"
+)
+
+// Scan reads the first table of the HTML document from r like Parse, but
+// without building the document tree: it calls header with each row of
+// the thead of the table and row with each row of its first tbody as they
+// are read, and stops reading after the table. Rows outside thead and
+// tbody are body rows, as the HTML parser would put them in an implied
+// tbody. Nested tables are skipped.
+//
+// If a callback returns an error, Scan stops and returns it.
+func (l Limits) Scan(r io.Reader, header, row func(cells []string) error) error {
+	l = l.effective()
+	var lr *limitedReader
+	if l.Bytes > 0 {
+		lr = &limitedReader{r: r, n: l.Bytes}
+		r = lr
+	}
+	s := scanner{limits: l, header: header, row: row}
+	z := html.NewTokenizer(r)
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if lr != nil && lr.n < 0 {
+				return &LimitError{"bytes", l.Bytes}
+			}
+			if z.Err() != io.EOF {
+				return fmt.Errorf("failed to parse HTML: %s", z.Err())
+			}
+			return s.endTable()
+		case html.TextToken:
+			if s.inCell {
+				s.cell.Write(z.Text())
+			}
+		case html.StartTagToken, html.EndTagToken:
+			name, _ := z.TagName()
+			done, err := s.tag(string(name), tt == html.StartTagToken)
+			if err != nil || done {
+				return err
+			}
+		}
+	}
+}
+
+// section is the part of the table a row is in.
+type section int
+
+const (
+	sectionNone section = iota
+	sectionHead
+	sectionBody
+	sectionSkip
+)
+
+// scanner is the state of Scan.
+type scanner struct {
+	limits      Limits
+	header, row func(cells []string) error
+
+	tables  int // tables started
+	depth   int // of nested tables; 1 in the first table
+	section section
+	bodies  int // tbody sections seen, explicit or implied
+	rows    int
+
+	inRow, inCell bool
+	cells         []string
+	cell          bytes.Buffer
+}
+
+// tag handles a start or end tag, reporting done after the first table.
+func (s *scanner) tag(name string, start bool) (done bool, err error) {
+	if name == "table" {
+		if start {
+			if s.tables++; s.limits.Tables > 0 && s.tables > s.limits.Tables {
+				return false, &LimitError{"tables", int64(s.limits.Tables)}
+			}
+			if s.depth > 0 || s.tables == 1 {
+				s.depth++
+			}
+			return false, nil
+		}
+		if s.depth == 0 {
+			return false, nil
+		}
+		if s.depth--; s.depth > 0 {
+			return false, nil
+		}
+		return true, s.endTable()
+	}
+	if s.depth != 1 {
+		return false, nil
+	}
+	switch {
+	case name == "thead" || name == "tbody":
+		if err := s.endRow(); err != nil {
+			return false, err
+		}
+		s.section = sectionNone
+		if start {
+			s.section = sectionHead
+			if name == "tbody" {
+				s.section = s.bodySection()
+			}
+		}
+	case name == "tr" && start:
+		if err := s.endRow(); err != nil {
+			return false, err
+		}
+		if s.section == sectionNone {
+			s.section = s.bodySection()
+		}
+		s.inRow, s.cells = true, []string{}
+	case name == "tr":
+		return false, s.endRow()
+	case name == "td" && start:
+		if err := s.endCell(); err != nil {
+			return false, err
+		}
+		s.inCell = s.inRow
+		s.cell.Reset()
+	case name == "td":
+		return false, s.endCell()
+	}
+	return false, nil
+}
+
+// bodySection returns the section of a new tbody: only the first is read.
+func (s *scanner) bodySection() section {
+	if s.bodies++; s.bodies > 1 {
+		return sectionSkip
+	}
+	return sectionBody
+}
+
+func (s *scanner) endCell() error {
+	if !s.inCell {
+		return nil
+	}
+	s.inCell = false
+	if s.limits.Cell > 0 && s.cell.Len() > s.limits.Cell {
+		return &LimitError{"cell", int64(s.limits.Cell)}
+	}
+	s.cells = append(s.cells, s.cell.String())
+	return nil
+}
+
+func (s *scanner) endRow() error {
+	if err := s.endCell(); err != nil || !s.inRow {
+		return err
+	}
+	s.inRow = false
+	var emit func([]string) error
+	switch s.section {
+	case sectionHead:
+		emit = s.header
+	case sectionBody:
+		emit = s.row
+	default:
+		return nil
+	}
+	if s.rows++; s.limits.Rows > 0 && s.rows > s.limits.Rows {
+		return &LimitError{"rows", int64(s.limits.Rows)}
+	}
+	return emit(s.cells)
+}
+
+// endTable ends the first table, also if the document ends inside it.
+func (s *scanner) endTable() error {
+	err := s.endRow()
+	s.depth = 0
+	return err
+}
@@ -0,0 +1,118 @@
+// Package importer runs an import pipeline: a Source reads a batch of
+// data, hooks inspect or change it, and each Sink loads it. The import
+// commands are built on it, and programs embedding etget can combine their
+// own sources, sinks and hooks the same way.
+package importer
+
+import (
+	"context"
+	"fmt"
+)
+
+// Batch is the data of one import.
+type Batch struct {
+	// Source names the kind of data, e.g. "intraday".
+	Source string
+
+	// Inputs are the files or URLs the data was read from.
+	Inputs []Input
+
+	// Data is the parsed data. Its type depends on the source, e.g.
+	// []intraday.Hour; sinks assert the type they load.
+	Data interface{}
+}
+
+// Input identifies an input file by its contents.
+type Input struct {
+	// Name is the absolute path or URL of the file.
+	Name string `json:"name"`
+
+	// SHA256 is the hex encoded digest of the contents.
+	SHA256 string `json:"sha256"`
+
+	Size int64 `json:"size"`
+}
+
+// Source reads the batch to import.
+type Source interface {
+	Read(ctx context.Context) (*Batch, error)
+}
+
+// SourceFunc adapts a function to Source.
+type SourceFunc func(ctx context.Context) (*Batch, error)
+
+// Read calls f.
+func (f SourceFunc) Read(ctx context.Context) (*Batch, error) { return f(ctx) }
+
+// Sink loads a batch, e.g. into a database, and returns the number of rows
+// affected.
+type Sink interface {
+	// Name identifies the sink in results. It must not contain secrets.
+	Name() string
+	Load(ctx context.Context, b *Batch) (rowsAffected int64, err error)
+}
+
+// NewSink returns a Sink named name that loads batches with load.
+func NewSink(name string, load func(ctx context.Context, b *Batch) (int64, error)) Sink {
+	return funcSink{name, load}
+}
+
+type funcSink struct {
+	name string
+	load func(ctx context.Context, b *Batch) (int64, error)
+}
+
+func (s funcSink) Name() string { return s.name }
+
+func (s funcSink) Load(ctx context.Context, b *Batch) (int64, error) { return s.load(ctx, b) }
+
+// Result is the outcome of loading a batch into one sink.
+type Result struct {
+	Sink         string
+	RowsAffected int64
+	Err          error
+}
+
+// Importer reads from Source and loads into every sink in Sinks.
+type Importer struct {
+	Source Source
+	Sinks  []Sink
+
+	// BeforeLoad hooks are called in order with the batch read from
+	// Source. They may change the batch; an error aborts the import
+	// before anything is loaded.
+	BeforeLoad []func(ctx context.Context, b *Batch) error
+
+	// AfterLoad hooks are called in order with the batch and the results
+	// of all sinks, including failed ones.
+	AfterLoad []func(ctx context.Context, b *Batch, results []Result)
+}
+
+// Run reads the batch and loads it into the sinks one at a time. It
+// returns an error only if reading or a BeforeLoad hook fails; errors of
+// the sinks are in the results, so that one unreachable database does not
+// stop the others from being loaded.
+func (imp *Importer) Run(ctx context.Context) ([]Result, error) {
+	b, err := imp.Source.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, hook := range imp.BeforeLoad {
+		if err := hook(ctx, b); err != nil {
+			return nil, fmt.Errorf("before load: %s", err)
+		}
+	}
+	results := make([]Result, len(imp.Sinks))
+	for i, s := range imp.Sinks {
+		results[i].Sink = s.Name()
+		if err := ctx.Err(); err != nil {
+			results[i].Err = err
+			continue
+		}
+		results[i].RowsAffected, results[i].Err = s.Load(ctx, b)
+	}
+	for _, hook := range imp.AfterLoad {
+		hook(ctx, b, results)
+	}
+	return results, nil
+}
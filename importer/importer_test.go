@@ -0,0 +1,77 @@
+package importer_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/joneskoo/etget/importer"
+)
+
+func TestImporterRun(t *testing.T) {
+	var calls []string
+	imp := importer.Importer{
+		Source: importer.SourceFunc(func(ctx context.Context) (*importer.Batch, error) {
+			calls = append(calls, "read")
+			return &importer.Batch{Source: "test", Data: []int{1, 2, 3}}, nil
+		}),
+		Sinks: []importer.Sink{
+			importer.NewSink("a", func(ctx context.Context, b *importer.Batch) (int64, error) {
+				calls = append(calls, "load a")
+				return int64(len(b.Data.([]int))), nil
+			}),
+			importer.NewSink("b", func(ctx context.Context, b *importer.Batch) (int64, error) {
+				calls = append(calls, "load b")
+				return 0, errors.New("refused")
+			}),
+		},
+		BeforeLoad: []func(context.Context, *importer.Batch) error{
+			func(ctx context.Context, b *importer.Batch) error {
+				calls = append(calls, "before")
+				b.Data = b.Data.([]int)[1:]
+				return nil
+			},
+		},
+		AfterLoad: []func(context.Context, *importer.Batch, []importer.Result){
+			func(ctx context.Context, b *importer.Batch, results []importer.Result) {
+				calls = append(calls, "after")
+			},
+		},
+	}
+	results, err := imp.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run: %s", err)
+	}
+	if want := []string{"read", "before", "load a", "load b", "after"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	if len(results) != 2 || results[0].Sink != "a" || results[0].RowsAffected != 2 || results[0].Err != nil {
+		t.Errorf("results[0] = %+v, want a with 2 rows", results[0])
+	}
+	if len(results) == 2 && (results[1].Sink != "b" || results[1].Err == nil) {
+		t.Errorf("results[1] = %+v, want b with error", results[1])
+	}
+}
+
+func TestImporterBeforeLoadAborts(t *testing.T) {
+	loaded := false
+	imp := importer.Importer{
+		Source: importer.SourceFunc(func(ctx context.Context) (*importer.Batch, error) {
+			return &importer.Batch{}, nil
+		}),
+		Sinks: []importer.Sink{importer.NewSink("a", func(ctx context.Context, b *importer.Batch) (int64, error) {
+			loaded = true
+			return 0, nil
+		})},
+		BeforeLoad: []func(context.Context, *importer.Batch) error{
+			func(ctx context.Context, b *importer.Batch) error { return errors.New("no data") },
+		},
+	}
+	if _, err := imp.Run(context.Background()); err == nil {
+		t.Error("Run did not return the BeforeLoad error")
+	}
+	if loaded {
+		t.Error("sink loaded after BeforeLoad failed")
+	}
+}
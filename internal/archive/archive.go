@@ -0,0 +1,228 @@
+// Package archive keeps the raw input files of imports, compressed and
+// named by the SHA-256 of their contents, the digest the ledger records
+// for each import. Any recorded import can then be reproduced, or its
+// files parsed again after a parser fix.
+//
+// A file is stored as DIR/ab/abcdef…(64 hex digits).EXT, EXT naming the
+// compression. Storing the same contents again does nothing.
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ZstdCommand is the zstd program used by the zstd codec.
+var ZstdCommand = "zstd"
+
+// Codec compresses archived files.
+type Codec interface {
+	// Ext is the file name extension of compressed files, without the
+	// dot; empty for none.
+	Ext() string
+
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+// DefaultCodec is the name of the codec used unless another is chosen.
+const DefaultCodec = "zstd"
+
+var codecs = map[string]Codec{
+	"zstd": zstdCodec{},
+	"gzip": gzipCodec{},
+	"none": noCodec{},
+}
+
+// Codecs returns the names of the codecs, sorted.
+func Codecs() []string {
+	names := make([]string, 0, len(codecs))
+	for name := range codecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type noCodec struct{}
+
+func (noCodec) Ext() string                            { return "" }
+func (noCodec) Compress(data []byte) ([]byte, error)   { return data, nil }
+func (noCodec) Decompress(data []byte) ([]byte, error) { return data, nil }
+
+type gzipCodec struct{}
+
+func (gzipCodec) Ext() string { return "gz" }
+
+func (gzipCodec) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) Decompress(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return ioutil.ReadAll(zr)
+}
+
+// zstdCodec runs ZstdCommand, as there is no zstd in the standard library.
+type zstdCodec struct{}
+
+func (zstdCodec) Ext() string { return "zst" }
+
+func (zstdCodec) Compress(data []byte) ([]byte, error) {
+	return runZstd(data, "-q", "-c", "-19")
+}
+
+func (zstdCodec) Decompress(data []byte) ([]byte, error) {
+	return runZstd(data, "-q", "-d", "-c")
+}
+
+func runZstd(data []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command(ZstdCommand, args...)
+	cmd.Stdin = bytes.NewReader(data)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = errors.New(msg)
+		}
+		return nil, fmt.Errorf("%s: %s", ZstdCommand, err)
+	}
+	return out, nil
+}
+
+// Archive is an archive directory.
+type Archive struct {
+	Dir   string
+	Codec Codec
+}
+
+// Open returns the archive in dir storing new files with the named codec.
+// The directory is created if needed.
+func Open(dir, codec string) (*Archive, error) {
+	c, ok := codecs[codec]
+	if !ok {
+		return nil, fmt.Errorf("unknown compression %q, want one of %s", codec, strings.Join(Codecs(), ", "))
+	}
+	if _, ok := c.(zstdCodec); ok {
+		if _, err := exec.LookPath(ZstdCommand); err != nil {
+			return nil, fmt.Errorf("compression zstd needs the %s program: %s", ZstdCommand, err)
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Archive{Dir: dir, Codec: c}, nil
+}
+
+func (a *Archive) path(sum, ext string) string {
+	name := filepath.Join(a.Dir, sum[:2], sum)
+	if ext != "" {
+		name += "." + ext
+	}
+	return name
+}
+
+// find returns the stored file of sum and its codec, whatever the codec
+// it was stored with.
+func (a *Archive) find(sum string) (string, Codec, error) {
+	for _, c := range codecs {
+		name := a.path(sum, c.Ext())
+		if _, err := os.Stat(name); err == nil {
+			return name, c, nil
+		} else if !os.IsNotExist(err) {
+			return "", nil, err
+		}
+	}
+	return "", nil, os.ErrNotExist
+}
+
+// Put stores data unless the archive has it already, and returns its
+// SHA-256 digest in hex.
+func (a *Archive) Put(data []byte) (string, error) {
+	s := sha256.Sum256(data)
+	sum := hex.EncodeToString(s[:])
+	if _, _, err := a.find(sum); err == nil {
+		return sum, nil
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+	compressed, err := a.Codec.Compress(data)
+	if err != nil {
+		return "", err
+	}
+	name := a.path(sum, a.Codec.Ext())
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return "", err
+	}
+	// Write to a temporary name so that a crash never leaves a truncated
+	// file under the digest.
+	tmp, err := ioutil.TempFile(filepath.Dir(name), sum+".*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmp.Write(compressed); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return sum, nil
+}
+
+// Get returns the contents of the file with the SHA-256 digest sum,
+// verifying the digest. A missing file is an os.IsNotExist error.
+func (a *Archive) Get(sum string) ([]byte, error) {
+	sum = strings.ToLower(sum)
+	if _, err := hex.DecodeString(sum); err != nil || len(sum) != sha256.Size*2 {
+		return nil, fmt.Errorf("invalid SHA-256 digest %q", sum)
+	}
+	name, c, err := a.find(sum)
+	if err != nil {
+		return nil, err
+	}
+	compressed, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.Decompress(compressed)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err)
+	}
+	if s := sha256.Sum256(data); hex.EncodeToString(s[:]) != sum {
+		return nil, fmt.Errorf("%s: contents do not match the digest", name)
+	}
+	return data, nil
+}
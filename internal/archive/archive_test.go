@@ -0,0 +1,62 @@
+package archive
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPutGet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The fake zstd passes the data through unchanged.
+	defer func(c string) { ZstdCommand = c }(ZstdCommand)
+	ZstdCommand = filepath.Join(dir, "fake-zstd")
+	if err := ioutil.WriteFile(ZstdCommand, []byte("#!/bin/sh\ncat\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte(strings.Repeat("<tr><td>01.01.2020</td><td>12,34</td></tr>\n", 100))
+	for _, codec := range Codecs() {
+		a, err := Open(filepath.Join(dir, codec), codec)
+		if err != nil {
+			t.Fatalf("Open(%s): %s", codec, err)
+		}
+		sum, err := a.Put(data)
+		if err != nil {
+			t.Fatalf("%s: Put: %s", codec, err)
+		}
+		if _, err := a.Put(data); err != nil {
+			t.Errorf("%s: Put again: %s", codec, err)
+		}
+		files, _ := filepath.Glob(filepath.Join(a.Dir, sum[:2], "*"))
+		if want := a.path(sum, a.Codec.Ext()); len(files) != 1 || files[0] != want {
+			t.Errorf("%s: stored %v, want %s", codec, files, want)
+		}
+		got, err := a.Get(strings.ToUpper(sum))
+		if err != nil || string(got) != string(data) {
+			t.Errorf("%s: Get = %d bytes, %v; want the data", codec, len(got), err)
+		}
+		if _, err := a.Get(strings.Repeat("0", 64)); !os.IsNotExist(err) {
+			t.Errorf("%s: Get(missing) error = %v, want not exist", codec, err)
+		}
+	}
+
+	// A file stored with one codec is found by an archive using another.
+	gz, _ := Open(filepath.Join(dir, "mixed"), "gzip")
+	sum, _ := gz.Put(data)
+	plain, _ := Open(filepath.Join(dir, "mixed"), "none")
+	if got, err := plain.Get(sum); err != nil || string(got) != string(data) {
+		t.Errorf("Get of gzip file with codec none = %v", err)
+	}
+
+	if _, err := Open(dir, "lz4"); err == nil {
+		t.Error("Open with unknown compression succeeded")
+	}
+}
@@ -0,0 +1,278 @@
+// Package config reads the etget configuration file.
+//
+// The file is JSON, optionally encrypted with age or GPG (see package
+// secretfile). All sections are optional; see Config for the fields.
+// Named profiles in "profiles" override parts of it for one environment,
+// e.g. a "local" and a "prod" database:
+//
+//	{
+//		"contract": {"margin_per_kwh": 0.0025},
+//		"profiles": {
+//			"local": {"connstring": "sslmode=disable"},
+//			"prod": {"connstring": "host=db.example.com", "elspot": {"areas": ["FI", "EE"]}}
+//		}
+//	}
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/joneskoo/etget/internal/dbpool"
+	"github.com/joneskoo/etget/internal/hook"
+	"github.com/joneskoo/etget/internal/imapfetch"
+	"github.com/joneskoo/etget/internal/preset"
+	"github.com/joneskoo/etget/internal/pubcal"
+	"github.com/joneskoo/etget/internal/secretfile"
+	"github.com/joneskoo/etget/internal/server"
+	"github.com/joneskoo/etget/internal/telemetry"
+	"github.com/joneskoo/etget/tariff"
+)
+
+// DefaultFile is the configuration file used when none is given.
+const DefaultFile = "etget.json"
+
+// ProfileEnv is the environment variable naming the profile Load selects.
+// The -profile flags of the commands set it.
+const ProfileEnv = "ETGET_PROFILE"
+
+// Config is the contents of the configuration file.
+type Config struct {
+	// Connstring is the database the commands use when no -connstring
+	// flag is given.
+	Connstring string `json:"connstring"`
+
+	// Preset is the country preset, e.g. "se", providing the VAT rate
+	// when the contract does not set one.
+	Preset string `json:"preset"`
+
+	// Contract is the electricity contract used for cost calculations.
+	Contract Contract `json:"contract"`
+
+	// Server configures authentication of the HTTP endpoints.
+	Server server.Options `json:"server"`
+
+	// Report configures delivery of etget report.
+	Report Report `json:"report"`
+
+	// Mailbox is the IMAP mailbox import-energiatili -imap fetches
+	// consumption reports from.
+	Mailbox imapfetch.Options `json:"mailbox"`
+
+	// Database tunes the connection pool of the HTTP endpoints.
+	Database dbpool.Options `json:"database"`
+
+	// Meters names the metering points, e.g. {"home": "643000000012345678",
+	// "cottage": "643000000087654321"}, by the meter_id their consumption
+	// is stored under. The -meter flags accept the names; other values
+	// are used as the meter_id as such.
+	Meters map[string]string `json:"meters"`
+
+	// Elspot configures the columns import-elspot reads.
+	Elspot Elspot `json:"elspot"`
+
+	// Calendar is when the prices of each day are expected, with the
+	// exceptions to the daily schedule.
+	Calendar pubcal.Options `json:"calendar"`
+
+	// Telemetry exports traces of the import stages to an OTLP endpoint.
+	// It is off unless an endpoint is set here or in the environment.
+	Telemetry telemetry.Options `json:"telemetry"`
+
+	// Hooks are run after each successful import, with the run report.
+	Hooks []hook.Hook `json:"hooks"`
+
+	// Profiles are overrides of the configuration by profile name. A
+	// profile has the fields of Config except profiles; the fields it
+	// sets replace those of the file, and sections are merged field by
+	// field.
+	Profiles map[string]json.RawMessage `json:"profiles"`
+
+	// Profile is the name of the selected profile, if any.
+	Profile string `json:"-"`
+}
+
+// Elspot maps the price columns of elspot files to the area names used in
+// the database.
+type Elspot struct {
+	// Columns renames columns, e.g. {"SE3": "stockholm"}. Headers are
+	// compared case-insensitively. The renamed areas are loaded into
+	// columns of table elspot of the new name, except the Finnish price,
+	// which stays in column fi that etget reads.
+	Columns map[string]string `json:"columns"`
+
+	// Ignore lists columns that are not imported.
+	Ignore []string `json:"ignore"`
+
+	// Areas are the default -areas of import-elspot.
+	Areas []string `json:"areas"`
+
+	// Sources are the files or URLs import-elspot loads when none are
+	// given on the command line.
+	Sources []string `json:"sources"`
+}
+
+// Report configures the daily report.
+type Report struct {
+	// SMTP is the mail server the report is sent through.
+	SMTP SMTP `json:"smtp"`
+}
+
+// SMTP is a mail server and the report envelope. The server must support
+// STARTTLS if a username is set.
+type SMTP struct {
+	// Addr is the host:port of the server, e.g. "smtp.example.com:587".
+	Addr     string   `json:"addr"`
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+// Contract describes a spot-priced electricity contract and the network
+// transfer tariff. All prices are in euros and include VAT, except the
+// spot price to which VAT is added.
+type Contract struct {
+	// MarginPerKWh is the retailer's margin on top of the spot price.
+	MarginPerKWh float64 `json:"margin_per_kwh"`
+
+	// MonthlyFee is the retailer's fixed monthly fee.
+	MonthlyFee float64 `json:"monthly_fee"`
+
+	// VAT is the value added tax rate applied to the spot price, e.g. 0.24.
+	VAT float64 `json:"vat"`
+
+	// Transfer is the network operator's tariff.
+	Transfer Transfer `json:"transfer"`
+
+	// SaleMarginPerKWh is deducted from the spot price of produced energy
+	// sold to the retailer. No VAT is applied to sales.
+	SaleMarginPerKWh float64 `json:"sale_margin_per_kwh"`
+
+	// Rules, if set, price the contract instead of the fields above,
+	// VAT included: the rule of the spot price has it in its rate.
+	Rules tariff.Tariff `json:"tariff"`
+}
+
+// Tariff returns the rules pricing the contract, made of the fields of c
+// unless it has Rules.
+func (c Contract) Tariff() tariff.Tariff {
+	if len(c.Rules) > 0 {
+		return c.Rules
+	}
+	t := tariff.Tariff{
+		{Name: "spot", Item: tariff.Energy, Unit: tariff.PerSpot, Rate: 1 + c.VAT},
+		{Name: "margin", Item: tariff.Energy, Unit: tariff.PerKWh, Rate: c.MarginPerKWh},
+		{Name: "monthly fee", Item: tariff.Energy, Unit: tariff.PerMonth, Rate: c.MonthlyFee},
+		{Name: "transfer monthly fee", Item: tariff.Transfer, Unit: tariff.PerMonth, Rate: c.Transfer.MonthlyFee},
+		{Name: "sold", Item: tariff.Sold, Unit: tariff.PerSpot, Rate: 1},
+		{Name: "sale margin", Item: tariff.Sold, Unit: tariff.PerKWh, Rate: -c.SaleMarginPerKWh},
+	}
+	tr := c.Transfer
+	if tr.NightStart == tr.NightEnd {
+		return append(t, tariff.Rule{Name: "transfer", Item: tariff.Transfer, Unit: tariff.PerKWh, Rate: tr.DayPerKWh})
+	}
+	return append(t,
+		tariff.Rule{Name: "transfer night", Item: tariff.Transfer, Unit: tariff.PerKWh, Rate: tr.NightPerKWh,
+			Hours: &tariff.Hours{From: tr.NightStart, To: tr.NightEnd}},
+		tariff.Rule{Name: "transfer day", Item: tariff.Transfer, Unit: tariff.PerKWh, Rate: tr.DayPerKWh,
+			Except: "transfer night"},
+	)
+}
+
+// Transfer is a day/night network transfer tariff.
+type Transfer struct {
+	MonthlyFee  float64 `json:"monthly_fee"`
+	DayPerKWh   float64 `json:"day_per_kwh"`
+	NightPerKWh float64 `json:"night_per_kwh"`
+
+	// NightStart and NightEnd are the local hours when the night rate
+	// starts and ends, e.g. 22 and 7.
+	NightStart int `json:"night_start"`
+	NightEnd   int `json:"night_end"`
+}
+
+// Load reads the configuration from file with the profile named by
+// ProfileEnv, if set. A missing file is not an error if it is the default
+// file and no profile is selected; an empty configuration is returned
+// instead.
+func Load(file string) (*Config, error) {
+	return LoadProfile(file, os.Getenv(ProfileEnv))
+}
+
+// LoadProfile reads the configuration from file with the overrides of
+// profile, unless profile is empty.
+func LoadProfile(file, profile string) (*Config, error) {
+	var c Config
+	b, err := secretfile.ReadFile(file)
+	if os.IsNotExist(err) && file == DefaultFile && profile == "" {
+		return &c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// A VAT rate of zero is valid, so unset is told apart by a sentinel.
+	c.Contract.VAT = -1
+	if err = json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("config %s: %s", file, err)
+	}
+	if profile != "" {
+		override, ok := c.Profiles[profile]
+		if !ok {
+			return nil, fmt.Errorf("config %s: no profile %q, want one of %s", file, profile, strings.Join(c.ProfileNames(), ", "))
+		}
+		var nested struct {
+			Profiles json.RawMessage `json:"profiles"`
+		}
+		if err = json.Unmarshal(override, &nested); err == nil && nested.Profiles != nil {
+			err = errors.New("profiles cannot be nested")
+		}
+		if err == nil {
+			err = json.Unmarshal(override, &c)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("config %s: profile %s: %s", file, profile, err)
+		}
+		c.Profile = profile
+	}
+	vatUnset := c.Contract.VAT == -1
+	if vatUnset {
+		c.Contract.VAT = 0
+	}
+	if c.Preset != "" {
+		p, err := preset.Lookup(c.Preset)
+		if err != nil {
+			return nil, fmt.Errorf("config %s: %s", file, err)
+		}
+		if vatUnset {
+			c.Contract.VAT = p.VAT
+		}
+	}
+	if err := c.Contract.Rules.Validate(); err != nil {
+		return nil, fmt.Errorf("config %s: contract: %s", file, err)
+	}
+	return &c, nil
+}
+
+// MeterID returns the meter_id of the meter named name in Meters, or name
+// itself if it is not one of them.
+func (c *Config) MeterID(name string) string {
+	if id, ok := c.Meters[name]; ok {
+		return id
+	}
+	return name
+}
+
+// ProfileNames returns the names of the profiles in c, sorted.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,139 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/joneskoo/etget/internal/config"
+	"github.com/joneskoo/etget/internal/secretfile"
+)
+
+func TestPresetVAT(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		json string
+		vat  float64
+	}{
+		{`{}`, 0},
+		{`{"preset": "se"}`, 0.25},
+		{`{"preset": "se", "contract": {"vat": 0}}`, 0},
+		{`{"preset": "fi", "contract": {"vat": 0.24}}`, 0.24},
+	}
+	for _, c := range cases {
+		file := filepath.Join(dir, "etget.json")
+		if err := ioutil.WriteFile(file, []byte(c.json), 0600); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := config.Load(file)
+		if err != nil {
+			t.Errorf("Load(%s): %s", c.json, err)
+			continue
+		}
+		if cfg.Contract.VAT != c.vat {
+			t.Errorf("Load(%s): VAT = %v, want %v", c.json, cfg.Contract.VAT, c.vat)
+		}
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "etget.json")
+	err = ioutil.WriteFile(file, []byte(`{
+		"connstring": "sslmode=disable",
+		"contract": {"vat": 0.24, "margin_per_kwh": 0.002},
+		"elspot": {"areas": ["FI"], "ignore": ["SYS"]},
+		"profiles": {
+			"prod": {"connstring": "host=db", "contract": {"vat": 0.255}, "elspot": {"areas": ["FI", "EE"]}},
+			"nested": {"profiles": {}}
+		}
+	}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := config.LoadProfile(file, "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Profile != "prod" || cfg.Connstring != "host=db" {
+		t.Errorf("Profile, Connstring = %q, %q; want prod, host=db", cfg.Profile, cfg.Connstring)
+	}
+	if cfg.Contract.VAT != 0.255 || cfg.Contract.MarginPerKWh != 0.002 {
+		t.Errorf("Contract = %+v, want VAT from profile and margin from file", cfg.Contract)
+	}
+	if !reflect.DeepEqual(cfg.Elspot.Areas, []string{"FI", "EE"}) || !reflect.DeepEqual(cfg.Elspot.Ignore, []string{"SYS"}) {
+		t.Errorf("Elspot = %+v, want areas from profile and ignore from file", cfg.Elspot)
+	}
+
+	if cfg, err := config.LoadProfile(file, ""); err != nil || cfg.Connstring != "sslmode=disable" {
+		t.Errorf("Load without profile: %v, %v", cfg, err)
+	}
+	for _, bad := range []string{"nested", "staging"} {
+		if _, err := config.LoadProfile(file, bad); err == nil {
+			t.Errorf("LoadProfile(%s) did not return error", bad)
+		}
+	}
+}
+
+func TestLoadEncrypted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	gpg := filepath.Join(dir, "gpg")
+	if err := ioutil.WriteFile(gpg, []byte("#!/bin/sh\ncat >/dev/null\necho '{\"connstring\": \"host=secret\"}'\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	defer func(c string) { secretfile.GPGCommand = c }(secretfile.GPGCommand)
+	secretfile.GPGCommand = gpg
+
+	file := filepath.Join(dir, "etget.json.asc")
+	if err := ioutil.WriteFile(file, []byte("-----BEGIN PGP MESSAGE-----\n\nhQEMA\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadProfile(file, "")
+	if err != nil || cfg.Connstring != "host=secret" {
+		t.Errorf("LoadProfile(encrypted) = %+v, %v; want the decrypted connstring", cfg, err)
+	}
+}
+
+func TestContractTariff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "etget.json")
+
+	write := func(s string) {
+		if err := ioutil.WriteFile(file, []byte(s), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"contract": {"margin_per_kwh": 0.01, "tariff": [{"name": "flat", "item": "energy", "unit": "kwh", "rate": 0.1}]}}`)
+	cfg, err := config.Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules := cfg.Contract.Tariff(); len(rules) != 1 || rules[0].Name != "flat" {
+		t.Errorf("Tariff() = %+v, want the rules of the file", rules)
+	}
+
+	write(`{"contract": {"tariff": [{"name": "flat", "item": "energy", "unit": "kwh", "months": [0]}]}}`)
+	if _, err := config.Load(file); err == nil {
+		t.Error("Load accepted a rule with month 0")
+	}
+}
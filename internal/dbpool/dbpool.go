@@ -0,0 +1,149 @@
+// Package dbpool opens the Postgres connection pool of long-running
+// commands with limits, a statement timeout and TCP keepalive, so that
+// connections silently dropped by a home router are noticed and replaced.
+package dbpool
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Options tunes the pool. Zero fields keep the database/sql and lib/pq
+// defaults.
+type Options struct {
+	MaxOpen int `json:"max_open"`
+	MaxIdle int `json:"max_idle"`
+
+	// MaxLifetime closes connections older than this.
+	MaxLifetime Duration `json:"max_lifetime"`
+
+	// StatementTimeout makes the server cancel statements running longer
+	// than this.
+	StatementTimeout Duration `json:"statement_timeout"`
+
+	// Keepalive is the interval of TCP keepalive probes.
+	Keepalive Duration `json:"keepalive"`
+}
+
+// Duration is a time.Duration written as a string such as "30s" in JSON.
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// RegisterFlags defines -db-* flags setting o in fs.
+func (o *Options) RegisterFlags(fs *flag.FlagSet) {
+	fs.IntVar(&o.MaxOpen, "db-max-open", o.MaxOpen, "maximum number of open database connections (default unlimited)")
+	fs.IntVar(&o.MaxIdle, "db-max-idle", o.MaxIdle, "maximum number of idle database connections (default 2)")
+	fs.DurationVar((*time.Duration)(&o.MaxLifetime), "db-max-lifetime", time.Duration(o.MaxLifetime), "close database connections older than this (default never)")
+	fs.DurationVar((*time.Duration)(&o.StatementTimeout), "db-statement-timeout", time.Duration(o.StatementTimeout), "cancel queries running longer than this (default never)")
+	fs.DurationVar((*time.Duration)(&o.Keepalive), "db-keepalive", time.Duration(o.Keepalive), "TCP keepalive interval of database connections (default 15s)")
+}
+
+// Or returns o with its zero fields taken from def, e.g. to let flags
+// override the config file.
+func (o Options) Or(def Options) Options {
+	if o.MaxOpen == 0 {
+		o.MaxOpen = def.MaxOpen
+	}
+	if o.MaxIdle == 0 {
+		o.MaxIdle = def.MaxIdle
+	}
+	if o.MaxLifetime == 0 {
+		o.MaxLifetime = def.MaxLifetime
+	}
+	if o.StatementTimeout == 0 {
+		o.StatementTimeout = def.StatementTimeout
+	}
+	if o.Keepalive == 0 {
+		o.Keepalive = def.Keepalive
+	}
+	return o
+}
+
+// Open opens a pool of connections to connstring with the options.
+func (o Options) Open(connstring string) (*sql.DB, error) {
+	dsn, err := withStatementTimeout(connstring, time.Duration(o.StatementTimeout))
+	if err != nil {
+		return nil, err
+	}
+	var db *sql.DB
+	if o.Keepalive != 0 {
+		db = sql.OpenDB(connector{dsn, dialer{net.Dialer{KeepAlive: time.Duration(o.Keepalive)}}})
+	} else if db, err = sql.Open("postgres", dsn); err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(o.MaxOpen)
+	if o.MaxIdle != 0 {
+		db.SetMaxIdleConns(o.MaxIdle)
+	}
+	db.SetConnMaxLifetime(time.Duration(o.MaxLifetime))
+	return db, nil
+}
+
+// withStatementTimeout adds the statement_timeout run-time parameter to
+// connstring, either a URL or key=value pairs. lib/pq sends parameters it
+// does not know to the server.
+func withStatementTimeout(connstring string, timeout time.Duration) (string, error) {
+	if timeout == 0 {
+		return connstring, nil
+	}
+	ms := fmt.Sprint(timeout.Milliseconds())
+	if strings.HasPrefix(connstring, "postgres://") || strings.HasPrefix(connstring, "postgresql://") {
+		u, err := url.Parse(connstring)
+		if err != nil {
+			return "", fmt.Errorf("connection URL: %s", err)
+		}
+		q := u.Query()
+		q.Set("statement_timeout", ms)
+		u.RawQuery = q.Encode()
+		return u.String(), nil
+	}
+	return strings.TrimSpace(connstring + " statement_timeout=" + ms), nil
+}
+
+// connector opens connections with dialer.
+type connector struct {
+	dsn    string
+	dialer pq.Dialer
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) {
+	return pq.DialOpen(c.dialer, c.dsn)
+}
+
+func (c connector) Driver() driver.Driver { return &pq.Driver{} }
+
+// dialer implements pq.Dialer with a net.Dialer.
+type dialer struct{ d net.Dialer }
+
+func (d dialer) Dial(network, address string) (net.Conn, error) {
+	return d.d.Dial(network, address)
+}
+
+func (d dialer) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
+	nd := d.d
+	nd.Timeout = timeout
+	return nd.Dial(network, address)
+}
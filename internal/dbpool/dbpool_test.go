@@ -0,0 +1,43 @@
+package dbpool
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestWithStatementTimeout(t *testing.T) {
+	tests := []struct {
+		connstring string
+		timeout    time.Duration
+		want       string
+	}{
+		{"sslmode=disable", 0, "sslmode=disable"},
+		{"sslmode=disable", 30 * time.Second, "sslmode=disable statement_timeout=30000"},
+		{"", time.Second, "statement_timeout=1000"},
+		{"postgres://u@db/etget?sslmode=disable", 1500 * time.Millisecond, "postgres://u@db/etget?sslmode=disable&statement_timeout=1500"},
+	}
+	for _, tt := range tests {
+		got, err := withStatementTimeout(tt.connstring, tt.timeout)
+		if err != nil {
+			t.Errorf("withStatementTimeout(%q, %s): %s", tt.connstring, tt.timeout, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("withStatementTimeout(%q, %s) = %q, want %q", tt.connstring, tt.timeout, got, tt.want)
+		}
+	}
+}
+
+func TestOptionsOr(t *testing.T) {
+	var cfg Options
+	if err := json.Unmarshal([]byte(`{"max_open": 4, "max_idle": 2, "keepalive": "30s"}`), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	flags := Options{MaxOpen: 8, StatementTimeout: Duration(time.Minute)}
+	got := flags.Or(cfg)
+	want := Options{MaxOpen: 8, MaxIdle: 2, StatementTimeout: Duration(time.Minute), Keepalive: Duration(30 * time.Second)}
+	if got != want {
+		t.Errorf("Or = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,135 @@
+// Package drift compares the columns of a table with those an importer
+// loads, so that a table changed by hand or by an older version is
+// reported with the statements fixing it, rather than failing in the
+// middle of a COPY.
+package drift
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Column is a column an importer loads.
+type Column struct {
+	Name string
+
+	// Types are the data types, as in information_schema.columns, that
+	// the importer can load into the column; e.g. "double precision" or
+	// "timestamp with time zone". The first is suggested in fixes.
+	Types []string
+}
+
+// Problem is a column that is missing (Have is empty) or has a type the
+// importer cannot load.
+type Problem struct {
+	Column Column
+	Have   string
+}
+
+// Report lists the problems of a table. It is an error.
+type Report struct {
+	Table    string
+	Problems []Problem
+}
+
+func (r *Report) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "table %s does not have the columns this importer loads:", r.Table)
+	for _, p := range r.Problems {
+		if p.Have == "" {
+			fmt.Fprintf(&b, "\n  column %s is missing", p.Column.Name)
+		} else {
+			fmt.Fprintf(&b, "\n  column %s is %s, want %s", p.Column.Name, p.Have, strings.Join(p.Column.Types, " or "))
+		}
+	}
+	b.WriteString("\nfix it with:")
+	for _, stmt := range r.Fixes() {
+		b.WriteString("\n  " + stmt + ";")
+	}
+	return b.String()
+}
+
+// Fixes returns the statements adding the missing columns and converting
+// the others to their first type. Converting may fail for values that do
+// not convert, which is better found before loading.
+func (r *Report) Fixes() []string {
+	stmts := make([]string, len(r.Problems))
+	for i, p := range r.Problems {
+		name, typ := quoteIdentifier(p.Column.Name), p.Column.Types[0]
+		if p.Have == "" {
+			stmts[i] = fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", quoteIdentifier(r.Table), name, typ)
+		} else {
+			stmts[i] = fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s::%s", quoteIdentifier(r.Table), name, typ, name, typ)
+		}
+	}
+	return stmts
+}
+
+// Check compares the columns of table in the current schema with want. It
+// returns a *Report if any differ, and nil if they match or the table does
+// not exist yet.
+func Check(db *sql.DB, table string, want []Column) error {
+	rows, err := db.Query(`SELECT column_name, data_type FROM information_schema.columns
+    WHERE table_schema = current_schema() AND table_name = $1`, table)
+	if err != nil {
+		return fmt.Errorf("read columns of %s: %s", table, err)
+	}
+	defer rows.Close()
+	have := make(map[string]string)
+	for rows.Next() {
+		var name, typ string
+		if err := rows.Scan(&name, &typ); err != nil {
+			return err
+		}
+		have[name] = typ
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(have) == 0 {
+		return nil
+	}
+	if r := Compare(table, have, want); r != nil {
+		return r
+	}
+	return nil
+}
+
+// Compare returns the report of the table whose columns have the types
+// in have, or nil if it has the columns in want.
+func Compare(table string, have map[string]string, want []Column) *Report {
+	r := &Report{Table: table}
+	for _, c := range want {
+		typ, ok := have[c.Name]
+		switch {
+		case !ok:
+			r.Problems = append(r.Problems, Problem{Column: c})
+		case !contains(c.Types, typ):
+			r.Problems = append(r.Problems, Problem{Column: c, Have: typ})
+		}
+	}
+	if len(r.Problems) == 0 {
+		return nil
+	}
+	return r
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// quoteIdentifier quotes names that are not plain lowercase identifiers.
+func quoteIdentifier(name string) string {
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_') {
+			return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+		}
+	}
+	return name
+}
@@ -0,0 +1,47 @@
+package drift
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	want := []Column{
+		{"ts", []string{"timestamp with time zone"}},
+		{"fi", []string{"real", "double precision", "numeric"}},
+		{"se3", []string{"real", "double precision", "numeric"}},
+		{"status", []string{"text"}},
+	}
+	if r := Compare("elspot", map[string]string{"ts": "timestamp with time zone", "fi": "numeric", "se3": "real", "status": "text", "id": "integer"}, want); r != nil {
+		t.Errorf("Compare(matching) = %v, want nil", r)
+	}
+
+	r := Compare("elspot", map[string]string{"ts": "timestamp without time zone", "fi": "text", "status": "text"}, want)
+	if r == nil || len(r.Problems) != 3 {
+		t.Fatalf("Compare = %v, want 3 problems", r)
+	}
+	wantFixes := []string{
+		"ALTER TABLE elspot ALTER COLUMN ts TYPE timestamp with time zone USING ts::timestamp with time zone",
+		"ALTER TABLE elspot ALTER COLUMN fi TYPE real USING fi::real",
+		"ALTER TABLE elspot ADD COLUMN se3 real",
+	}
+	for i, fix := range r.Fixes() {
+		if fix != wantFixes[i] {
+			t.Errorf("fix %d = %s, want %s", i, fix, wantFixes[i])
+		}
+	}
+	msg := r.Error()
+	for _, s := range []string{"column ts is timestamp without time zone, want timestamp with time zone", "column fi is text, want real or double precision or numeric", "column se3 is missing", "ADD COLUMN se3 real;"} {
+		if !strings.Contains(msg, s) {
+			t.Errorf("report does not contain %q:\n%s", s, msg)
+		}
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	for in, want := range map[string]string{"fi": "fi", "dst_corrected": "dst_corrected", "FI": `"FI"`, `a"b`: `"a""b"`} {
+		if got := quoteIdentifier(in); got != want {
+			t.Errorf("quoteIdentifier(%s) = %s, want %s", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Package hook runs the post-import actions of the configuration file: a
+// shell command or a webhook receiving the JSON run report, e.g. to
+// refresh caches or trigger home automation after new prices arrive.
+package hook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// Timeout limits the run of each hook.
+const Timeout = time.Minute
+
+// Hook is an action run after a successful import. It has a Command, a
+// URL or both.
+type Hook struct {
+	// Command is run with sh -c, the report on standard input and the
+	// import command in ETGET_COMMAND.
+	Command string `json:"command"`
+
+	// URL receives the report in a POST request.
+	URL string `json:"url"`
+
+	// Headers are added to the POST request, e.g. for authentication.
+	Headers map[string]string `json:"headers"`
+
+	// Commands are the import commands, e.g. "import-elspot", after which
+	// the hook runs. It runs after all if empty.
+	Commands []string `json:"commands"`
+}
+
+// runsAfter reports whether h runs after command.
+func (h Hook) runsAfter(command string) bool {
+	if len(h.Commands) == 0 {
+		return true
+	}
+	for _, c := range h.Commands {
+		if c == command {
+			return true
+		}
+	}
+	return false
+}
+
+// Run runs the hooks of command in order with the JSON report. A failing
+// hook does not stop the others; their errors are returned.
+func Run(ctx context.Context, hooks []Hook, command string, report []byte) (errs []error) {
+	for i, h := range hooks {
+		if !h.runsAfter(command) {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(ctx, Timeout)
+		if h.Command != "" {
+			if err := runCommand(ctx, h.Command, command, report); err != nil {
+				errs = append(errs, fmt.Errorf("hook %d: command: %s", i+1, err))
+			}
+		}
+		if h.URL != "" {
+			if err := post(ctx, h, report); err != nil {
+				errs = append(errs, fmt.Errorf("hook %d: %s: %s", i+1, h.URL, err))
+			}
+		}
+		cancel()
+	}
+	return errs
+}
+
+func runCommand(ctx context.Context, shell, command string, report []byte) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", shell)
+	cmd.Stdin = bytes.NewReader(report)
+	cmd.Env = append(os.Environ(), "ETGET_COMMAND="+command)
+	// Standard output is kept for the import's summary.
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func post(ctx context.Context, h Hook, report []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", h.URL, bytes.NewReader(report))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range h.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	ioutil.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("HTTP status %d", resp.StatusCode)
+	}
+	return nil
+}
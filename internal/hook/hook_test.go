@@ -0,0 +1,55 @@
+package hook
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var posted []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		posted, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	out := filepath.Join(dir, "out")
+	hooks := []Hook{
+		{Command: `cat > ` + out + `; echo "$ETGET_COMMAND" >> ` + out},
+		{URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer secret"}},
+		{URL: srv.URL},
+		{Command: "exit 3"},
+		{Command: "touch " + filepath.Join(dir, "skipped"), Commands: []string{"import-energiatili"}},
+	}
+	errs := Run(context.Background(), hooks, "import-elspot", []byte(`{"command":"import-elspot"}`))
+	if len(errs) != 2 {
+		t.Errorf("Run returned errors %v, want the unauthorized post and exit status", errs)
+	}
+	b, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "{\"command\":\"import-elspot\"}import-elspot\n"; got != want {
+		t.Errorf("command got %q, want %q", got, want)
+	}
+	if string(posted) != `{"command":"import-elspot"}` {
+		t.Errorf("posted %q, want the report", posted)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "skipped")); !os.IsNotExist(err) {
+		t.Error("hook of import-energiatili ran after import-elspot")
+	}
+}
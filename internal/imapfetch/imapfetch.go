@@ -0,0 +1,352 @@
+// Package imapfetch fetches email attachments from an IMAP mailbox, for
+// utilities that send consumption reports by email. It speaks only what
+// that takes of IMAP4rev1 (RFC 3501) over TLS: LOGIN, SELECT and the UID
+// SEARCH, FETCH and STORE commands.
+package imapfetch
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultAttachment is the pattern of the attachment names fetched unless
+// Options sets one.
+const DefaultAttachment = "*.json"
+
+// maxLiteral bounds the size of a message read from the server.
+const maxLiteral = 64 << 20
+
+// Options is the mailbox to fetch from.
+type Options struct {
+	// Addr is the host:port of the server, e.g. "imap.example.com:993".
+	// The connection is TLS from the start, as on port 993.
+	Addr     string `json:"addr"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+
+	// Mailbox is the folder searched (default INBOX).
+	Mailbox string `json:"mailbox"`
+
+	// From, if set, restricts the search to messages from this address.
+	From string `json:"from"`
+
+	// Attachment is the path.Match pattern of the attachment names to
+	// fetch, compared case-insensitively (default DefaultAttachment).
+	Attachment string `json:"attachment"`
+}
+
+func (o Options) mailbox() string {
+	if o.Mailbox == "" {
+		return "INBOX"
+	}
+	return o.Mailbox
+}
+
+// Attachment is a file attached to a message.
+type Attachment struct {
+	Name string
+	Data []byte
+}
+
+// Client is a session with the mailbox of Options selected.
+type Client struct {
+	conn net.Conn
+	r    *textproto.Reader
+	tag  int
+	opts Options
+}
+
+// Dial connects to the server of o, logs in and selects the mailbox.
+func Dial(o Options) (*Client, error) {
+	if o.Addr == "" {
+		return nil, errors.New("no server address")
+	}
+	host, _, err := net.SplitHostPort(o.Addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 10 * time.Second}, "tcp", o.Addr, &tls.Config{ServerName: host})
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn, o)
+}
+
+// NewClient starts a session on conn, an established connection to the
+// server of o, and selects the mailbox.
+func NewClient(conn net.Conn, o Options) (*Client, error) {
+	c := &Client{conn: conn, r: textproto.NewReader(bufio.NewReader(conn)), opts: o}
+	conn.SetDeadline(time.Now().Add(time.Minute))
+	greeting, err := c.r.ReadLine()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	switch {
+	case strings.HasPrefix(greeting, "* PREAUTH"):
+	case strings.HasPrefix(greeting, "* OK"):
+		user, err := quote(o.Username)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("username: %s", err)
+		}
+		pass, err := quote(o.Password)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("password: %s", err)
+		}
+		if _, err := c.cmd("LOGIN %s %s", user, pass); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("LOGIN: %s", err)
+		}
+	default:
+		conn.Close()
+		return nil, fmt.Errorf("unexpected greeting %q", greeting)
+	}
+	mailbox, err := quote(o.mailbox())
+	if err == nil {
+		_, err = c.cmd("SELECT %s", mailbox)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("SELECT %s: %s", o.mailbox(), err)
+	}
+	return c, nil
+}
+
+// Close closes the connection without logging out.
+func (c *Client) Close() error { return c.conn.Close() }
+
+// Logout ends the session and closes the connection.
+func (c *Client) Logout() error {
+	_, err := c.cmd("LOGOUT")
+	if cerr := c.conn.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Unseen returns the UIDs of the unseen messages in the mailbox, only
+// those from Options.From if set.
+func (c *Client) Unseen() ([]uint32, error) {
+	criteria := "UNSEEN"
+	if c.opts.From != "" {
+		from, err := quote(c.opts.From)
+		if err != nil {
+			return nil, fmt.Errorf("from: %s", err)
+		}
+		criteria += " FROM " + from
+	}
+	responses, err := c.cmd("UID SEARCH %s", criteria)
+	if err != nil {
+		return nil, fmt.Errorf("SEARCH: %s", err)
+	}
+	var uids []uint32
+	for _, r := range responses {
+		fields := strings.Fields(r.line)
+		if len(fields) == 0 || fields[0] != "SEARCH" {
+			continue
+		}
+		for _, f := range fields[1:] {
+			uid, err := strconv.ParseUint(f, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("SEARCH: malformed UID %q", f)
+			}
+			uids = append(uids, uint32(uid))
+		}
+	}
+	return uids, nil
+}
+
+// Attachments returns the attachments of message uid whose names match
+// Options.Attachment. The message is not marked seen.
+func (c *Client) Attachments(uid uint32) ([]Attachment, error) {
+	responses, err := c.cmd("UID FETCH %d BODY.PEEK[]", uid)
+	if err != nil {
+		return nil, fmt.Errorf("FETCH %d: %s", uid, err)
+	}
+	for _, r := range responses {
+		if strings.Contains(r.line, " FETCH ") && len(r.literals) > 0 {
+			pattern := c.opts.Attachment
+			if pattern == "" {
+				pattern = DefaultAttachment
+			}
+			return attachments(r.literals[0], pattern)
+		}
+	}
+	return nil, fmt.Errorf("FETCH %d: no message", uid)
+}
+
+// MarkSeen marks message uid seen, so that Unseen no longer returns it.
+func (c *Client) MarkSeen(uid uint32) error {
+	if _, err := c.cmd(`UID STORE %d +FLAGS.SILENT (\Seen)`, uid); err != nil {
+		return fmt.Errorf("STORE %d: %s", uid, err)
+	}
+	return nil
+}
+
+// response is an untagged response with the literals it contains.
+type response struct {
+	line     string
+	literals [][]byte
+}
+
+// cmd sends a command and returns its untagged responses, or the status
+// text as an error unless it completes with OK.
+func (c *Client) cmd(format string, args ...interface{}) ([]response, error) {
+	c.tag++
+	tag := fmt.Sprintf("a%d", c.tag)
+	c.conn.SetDeadline(time.Now().Add(time.Minute))
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, fmt.Sprintf(format, args...)); err != nil {
+		return nil, err
+	}
+	var responses []response
+	for {
+		line, err := c.r.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(line, tag+" ") {
+			status := strings.TrimPrefix(line, tag+" ")
+			if !strings.HasPrefix(status, "OK") {
+				return nil, errors.New(status)
+			}
+			return responses, nil
+		}
+		if !strings.HasPrefix(line, "* ") {
+			continue
+		}
+		r := response{line: line[2:]}
+		for {
+			n, ok := literalSize(line)
+			if !ok {
+				break
+			}
+			if n > maxLiteral {
+				return nil, fmt.Errorf("literal of %d bytes exceeds %d", n, maxLiteral)
+			}
+			literal := make([]byte, n)
+			if _, err := io.ReadFull(c.r.R, literal); err != nil {
+				return nil, err
+			}
+			r.literals = append(r.literals, literal)
+			if line, err = c.r.ReadLine(); err != nil {
+				return nil, err
+			}
+			r.line += line
+		}
+		responses = append(responses, r)
+	}
+}
+
+// literalSize returns the size n of the literal announced by {n} at the
+// end of line, if any.
+func literalSize(line string) (int, bool) {
+	if !strings.HasSuffix(line, "}") {
+		return 0, false
+	}
+	i := strings.LastIndexByte(line, '{')
+	if i < 0 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(line[i+1 : len(line)-1])
+	return n, err == nil && n >= 0
+}
+
+// quote returns s as an IMAP quoted string.
+func quote(s string) (string, error) {
+	if strings.ContainsAny(s, "\r\n") {
+		return "", errors.New("line breaks are not allowed")
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`, nil
+}
+
+// attachments returns the decoded attachments of message msg whose names
+// match pattern.
+func attachments(msg []byte, pattern string) ([]Attachment, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(msg))
+	if err != nil {
+		return nil, err
+	}
+	var found []Attachment
+	err = walk(textproto.MIMEHeader(m.Header), m.Body, strings.ToLower(pattern), &found)
+	return found, err
+}
+
+// walk adds the matching attachments of the MIME entity with header h and
+// body to found, descending into multipart entities.
+func walk(h textproto.MIMEHeader, body io.Reader, pattern string, found *[]Attachment) error {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := walk(p.Header, p, pattern, found); err != nil {
+				return err
+			}
+		}
+	}
+	name := fileName(h, params)
+	if name == "" {
+		return nil
+	}
+	if ok, err := path.Match(pattern, strings.ToLower(name)); err != nil || !ok {
+		return err
+	}
+	switch strings.ToLower(h.Get("Content-Transfer-Encoding")) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		// multipart.Reader decodes parts itself and drops the header.
+		body = quotedprintable.NewReader(body)
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return fmt.Errorf("attachment %s: %s", name, err)
+	}
+	*found = append(*found, Attachment{Name: name, Data: data})
+	return nil
+}
+
+var wordDecoder mime.WordDecoder
+
+// fileName returns the file name of the MIME entity with header h and
+// Content-Type parameters typeParams, without any directories.
+func fileName(h textproto.MIMEHeader, typeParams map[string]string) string {
+	var name string
+	if _, params, err := mime.ParseMediaType(h.Get("Content-Disposition")); err == nil {
+		name = params["filename"]
+	}
+	if name == "" {
+		name = typeParams["name"]
+	}
+	if decoded, err := wordDecoder.DecodeHeader(name); err == nil {
+		name = decoded
+	}
+	return name[strings.LastIndexAny(name, `/\`)+1:]
+}
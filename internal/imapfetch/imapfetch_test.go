@@ -0,0 +1,159 @@
+package imapfetch_test
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/joneskoo/etget/internal/imapfetch"
+)
+
+const message = "From: reports@utility.example\r\n" +
+	"Subject: Consumption report\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=outer\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=inner\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"Your report is attached.\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/json\r\n" +
+	"Content-Disposition: attachment; filename=\"Report.JSON\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"eyJIb3VyIjpb\r\n" +
+	"XX0=\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/json; name=\"=?utf-8?q?s=C3=A4hk=C3=B6.json?=\"\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"{\"a\":=\r\n" +
+	"1}\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/pdf\r\n" +
+	"Content-Disposition: attachment; filename=\"report.pdf\"\r\n" +
+	"\r\n" +
+	"%PDF\r\n" +
+	"--outer--\r\n"
+
+// fakeIMAP accepts one connection and answers the commands of a client
+// with one unseen message, recording the commands.
+func fakeIMAP(t *testing.T) (addr string, commands <-chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := make(chan string, 100)
+	go func() {
+		defer close(ch)
+		conn, err := l.Accept()
+		l.Close()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprint(conn, "* OK IMAP4rev1 ready\r\n")
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.SplitN(strings.TrimSuffix(line, "\r\n"), " ", 2)
+			tag, cmd := fields[0], fields[1]
+			ch <- cmd
+			switch {
+			case cmd == `LOGIN "user" "p\"ss"`:
+			case strings.HasPrefix(cmd, "LOGIN"):
+				fmt.Fprintf(conn, "%s NO authentication failed\r\n", tag)
+				continue
+			case strings.HasPrefix(cmd, "SELECT"):
+				fmt.Fprint(conn, "* 3 EXISTS\r\n")
+			case strings.HasPrefix(cmd, "UID SEARCH"):
+				fmt.Fprint(conn, "* SEARCH 7\r\n")
+			case cmd == "UID FETCH 7 BODY.PEEK[]":
+				fmt.Fprintf(conn, "* 2 FETCH (UID 7 BODY[] {%d}\r\n%s)\r\n", len(message), message)
+			case cmd == "LOGOUT":
+				fmt.Fprint(conn, "* BYE\r\n")
+			}
+			fmt.Fprintf(conn, "%s OK done\r\n", tag)
+		}
+	}()
+	return l.Addr().String(), ch
+}
+
+func TestClient(t *testing.T) {
+	addr, commands := fakeIMAP(t)
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := imapfetch.NewClient(conn, imapfetch.Options{
+		Username: "user",
+		Password: `p"ss`,
+		From:     "reports@utility.example",
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %s", err)
+	}
+	uids, err := c.Unseen()
+	if err != nil {
+		t.Fatalf("Unseen: %s", err)
+	}
+	if !reflect.DeepEqual(uids, []uint32{7}) {
+		t.Fatalf("Unseen = %v, want [7]", uids)
+	}
+	got, err := c.Attachments(7)
+	if err != nil {
+		t.Fatalf("Attachments: %s", err)
+	}
+	want := []imapfetch.Attachment{
+		{Name: "Report.JSON", Data: []byte(`{"Hour":[]}`)},
+		{Name: "sähkö.json", Data: []byte(`{"a":1}`)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Attachments = %q, want %q", got, want)
+	}
+	if err := c.MarkSeen(7); err != nil {
+		t.Fatalf("MarkSeen: %s", err)
+	}
+	if err := c.Logout(); err != nil {
+		t.Fatalf("Logout: %s", err)
+	}
+
+	var sent []string
+	for cmd := range commands {
+		sent = append(sent, cmd)
+	}
+	wantSent := []string{
+		`LOGIN "user" "p\"ss"`,
+		`SELECT "INBOX"`,
+		`UID SEARCH UNSEEN FROM "reports@utility.example"`,
+		`UID FETCH 7 BODY.PEEK[]`,
+		`UID STORE 7 +FLAGS.SILENT (\Seen)`,
+		`LOGOUT`,
+	}
+	if !reflect.DeepEqual(sent, wantSent) {
+		t.Errorf("commands sent:\n%s\nwant:\n%s", strings.Join(sent, "\n"), strings.Join(wantSent, "\n"))
+	}
+}
+
+func TestClientLoginFailure(t *testing.T) {
+	addr, _ := fakeIMAP(t)
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = imapfetch.NewClient(conn, imapfetch.Options{Username: "user", Password: "wrong"})
+	if err == nil || !strings.Contains(err.Error(), "authentication failed") {
+		t.Errorf("NewClient with a wrong password returned %v, want the server's refusal", err)
+	}
+}
@@ -0,0 +1,236 @@
+// Package ledger records completed imports in the imports table, so that
+// monitoring can tell when data was last loaded from each source.
+package ledger
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"os"
+	"time"
+
+	"github.com/joneskoo/etget/importer"
+)
+
+// CreateTableSQL creates the imports table, the import_files table of
+// input file digests and the import_cursors table of incremental imports.
+//
+// The timestamps default to clock_timestamp(), the time of the insert:
+// now() is the start of the transaction, which for a long import is long
+// before it finished.
+const CreateTableSQL = `CREATE TABLE IF NOT EXISTS imports (
+    id            SERIAL PRIMARY KEY,
+    source        TEXT NOT NULL,
+    finished_at   TIMESTAMPTZ NOT NULL DEFAULT clock_timestamp(),
+    rows_affected BIGINT NOT NULL
+    );
+    CREATE TABLE IF NOT EXISTS import_files (
+    import_id     INTEGER NOT NULL REFERENCES imports (id),
+    name          TEXT NOT NULL,
+    sha256        TEXT NOT NULL,
+    size          BIGINT NOT NULL
+    );
+    CREATE TABLE IF NOT EXISTS import_cursors (
+    source        TEXT NOT NULL,
+    key           TEXT NOT NULL,
+    last_ts       TIMESTAMPTZ NOT NULL,
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT clock_timestamp(),
+    PRIMARY KEY (source, key)
+    );`
+
+// Sources of imports.
+const (
+	SourceElspot      = "elspot"
+	SourceEnergiatili = "energiatili"
+	SourceEntsoe      = "entsoe"
+	SourceIntraday    = "intraday"
+	SourceFingrid     = "fingrid"
+	SourceCSV         = "csv"
+	SourceCapacity    = "capacity"
+	SourceFlow        = "flow"
+	SourceSync        = "sync"
+	SourceSpotHinta   = "spot-hinta"
+	SourceReplay      = "replay"
+)
+
+// Entry is a completed import.
+type Entry struct {
+	Source       string
+	FinishedAt   time.Time
+	RowsAffected int64
+}
+
+// File is an input file of an import. It is the importer.Input of the
+// batch, so that sinks can record the inputs without conversion.
+type File = importer.Input
+
+// Record adds an entry for source with its input files. It should be
+// called in the import transaction just before commit so that only
+// successful imports are recorded. The entry is timestamped when Record
+// runs, also in tables created with an older default.
+func Record(txn *sql.Tx, source string, rowsAffected int64, files ...File) error {
+	var id int64
+	err := txn.QueryRow("INSERT INTO imports (source, finished_at, rows_affected) VALUES ($1, clock_timestamp(), $2) RETURNING id", source, rowsAffected).Scan(&id)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		_, err = txn.Exec("INSERT INTO import_files (import_id, name, sha256, size) VALUES ($1, $2, $3, $4)", id, f.Name, f.SHA256, f.Size)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NotifyChannel is the Postgres channel Notify sends to.
+const NotifyChannel = "etget_prices"
+
+// Notification is the JSON payload of Notify: the rows of source from
+// From until To were added or changed.
+type Notification struct {
+	Source       string    `json:"source"`
+	From         time.Time `json:"from"`
+	To           time.Time `json:"to"`
+	RowsAffected int64     `json:"rows_affected"`
+}
+
+// Notify sends n to listeners of NotifyChannel in the import transaction.
+// Postgres delivers it when the transaction commits, and not at all if it
+// rolls back.
+func Notify(txn *sql.Tx, n Notification) error {
+	b, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	_, err = txn.Exec("SELECT pg_notify($1, $2)", NotifyChannel, string(b))
+	return err
+}
+
+// Cursor returns the last hour an incremental import of source loaded for
+// key, e.g. a metering point, or the zero time if there is none yet.
+func Cursor(db *sql.DB, source, key string) (last time.Time, err error) {
+	var exists bool
+	if err = db.QueryRow("SELECT to_regclass('import_cursors') IS NOT NULL").Scan(&exists); err != nil || !exists {
+		return time.Time{}, err
+	}
+	err = db.QueryRow("SELECT last_ts FROM import_cursors WHERE source = $1 AND key = $2", source, key).Scan(&last)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	return last, err
+}
+
+// SetCursor moves the cursor of source and key forward to last in the
+// import transaction. A cursor is never moved backwards.
+func SetCursor(txn *sql.Tx, source, key string, last time.Time) error {
+	_, err := txn.Exec(`INSERT INTO import_cursors AS c (source, key, last_ts, updated_at) VALUES ($1, $2, $3, clock_timestamp())
+    ON CONFLICT (source, key) DO UPDATE SET last_ts = GREATEST(c.last_ts, EXCLUDED.last_ts), updated_at = EXCLUDED.updated_at`, source, key, last)
+	return err
+}
+
+// ClearCursor removes the cursor of source and key in the import
+// transaction, once the import it tracked is complete.
+func ClearCursor(txn *sql.Tx, source, key string) error {
+	_, err := txn.Exec("DELETE FROM import_cursors WHERE source = $1 AND key = $2", source, key)
+	return err
+}
+
+// Files returns the most recently imported digest of each input file,
+// ordered by name.
+func Files(db *sql.DB) (files []File, err error) {
+	rows, err := db.Query(`SELECT DISTINCT ON (f.name) f.name, f.sha256, f.size
+    FROM import_files f JOIN imports i ON i.id = f.import_id
+    ORDER BY f.name, i.finished_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var f File
+		if err = rows.Scan(&f.Name, &f.SHA256, &f.Size); err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
+
+// FilesSince returns the input files of the imports from source finished
+// at or after since, ordered by their latest import, oldest first. Files
+// of the same contents are returned once.
+func FilesSince(db *sql.DB, source string, since time.Time) (files []File, err error) {
+	rows, err := db.Query(`SELECT min(f.name), f.sha256, max(f.size)
+    FROM import_files f JOIN imports i ON i.id = f.import_id
+    WHERE i.source = $1 AND i.finished_at >= $2
+    GROUP BY f.sha256 ORDER BY max(i.finished_at), min(f.name)`, source, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var f File
+		if err = rows.Scan(&f.Name, &f.SHA256, &f.Size); err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
+
+// Hash computes the File digest of the data written to it. Use it with
+// io.TeeReader to hash an input while it is parsed.
+type Hash struct {
+	h hash.Hash
+	n int64
+}
+
+// NewHash returns an empty Hash.
+func NewHash() *Hash {
+	return &Hash{h: sha256.New()}
+}
+
+func (h *Hash) Write(p []byte) (int, error) {
+	h.n += int64(len(p))
+	return h.h.Write(p)
+}
+
+// File returns the digest of the data written so far as the File name.
+func (h *Hash) File(name string) File {
+	return File{Name: name, SHA256: hex.EncodeToString(h.h.Sum(nil)), Size: h.n}
+}
+
+// HashFile reads and hashes the file name.
+func HashFile(name string) (File, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return File{}, err
+	}
+	defer f.Close()
+	h := NewHash()
+	if _, err = io.Copy(h, f); err != nil {
+		return File{}, err
+	}
+	return h.File(name), nil
+}
+
+// Latest returns the most recent entry of each source, ordered by source.
+func Latest(db *sql.DB) (entries []Entry, err error) {
+	rows, err := db.Query(`SELECT DISTINCT ON (source) source, finished_at, rows_affected
+    FROM imports ORDER BY source, finished_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e Entry
+		if err = rows.Scan(&e.Source, &e.FinishedAt, &e.RowsAffected); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
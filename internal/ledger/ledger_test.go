@@ -0,0 +1,98 @@
+package ledger
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joneskoo/etget/pgtest"
+)
+
+func TestMain(m *testing.M) { os.Exit(pgtest.Main(m)) }
+
+func TestHash(t *testing.T) {
+	const data = "hello\n"
+	const want = "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+
+	h := NewHash()
+	if _, err := io.Copy(ioutil.Discard, io.TeeReader(strings.NewReader(data), h)); err != nil {
+		t.Fatal(err)
+	}
+	if got := h.File("x"); got.SHA256 != want || got.Size != int64(len(data)) {
+		t.Errorf("Hash.File() = %+v, want sha256 %s size %d", got, want, len(data))
+	}
+
+	f, err := ioutil.TempFile("", "ledger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(data)
+	f.Close()
+
+	got, err := HashFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.SHA256 != want || got.Name != f.Name() {
+		t.Errorf("HashFile() = %+v, want sha256 %s", got, want)
+	}
+}
+
+// TestNotificationJSON pins the payload listeners parse.
+func TestNotificationJSON(t *testing.T) {
+	n := Notification{
+		Source:       SourceElspot,
+		From:         time.Date(2026, 10, 15, 22, 0, 0, 0, time.UTC),
+		To:           time.Date(2026, 10, 16, 22, 0, 0, 0, time.UTC),
+		RowsAffected: 24,
+	}
+	b, err := json.Marshal(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"source":"elspot","from":"2026-10-15T22:00:00Z","to":"2026-10-16T22:00:00Z","rows_affected":24}`
+	if string(b) != want {
+		t.Errorf("payload = %s, want %s", b, want)
+	}
+}
+
+func TestRecordFinishedAt(t *testing.T) {
+	db := pgtest.New(t)
+	defer db.Close()
+	if _, err := db.Exec(CreateTableSQL); err != nil {
+		t.Fatal(err)
+	}
+	txn, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer txn.Rollback()
+	var begun time.Time
+	if err := txn.QueryRow("SELECT now()").Scan(&begun); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := txn.Exec("SELECT pg_sleep(0.2)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Record(txn, SourceElspot, 24); err != nil {
+		t.Fatalf("Record: %s", err)
+	}
+	if err := txn.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := Latest(db.DB)
+	if err != nil {
+		t.Fatalf("Latest: %s", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	if d := entries[0].FinishedAt.Sub(begun); d < 200*time.Millisecond {
+		t.Errorf("finished_at is %s after the transaction began, want the time of Record", d)
+	}
+}
@@ -0,0 +1,27 @@
+// Package mmap maps input files into memory read-only, so that very large
+// files can be parsed and hashed without also copying them to the heap.
+package mmap
+
+// File is the contents of a file mapped into memory.
+type File struct {
+	data []byte
+
+	// unmap releases data, if it is mapped.
+	unmap func([]byte) error
+}
+
+// Bytes returns the contents of the file. They are only valid until Close
+// and must not be modified.
+func (f *File) Bytes() []byte {
+	return f.data
+}
+
+// Close releases the mapping.
+func (f *File) Close() error {
+	data := f.data
+	f.data = nil
+	if f.unmap == nil || data == nil {
+		return nil
+	}
+	return f.unmap(data)
+}
@@ -0,0 +1,16 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package mmap
+
+import "io/ioutil"
+
+// Open reads the file name into memory; this system has no mmap support
+// in the syscall package.
+func Open(name string) (*File, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return &File{data: data}, nil
+}
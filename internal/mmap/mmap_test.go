@@ -0,0 +1,47 @@
+package mmap_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/joneskoo/etget/internal/mmap"
+)
+
+func TestOpen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mmap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name, contents string
+	}{
+		{"elspot.xls", "<table><tr><td>1</td></tr></table>"},
+		{"empty.xls", ""},
+	}
+	for _, tt := range tests {
+		name := filepath.Join(dir, tt.name)
+		if err := ioutil.WriteFile(name, []byte(tt.contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+		f, err := mmap.Open(name)
+		if err != nil {
+			t.Fatalf("Open(%s): %s", tt.name, err)
+		}
+		if got := string(f.Bytes()); got != tt.contents {
+			t.Errorf("Open(%s).Bytes() = %q, want %q", tt.name, got, tt.contents)
+		}
+		if err := f.Close(); err != nil {
+			t.Errorf("Close(%s): %s", tt.name, err)
+		}
+		if err := f.Close(); err != nil {
+			t.Errorf("second Close(%s): %s", tt.name, err)
+		}
+	}
+	if _, err := mmap.Open(filepath.Join(dir, "missing.xls")); !os.IsNotExist(err) {
+		t.Errorf("Open(missing.xls) error = %v, want not exist", err)
+	}
+}
@@ -0,0 +1,49 @@
+// Package partition creates monthly range partitions of the imported
+// tables, so that old months can be detached or dropped cheaply and
+// queries on a time range only scan the months they touch.
+package partition
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Name returns the name of the partition of table for the month of t,
+// e.g. elspot_y2024m12.
+func Name(table string, t time.Time) string {
+	t = t.UTC()
+	return fmt.Sprintf("%s_y%04dm%02d", table, t.Year(), int(t.Month()))
+}
+
+// MonthlySQL returns statements creating the missing partitions of table
+// for the UTC months from first to last, inclusive.
+func MonthlySQL(table string, first, last time.Time) []string {
+	first, last = first.UTC(), last.UTC()
+	month := time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, time.UTC)
+	var stmts []string
+	for !month.After(last) {
+		next := month.AddDate(0, 1, 0)
+		stmts = append(stmts, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM (%s) TO (%s)",
+			pq.QuoteIdentifier(Name(table, month)), pq.QuoteIdentifier(table),
+			pq.QuoteLiteral(month.Format(time.RFC3339)), pq.QuoteLiteral(next.Format(time.RFC3339))))
+		month = next
+	}
+	return stmts
+}
+
+// Check returns an error if table is not partitioned. Tables created
+// before partitioning was enabled have to be migrated by hand.
+func Check(db *sql.DB, table string) error {
+	var ok bool
+	err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM pg_partitioned_table WHERE partrelid = $1::regclass)", table).Scan(&ok)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("table %s exists and is not partitioned", table)
+	}
+	return nil
+}
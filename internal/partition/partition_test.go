@@ -0,0 +1,28 @@
+package partition
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMonthlySQL(t *testing.T) {
+	helsinki, err := time.LoadLocation("Europe/Helsinki")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Midnight of January 1 in Helsinki is still December in UTC.
+	first := time.Date(2025, 1, 1, 0, 0, 0, 0, helsinki)
+	last := time.Date(2025, 1, 31, 23, 0, 0, 0, time.UTC)
+	got := MonthlySQL("elspot", first, last)
+	want := []string{
+		`CREATE TABLE IF NOT EXISTS "elspot_y2024m12" PARTITION OF "elspot" FOR VALUES FROM ('2024-12-01T00:00:00Z') TO ('2025-01-01T00:00:00Z')`,
+		`CREATE TABLE IF NOT EXISTS "elspot_y2025m01" PARTITION OF "elspot" FOR VALUES FROM ('2025-01-01T00:00:00Z') TO ('2025-02-01T00:00:00Z')`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MonthlySQL() =\n%q\nwant\n%q", got, want)
+	}
+	if got := MonthlySQL("elspot", last, first); len(got) != 0 {
+		t.Errorf("MonthlySQL with last before first = %q, want none", got)
+	}
+}
@@ -0,0 +1,67 @@
+// Package preset provides per-country defaults, so that users outside
+// Finland need one setting instead of several matching ones.
+package preset
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joneskoo/etget/internal/zoneinfo"
+)
+
+// Preset is the defaults of a country.
+type Preset struct {
+	// Name is the preset name, the lowercased ISO 3166 country code.
+	Name string
+
+	// Location is the IANA time zone of days and hours shown to users.
+	Location string
+
+	// Currency is the ISO 4217 code of consumer prices. Elspot prices
+	// are published in EUR in every area.
+	Currency string
+
+	// VAT is the general value added tax rate on electricity.
+	VAT float64
+
+	// Areas are the elspot bidding areas of the country. Area is the one
+	// used where a single price is shown, the area of the capital.
+	Areas []string
+	Area  string
+}
+
+// Column returns the elspot table column of the price of Area.
+func (p Preset) Column() string { return strings.ToLower(p.Area) }
+
+// LoadLocation returns the time zone of the preset.
+func (p Preset) LoadLocation() (*time.Location, error) { return zoneinfo.Load(p.Location) }
+
+var presets = map[string]Preset{
+	"fi": {Location: "Europe/Helsinki", Currency: "EUR", VAT: 0.255, Areas: []string{"FI"}, Area: "FI"},
+	"se": {Location: "Europe/Stockholm", Currency: "SEK", VAT: 0.25, Areas: []string{"SE1", "SE2", "SE3", "SE4"}, Area: "SE3"},
+	"no": {Location: "Europe/Oslo", Currency: "NOK", VAT: 0.25, Areas: []string{"NO1", "NO2", "NO3", "NO4", "NO5"}, Area: "NO1"},
+	"ee": {Location: "Europe/Tallinn", Currency: "EUR", VAT: 0.24, Areas: []string{"EE"}, Area: "EE"},
+}
+
+// Names returns the names of the presets, sorted.
+func Names() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the preset name, case-insensitively.
+func Lookup(name string) (Preset, error) {
+	name = strings.ToLower(name)
+	p, ok := presets[name]
+	if !ok {
+		return Preset{}, fmt.Errorf("unknown preset %q, want one of %s", name, strings.Join(Names(), ", "))
+	}
+	p.Name = name
+	return p, nil
+}
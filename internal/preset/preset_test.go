@@ -0,0 +1,35 @@
+package preset_test
+
+import (
+	"testing"
+
+	"github.com/joneskoo/etget/internal/preset"
+)
+
+func TestPresets(t *testing.T) {
+	for _, name := range preset.Names() {
+		p, err := preset.Lookup(name)
+		if err != nil {
+			t.Fatalf("Lookup(%s): %s", name, err)
+		}
+		if _, err := p.LoadLocation(); err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+		found := false
+		for _, a := range p.Areas {
+			found = found || a == p.Area
+		}
+		if !found {
+			t.Errorf("%s: area %s not in %v", name, p.Area, p.Areas)
+		}
+		if p.VAT <= 0 || p.VAT >= 1 || len(p.Currency) != 3 {
+			t.Errorf("%s: VAT %v, currency %q", name, p.VAT, p.Currency)
+		}
+	}
+	if p, err := preset.Lookup("SE"); err != nil || p.Column() != "se3" {
+		t.Errorf("Lookup(SE) = %+v, %v; want column se3", p, err)
+	}
+	if _, err := preset.Lookup("dk"); err == nil {
+		t.Error("Lookup(dk) did not return error")
+	}
+}
@@ -0,0 +1,199 @@
+// Package pricecache keeps the upcoming elspot prices in Redis, so that
+// consumers polling often, such as heating controllers, do not query
+// PostgreSQL.
+//
+// Each area is a hash named by Key with a field per hour, the start of the
+// hour in RFC 3339 UTC, holding the price in EUR/MWh as in the elspot file.
+package pricecache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joneskoo/etget/elspot"
+)
+
+// Window is how far ahead prices are cached. The hashes expire after it
+// too, so a stopped importer does not leave stale prices behind forever.
+const Window = 48 * time.Hour
+
+// Key returns the name of the hash of area column, e.g. "etget:prices:fi".
+func Key(column string) string { return "etget:prices:" + strings.ToLower(column) }
+
+// Client is a connection to a Redis server speaking RESP.
+type Client struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// Dial connects to the server at rawurl, "redis://[:password@]host[:port][/db]"
+// or plain "host:port".
+func Dial(rawurl string) (*Client, error) {
+	addr, password, db := rawurl, "", ""
+	if strings.Contains(rawurl, "://") {
+		u, err := url.Parse(rawurl)
+		if err != nil {
+			return nil, err
+		}
+		if u.Scheme != "redis" {
+			return nil, fmt.Errorf("unsupported scheme %q, want redis", u.Scheme)
+		}
+		addr = u.Host
+		if p, ok := u.User.Password(); ok {
+			password = p
+		}
+		db = strings.TrimPrefix(u.Path, "/")
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "6379")
+	}
+	conn, err := net.DialTimeout("tcp", addr, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{conn: conn, r: bufio.NewReader(conn)}
+	if password != "" {
+		if _, err := c.Do("AUTH", password); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("AUTH: %s", err)
+		}
+	}
+	if db != "" {
+		if _, err := c.Do("SELECT", db); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("SELECT %s: %s", db, err)
+		}
+	}
+	return c, nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() error { return c.conn.Close() }
+
+// Do sends a command and returns its reply: a string, an int64, nil or a
+// []interface{} of those. Error replies are returned as errors.
+func (c *Client) Do(args ...string) (interface{}, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	c.conn.SetDeadline(time.Now().Add(30 * time.Second))
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return readReply(c.r)
+}
+
+// replyError is an error reply of the server.
+type replyError string
+
+func (e replyError) Error() string { return string(e) }
+
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("malformed reply %q", line)
+	}
+	kind, line := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return line, nil
+	case '-':
+		return nil, replyError(line)
+	case ':':
+		return strconv.ParseInt(line, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			item, err := readReply(r)
+			if _, ok := err.(replyError); err != nil && !ok {
+				return nil, err
+			}
+			if err != nil {
+				item = err
+			}
+			items[i] = item
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unknown reply type %q", kind)
+}
+
+// Area is a price column to cache: the area of elspot.Record.Prices and
+// the column name used in the key.
+type Area struct {
+	Area, Column string
+}
+
+// Write replaces the cached prices of areas with those of the hours from
+// the start of the current hour to Window ahead, atomically for all areas.
+// Areas without such hours in records, e.g. in a backfill of old files,
+// keep their cached prices. It returns the number of hours cached.
+func Write(c *Client, records []elspot.Record, areas []Area, now time.Time) (hours int, err error) {
+	from := now.Truncate(time.Hour)
+	to := from.Add(Window)
+	cmds := [][]string{{"MULTI"}}
+	for _, a := range areas {
+		key := Key(a.Column)
+		hset := []string{"HSET", key}
+		for _, r := range records {
+			if r.Timestamp.Before(from) || !r.Timestamp.Before(to) {
+				continue
+			}
+			if p := r.Prices[a.Area]; p != "" {
+				hset = append(hset, r.Timestamp.UTC().Format(time.RFC3339), p)
+			}
+		}
+		if len(hset) > 2 {
+			cmds = append(cmds, []string{"DEL", key}, hset, []string{"EXPIRE", key, strconv.Itoa(int(Window.Seconds()))})
+			if n := (len(hset) - 2) / 2; n > hours {
+				hours = n
+			}
+		}
+	}
+	cmds = append(cmds, []string{"EXEC"})
+	for _, cmd := range cmds {
+		reply, err := c.Do(cmd...)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %s", cmd[0], err)
+		}
+		if cmd[0] != "EXEC" {
+			continue
+		}
+		results, ok := reply.([]interface{})
+		if !ok {
+			return 0, errors.New("transaction aborted")
+		}
+		for _, r := range results {
+			if err, ok := r.(error); ok {
+				return 0, err
+			}
+		}
+	}
+	return hours, nil
+}
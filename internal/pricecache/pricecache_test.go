@@ -0,0 +1,148 @@
+package pricecache_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joneskoo/etget/elspot"
+	"github.com/joneskoo/etget/internal/pricecache"
+)
+
+// fakeRedis accepts one connection and records its commands, replying
+// like Redis does inside MULTI.
+func fakeRedis(t *testing.T) (addr string, commands <-chan []string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := make(chan []string, 100)
+	go func() {
+		defer close(ch)
+		conn, err := l.Accept()
+		l.Close()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		queued := 0
+		for {
+			cmd, err := readCommand(r)
+			if err != nil {
+				return
+			}
+			ch <- cmd
+			switch cmd[0] {
+			case "MULTI", "AUTH", "SELECT":
+				io.WriteString(conn, "+OK\r\n")
+			case "EXEC":
+				fmt.Fprintf(conn, "*%d\r\n%s", queued, strings.Repeat(":1\r\n", queued))
+				queued = 0
+			default:
+				queued++
+				io.WriteString(conn, "+QUEUED\r\n")
+			}
+		}
+	}()
+	return l.Addr().String(), ch
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	var n int
+	if _, err := fmt.Fscanf(r, "*%d\r\n", &n); err != nil {
+		return nil, err
+	}
+	cmd := make([]string, n)
+	for i := range cmd {
+		var size int
+		if _, err := fmt.Fscanf(r, "$%d\r\n", &size); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		cmd[i] = string(buf[:size])
+	}
+	return cmd, nil
+}
+
+func TestWrite(t *testing.T) {
+	addr, commands := fakeRedis(t)
+	c, err := pricecache.Dial("redis://:secret@" + addr + "/2")
+	if err != nil {
+		t.Fatalf("Dial: %s", err)
+	}
+
+	now := time.Date(2026, 10, 16, 10, 30, 0, 0, time.UTC)
+	var records []elspot.Record
+	for h := -2; h < 50; h++ {
+		records = append(records, elspot.Record{
+			Timestamp: now.Truncate(time.Hour).Add(time.Duration(h) * time.Hour),
+			Prices:    map[string]string{"FI": strconv.Itoa(h), "SE3": "1"},
+		})
+	}
+	hours, err := pricecache.Write(c, records, []pricecache.Area{{"FI", "fi"}}, now)
+	if err != nil {
+		t.Fatalf("Write: %s", err)
+	}
+	c.Close()
+	if hours != 48 {
+		t.Errorf("cached %d hours, want 48", hours)
+	}
+
+	var got [][]string
+	for cmd := range commands {
+		got = append(got, cmd)
+	}
+	if len(got) != 7 {
+		t.Fatalf("got commands %q, want AUTH, SELECT, MULTI, DEL, HSET, EXPIRE, EXEC", got)
+	}
+	if !reflect.DeepEqual(got[0], []string{"AUTH", "secret"}) || !reflect.DeepEqual(got[1], []string{"SELECT", "2"}) {
+		t.Errorf("handshake = %q", got[:2])
+	}
+	hset := got[4]
+	if hset[0] != "HSET" || hset[1] != "etget:prices:fi" || len(hset) != 2+2*48 {
+		t.Fatalf("HSET = %q", hset[:4])
+	}
+	if hset[2] != "2026-10-16T10:00:00Z" || hset[3] != "0" {
+		t.Errorf("first field = %s %s, want the current hour", hset[2], hset[3])
+	}
+	if !reflect.DeepEqual(got[5], []string{"EXPIRE", "etget:prices:fi", "172800"}) {
+		t.Errorf("EXPIRE = %q", got[5])
+	}
+}
+
+// TestWriteOld checks that importing old prices leaves the cache alone.
+func TestWriteOld(t *testing.T) {
+	addr, commands := fakeRedis(t)
+	c, err := pricecache.Dial(addr)
+	if err != nil {
+		t.Fatalf("Dial: %s", err)
+	}
+	now := time.Date(2026, 10, 16, 10, 30, 0, 0, time.UTC)
+	records := []elspot.Record{{
+		Timestamp: time.Date(2015, 10, 25, 0, 0, 0, 0, time.UTC),
+		Prices:    map[string]string{"FI": "20.51"},
+	}}
+	hours, err := pricecache.Write(c, records, []pricecache.Area{{"FI", "fi"}}, now)
+	if err != nil {
+		t.Fatalf("Write: %s", err)
+	}
+	c.Close()
+	if hours != 0 {
+		t.Errorf("cached %d hours, want 0", hours)
+	}
+	for cmd := range commands {
+		if cmd[0] != "MULTI" && cmd[0] != "EXEC" {
+			t.Errorf("command %q changes the cached prices", cmd)
+		}
+	}
+}
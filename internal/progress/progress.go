@@ -0,0 +1,126 @@
+// Package progress writes the progress of an import as newline-delimited
+// JSON events, for programs that run the importers as a subprocess and
+// show a progress bar.
+//
+// Each line is one Event. A run is a sequence of stages, each of a known
+// number of steps; Percent and ETA are of the current stage. The last
+// event of a successful run has stage "done".
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Event is the state of a stage after a step.
+type Event struct {
+	Time  time.Time `json:"time"`
+	Stage string    `json:"stage"`
+
+	// Detail names what the stage works on, such as an input file or a
+	// redacted connection string.
+	Detail string `json:"detail,omitempty"`
+
+	// Rows is the number of rows the stage has handled so far.
+	Rows int64 `json:"rows"`
+
+	// Percent is the share of the steps of the stage done.
+	Percent float64 `json:"percent"`
+
+	// ETA is the estimated seconds until the stage ends, from the pace of
+	// the steps done so far. It is left out until there is one.
+	ETA float64 `json:"eta,omitempty"`
+}
+
+// Reporter writes events to a writer. A nil *Reporter writes nothing, so
+// callers need not check whether progress events were requested.
+type Reporter struct {
+	// Now returns the current time; time.Now if nil.
+	Now func() time.Time
+
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// New returns a Reporter writing to w.
+func New(w io.Writer) *Reporter {
+	return &Reporter{enc: json.NewEncoder(w)}
+}
+
+func (r *Reporter) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+func (r *Reporter) write(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// A frontend that went away must not fail the import.
+	_ = r.enc.Encode(e)
+}
+
+// Stage is a stage of a run in progress.
+type Stage struct {
+	r      *Reporter
+	name   string
+	detail string
+	total  int
+	done   int
+	start  time.Time
+}
+
+// Stage starts a stage of total steps and writes its first event.
+func (r *Reporter) Stage(name, detail string, total int) *Stage {
+	if r == nil {
+		return nil
+	}
+	s := &Stage{r: r, name: name, detail: detail, total: total, start: r.now()}
+	s.emit(0)
+	return s
+}
+
+// Step records a step done, with the rows handled by the stage so far.
+func (s *Stage) Step(rows int64) {
+	if s == nil {
+		return
+	}
+	if s.done < s.total {
+		s.done++
+	}
+	s.emit(rows)
+}
+
+// Done ends the stage with rows handled, whatever steps were left.
+func (s *Stage) Done(rows int64) {
+	if s == nil {
+		return
+	}
+	s.done = s.total
+	s.emit(rows)
+}
+
+func (s *Stage) emit(rows int64) {
+	now := s.r.now()
+	e := Event{Time: now.UTC(), Stage: s.name, Detail: s.detail, Rows: rows, Percent: 100}
+	if s.total > 0 {
+		e.Percent = float64(s.done) * 100 / float64(s.total)
+	}
+	if s.done > 0 && s.done < s.total {
+		perStep := now.Sub(s.start).Seconds() / float64(s.done)
+		e.ETA = perStep * float64(s.total-s.done)
+	}
+	s.r.write(e)
+}
+
+// Done writes the final event of a successful run, with the rows of the
+// whole run.
+func (r *Reporter) Done(rows int64) {
+	if r == nil {
+		return
+	}
+	r.write(Event{Time: r.now().UTC(), Stage: "done", Rows: rows, Percent: 100})
+}
@@ -0,0 +1,60 @@
+package progress_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/joneskoo/etget/internal/progress"
+)
+
+func TestStage(t *testing.T) {
+	var buf bytes.Buffer
+	r := progress.New(&buf)
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	r.Now = func() time.Time { return now }
+
+	s := r.Stage("load", "dbname=prices", 4)
+	now = now.Add(10 * time.Second)
+	s.Step(100)
+	now = now.Add(10 * time.Second)
+	s.Step(250)
+	s.Done(400)
+	r.Done(400)
+
+	want := []progress.Event{
+		{Stage: "load", Detail: "dbname=prices", Rows: 0, Percent: 0},
+		{Stage: "load", Detail: "dbname=prices", Rows: 100, Percent: 25, ETA: 30},
+		{Stage: "load", Detail: "dbname=prices", Rows: 250, Percent: 50, ETA: 20},
+		{Stage: "load", Detail: "dbname=prices", Rows: 400, Percent: 100},
+		{Stage: "done", Rows: 400, Percent: 100},
+	}
+	sc := bufio.NewScanner(&buf)
+	var got []progress.Event
+	for sc.Scan() {
+		var e progress.Event
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatalf("line %q: %s", sc.Text(), err)
+		}
+		e.Time = time.Time{}
+		got = append(got, e)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d:\n%+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestNilReporter(t *testing.T) {
+	var r *progress.Reporter
+	s := r.Stage("parse", "", 1)
+	s.Step(1)
+	s.Done(1)
+	r.Done(1)
+}
@@ -0,0 +1,154 @@
+// Package pubcal is the publication calendar of day-ahead prices: when the
+// prices of a delivery day are due, how many hours the day has, and the
+// exceptions announced by the exchange, such as an early auction before a
+// holiday or a day whose results are delayed.
+//
+// Days are calendar days in the calendar's time zone, so the days on which
+// summer time starts and ends have 23 and 25 hours.
+package pubcal
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultDue is the local time of the day before delivery by which the
+// prices are expected when Options does not set one. Nord Pool publishes
+// the results of the noon auction at about 13:45 Finnish time.
+const DefaultDue = "14:00"
+
+// Options configures the calendar, e.g.
+//
+//	{"due": "14:00", "exceptions": [{"day": "2026-12-25", "due": "15:30", "note": "late holiday auction"}]}
+type Options struct {
+	// Due is the local time "15:04" of the day before delivery by which
+	// the prices are expected (default DefaultDue).
+	Due string `json:"due"`
+
+	Exceptions []Exception `json:"exceptions"`
+}
+
+// Exception changes the schedule of one delivery day.
+type Exception struct {
+	// Day is the delivery day, YYYY-MM-DD.
+	Day string `json:"day"`
+
+	// Due replaces the due time of the day.
+	Due string `json:"due,omitempty"`
+
+	// Skip tells that the prices of the day are not published, so their
+	// absence is expected.
+	Skip bool `json:"skip,omitempty"`
+
+	// Note is shown in reports of the day.
+	Note string `json:"note,omitempty"`
+}
+
+// Calendar is the schedule in a time zone.
+type Calendar struct {
+	loc        *time.Location
+	due        time.Duration
+	exceptions map[string]exception
+}
+
+type exception struct {
+	due  time.Duration // -1 if not set
+	skip bool
+	note string
+}
+
+// parseClock parses "15:04" as the time since midnight.
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("time %q: want HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// New returns the calendar of o in loc.
+func (o Options) New(loc *time.Location) (*Calendar, error) {
+	due := o.Due
+	if due == "" {
+		due = DefaultDue
+	}
+	c := &Calendar{loc: loc, exceptions: make(map[string]exception)}
+	var err error
+	if c.due, err = parseClock(due); err != nil {
+		return nil, fmt.Errorf("calendar due: %s", err)
+	}
+	for _, e := range o.Exceptions {
+		if _, err := time.Parse("2006-01-02", e.Day); err != nil {
+			return nil, fmt.Errorf("calendar exception: day %q: want YYYY-MM-DD", e.Day)
+		}
+		x := exception{due: -1, skip: e.Skip, note: e.Note}
+		if e.Due != "" {
+			if x.due, err = parseClock(e.Due); err != nil {
+				return nil, fmt.Errorf("calendar exception %s: %s", e.Day, err)
+			}
+		}
+		c.exceptions[e.Day] = x
+	}
+	return c, nil
+}
+
+// Day returns the start of the delivery day containing t.
+func (c *Calendar) Day(t time.Time) time.Time {
+	t = t.In(c.loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, c.loc)
+}
+
+// next returns the start of the day after day.
+func (c *Calendar) next(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, c.loc)
+}
+
+// Hours returns the number of hours of the delivery day containing t: 23
+// or 25 on the days the clocks change, otherwise 24.
+func (c *Calendar) Hours(t time.Time) int {
+	day := c.Day(t)
+	return int(c.next(day).Sub(day) / time.Hour)
+}
+
+// Status is the state of the prices of a delivery day.
+type Status struct {
+	Day time.Time
+
+	// Hours is the number of hours of the day, and Rows the number of
+	// them with a price.
+	Hours, Rows int
+
+	// Due is when the prices are expected; zero if they are skipped.
+	Due     time.Time
+	Skipped bool
+	Note    string
+}
+
+// Complete reports whether every hour of the day has a price.
+func (s Status) Complete() bool { return s.Rows >= s.Hours }
+
+// Overdue reports whether the prices are incomplete after they were due.
+// Skipped days are never overdue.
+func (s Status) Overdue(now time.Time) bool {
+	return !s.Skipped && !s.Complete() && !now.Before(s.Due)
+}
+
+// Check returns the status of the delivery day containing day, which has
+// prices for rows hours.
+func (c *Calendar) Check(day time.Time, rows int) Status {
+	start := c.Day(day)
+	s := Status{Day: start, Hours: c.Hours(start), Rows: rows}
+	due := c.due
+	if x, ok := c.exceptions[start.Format("2006-01-02")]; ok {
+		s.Skipped, s.Note = x.skip, x.note
+		if x.due >= 0 {
+			due = x.due
+		}
+	}
+	if !s.Skipped {
+		// Wall clock time of the day before, also when the clocks
+		// change that day.
+		s.Due = time.Date(start.Year(), start.Month(), start.Day()-1, 0, int(due/time.Minute), 0, 0, c.loc)
+	}
+	return s
+}
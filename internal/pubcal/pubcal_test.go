@@ -0,0 +1,69 @@
+package pubcal_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joneskoo/etget/internal/pubcal"
+	"github.com/joneskoo/etget/internal/zoneinfo"
+)
+
+func TestCheck(t *testing.T) {
+	helsinki, err := zoneinfo.Load("Europe/Helsinki")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cal, err := pubcal.Options{Exceptions: []pubcal.Exception{
+		{Day: "2026-12-25", Due: "15:30", Note: "late holiday auction"},
+		{Day: "2026-12-26", Skip: true},
+	}}.New(helsinki)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(s string) time.Time {
+		ts, err := time.ParseInLocation("2006-01-02 15:04", s, helsinki)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+	cases := []struct {
+		day     string
+		rows    int
+		now     string
+		hours   int
+		overdue bool
+	}{
+		{"2026-10-16 12:00", 0, "2026-10-15 13:59", 24, false},
+		{"2026-10-16 12:00", 0, "2026-10-15 14:00", 24, true},
+		{"2026-10-16 12:00", 24, "2026-10-15 18:00", 24, false},
+		// Summer time ends: 25 hours, so 24 rows are not complete.
+		{"2026-10-25 00:00", 24, "2026-10-24 18:00", 25, true},
+		{"2026-10-25 00:00", 25, "2026-10-24 18:00", 25, false},
+		// Summer time starts: 23 hours are complete.
+		{"2026-03-29 00:00", 23, "2026-03-28 18:00", 23, false},
+		// Summer time starts on the day before delivery; still 14:00.
+		{"2026-03-30 00:00", 0, "2026-03-29 13:30", 24, false},
+		{"2026-12-25 00:00", 0, "2026-12-24 15:00", 24, false},
+		{"2026-12-25 00:00", 0, "2026-12-24 15:30", 24, true},
+		{"2026-12-26 00:00", 0, "2026-12-26 20:00", 24, false},
+	}
+	for _, c := range cases {
+		s := cal.Check(at(c.day), c.rows)
+		if s.Hours != c.hours || s.Overdue(at(c.now)) != c.overdue {
+			t.Errorf("Check(%s, %d) at %s = %d hours, overdue %v; want %d, %v", c.day, c.rows, c.now, s.Hours, s.Overdue(at(c.now)), c.hours, c.overdue)
+		}
+	}
+}
+
+func TestOptionsErrors(t *testing.T) {
+	for _, o := range []pubcal.Options{
+		{Due: "2pm"},
+		{Exceptions: []pubcal.Exception{{Day: "25.12.2026"}}},
+		{Exceptions: []pubcal.Exception{{Day: "2026-12-25", Due: "25:00"}}},
+	} {
+		if _, err := o.New(time.UTC); err == nil {
+			t.Errorf("%+v: want error", o)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Package runreport writes a machine-readable JSON report of each import
+// run, so that pipelines can archive exactly what a run read, how it was
+// configured and what it changed.
+package runreport
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/joneskoo/etget/internal/hook"
+	"github.com/joneskoo/etget/internal/ledger"
+	"github.com/joneskoo/etget/internal/target"
+)
+
+// Report is the record of one run. The zero Dir disables writing it.
+type Report struct {
+	Command  string            `json:"command"`
+	Started  time.Time         `json:"started"`
+	Finished time.Time         `json:"finished"`
+	Options  map[string]string `json:"options"`
+	Inputs   []ledger.File     `json:"inputs"`
+	Targets  []Target          `json:"targets"`
+	Warnings []string          `json:"warnings"`
+
+	// Error is the error that ended the run, if any.
+	Error string `json:"error,omitempty"`
+
+	// Dir is the directory the report is written to.
+	Dir string `json:"-"`
+}
+
+// Target is the outcome of loading one database.
+type Target struct {
+	Target       string `json:"target"`
+	RowsAffected int64  `json:"rows_affected"`
+	Error        string `json:"error,omitempty"`
+
+	// Rows compares the incoming rows with the stored ones, for
+	// telling runs that changed nothing from those applying corrections.
+	Rows *target.Stats `json:"rows,omitempty"`
+}
+
+// New starts a report of command with the values of the flags in fs.
+// Flag values that are connection strings have their passwords redacted.
+func New(command, dir string, fs *flag.FlagSet) *Report {
+	r := &Report{
+		Command:  command,
+		Started:  time.Now().UTC(),
+		Options:  make(map[string]string),
+		Inputs:   []ledger.File{},
+		Targets:  []Target{},
+		Warnings: []string{},
+		Dir:      dir,
+	}
+	fs.VisitAll(func(f *flag.Flag) {
+		v := f.Value.String()
+		if strings.Contains(f.Name, "connstring") {
+			v = target.Redact(v)
+		}
+		r.Options[f.Name] = v
+	})
+	return r
+}
+
+// Warnf records a warning and logs it.
+func (r *Report) Warnf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	r.Warnings = append(r.Warnings, msg)
+	log.Printf("WARNING %s", msg)
+}
+
+// SetTargets records the results of target.Load.
+func (r *Report) SetTargets(results []target.Result) {
+	r.Targets = make([]Target, len(results))
+	for i, res := range results {
+		r.Targets[i] = Target{Target: res.Target, RowsAffected: res.RowsAffected, Rows: res.Stats}
+		if res.Err != nil {
+			r.Targets[i].Error = res.Err.Error()
+		}
+	}
+}
+
+// Fatalf records the error, writes the report and exits like log.Fatalf.
+func (r *Report) Fatalf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	r.Error = strings.TrimPrefix(msg, "ERROR ")
+	if err := r.Write(); err != nil {
+		log.Printf("ERROR writing run report: %s", err)
+	}
+	log.Fatal(msg)
+}
+
+// Finish ends a run that loaded every target: it runs hooks with the
+// report, recording their failures as warnings, and then writes it.
+func (r *Report) Finish(hooks []hook.Hook) error {
+	r.Finished = time.Now().UTC()
+	failed := r.Error != ""
+	for _, t := range r.Targets {
+		failed = failed || t.Error != ""
+	}
+	if !failed && len(hooks) > 0 {
+		b, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		for _, err := range hook.Run(context.Background(), hooks, r.Command, b) {
+			r.Warnf("%s", err)
+		}
+	}
+	return r.Write()
+}
+
+// Write writes the report to a new file in Dir, named after the command
+// and start time. It does nothing if Dir is empty.
+func (r *Report) Write() error {
+	if r.Dir == "" {
+		return nil
+	}
+	r.Finished = time.Now().UTC()
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(r.Dir, 0755); err != nil {
+		return err
+	}
+	name := filepath.Join(r.Dir, fmt.Sprintf("%s-%s.json", r.Command, r.Started.Format("20060102T150405.000000000Z")))
+	return ioutil.WriteFile(name, append(b, '\n'), 0644)
+}
@@ -0,0 +1,96 @@
+package runreport
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/joneskoo/etget/internal/hook"
+	"github.com/joneskoo/etget/internal/ledger"
+	"github.com/joneskoo/etget/internal/target"
+)
+
+func TestWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "runreport")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fs := flag.NewFlagSet("import-elspot", flag.ContinueOnError)
+	fs.String("connstring", "host=db password=secret", "")
+	fs.Bool("per-area", true, "")
+	r := New("import-elspot", dir, fs)
+	r.Inputs = append(r.Inputs, ledger.File{Name: "/data/elspot.xls", SHA256: "ab", Size: 2})
+	r.Warnf("%d rows skipped", 3)
+	r.SetTargets([]target.Result{{Target: "host=db", RowsAffected: 24, Stats: &target.Stats{New: 24, Identical: 1}}, {Target: "host=b", Err: errors.New("refused")}})
+	if err = r.Write(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "import-elspot-*.json"))
+	if len(files) != 1 {
+		t.Fatalf("got report files %v, want one", files)
+	}
+	b, err := ioutil.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Report
+	if err = json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Options["connstring"] != "host=db password=xxxxx" || got.Options["per-area"] != "true" {
+		t.Errorf("Options = %v, want redacted connstring and per-area", got.Options)
+	}
+	if len(got.Inputs) != 1 || got.Inputs[0].SHA256 != "ab" {
+		t.Errorf("Inputs = %+v", got.Inputs)
+	}
+	if len(got.Warnings) != 1 || got.Warnings[0] != "3 rows skipped" {
+		t.Errorf("Warnings = %q", got.Warnings)
+	}
+	if len(got.Targets) != 2 || got.Targets[0].RowsAffected != 24 || got.Targets[1].Error != "refused" {
+		t.Errorf("Targets = %+v", got.Targets)
+	} else if rows := got.Targets[0].Rows; rows == nil || *rows != (target.Stats{New: 24, Identical: 1}) || got.Targets[1].Rows != nil {
+		t.Errorf("Targets rows = %v, %v; want 24 new 1 identical and none", rows, got.Targets[1].Rows)
+	}
+	if got.Finished.Before(got.Started) {
+		t.Errorf("Finished %s before Started %s", got.Finished, got.Started)
+	}
+}
+
+func TestFinishHooks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "runreport")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "hook.json")
+	hooks := []hook.Hook{{Command: "cat > " + out}}
+	r := New("import-elspot", "", flag.NewFlagSet("import-elspot", flag.ContinueOnError))
+	r.SetTargets([]target.Result{{Target: "host=db", RowsAffected: 24}, {Target: "host=b", Err: errors.New("refused")}})
+	if err = r.Finish(hooks); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Fatal("hook ran after a failed target")
+	}
+
+	r.SetTargets([]target.Result{{Target: "host=db", RowsAffected: 24}})
+	if err = r.Finish(hooks); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatalf("hook did not run: %s", err)
+	}
+	var got Report
+	if err := json.Unmarshal(b, &got); err != nil || got.Command != "import-elspot" || len(got.Targets) != 1 {
+		t.Errorf("hook got %s, %v; want the report", b, err)
+	}
+}
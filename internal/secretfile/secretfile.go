@@ -0,0 +1,107 @@
+// Package secretfile reads files that may be encrypted with age or GPG,
+// so that the configuration and credential files can be kept in e.g. a
+// dotfiles repository. Decryption runs the age or gpg command; plain files
+// are read as they are.
+package secretfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// IdentityEnv is the environment variable naming the age identity file
+// that decrypts age files.
+const IdentityEnv = "ETGET_AGE_IDENTITY"
+
+// Commands run to decrypt, replaceable in tests.
+var (
+	AgeCommand = "age"
+	GPGCommand = "gpg"
+)
+
+// Format is the encryption of a file.
+type Format int
+
+const (
+	Plain Format = iota
+	Age
+	GPG
+)
+
+func (f Format) String() string {
+	switch f {
+	case Age:
+		return "age"
+	case GPG:
+		return "gpg"
+	}
+	return "plain"
+}
+
+// Detect returns the encryption of the file contents b.
+func Detect(b []byte) Format {
+	switch {
+	case bytes.HasPrefix(b, []byte("age-encryption.org/")),
+		bytes.HasPrefix(b, []byte("-----BEGIN AGE ENCRYPTED FILE-----")):
+		return Age
+	case bytes.HasPrefix(b, []byte("-----BEGIN PGP MESSAGE-----")):
+		return GPG
+	case len(b) > 0 && b[0]&0x80 != 0:
+		// A binary OpenPGP message starts with the packet of the session
+		// key encrypted to a public key (tag 1) or a passphrase (tag 3).
+		tag := b[0] & 0x3f
+		if b[0]&0x40 == 0 {
+			tag = (b[0] & 0x3c) >> 2
+		}
+		if tag == 1 || tag == 3 {
+			return GPG
+		}
+	}
+	return Plain
+}
+
+// Encrypted reports whether the file name exists and is encrypted.
+func Encrypted(name string) bool {
+	b, err := ioutil.ReadFile(name)
+	return err == nil && Detect(b) != Plain
+}
+
+// ReadFile returns the contents of the file name, decrypted if it is
+// encrypted. Age files are decrypted with the identity file in IdentityEnv
+// and GPG files with the keys of gpg-agent. Errors reading the file are
+// returned as they are, so os.IsNotExist works on them.
+func ReadFile(name string) ([]byte, error) {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var cmd *exec.Cmd
+	switch Detect(b) {
+	case Plain:
+		return b, nil
+	case Age:
+		identity := os.Getenv(IdentityEnv)
+		if identity == "" {
+			return nil, fmt.Errorf("%s is encrypted with age; set %s to the identity file", name, IdentityEnv)
+		}
+		cmd = exec.Command(AgeCommand, "--decrypt", "--identity", identity)
+	case GPG:
+		cmd = exec.Command(GPGCommand, "--batch", "--quiet", "--decrypt")
+	}
+	cmd.Stdin = bytes.NewReader(b)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = errors.New(msg)
+		}
+		return nil, fmt.Errorf("decrypt %s: %s", name, err)
+	}
+	return out, nil
+}
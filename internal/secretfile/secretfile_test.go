@@ -0,0 +1,80 @@
+package secretfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Format
+	}{
+		{`{"connstring": "host=db"}`, Plain},
+		{"", Plain},
+		{"age-encryption.org/v1\n-> X25519 abc\n", Age},
+		{"-----BEGIN AGE ENCRYPTED FILE-----\nYWdl\n", Age},
+		{"-----BEGIN PGP MESSAGE-----\n\nhQEMA\n", GPG},
+		{"\x85\x01\x0c\x03", GPG}, // old format, public key session key
+		{"\xc1\x01\x0c\x03", GPG}, // new format, public key session key
+		{"\x8c\x0d\x04\x09", GPG}, // old format, passphrase
+		{"\xc3\x0d\x04\x09", GPG}, // new format, passphrase
+		{"\x89\x50\x4e\x47", Plain},
+	}
+	for _, tt := range tests {
+		if got := Detect([]byte(tt.in)); got != tt.want {
+			t.Errorf("Detect(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secretfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, content string, perm os.FileMode) string {
+		name = filepath.Join(dir, name)
+		if err := ioutil.WriteFile(name, []byte(content), perm); err != nil {
+			t.Fatal(err)
+		}
+		return name
+	}
+	// The fake age checks its arguments and prints the decrypted file.
+	defer func(c string) { AgeCommand = c }(AgeCommand)
+	AgeCommand = write("age", `#!/bin/sh
+[ "$1 $2 $3" = "--decrypt --identity key.txt" ] || { echo "bad arguments $*" >&2; exit 1; }
+cat >/dev/null
+echo '{"connstring": "host=secret"}'
+`, 0755)
+
+	plain := write("plain.json", `{"connstring": "host=db"}`, 0644)
+	if b, err := ReadFile(plain); err != nil || string(b) != `{"connstring": "host=db"}` {
+		t.Errorf("ReadFile(plain) = %q, %v", b, err)
+	}
+	if _, err := ReadFile(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Errorf("ReadFile(missing) error = %v, want not exist", err)
+	}
+
+	encrypted := write("etget.json.age", "age-encryption.org/v1\n", 0644)
+	os.Setenv(IdentityEnv, "")
+	if _, err := ReadFile(encrypted); err == nil || !strings.Contains(err.Error(), IdentityEnv) {
+		t.Errorf("ReadFile without identity error = %v, want mention of %s", err, IdentityEnv)
+	}
+	os.Setenv(IdentityEnv, "key.txt")
+	defer os.Unsetenv(IdentityEnv)
+	if b, err := ReadFile(encrypted); err != nil || strings.TrimSpace(string(b)) != `{"connstring": "host=secret"}` {
+		t.Errorf("ReadFile(age) = %q, %v", b, err)
+	}
+	os.Setenv(IdentityEnv, "other.txt")
+	if _, err := ReadFile(encrypted); err == nil || !strings.Contains(err.Error(), "bad arguments") {
+		t.Errorf("ReadFile with failing age error = %v, want its standard error", err)
+	}
+	if !Encrypted(encrypted) || Encrypted(plain) {
+		t.Error("Encrypted does not tell the age file from the plain one")
+	}
+}
@@ -0,0 +1,139 @@
+// Package server runs the HTTP endpoints of the etget commands with
+// optional bearer token and mutual TLS authentication.
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ShutdownTimeout bounds how long ListenAndServe waits for the requests in
+// progress when it is stopped.
+const ShutdownTimeout = 10 * time.Second
+
+// TokensEnv is the environment variable with comma-separated API tokens.
+const TokensEnv = "ETGET_API_TOKENS"
+
+// Options configures authentication. The zero value serves plain HTTP
+// without authentication, which is only suitable for localhost.
+type Options struct {
+	// Tokens are the accepted bearer tokens. If empty, requests are not
+	// authenticated by token.
+	Tokens []string `json:"tokens"`
+
+	// CertFile and KeyFile enable TLS.
+	CertFile string `json:"tls_cert"`
+	KeyFile  string `json:"tls_key"`
+
+	// ClientCAFile requires clients to present a certificate signed by
+	// one of the CAs in the file. It requires TLS.
+	ClientCAFile string `json:"client_ca"`
+}
+
+// WithEnv returns a copy of o with the tokens from TokensEnv added.
+func (o Options) WithEnv() Options {
+	for _, t := range strings.Split(os.Getenv(TokensEnv), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			o.Tokens = append(o.Tokens, t)
+		}
+	}
+	return o
+}
+
+// Authenticated reports whether o authenticates clients, by token or by
+// client certificate.
+func (o Options) Authenticated() bool {
+	return len(o.Tokens) > 0 || o.ClientCAFile != ""
+}
+
+// Authenticate wraps h to require one of tokens, given either in the
+// Authorization header or, for clients such as calendar apps that cannot
+// set headers, in the access_token query parameter. If tokens is empty, h
+// is returned unchanged.
+func Authenticate(tokens []string, h http.Handler) http.Handler {
+	if len(tokens) == 0 {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := r.URL.Query().Get("access_token")
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			got = strings.TrimPrefix(auth, "Bearer ")
+		}
+		for _, t := range tokens {
+			if subtle.ConstantTimeCompare([]byte(got), []byte(t)) == 1 {
+				h.ServeHTTP(w, r)
+				return
+			}
+		}
+		w.Header().Set("WWW-Authenticate", `Bearer realm="etget"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	})
+}
+
+// ListenAndServe serves h on addr with the authentication in o until the
+// process receives SIGINT or SIGTERM, as from systemctl stop. It then
+// stops accepting connections, waits up to ShutdownTimeout for the
+// requests in progress and returns nil.
+func ListenAndServe(addr string, h http.Handler, o Options) error {
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: Authenticate(o.Tokens, h),
+	}
+	listen := srv.ListenAndServe
+	if o.CertFile == "" {
+		if o.ClientCAFile != "" {
+			return errors.New("client certificate authentication requires TLS")
+		}
+	} else {
+		if o.ClientCAFile != "" {
+			pem, err := ioutil.ReadFile(o.ClientCAFile)
+			if err != nil {
+				return err
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return errors.New("no certificates in " + o.ClientCAFile)
+			}
+			srv.TLSConfig = &tls.Config{
+				ClientCAs:  pool,
+				ClientAuth: tls.RequireAndVerifyClientCert,
+			}
+		}
+		listen = func() error { return srv.ListenAndServeTLS(o.CertFile, o.KeyFile) }
+	}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+	return serve(srv, listen, stop)
+}
+
+// serve runs listen, which serves srv, until it fails or a value is
+// received from stop, and then shuts srv down.
+func serve(srv *http.Server, listen func() error, stop <-chan os.Signal) error {
+	errc := make(chan error, 1)
+	go func() { errc <- listen() }()
+	select {
+	case err := <-errc:
+		return err
+	case <-stop:
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		return err
+	}
+	if err := <-errc; err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestAuthenticate(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	h := Authenticate([]string{"secret"}, ok)
+
+	cases := []struct {
+		url, header string
+		want        int
+	}{
+		{"/metrics", "", http.StatusUnauthorized},
+		{"/metrics", "Bearer wrong", http.StatusUnauthorized},
+		{"/metrics", "Bearer secret", http.StatusOK},
+		{"/metrics?access_token=secret", "", http.StatusOK},
+		{"/metrics?access_token=secret", "Bearer wrong", http.StatusUnauthorized},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", c.url, nil)
+		if c.header != "" {
+			req.Header.Set("Authorization", c.header)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != c.want {
+			t.Errorf("GET %s with %q: status %d, want %d", c.url, c.header, rec.Code, c.want)
+		}
+	}
+
+	if Authenticate(nil, ok) == nil {
+		t.Error("Authenticate(nil, h) returned nil")
+	}
+}
+
+func TestAuthenticated(t *testing.T) {
+	cases := []struct {
+		o    Options
+		want bool
+	}{
+		{Options{}, false},
+		{Options{CertFile: "cert.pem", KeyFile: "key.pem"}, false},
+		{Options{Tokens: []string{"secret"}}, true},
+		{Options{CertFile: "cert.pem", KeyFile: "key.pem", ClientCAFile: "ca.pem"}, true},
+	}
+	for _, c := range cases {
+		if got := c.o.Authenticated(); got != c.want {
+			t.Errorf("%+v.Authenticated() = %t, want %t", c.o, got, c.want)
+		}
+	}
+}
+
+func TestServeShutdown(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("done"))
+	})}
+	stop := make(chan os.Signal, 1)
+	served := make(chan error, 1)
+	go func() { served <- serve(srv, func() error { return srv.Serve(l) }, stop) }()
+
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + l.Addr().String() + "/")
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		body <- string(b)
+	}()
+	<-started
+	stop <- syscall.SIGTERM
+
+	if got := <-body; got != "done" {
+		t.Errorf("request in progress at shutdown got %q, want done", got)
+	}
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("serve returned %v after SIGTERM, want nil", err)
+		}
+	case <-time.After(ShutdownTimeout):
+		t.Fatal("serve did not return after SIGTERM")
+	}
+	if _, err := http.Get("http://" + l.Addr().String() + "/"); err == nil {
+		t.Error("server still accepts requests after shutdown")
+	}
+}
@@ -0,0 +1,200 @@
+// Package spool queues fetched input files on disk when they cannot be
+// loaded, so that a run during a database outage is retried by the next
+// run instead of being lost. Files that keep failing are moved to a
+// dead-letter directory with a file telling why.
+//
+// The queue directory holds pending/ and dead/, each file ID.html with
+// its Item in ID.json; dead files also have ID.reason.
+package spool
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultMaxAttempts is the number of failed loads after which a file is
+// moved to the dead letters, if Spool.MaxAttempts is not set.
+const DefaultMaxAttempts = 5
+
+const (
+	pendingDir = "pending"
+	deadDir    = "dead"
+)
+
+// ErrFull is returned by Put when MaxPending files are already queued.
+var ErrFull = errors.New("spool: queue full")
+
+// Spool is a queue in directory Dir.
+type Spool struct {
+	Dir string
+
+	// MaxAttempts is the number of failed loads after which Fail moves a
+	// file to the dead letters (default DefaultMaxAttempts).
+	MaxAttempts int
+
+	// MaxPending, if positive, bounds the queue so that a long outage
+	// fails the runs rather than filling the disk.
+	MaxPending int
+}
+
+// Item is a queued file.
+type Item struct {
+	// ID names the files of the item in the queue.
+	ID string `json:"-"`
+
+	// Source is the file name or URL the data was read from.
+	Source string `json:"source"`
+
+	// ModTime is the modification time of the source, kept as the
+	// modification time of the queued file.
+	ModTime time.Time `json:"mod_time"`
+
+	Queued    time.Time `json:"queued"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// Path returns the file holding the data of it.
+func (s Spool) Path(it Item) string {
+	return filepath.Join(s.Dir, pendingDir, it.ID+".html")
+}
+
+// Put queues data read from source. Data already pending is not queued
+// twice; the pending item is returned instead.
+func (s Spool) Put(source string, data []byte, modTime time.Time) (Item, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:6])
+	pending, err := s.Pending()
+	if err != nil {
+		return Item{}, err
+	}
+	for _, it := range pending {
+		if strings.HasSuffix(it.ID, "-"+hash) {
+			return it, nil
+		}
+	}
+	if s.MaxPending > 0 && len(pending) >= s.MaxPending {
+		return Item{}, ErrFull
+	}
+	if err := os.MkdirAll(filepath.Join(s.Dir, pendingDir), 0755); err != nil {
+		return Item{}, err
+	}
+	now := time.Now().UTC()
+	it := Item{
+		ID:      now.Format("20060102T150405.000000000") + "-" + hash,
+		Source:  source,
+		ModTime: modTime,
+		Queued:  now,
+	}
+	if err := writeFile(s.Path(it), data); err != nil {
+		return Item{}, err
+	}
+	if !modTime.IsZero() {
+		os.Chtimes(s.Path(it), modTime, modTime)
+	}
+	return it, s.writeItem(pendingDir, it)
+}
+
+// Pending returns the queued items, oldest first.
+func (s Spool) Pending() ([]Item, error) {
+	names, err := filepath.Glob(filepath.Join(s.Dir, pendingDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	items := make([]Item, 0, len(names))
+	for _, name := range names {
+		b, err := ioutil.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		var it Item
+		if err := json.Unmarshal(b, &it); err != nil {
+			return nil, fmt.Errorf("spool: %s: %s", name, err)
+		}
+		it.ID = strings.TrimSuffix(filepath.Base(name), ".json")
+		items = append(items, it)
+	}
+	return items, nil
+}
+
+// Done removes a loaded item from the queue.
+func (s Spool) Done(it Item) error {
+	if err := os.Remove(s.Path(it)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Remove(filepath.Join(s.Dir, pendingDir, it.ID+".json"))
+}
+
+// Fail records a failed load of it. After MaxAttempts failures the item
+// is moved to the dead letters and Fail reports dead.
+func (s Spool) Fail(it Item, cause error) (dead bool, err error) {
+	it.Attempts++
+	it.LastError = cause.Error()
+	max := s.MaxAttempts
+	if max <= 0 {
+		max = DefaultMaxAttempts
+	}
+	if it.Attempts >= max {
+		return true, s.Bury(it, fmt.Errorf("%d failed attempts, last: %s", it.Attempts, cause))
+	}
+	return false, s.writeItem(pendingDir, it)
+}
+
+// Bury moves it to the dead letters at once, with reason written in
+// ID.reason, e.g. for a file that cannot be parsed.
+func (s Spool) Bury(it Item, reason error) error {
+	dir := filepath.Join(s.Dir, deadDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	it.LastError = reason.Error()
+	if err := writeFile(filepath.Join(dir, it.ID+".reason"), []byte(reason.Error()+"\n")); err != nil {
+		return err
+	}
+	if err := s.writeItem(deadDir, it); err != nil {
+		return err
+	}
+	if err := os.Rename(s.Path(it), filepath.Join(dir, it.ID+".html")); err != nil {
+		return err
+	}
+	return os.Remove(filepath.Join(s.Dir, pendingDir, it.ID+".json"))
+}
+
+// Count returns the number of pending and dead items. A missing
+// directory counts as empty.
+func (s Spool) Count() (pending, dead int, err error) {
+	p, err := filepath.Glob(filepath.Join(s.Dir, pendingDir, "*.json"))
+	if err != nil {
+		return 0, 0, err
+	}
+	d, err := filepath.Glob(filepath.Join(s.Dir, deadDir, "*.json"))
+	return len(p), len(d), err
+}
+
+func (s Spool) writeItem(dir string, it Item) error {
+	b, err := json.MarshalIndent(it, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(s.Dir, dir, it.ID+".json"), append(b, '\n'))
+}
+
+// writeFile writes name through a temporary file, so that a crash leaves
+// either the old or the new contents.
+func writeFile(name string, data []byte) error {
+	tmp := name + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
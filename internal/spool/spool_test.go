@@ -0,0 +1,73 @@
+package spool_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joneskoo/etget/internal/spool"
+)
+
+func TestSpool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s := spool.Spool{Dir: dir, MaxAttempts: 2, MaxPending: 2}
+
+	if p, d, err := s.Count(); err != nil || p != 0 || d != 0 {
+		t.Fatalf("Count of empty spool = %d, %d, %v", p, d, err)
+	}
+	modTime := time.Date(2017, 7, 14, 2, 40, 0, 0, time.UTC)
+	a, err := s.Put("https://example.com/a.xls", []byte("a"), modTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := s.Put("a.xls", []byte("a"), modTime); err != nil || again.ID != a.ID {
+		t.Errorf("Put of pending data = %v, %v; want item %s", again, err, a.ID)
+	}
+	if fi, err := os.Stat(s.Path(a)); err != nil || !fi.ModTime().Equal(modTime) {
+		t.Errorf("queued file modification time = %v, %v; want %s", fi.ModTime(), err, modTime)
+	}
+	b, err := s.Put("b.xls", []byte("b"), time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Put("c.xls", []byte("c"), time.Time{}); err != spool.ErrFull {
+		t.Errorf("Put beyond MaxPending returned %v, want ErrFull", err)
+	}
+
+	pending, err := s.Pending()
+	if err != nil || len(pending) != 2 || pending[0].Source != "https://example.com/a.xls" {
+		t.Fatalf("Pending = %+v, %v", pending, err)
+	}
+	if dead, err := s.Fail(pending[0], errors.New("connection refused")); dead || err != nil {
+		t.Errorf("first Fail = %v, %v; want pending", dead, err)
+	}
+	pending, _ = s.Pending()
+	if pending[0].Attempts != 1 || pending[0].LastError != "connection refused" {
+		t.Errorf("after Fail item = %+v", pending[0])
+	}
+	if dead, err := s.Fail(pending[0], errors.New("connection refused")); !dead || err != nil {
+		t.Errorf("second Fail = %v, %v; want dead", dead, err)
+	}
+	reason, err := ioutil.ReadFile(filepath.Join(dir, "dead", a.ID+".reason"))
+	if err != nil || !strings.Contains(string(reason), "2 failed attempts, last: connection refused") {
+		t.Errorf("reason = %q, %v", reason, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "dead", a.ID+".html")); err != nil {
+		t.Errorf("dead letter data: %s", err)
+	}
+
+	if err := s.Done(b); err != nil {
+		t.Fatal(err)
+	}
+	if p, d, err := s.Count(); err != nil || p != 0 || d != 1 {
+		t.Errorf("Count = %d, %d, %v; want 0 pending, 1 dead", p, d, err)
+	}
+}
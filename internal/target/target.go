@@ -0,0 +1,165 @@
+// Package target loads an import into several databases, so that one
+// parse of the input can feed e.g. a local and a remote database.
+package target
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/joneskoo/etget/importer"
+)
+
+// List is a flag.Value of connection strings. The flag can be repeated;
+// the first value given on the command line replaces the default.
+type List struct {
+	Values []string
+	set    bool
+}
+
+// NewList returns a list with the default connection string def.
+func NewList(def string) *List {
+	return &List{Values: []string{def}}
+}
+
+func (l *List) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(l.Values, ", ")
+}
+
+// Set adds a connection string.
+func (l *List) Set(s string) error {
+	if !l.set {
+		l.Values = nil
+		l.set = true
+	}
+	l.Values = append(l.Values, s)
+	return nil
+}
+
+// Result is the outcome of loading one target.
+type Result struct {
+	Target       string
+	RowsAffected int64
+	Err          error
+
+	// Stats compares the incoming rows with the stored ones, if the
+	// importer counts them.
+	Stats *Stats
+}
+
+// Stats counts the incoming rows of a load by how they compare with the
+// rows already stored: identical rows change nothing, conflicting ones
+// differ in some value and new ones had no stored row.
+type Stats struct {
+	New         int64 `json:"new"`
+	Identical   int64 `json:"identical"`
+	Conflicting int64 `json:"conflicting"`
+}
+
+// Add adds the counts of o to s.
+func (s *Stats) Add(o Stats) {
+	s.New += o.New
+	s.Identical += o.Identical
+	s.Conflicting += o.Conflicting
+}
+
+func (s Stats) String() string {
+	if s.New == 0 && s.Conflicting == 0 {
+		return fmt.Sprintf("nothing changed, %d identical", s.Identical)
+	}
+	return fmt.Sprintf("%d new, %d identical, %d conflicting", s.New, s.Identical, s.Conflicting)
+}
+
+// Load calls load for each connection string in turn. A failing target
+// does not stop the others.
+func Load(connstrings []string, load func(connstring string) (int64, error)) []Result {
+	results := make([]Result, len(connstrings))
+	for i, cs := range connstrings {
+		n, err := load(cs)
+		results[i] = Result{Target: Redact(cs), RowsAffected: n, Err: err}
+	}
+	return results
+}
+
+// LoadStats is Load for importers that also count the incoming rows
+// against the stored ones in the Stats passed to load.
+func LoadStats(connstrings []string, load func(connstring string, stats *Stats) (int64, error)) []Result {
+	results := make([]Result, len(connstrings))
+	for i, cs := range connstrings {
+		stats := new(Stats)
+		n, err := load(cs, stats)
+		results[i] = Result{Target: Redact(cs), RowsAffected: n, Err: err}
+		if err == nil {
+			results[i].Stats = stats
+		}
+	}
+	return results
+}
+
+// Sinks returns an importer sink for each connection string, named by the
+// redacted connection string.
+func Sinks(connstrings []string, load func(ctx context.Context, connstring string, b *importer.Batch) (int64, error)) []importer.Sink {
+	sinks := make([]importer.Sink, len(connstrings))
+	for i, cs := range connstrings {
+		cs := cs
+		sinks[i] = importer.NewSink(Redact(cs), func(ctx context.Context, b *importer.Batch) (int64, error) {
+			return load(ctx, cs, b)
+		})
+	}
+	return sinks
+}
+
+// Results converts the results of an importer.Importer for Summarize.
+func Results(results []importer.Result) []Result {
+	rs := make([]Result, len(results))
+	for i, r := range results {
+		rs[i] = Result{Target: r.Sink, RowsAffected: r.RowsAffected, Err: r.Err}
+	}
+	return rs
+}
+
+// Summarize writes one line per result and returns an error if any
+// target failed.
+func Summarize(w io.Writer, results []Result) error {
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			fmt.Fprintf(w, "ERROR %s: %s\n", r.Target, r.Err)
+			continue
+		}
+		if r.Stats != nil {
+			fmt.Fprintf(w, "OK! %s: %d rows affected (%s)\n", r.Target, r.RowsAffected, r.Stats)
+			continue
+		}
+		fmt.Fprintf(w, "OK! %s: %d rows affected\n", r.Target, r.RowsAffected)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d targets failed", failed, len(results))
+	}
+	return nil
+}
+
+var passwordParam = regexp.MustCompile(`(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// Redact hides the password of a key=value or URL connection string.
+func Redact(connstring string) string {
+	if u, err := url.Parse(connstring); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "xxxxx")
+		}
+		q := u.Query()
+		if q.Get("password") != "" {
+			q.Set("password", "xxxxx")
+			u.RawQuery = q.Encode()
+		}
+		return u.String()
+	}
+	return passwordParam.ReplaceAllString(connstring, "${1}xxxxx")
+}
@@ -0,0 +1,85 @@
+package target
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"reflect"
+	"testing"
+)
+
+func TestList(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	l := NewList("sslmode=disable")
+	fs.Var(l, "connstring", "")
+	if err := fs.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"sslmode=disable"}; !reflect.DeepEqual(l.Values, want) {
+		t.Errorf("default = %q, want %q", l.Values, want)
+	}
+	if err := fs.Parse([]string{"-connstring", "host=a", "-connstring", "host=b"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"host=a", "host=b"}; !reflect.DeepEqual(l.Values, want) {
+		t.Errorf("Values = %q, want %q", l.Values, want)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"host=db password=secret user=etget", "host=db password=xxxxx user=etget"},
+		{"host=db password = 'se cret'", "host=db password = xxxxx"},
+		{"postgres://etget:secret@db/etget", "postgres://etget:xxxxx@db/etget"},
+		{"postgres://db/etget?password=secret", "postgres://db/etget?password=xxxxx"},
+		{"sslmode=disable", "sslmode=disable"},
+	}
+	for _, c := range cases {
+		if got := Redact(c.in); got != c.want {
+			t.Errorf("Redact(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestLoadContinuesAfterFailure(t *testing.T) {
+	results := Load([]string{"host=a", "host=b password=x"}, func(cs string) (int64, error) {
+		if cs == "host=a" {
+			return 0, errors.New("connection refused")
+		}
+		return 24, nil
+	})
+	var buf bytes.Buffer
+	if err := Summarize(&buf, results); err == nil {
+		t.Error("Summarize did not return error for a failed target")
+	}
+	want := "ERROR host=a: connection refused\nOK! host=b password=xxxxx: 24 rows affected\n"
+	if buf.String() != want {
+		t.Errorf("Summarize wrote:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestLoadStats(t *testing.T) {
+	results := LoadStats([]string{"host=a", "host=b", "host=c"}, func(cs string, stats *Stats) (int64, error) {
+		switch cs {
+		case "host=a":
+			stats.Add(Stats{Identical: 24})
+			return 0, nil
+		case "host=b":
+			stats.Add(Stats{New: 2, Identical: 20, Conflicting: 2})
+			return 4, nil
+		}
+		stats.Add(Stats{New: 1})
+		return 0, errors.New("connection refused")
+	})
+	if results[2].Stats != nil {
+		t.Errorf("failed target has stats %v", results[2].Stats)
+	}
+	var buf bytes.Buffer
+	Summarize(&buf, results)
+	want := "OK! host=a: 0 rows affected (nothing changed, 24 identical)\n" +
+		"OK! host=b: 4 rows affected (2 new, 20 identical, 2 conflicting)\n" +
+		"ERROR host=c: connection refused\n"
+	if buf.String() != want {
+		t.Errorf("Summarize wrote:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,255 @@
+// Package telemetry exports spans of the import stages as OpenTelemetry
+// traces over OTLP/HTTP with JSON encoding. Tracing is opt-in: without an
+// endpoint every operation is a no-op.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EndpointEnv is the standard OpenTelemetry variable read by WithEnv.
+const EndpointEnv = "OTEL_EXPORTER_OTLP_ENDPOINT"
+
+// Options configures the exporter.
+type Options struct {
+	// Endpoint is the base URL of the OTLP/HTTP receiver, e.g.
+	// "http://otel-collector:4318". Spans are posted to /v1/traces.
+	Endpoint string `json:"otlp_endpoint"`
+
+	// Headers are added to export requests, e.g. for authentication.
+	Headers map[string]string `json:"otlp_headers"`
+}
+
+// WithEnv returns o with the endpoint from EndpointEnv if o has none.
+func (o Options) WithEnv() Options {
+	if o.Endpoint == "" {
+		o.Endpoint = os.Getenv(EndpointEnv)
+	}
+	return o
+}
+
+// Tracer collects the spans of one trace, a run of a command.
+type Tracer struct {
+	service string
+	opts    Options
+	traceID string
+
+	mu    sync.Mutex
+	spans []*Span
+
+	// Client posts the spans; http.DefaultClient if nil.
+	Client *http.Client
+}
+
+// New returns a tracer of service. It returns nil, which is a valid
+// tracer that records nothing, if o has no endpoint.
+func New(service string, o Options) *Tracer {
+	if o.Endpoint == "" {
+		return nil
+	}
+	return &Tracer{service: service, opts: o, traceID: randomID(16)}
+}
+
+// Span is a timed stage. A nil span ignores all calls.
+type Span struct {
+	tracer *Tracer
+	name   string
+	id     string
+	parent string
+	start  time.Time
+	end    time.Time
+	attrs  map[string]interface{}
+	err    error
+}
+
+// Start begins a span named name, a child of parent if that is not nil.
+func (t *Tracer) Start(name string, parent *Span) *Span {
+	if t == nil {
+		return nil
+	}
+	s := &Span{tracer: t, name: name, id: randomID(8), start: time.Now(), attrs: map[string]interface{}{}}
+	if parent != nil {
+		s.parent = parent.id
+	}
+	t.mu.Lock()
+	t.spans = append(t.spans, s)
+	t.mu.Unlock()
+	return s
+}
+
+// SetAttr sets an attribute; value is a string, bool, int, int64 or
+// float64.
+func (s *Span) SetAttr(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.tracer.mu.Lock()
+	s.attrs[key] = value
+	s.tracer.mu.Unlock()
+}
+
+// End ends the span, marking it failed if err is not nil.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.tracer.mu.Lock()
+	s.end = time.Now()
+	s.err = err
+	s.tracer.mu.Unlock()
+}
+
+// Flush posts the ended spans to the endpoint.
+func (t *Tracer) Flush(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	body, err := json.Marshal(t.request())
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", strings.TrimSuffix(t.opts.Endpoint, "/")+"/v1/traces", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.opts.Headers {
+		req.Header.Set(k, v)
+	}
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("export traces: %s", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("export traces: HTTP status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// The types below are the subset of the OTLP JSON encoding the tracer
+// writes.
+
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope      `json:"scope"`
+	Spans []spanJSON `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type spanJSON struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            status     `json:"status"`
+}
+
+type keyValue struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+type status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// Span kind and status codes of OTLP.
+const (
+	kindInternal = 1
+	statusOK     = 1
+	statusError  = 2
+)
+
+func (t *Tracer) request() exportRequest {
+	spans := make([]spanJSON, 0, len(t.spans))
+	for _, s := range t.spans {
+		if s.end.IsZero() {
+			continue
+		}
+		j := spanJSON{
+			TraceID:           t.traceID,
+			SpanID:            s.id,
+			ParentSpanID:      s.parent,
+			Name:              s.name,
+			Kind:              kindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Status:            status{Code: statusOK},
+		}
+		for k, v := range s.attrs {
+			j.Attributes = append(j.Attributes, attribute(k, v))
+		}
+		if s.err != nil {
+			j.Status = status{Code: statusError, Message: s.err.Error()}
+		}
+		spans = append(spans, j)
+	}
+	return exportRequest{ResourceSpans: []resourceSpans{{
+		Resource:   resource{Attributes: []keyValue{attribute("service.name", t.service)}},
+		ScopeSpans: []scopeSpans{{Scope: scope{Name: "github.com/joneskoo/etget"}, Spans: spans}},
+	}}}
+}
+
+func attribute(key string, v interface{}) keyValue {
+	var value map[string]interface{}
+	switch v := v.(type) {
+	case bool:
+		value = map[string]interface{}{"boolValue": v}
+	case int:
+		value = map[string]interface{}{"intValue": strconv.Itoa(v)}
+	case int64:
+		value = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+	case float64:
+		value = map[string]interface{}{"doubleValue": v}
+	default:
+		value = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+	}
+	return keyValue{Key: key, Value: value}
+}
+
+func randomID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
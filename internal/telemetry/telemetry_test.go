@@ -0,0 +1,67 @@
+package telemetry_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/joneskoo/etget/internal/telemetry"
+)
+
+func TestFlush(t *testing.T) {
+	var (
+		got    map[string]interface{}
+		path   string
+		header string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, header = r.URL.Path, r.Header.Get("Authorization")
+		b, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Errorf("body is not JSON: %s", err)
+		}
+	}))
+	defer srv.Close()
+
+	tr := telemetry.New("import-elspot", telemetry.Options{Endpoint: srv.URL + "/", Headers: map[string]string{"Authorization": "Bearer x"}})
+	root := tr.Start("import", nil)
+	load := tr.Start("load", root)
+	load.SetAttr("rows", int64(24))
+	load.End(errors.New("refused"))
+	root.End(nil)
+	tr.Start("unfinished", root)
+	if err := tr.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %s", err)
+	}
+
+	if path != "/v1/traces" || header != "Bearer x" {
+		t.Errorf("request to %s with Authorization %q", path, header)
+	}
+	rs := got["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	spans := rs["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+	if len(spans) != 2 {
+		t.Fatalf("exported %d spans, want the 2 ended ones", len(spans))
+	}
+	r := spans[0].(map[string]interface{})
+	l := spans[1].(map[string]interface{})
+	if l["name"] != "load" || l["parentSpanId"] != r["spanId"] || l["traceId"] != r["traceId"] {
+		t.Errorf("load span = %v, want child of %v", l, r)
+	}
+	if code := l["status"].(map[string]interface{})["code"]; code != 2.0 {
+		t.Errorf("load status code = %v, want 2 (error)", code)
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	tr := telemetry.New("x", telemetry.Options{})
+	s := tr.Start("a", nil)
+	s.SetAttr("k", "v")
+	s.End(nil)
+	if err := tr.Flush(context.Background()); err != nil {
+		t.Errorf("Flush of disabled tracer: %s", err)
+	}
+}
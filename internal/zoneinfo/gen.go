@@ -0,0 +1,94 @@
+//go:build ignore
+// +build ignore
+
+// This program generates zdata.go from the zoneinfo.zip of the Go
+// installation. Run it with go generate after updating Go to pin a newer
+// time zone database.
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// zones are the time zones pinned: those the commands and presets use.
+var zones = []string{
+	"Europe/Helsinki",
+	"Europe/Oslo",
+	"Europe/Paris",
+	"Europe/Stockholm",
+	"Europe/Tallinn",
+	"UTC",
+}
+
+func main() {
+	dir := filepath.Join(runtime.GOROOT(), "lib", "time")
+	version, err := dataVersion(filepath.Join(dir, "update.bash"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	z, err := zip.OpenReader(filepath.Join(dir, "zoneinfo.zip"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer z.Close()
+	files := make(map[string]*zip.File)
+	for _, f := range z.File {
+		files[f.Name] = f
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by gen.go; DO NOT EDIT.\n\npackage zoneinfo\n\n")
+	fmt.Fprintf(&b, "// Version is the IANA release of the pinned time zone data.\nconst Version = %q\n\n", version)
+	fmt.Fprintf(&b, "var pinned = map[string]string{\n")
+	for _, name := range zones {
+		f, ok := files[name]
+		if !ok {
+			log.Fatalf("%s not in zoneinfo.zip", name)
+		}
+		r, err := f.Open()
+		if err != nil {
+			log.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Fprintf(&b, "%q: %s,\n", name, strconv.Quote(string(data)))
+	}
+	fmt.Fprintf(&b, "}\n")
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile("zdata.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// dataVersion returns the DATA version set in Go's update.bash.
+func dataVersion(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if strings.HasPrefix(s.Text(), "DATA=") {
+			return strings.TrimPrefix(s.Text(), "DATA="), nil
+		}
+	}
+	return "", fmt.Errorf("%s: no DATA version", file)
+}
@@ -0,0 +1,108 @@
+// Package zoneinfo loads time zones, falling back to a pinned copy of the
+// zones etget uses when the system has no zone database, as in minimal
+// containers. Verify compares the system database with the pinned copy.
+package zoneinfo
+
+//go:generate go run gen.go
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Load returns the time zone name from the system zone database, or from
+// the pinned data if the system has no such zone.
+func Load(name string) (*time.Location, error) {
+	loc, err := time.LoadLocation(name)
+	if err == nil {
+		return loc, nil
+	}
+	if data, ok := pinned[name]; ok {
+		return time.LoadLocationFromTZData(name, []byte(data))
+	}
+	return nil, err
+}
+
+// MustLoad is like Load but panics if the zone cannot be loaded. It is for
+// initializing package variables with the pinned zones.
+func MustLoad(name string) *time.Location {
+	loc, err := Load(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+// Zones returns the names of the pinned zones, sorted.
+func Zones() []string {
+	names := make([]string, 0, len(pinned))
+	for name := range pinned {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Mismatch is a pinned zone that the system database is missing or
+// disagrees with.
+type Mismatch struct {
+	Zone string
+
+	// At is the first hour when the zones differ, with the abbreviation
+	// and offset of each.
+	At             time.Time
+	System, Pinned string
+
+	// Err is set if the system database does not have the zone.
+	Err error
+}
+
+func (m Mismatch) String() string {
+	if m.Err != nil {
+		return fmt.Sprintf("%s: %s", m.Zone, m.Err)
+	}
+	return fmt.Sprintf("%s: at %s the system has %s, pinned %s has %s", m.Zone, m.At.UTC().Format(time.RFC3339), m.System, Version, m.Pinned)
+}
+
+// Verify compares each pinned zone with the system database hour by hour
+// from from to to, returning the zones that differ.
+func Verify(from, to time.Time) []Mismatch {
+	var mismatches []Mismatch
+	for _, name := range Zones() {
+		pin, err := time.LoadLocationFromTZData(name, []byte(pinned[name]))
+		if err != nil {
+			panic(err) // generated data
+		}
+		sys, err := time.LoadLocation(name)
+		if err != nil {
+			mismatches = append(mismatches, Mismatch{Zone: name, Err: err})
+			continue
+		}
+		if m, ok := compare(name, sys, pin, from, to); !ok {
+			mismatches = append(mismatches, m)
+		}
+	}
+	return mismatches
+}
+
+// compare returns the first hour from from to to when sys and pin differ.
+func compare(name string, sys, pin *time.Location, from, to time.Time) (m Mismatch, ok bool) {
+	for t := from.Truncate(time.Hour); t.Before(to); t = t.Add(time.Hour) {
+		sysName, sysOffset := t.In(sys).Zone()
+		pinName, pinOffset := t.In(pin).Zone()
+		if sysName != pinName || sysOffset != pinOffset {
+			return Mismatch{
+				Zone:   name,
+				At:     t,
+				System: zone(sysName, sysOffset),
+				Pinned: zone(pinName, pinOffset),
+			}, false
+		}
+	}
+	return Mismatch{}, true
+}
+
+func zone(name string, offset int) string {
+	return fmt.Sprintf("%s (%+.1fh)", name, float64(offset)/3600)
+}
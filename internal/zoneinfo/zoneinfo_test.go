@@ -0,0 +1,36 @@
+package zoneinfo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPinned(t *testing.T) {
+	for _, name := range Zones() {
+		if _, err := time.LoadLocationFromTZData(name, []byte(pinned[name])); err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+	}
+	if _, err := Load("Mars/Olympus_Mons"); err == nil {
+		t.Error("Load of unknown zone did not return error")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	helsinki, _ := time.LoadLocationFromTZData("Europe/Helsinki", []byte(pinned["Europe/Helsinki"]))
+	tallinn, _ := time.LoadLocationFromTZData("Europe/Tallinn", []byte(pinned["Europe/Tallinn"]))
+	from := time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+	if m, ok := compare("x", helsinki, tallinn, from, from.AddDate(5, 0, 0)); !ok {
+		t.Errorf("Helsinki and Tallinn differ in 2015-2019: %s", m)
+	}
+
+	// Estonia did not observe summer time in 2000.
+	from = time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC)
+	m, ok := compare("Europe/Tallinn", tallinn, helsinki, from, from.AddDate(2, 0, 0))
+	if ok {
+		t.Fatal("Helsinki and Tallinn do not differ in 1999-2000")
+	}
+	if want := time.Date(2000, 3, 26, 1, 0, 0, 0, time.UTC); !m.At.Equal(want) || m.System != "EET (+2.0h)" || m.Pinned != "EEST (+3.0h)" {
+		t.Errorf("mismatch = %s, want at %s", m, want)
+	}
+}
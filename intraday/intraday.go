@@ -0,0 +1,188 @@
+// Package intraday parses Nord Pool intraday (Elbas) trade exports and
+// aggregates the trades into hourly volume-weighted prices that can be
+// compared with day-ahead prices.
+//
+// The export is a CSV file with a header row. Columns are found by name;
+// the delivery start, price and quantity columns are required, the area
+// column is optional. Fields may be separated by commas or semicolons,
+// and prices may use a decimal comma when semicolons are used.
+package intraday
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joneskoo/etget/notz"
+)
+
+// Trade is one intraday trade.
+type Trade struct {
+	Area string
+
+	// Delivery is the start of the delivery period.
+	Delivery time.Time
+
+	Price    float64 // EUR/MWh
+	Quantity float64 // MW
+}
+
+// Hour is the aggregate of the trades of one delivery hour in one area.
+type Hour struct {
+	Area      string
+	Timestamp time.Time
+
+	// VWAP is the volume-weighted average price.
+	VWAP   float64
+	Volume float64
+	Trades int
+}
+
+// Header synonyms in normalized form.
+var (
+	deliveryHeaders = map[string]bool{"delivery start": true, "delivery": true, "deliverystart": true, "start": true}
+	priceHeaders    = map[string]bool{"price": true, "price (eur)": true, "price eur/mwh": true}
+	quantityHeaders = map[string]bool{"quantity": true, "qty": true, "volume": true, "quantity (mw)": true}
+	areaHeaders     = map[string]bool{"area": true, "delivery area": true, "deliveryarea": true}
+)
+
+// ParseTrades reads a trade export. Delivery times with a zone offset are
+// used as is; times without one are local times in loc.
+func ParseTrades(r io.Reader, loc *time.Location) ([]Trade, error) {
+	br := bufio.NewReader(r)
+	first, err := br.Peek(4096)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+	cr := csv.NewReader(br)
+	semicolon := strings.Count(firstLine(first), ";") > strings.Count(firstLine(first), ",")
+	if semicolon {
+		cr.Comma = ';'
+	}
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("intraday: empty file")
+	}
+	if err != nil {
+		return nil, err
+	}
+	cols := map[string]int{"delivery": -1, "price": -1, "quantity": -1, "area": -1}
+	for i, h := range header {
+		h = strings.ToLower(strings.Join(strings.Fields(h), " "))
+		for name, synonyms := range map[string]map[string]bool{"delivery": deliveryHeaders, "price": priceHeaders, "quantity": quantityHeaders, "area": areaHeaders} {
+			if synonyms[h] && cols[name] == -1 {
+				cols[name] = i
+			}
+		}
+	}
+	for _, name := range []string{"delivery", "price", "quantity"} {
+		if cols[name] == -1 {
+			return nil, fmt.Errorf("intraday: no %s column in header %q", name, header)
+		}
+	}
+
+	var trades []Trade
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(name string) string {
+			if i := cols[name]; i >= 0 && i < len(rec) {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+		var t Trade
+		if t.Delivery, err = parseTime(field("delivery"), loc); err != nil {
+			return nil, fmt.Errorf("intraday: line %d: %s", line, err)
+		}
+		if t.Price, err = parseNumber(field("price"), semicolon); err != nil {
+			return nil, fmt.Errorf("intraday: line %d: price: %s", line, err)
+		}
+		if t.Quantity, err = parseNumber(field("quantity"), semicolon); err != nil {
+			return nil, fmt.Errorf("intraday: line %d: quantity: %s", line, err)
+		}
+		t.Area = field("area")
+		trades = append(trades, t)
+	}
+	return trades, nil
+}
+
+func firstLine(b []byte) string {
+	s := string(b)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	return s
+}
+
+func parseTime(s string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return notz.ParseBrokenTime(s, loc)
+}
+
+func parseNumber(s string, decimalComma bool) (float64, error) {
+	if decimalComma {
+		s = strings.Replace(s, ",", ".", 1)
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// Aggregate groups trades by area and delivery hour. Trades with zero
+// quantity are ignored. The result is sorted by area and time.
+func Aggregate(trades []Trade) []Hour {
+	type key struct {
+		area string
+		hour int64
+	}
+	type sum struct {
+		value, volume float64
+		n             int
+	}
+	sums := make(map[key]*sum)
+	for _, t := range trades {
+		if t.Quantity == 0 {
+			continue
+		}
+		k := key{t.Area, t.Delivery.Truncate(time.Hour).Unix()}
+		s, ok := sums[k]
+		if !ok {
+			s = &sum{}
+			sums[k] = s
+		}
+		s.value += t.Price * t.Quantity
+		s.volume += t.Quantity
+		s.n++
+	}
+	hours := make([]Hour, 0, len(sums))
+	for k, s := range sums {
+		hours = append(hours, Hour{
+			Area:      k.area,
+			Timestamp: time.Unix(k.hour, 0).UTC(),
+			VWAP:      s.value / s.volume,
+			Volume:    s.volume,
+			Trades:    s.n,
+		})
+	}
+	sort.Slice(hours, func(i, j int) bool {
+		if hours[i].Area != hours[j].Area {
+			return hours[i].Area < hours[j].Area
+		}
+		return hours[i].Timestamp.Before(hours[j].Timestamp)
+	})
+	return hours
+}
@@ -0,0 +1,65 @@
+package intraday_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joneskoo/etget/intraday"
+)
+
+func TestParseAndAggregate(t *testing.T) {
+	cases := []struct {
+		name, input string
+	}{
+		{"comma", `Trade ID,Delivery Area,Delivery Start,Price,Quantity
+1,FI,2019-03-01T10:00:00Z,40.5,2
+2,FI,2019-03-01T10:15:00Z,43.5,1
+3,FI,2019-03-01T11:00:00Z,50,0
+4,SE3,2019-03-01T10:00:00Z,30,5
+`},
+		{"semicolon", `Delivery Area;Delivery Start;Price;Quantity
+FI;2019-03-01 11:00:00;40,5;2
+FI;2019-03-01 11:15;43,5;1
+FI;2019-03-01 12:00;50;0
+SE3;2019-03-01 11:00;30;5
+`},
+	}
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	want := []intraday.Hour{
+		{Area: "FI", Timestamp: ts, VWAP: 41.5, Volume: 3, Trades: 2},
+		{Area: "SE3", Timestamp: ts, VWAP: 30, Volume: 5, Trades: 1},
+	}
+	for _, c := range cases {
+		trades, err := intraday.ParseTrades(strings.NewReader(c.input), paris)
+		if err != nil {
+			t.Errorf("%s: ParseTrades: %s", c.name, err)
+			continue
+		}
+		if len(trades) != 4 {
+			t.Errorf("%s: ParseTrades returned %d trades, want 4", c.name, len(trades))
+		}
+		got := intraday.Aggregate(trades)
+		if len(got) != len(want) {
+			t.Errorf("%s: Aggregate = %+v, want %+v", c.name, got, want)
+			continue
+		}
+		for i, w := range want {
+			g := got[i]
+			if g.Area != w.Area || !g.Timestamp.Equal(w.Timestamp) || math.Abs(g.VWAP-w.VWAP) > 1e-9 || g.Volume != w.Volume || g.Trades != w.Trades {
+				t.Errorf("%s: hour %d = %+v, want %+v", c.name, i, g, w)
+			}
+		}
+	}
+}
+
+func TestParseTradesMissingColumn(t *testing.T) {
+	if _, err := intraday.ParseTrades(strings.NewReader("Delivery Start,Price\n"), time.UTC); err == nil {
+		t.Error("ParseTrades did not return error for missing quantity column")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"log"
 
+	"github.com/joneskoo/etget/internal/secretfile"
 	"golang.org/x/crypto/ssh/terminal"
 )
 
@@ -25,7 +26,8 @@ type CredentialStore struct {
 
 // UsernamePassword returns username and password from cache or prompts user.
 // After first call, it will assume cache is invalid and prompt for new
-// credentials, storing new value in cache.
+// credentials, storing new value in cache. A cache file encrypted with age
+// or GPG is decrypted but never prompted for or overwritten.
 func (c *CredentialStore) UsernamePassword() (username, password string, err error) {
 	if !c.cacheReturned {
 		c.cacheReturned = true
@@ -35,6 +37,14 @@ func (c *CredentialStore) UsernamePassword() (username, password string, err err
 		}
 	}
 
+	if secretfile.Encrypted(c.File) {
+		// Storing the new credentials would replace the encrypted file
+		// with a plaintext one.
+		if err == nil {
+			err = fmt.Errorf("credentials in encrypted %s were rejected; update the file by hand", c.File)
+		}
+		return "", "", err
+	}
 	log.Println("Please enter credentials, I will remember them")
 	if username, password, err = promptCredentials(c.Domain); err != nil {
 		return "", "", err
@@ -46,6 +56,19 @@ func (c *CredentialStore) UsernamePassword() (username, password string, err err
 	return username, password, nil
 }
 
+// Stored returns an error unless File holds a username and password. Unlike
+// UsernamePassword it never prompts.
+func (c *CredentialStore) Stored() error {
+	username, password, err := readCachedCredentials(c.File)
+	if err != nil {
+		return err
+	}
+	if username == "" || password == "" {
+		return fmt.Errorf("%s: no username or password", c.File)
+	}
+	return nil
+}
+
 const jsonIndent = "    "
 
 type credentials struct {
@@ -74,7 +97,7 @@ func readCachedCredentials(filename string) (username, password string, err erro
 		b []byte
 	)
 
-	if b, err = ioutil.ReadFile(filename); err != nil {
+	if b, err = secretfile.ReadFile(filename); err != nil {
 		return
 	}
 
@@ -0,0 +1,73 @@
+package notz
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// naiveLayouts are the accepted ISO-8601 layouts without a zone offset.
+var naiveLayouts = []string{
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+}
+
+// millisThreshold separates epoch seconds from epoch milliseconds: 1e11
+// seconds is in the year 5138, 1e11 milliseconds in 1973.
+const millisThreshold = 1e11
+
+// minEpoch and maxEpoch bound the epoch seconds of years 1 to 9999.
+const (
+	minEpoch = -62135596800
+	maxEpoch = 253402300800
+)
+
+// ParseBrokenTime parses a timestamp that an export source wrote in local
+// time but encoded as if it were UTC, and returns the same wall clock time
+// in loc. The encoding is detected from s:
+//
+//	1445731200            epoch seconds
+//	1445731200000         epoch milliseconds
+//	2015-10-25T03:00:00   ISO-8601 without offset (also with a space, or
+//	                      without seconds)
+//
+// The wall clock time is ambiguous in the hour repeated at the end of DST;
+// restore series with CheckedFixDST.
+func ParseBrokenTime(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, errors.New("notz: empty timestamp")
+	}
+	if v, err := strconv.ParseFloat(s, 64); err == nil {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return time.Time{}, fmt.Errorf("notz: epoch time %q is not a number", s)
+		}
+		if math.Abs(v) >= millisThreshold {
+			v /= 1000
+		}
+		if v < minEpoch || v >= maxEpoch {
+			return time.Time{}, fmt.Errorf("notz: epoch time %q is out of range", s)
+		}
+		sec, frac := math.Modf(v)
+		return WallClock(time.Unix(int64(sec), int64(frac*1e9)).UTC(), loc), nil
+	}
+	for _, layout := range naiveLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return WallClock(t, loc), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("notz: cannot parse %q as epoch seconds, epoch milliseconds or ISO-8601 local time", s)
+}
+
+// WallClock returns the time in loc with the same wall clock reading as t
+// has in its own location.
+func WallClock(t time.Time, loc *time.Location) time.Time {
+	year, month, day := t.Date()
+	hour, min, sec := t.Clock()
+	return time.Date(year, month, day, hour, min, sec, t.Nanosecond(), loc)
+}
@@ -17,25 +17,154 @@
 // Note that the local time is identical, with difference only in
 // time zone name. If we parse "Sun Oct 25 03:00:00 2015",
 // we get "Sun Oct 25 03:00:00 EET 2015".
-// If we get two identical timestamps, we assume that the first
-// was mis-interpreted and restore it by subtracting 1 hour.
+// When the series repeats wall clock times, CheckedFixDST looks up the
+// transition in the time zone database of the timestamps' location and
+// moves the first pass through the repeated times to the earlier offset.
+// This works for any zone and shift, such as the 30-minute shift of
+// Australia/Lord_Howe, as long as the series is sampled at least as often
+// as the shift is long.
 package notz
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
-// FixDST fixes DST ambiguoity in a slice of values.
-// All values must be hours with no minutes, seconds or nanoseconds part
-// in sequential order.
+// CheckedFixDST fixes DST ambiguoity in a slice of values.
+// The values must be in sequential order at a fixed interval, in the
+// location whose DST rules apply. In locations without DST transitions,
+// such as fixed zones, a repeated timestamp is taken to be an hour
+// earlier, as at the end of European summer time.
+//
+// If the series goes backwards by more than the one hour a DST transition
+// can explain, for example after a meter clock reset, CheckedFixDST returns a
+// *NonMonotonicError and leaves data unmodified.
+func CheckedFixDST(data Interface) error {
+	if err := checkMonotonic(data); err != nil {
+		return err
+	}
+	for i := 1; i < data.Len(); i++ {
+		t, prev := data.Time(i), data.Time(i-1)
+		if t.After(prev) {
+			continue
+		}
+		_, late, ok := ambiguous(t)
+		if !ok {
+			if t.Equal(prev) {
+				data.SetTime(i-1, prev.Add(-time.Hour))
+			}
+			continue
+		}
+		// The series went back in wall clock time: points before i that
+		// are at or after t are the first pass through the repeated
+		// times, and the points from i on the second.
+		for j := i - 1; j >= 0; j-- {
+			e, l, ok := ambiguous(data.Time(j))
+			if !ok || l.Before(late) {
+				break
+			}
+			data.SetTime(j, e)
+		}
+		for k := i; k < data.Len(); k++ {
+			_, l, ok := ambiguous(data.Time(k))
+			if !ok {
+				break
+			}
+			data.SetTime(k, l)
+		}
+	}
+	return nil
+}
+
+// FixDST is like CheckedFixDST but leaves a series that goes backwards
+// unmodified without telling.
+//
+// Deprecated: Use CheckedFixDST, which reports such series.
 func FixDST(data Interface) {
+	CheckedFixDST(data)
+}
+
+// maxShift bounds the clock change of a DST transition searched for by
+// ambiguous. The largest in the time zone database is two hours.
+const maxShift = 3 * time.Hour
+
+// ambiguous returns the two instants with the wall clock time of t in its
+// location, if the clock shows that time twice because it is turned back
+// around t.
+func ambiguous(t time.Time) (early, late time.Time, ok bool) {
+	_, before := t.Add(-maxShift).Zone()
+	_, after := t.Add(maxShift).Zone()
+	shift := time.Duration(before-after) * time.Second
+	if shift <= 0 {
+		return t, t, false
+	}
+	for _, other := range []time.Time{t.Add(-shift), t.Add(shift)} {
+		if !sameWallClock(t, other) {
+			continue
+		}
+		if other.Before(t) {
+			return other, t, true
+		}
+		return t, other, true
+	}
+	return t, t, false
+}
+
+func sameWallClock(a, b time.Time) bool {
+	a, b = a.In(a.Location()), b.In(a.Location())
+	y1, m1, d1 := a.Date()
+	y2, m2, d2 := b.Date()
+	h1, n1, s1 := a.Clock()
+	h2, n2, s2 := b.Clock()
+	return y1 == y2 && m1 == m2 && d1 == d2 && h1 == h2 && n1 == n2 && s1 == s2 && a.Nanosecond() == b.Nanosecond()
+}
+
+// NonMonotonicError reports points that are earlier than their predecessor
+// by more than one hour.
+type NonMonotonicError struct {
+	Points []BackwardsPoint
+}
+
+// BackwardsPoint is a single offending point in a NonMonotonicError.
+type BackwardsPoint struct {
+	// Index is the position of the point in the series.
+	Index int
+
+	// Time is the timestamp of the point and Prev that of the point before.
+	Time, Prev time.Time
+}
+
+func (e *NonMonotonicError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "notz: %d timestamps go backwards", len(e.Points))
+	for i, p := range e.Points {
+		if i == 3 {
+			b.WriteString(", ...")
+			break
+		}
+		fmt.Fprintf(&b, "; [%d] %s after %s", p.Index, p.Time, p.Prev)
+	}
+	return b.String()
+}
+
+// checkMonotonic returns a *NonMonotonicError if data goes backwards by
+// more than one hour anywhere.
+func checkMonotonic(data Interface) error {
+	var e NonMonotonicError
 	for i := 1; i < data.Len(); i++ {
-		prev := data.Time(i - 1)
-		if data.Time(i).Equal(prev) {
-			data.SetTime(i-1, prev.Add(-time.Hour))
+		prev, t := data.Time(i-1), data.Time(i)
+		if prev.Sub(t) > time.Hour {
+			e.Points = append(e.Points, BackwardsPoint{Index: i, Time: t, Prev: prev})
 		}
 	}
+	if len(e.Points) > 0 {
+		return &e
+	}
+	return nil
 }
 
-// Interface must be implemented by values used with FixDST.
+// Interface must be implemented by values used with CheckedFixDST.
 type Interface interface {
 	// Len is the number of elements in the collection.
 	Len() int
@@ -43,7 +172,9 @@ type Interface interface {
 	// Time retrieves the timestamp value to be fixed.
 	Time(i int) time.Time
 
-	// SetTime sets the timestamp value to be fixed.
+	// SetTime sets the timestamp value to be fixed. CheckedFixDST calls
+	// it only for the points whose time it infers, so implementations may
+	// use it to flag them for auditing.
 	SetTime(i int, t time.Time)
 }
 
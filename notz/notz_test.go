@@ -2,6 +2,7 @@ package notz_test
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -58,7 +59,9 @@ func TestTimeFixer(t *testing.T) {
 			nano := tHelsinki.Nanosecond()
 			tcTimes = append(tcTimes, time.Date(year, month, day, hour, min, sec, nano, helsinki))
 		}
-		notz.FixDST(notz.Times(tcTimes))
+		if err := notz.CheckedFixDST(notz.Times(tcTimes)); err != nil {
+			t.Errorf("testcase #%d: CheckedFixDST returned error: %s", tc, err)
+		}
 
 		for i, tt := range testcase {
 			if !tt.Equal(tcTimes[i]) {
@@ -69,7 +72,86 @@ func TestTimeFixer(t *testing.T) {
 	}
 }
 
-func ExampleFixDST() {
+// TestCheckedFixDSTZones checks the end of DST in zones with other transition
+// times and shifts, and series sampled more often than hourly.
+func TestCheckedFixDSTZones(t *testing.T) {
+	tests := []struct {
+		zone  string
+		start time.Time
+		step  time.Duration
+		n     int
+	}{
+		{"Europe/Helsinki", time.Date(2015, 10, 24, 23, 0, 0, 0, time.UTC), 15 * time.Minute, 16},
+		{"America/New_York", time.Date(2021, 11, 7, 3, 0, 0, 0, time.UTC), time.Hour, 6},
+		{"Australia/Lord_Howe", time.Date(2021, 4, 3, 13, 30, 0, 0, time.UTC), 30 * time.Minute, 7},
+		{"Australia/Sydney", time.Date(2021, 4, 3, 14, 0, 0, 0, time.UTC), time.Hour, 5},
+	}
+	for _, tt := range tests {
+		loc, err := time.LoadLocation(tt.zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := make([]time.Time, tt.n)
+		got := make([]time.Time, tt.n)
+		for i := range want {
+			want[i] = tt.start.Add(time.Duration(i) * tt.step).In(loc)
+			got[i] = notz.WallClock(want[i], loc)
+		}
+		if err := notz.CheckedFixDST(notz.Times(got)); err != nil {
+			t.Errorf("%s: CheckedFixDST returned error: %s", tt.zone, err)
+			continue
+		}
+		for i := range want {
+			if !got[i].Equal(want[i]) {
+				t.Errorf("%s[%d]: got %s, want %s", tt.zone, i, got[i], want[i])
+			}
+		}
+	}
+}
+
+func TestCheckedFixDSTBackwards(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	data := []time.Time{
+		start,
+		start.Add(1 * time.Hour),
+		start.Add(2 * time.Hour),
+		start, // meter clock reset
+		start.Add(1 * time.Hour),
+	}
+	orig := append([]time.Time(nil), data...)
+
+	err := notz.CheckedFixDST(notz.Times(data))
+	e, ok := err.(*notz.NonMonotonicError)
+	if !ok {
+		t.Fatalf("CheckedFixDST error = %#v, want *notz.NonMonotonicError", err)
+	}
+	if len(e.Points) != 1 || e.Points[0].Index != 3 {
+		t.Errorf("CheckedFixDST error points = %+v, want index 3", e.Points)
+	}
+	for i := range data {
+		if !data[i].Equal(orig[i]) {
+			t.Errorf("data[%d] = %s after error, want unmodified %s", i, data[i], orig[i])
+		}
+	}
+}
+
+// TestFixDST checks that the deprecated FixDST still fixes series.
+func TestFixDST(t *testing.T) {
+	helsinki, err := time.LoadLocation("Europe/Helsinki")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []time.Time{
+		time.Date(2015, 10, 25, 3, 0, 0, 0, helsinki),
+		time.Date(2015, 10, 25, 3, 0, 0, 0, helsinki),
+	}
+	notz.FixDST(notz.Times(data))
+	if got := data[1].Sub(data[0]); got != time.Hour {
+		t.Errorf("FixDST left the repeated hour %s apart, want 1h", got)
+	}
+}
+
+func ExampleCheckedFixDST() {
 	helsinki, err := time.LoadLocation("Europe/Helsinki")
 	if err != nil {
 		panic(err)
@@ -81,7 +163,9 @@ func ExampleFixDST() {
 		time.Date(2015, 10, 25, 3, 0, 0, 0, helsinki),
 		time.Date(2015, 10, 25, 4, 0, 0, 0, helsinki),
 	}
-	notz.FixDST(notz.Times(data))
+	if err := notz.CheckedFixDST(notz.Times(data)); err != nil {
+		panic(err)
+	}
 	for _, t := range data {
 		fmt.Printf("%s\n", t)
 	}
@@ -98,3 +182,77 @@ func must(t time.Time, err error) time.Time {
 	}
 	return t
 }
+
+func TestParseBrokenTime(t *testing.T) {
+	helsinki, err := time.LoadLocation("Europe/Helsinki")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2015, 10, 25, 2, 0, 0, 0, helsinki)
+	for _, in := range []string{
+		"1445738400",
+		"1445738400000",
+		"1445738400000.0",
+		"2015-10-25T02:00:00",
+		"2015-10-25 02:00:00",
+		"2015-10-25T02:00",
+		" 2015-10-25 02:00 ",
+	} {
+		got, err := notz.ParseBrokenTime(in, helsinki)
+		if err != nil {
+			t.Errorf("ParseBrokenTime(%q): %s", in, err)
+			continue
+		}
+		if !got.Equal(want) || got.Location() != helsinki {
+			t.Errorf("ParseBrokenTime(%q) = %s, want %s", in, got, want)
+		}
+	}
+	for _, in := range []string{
+		"", "yesterday", "2015-10-25T02:00:00Z", "2015-10-25T02:00:00+02:00",
+		"NaN", "Inf", "-Inf", "+Infinity", "1e400", "1e300", "-1e300", "253402300800000",
+	} {
+		if _, err := notz.ParseBrokenTime(in, helsinki); err == nil {
+			t.Errorf("ParseBrokenTime(%q) did not return error", in)
+		}
+	}
+}
+
+func TestSnapHours(t *testing.T) {
+	helsinki, err := time.LoadLocation("Europe/Helsinki")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		in, want time.Time
+		drift    time.Duration // reported offset, 0 if snapped
+	}{
+		{time.Date(2016, 1, 1, 1, 0, 0, 0, helsinki), time.Date(2016, 1, 1, 1, 0, 0, 0, helsinki), 0},
+		{time.Date(2016, 1, 1, 1, 0, 42, 0, helsinki), time.Date(2016, 1, 1, 1, 0, 0, 0, helsinki), 0},
+		{time.Date(2016, 1, 1, 1, 58, 0, 0, helsinki), time.Date(2016, 1, 1, 2, 0, 0, 0, helsinki), 0},
+		{time.Date(2016, 1, 1, 3, 10, 0, 0, helsinki), time.Date(2016, 1, 1, 3, 10, 0, 0, helsinki), 10 * time.Minute},
+		{time.Date(2016, 1, 1, 3, 50, 0, 0, helsinki), time.Date(2016, 1, 1, 3, 50, 0, 0, helsinki), -10 * time.Minute},
+		// UTC+5:30: whole local hours are half past in UTC.
+		{time.Date(2016, 1, 1, 4, 1, 0, 0, kolkata), time.Date(2016, 1, 1, 4, 0, 0, 0, kolkata), 0},
+	}
+	times := make(notz.Times, len(cases))
+	for i, c := range cases {
+		times[i] = c.in
+	}
+	drifts := notz.SnapHours(times, 5*time.Minute)
+	for i, c := range cases {
+		if !times[i].Equal(c.want) {
+			t.Errorf("[%d] %s snapped to %s, want %s", i, c.in, times[i], c.want)
+		}
+	}
+	var got []string
+	for _, d := range drifts {
+		got = append(got, fmt.Sprintf("%d %s", d.Index, d.Offset))
+	}
+	if want := "3 10m0s,4 -10m0s"; strings.Join(got, ",") != want {
+		t.Errorf("drifts = %v, want %s", got, want)
+	}
+}
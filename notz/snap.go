@@ -0,0 +1,58 @@
+package notz
+
+import (
+	"fmt"
+	"time"
+)
+
+// Drift is a timestamp that SnapHours left unmodified because it is
+// further from a whole hour than the tolerance.
+type Drift struct {
+	// Index is the position of the point in the series.
+	Index int
+
+	// Time is the timestamp of the point.
+	Time time.Time
+
+	// Offset is the distance from the nearest whole hour, negative if the
+	// point is early.
+	Offset time.Duration
+}
+
+func (d Drift) String() string {
+	return fmt.Sprintf("[%d] %s is %s off the hour", d.Index, d.Time.Format(time.RFC3339), d.Offset)
+}
+
+// SnapHours rounds the timestamps of data that are within tolerance of a
+// whole hour, on the wall clock of their location, to that hour. Meters
+// whose clock drifts report hourly values a few seconds or minutes off,
+// which would otherwise be counted in the wrong hour. The points further
+// off are left unmodified and returned, for the caller to reject or
+// report.
+//
+// Like CheckedFixDST, SnapHours calls SetTime only for the points it
+// moves. Run it before CheckedFixDST, which needs the series on whole
+// hours.
+func SnapHours(data Interface, tolerance time.Duration) (drifts []Drift) {
+	for i := 0; i < data.Len(); i++ {
+		t := data.Time(i)
+		hour := nearestHour(t)
+		offset := t.Sub(hour)
+		switch {
+		case offset == 0:
+		case offset <= tolerance && offset >= -tolerance:
+			data.SetTime(i, hour)
+		default:
+			drifts = append(drifts, Drift{Index: i, Time: t, Offset: offset})
+		}
+	}
+	return drifts
+}
+
+// nearestHour returns the whole hour of the wall clock nearest to t, which
+// differs from rounding UTC in zones offset by a fraction of an hour.
+func nearestHour(t time.Time) time.Time {
+	_, offset := t.Zone()
+	shift := time.Duration(offset) * time.Second
+	return t.Add(shift).Round(time.Hour).Add(-shift).In(t.Location())
+}
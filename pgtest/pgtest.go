@@ -0,0 +1,202 @@
+// Package pgtest provides disposable Postgres databases for integration
+// tests of the importers and of programs embedding them.
+//
+// The database server is the one in ETGET_TEST_CONNSTRING or, if that is
+// unset and ETGET_TEST_DOCKER=1, a Postgres container started with the
+// docker command for the test binary. Otherwise tests using New are
+// skipped. Each New call gets an empty schema of its own, so tests can run
+// in parallel and leave nothing behind.
+package pgtest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Environment variables configuring the server.
+const (
+	ConnstringEnv = "ETGET_TEST_CONNSTRING"
+	DockerEnv     = "ETGET_TEST_DOCKER"
+	ImageEnv      = "ETGET_TEST_POSTGRES_IMAGE"
+)
+
+// DefaultImage is the Postgres image started when ImageEnv is unset.
+const DefaultImage = "postgres:16-alpine"
+
+// DB is an empty schema for one test.
+type DB struct {
+	*sql.DB
+
+	// Connstring connects to the database with the schema first in the
+	// search path, for code under test that opens its own connections.
+	Connstring string
+
+	// Schema is the name of the schema.
+	Schema string
+}
+
+// New creates a schema for t, skipping t if no server is configured.
+// Close the DB to drop the schema.
+func New(t testing.TB) *DB {
+	t.Helper()
+	server, err := serverConnstring()
+	if err != nil {
+		t.Fatalf("pgtest: %s", err)
+	}
+	if server == "" {
+		t.Skipf("set %s or %s=1 to run database integration tests", ConnstringEnv, DockerEnv)
+	}
+	db, err := newDB(server)
+	if err != nil {
+		t.Fatalf("pgtest: %s", err)
+	}
+	return db
+}
+
+func newDB(server string) (*DB, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	schema := "pgtest_" + hex.EncodeToString(b)
+	admin, err := sql.Open("postgres", server)
+	if err != nil {
+		return nil, err
+	}
+	defer admin.Close()
+	if _, err := admin.Exec("CREATE SCHEMA " + pq.QuoteIdentifier(schema)); err != nil {
+		return nil, fmt.Errorf("create schema: %s", err)
+	}
+	connstring, err := withParam(server, "search_path", schema)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("postgres", connstring)
+	if err != nil {
+		return nil, err
+	}
+	return &DB{DB: db, Connstring: connstring, Schema: schema}, nil
+}
+
+// Close drops the schema and closes the connections.
+func (db *DB) Close() error {
+	_, err := db.Exec("DROP SCHEMA " + pq.QuoteIdentifier(db.Schema) + " CASCADE")
+	if cerr := db.DB.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// withParam adds a run-time parameter to connstring, either a URL or
+// key=value pairs.
+func withParam(connstring, key, value string) (string, error) {
+	if strings.HasPrefix(connstring, "postgres://") || strings.HasPrefix(connstring, "postgresql://") {
+		u, err := url.Parse(connstring)
+		if err != nil {
+			return "", err
+		}
+		q := u.Query()
+		q.Set(key, value)
+		u.RawQuery = q.Encode()
+		return u.String(), nil
+	}
+	return strings.TrimSpace(connstring + " " + key + "=" + value), nil
+}
+
+var container struct {
+	once       sync.Once
+	id         string
+	connstring string
+	err        error
+}
+
+// serverConnstring returns the server of ConnstringEnv, or starts the
+// container on first use if DockerEnv is set. It returns "" if neither is.
+func serverConnstring() (string, error) {
+	if cs := os.Getenv(ConnstringEnv); cs != "" {
+		return cs, nil
+	}
+	if os.Getenv(DockerEnv) != "1" {
+		return "", nil
+	}
+	container.once.Do(func() {
+		container.id, container.connstring, container.err = startContainer()
+	})
+	return container.connstring, container.err
+}
+
+// startContainer runs Postgres in docker on a random local port and
+// waits until it accepts connections.
+func startContainer() (id, connstring string, err error) {
+	image := os.Getenv(ImageEnv)
+	if image == "" {
+		image = DefaultImage
+	}
+	out, err := docker("run", "-d", "--rm", "-e", "POSTGRES_PASSWORD=pgtest", "-p", "127.0.0.1::5432", image)
+	if err != nil {
+		return "", "", err
+	}
+	id = out
+	port, err := docker("port", id, "5432/tcp")
+	if err != nil {
+		docker("stop", id)
+		return "", "", err
+	}
+	// "127.0.0.1:49153", possibly followed by an IPv6 mapping.
+	addr := strings.Fields(port)[0]
+	connstring = fmt.Sprintf("postgres://postgres:pgtest@%s/postgres?sslmode=disable", addr)
+
+	db, err := sql.Open("postgres", connstring)
+	if err != nil {
+		docker("stop", id)
+		return "", "", err
+	}
+	defer db.Close()
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		if err = db.Ping(); err == nil {
+			return id, connstring, nil
+		}
+		if time.Now().After(deadline) {
+			docker("stop", id)
+			return "", "", fmt.Errorf("postgres container not ready: %s", err)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+func docker(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("docker", args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("docker %s: %s: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// Main runs the tests of m and stops the container, if one was started.
+// Call it from TestMain:
+//
+//	func TestMain(m *testing.M) { os.Exit(pgtest.Main(m)) }
+func Main(m *testing.M) int {
+	code := m.Run()
+	if container.id != "" {
+		if _, err := docker("stop", container.id); err != nil {
+			fmt.Fprintln(os.Stderr, "pgtest:", err)
+		}
+	}
+	return code
+}
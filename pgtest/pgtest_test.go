@@ -0,0 +1,31 @@
+package pgtest
+
+import "testing"
+
+func TestWithParam(t *testing.T) {
+	tests := []struct {
+		connstring, want string
+	}{
+		{"sslmode=disable", "sslmode=disable search_path=s1"},
+		{"", "search_path=s1"},
+		{"postgres://u@db/etget?sslmode=disable", "postgres://u@db/etget?search_path=s1&sslmode=disable"},
+	}
+	for _, tt := range tests {
+		got, err := withParam(tt.connstring, "search_path", "s1")
+		if err != nil || got != tt.want {
+			t.Errorf("withParam(%q) = %q, %v; want %q", tt.connstring, got, err, tt.want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	db := New(t)
+	defer db.Close()
+	var schema string
+	if err := db.QueryRow("SELECT current_schema()").Scan(&schema); err != nil {
+		t.Fatal(err)
+	}
+	if schema != db.Schema {
+		t.Errorf("current_schema() = %q, want %q", schema, db.Schema)
+	}
+}
@@ -0,0 +1,137 @@
+// Package resample converts time series between resolutions, such as the
+// 15-minute prices of the day-ahead market and the hourly rows older
+// consumers expect.
+//
+// Converting to a coarser resolution combines the points of each interval;
+// converting to a finer one splits every point. How values combine depends
+// on what they measure: prices average (Mean), energy adds up (Sum).
+package resample
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Point is the value of the interval starting at Time.
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Method is how values combine across intervals.
+type Method int
+
+const (
+	// Mean is for rates such as prices: a coarser interval has the mean
+	// of its parts, and finer intervals repeat the value.
+	Mean Method = iota
+
+	// Sum is for quantities such as energy: a coarser interval has the
+	// sum of its parts, and finer intervals share the value equally.
+	Sum
+)
+
+// ParseMethod parses "mean" or "sum".
+func ParseMethod(s string) (Method, error) {
+	switch s {
+	case "mean":
+		return Mean, nil
+	case "sum":
+		return Sum, nil
+	}
+	return 0, fmt.Errorf("unknown method %q, want mean or sum", s)
+}
+
+func (m Method) String() string {
+	if m == Sum {
+		return "sum"
+	}
+	return "mean"
+}
+
+// Convert resamples points of resolution from to resolution to. One of
+// the resolutions must be a whole multiple of the other, and the points
+// must start at multiples of from (in UTC, so whole hours are aligned in
+// every zone with an offset of whole hours).
+//
+// When combining, intervals missing any of their parts are left out,
+// since their mean or sum would be wrong. The result is sorted by time.
+func Convert(points []Point, from, to time.Duration, m Method) ([]Point, error) {
+	if from <= 0 || to <= 0 {
+		return nil, errors.New("resample: resolutions must be positive")
+	}
+	for _, p := range points {
+		if !p.Time.Truncate(from).Equal(p.Time) {
+			return nil, fmt.Errorf("resample: %s is not at a multiple of %s", p.Time.Format(time.RFC3339), from)
+		}
+	}
+	switch {
+	case from == to:
+		return append([]Point(nil), points...), nil
+	case to%from == 0:
+		return combine(points, from, to, m)
+	case from%to == 0:
+		return split(points, from, to, m), nil
+	}
+	return nil, fmt.Errorf("resample: %s and %s are not multiples of each other", from, to)
+}
+
+func combine(points []Point, from, to time.Duration, m Method) ([]Point, error) {
+	n := int(to / from)
+	type interval struct {
+		start time.Time
+		sum   float64
+		parts map[int64]bool
+	}
+	byStart := make(map[int64]*interval)
+	var order []*interval
+	for _, p := range points {
+		start := p.Time.Truncate(to)
+		iv, ok := byStart[start.Unix()]
+		if !ok {
+			iv = &interval{start: start, parts: make(map[int64]bool, n)}
+			byStart[start.Unix()] = iv
+			order = append(order, iv)
+		}
+		if iv.parts[p.Time.Unix()] {
+			return nil, fmt.Errorf("resample: %s repeated", p.Time.Format(time.RFC3339))
+		}
+		iv.parts[p.Time.Unix()] = true
+		iv.sum += p.Value
+	}
+	var out []Point
+	for _, iv := range order {
+		if len(iv.parts) != n {
+			continue
+		}
+		v := iv.sum
+		if m == Mean {
+			v /= float64(n)
+		}
+		out = append(out, Point{Time: iv.start, Value: v})
+	}
+	sortPoints(out)
+	return out, nil
+}
+
+func split(points []Point, from, to time.Duration, m Method) []Point {
+	n := int(from / to)
+	out := make([]Point, 0, len(points)*n)
+	for _, p := range points {
+		v := p.Value
+		if m == Sum {
+			v /= float64(n)
+		}
+		for i := 0; i < n; i++ {
+			out = append(out, Point{Time: p.Time.Add(time.Duration(i) * to), Value: v})
+		}
+	}
+	sortPoints(out)
+	return out
+}
+
+func sortPoints(points []Point) {
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+}
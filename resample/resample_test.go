@@ -0,0 +1,61 @@
+package resample_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/joneskoo/etget/resample"
+)
+
+func TestConvert(t *testing.T) {
+	t0 := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	at := func(min int, v float64) resample.Point {
+		return resample.Point{Time: t0.Add(time.Duration(min) * time.Minute), Value: v}
+	}
+	quarters := []resample.Point{at(0, 1), at(15, 2), at(30, 3), at(45, 6), at(60, 1), at(75, 1)}
+	tests := []struct {
+		name     string
+		points   []resample.Point
+		from, to time.Duration
+		method   resample.Method
+		want     []resample.Point
+	}{
+		{"mean of quarters, incomplete hour left out", quarters, 15 * time.Minute, time.Hour, resample.Mean, []resample.Point{at(0, 3)}},
+		{"sum of quarters", quarters, 15 * time.Minute, time.Hour, resample.Sum, []resample.Point{at(0, 12)}},
+		{"hour repeated", []resample.Point{at(60, 8)}, time.Hour, 15 * time.Minute, resample.Mean,
+			[]resample.Point{at(60, 8), at(75, 8), at(90, 8), at(105, 8)}},
+		{"hour shared", []resample.Point{at(60, 8)}, time.Hour, 15 * time.Minute, resample.Sum,
+			[]resample.Point{at(60, 2), at(75, 2), at(90, 2), at(105, 2)}},
+		{"same resolution", []resample.Point{at(0, 1)}, time.Hour, time.Hour, resample.Sum, []resample.Point{at(0, 1)}},
+	}
+	for _, tt := range tests {
+		got, err := resample.Convert(tt.points, tt.from, tt.to, tt.method)
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Convert() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	t0 := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		points   []resample.Point
+		from, to time.Duration
+	}{
+		{"not aligned", []resample.Point{{Time: t0.Add(10 * time.Minute)}}, 15 * time.Minute, time.Hour},
+		{"not multiples", nil, 15 * time.Minute, 20 * time.Minute},
+		{"repeated", []resample.Point{{Time: t0}, {Time: t0}}, 15 * time.Minute, time.Hour},
+		{"zero", nil, 0, time.Hour},
+	}
+	for _, tt := range tests {
+		if _, err := resample.Convert(tt.points, tt.from, tt.to, resample.Mean); err == nil {
+			t.Errorf("%s: Convert did not return error", tt.name)
+		}
+	}
+}
@@ -1,8 +1,11 @@
-CREATE OR REPLACE VIEW kwh_by_temp_years AS (
+DROP VIEW IF EXISTS kwh_by_temp_years;
+CREATE VIEW kwh_by_temp_years AS (
 	-- Select average kWh/h consumed based on Willab weather station temperature.
 	-- Calculate the average temperature and average consumption of each day to
-	-- smooth out noise, and group by temperature, segmented into 5°C ranges.
+	-- smooth out noise, and group by temperature, segmented into 5°C ranges,
+	-- separately for each metering point.
 	SELECT
+		kwh.meter_id,
 		FLOOR(temp.temp/5)*5 AS temp_range_lo,
 		FLOOR(temp.temp/5)*5+5 AS temp_range_hi,
 		EXTRACT(year FROM temp.date) AS year,
@@ -20,17 +23,19 @@ CREATE OR REPLACE VIEW kwh_by_temp_years AS (
 		(
 			-- Average consumption by day
 			SELECT
+				meter_id,
 				DATE(ts AT TIME ZONE 'Europe/Helsinki') AS date,
 				AVG(kwh) AS kwh
 			FROM energiatili
-			GROUP BY 1
+			GROUP BY 1, 2
 		) AS kwh
 	WHERE temp.date = kwh.date AND temp.date >= '2018-10-01'
-	GROUP BY 1,3
-	ORDER BY 1,3
+	GROUP BY 1,2,4
+	ORDER BY 1,2,4
 );
 
 -- SELECT
+--         meter_id,
 --         year,
 --         MAX(CASE WHEN temp_range_lo = -25 THEN kwh_avg END) "-25°C…-20°C",
 --         MAX(CASE WHEN temp_range_lo = -20 THEN kwh_avg END) "-20°C…-15°C",
@@ -43,6 +48,7 @@ CREATE OR REPLACE VIEW kwh_by_temp_years AS (
 --         MAX(CASE WHEN temp_range_lo = 15 THEN kwh_avg END) "+15°C…+20°C",
 --         MAX(CASE WHEN temp_range_lo = 20 THEN kwh_avg END) "+20°C…+25°C"
 -- FROM kwh_by_temp_years
+-- GROUP BY meter_id, year
 --  year | -25°C…-20°C | -20°C…-15°C | -15°C…-10°C | -10°C…-5°C | -5°C…0°C | +0°C…+5°C | +5°C…+10°C | +10°C…+15°C | +15°C…+20°C | +20°C…+25°C
 -- ------+-------------+-------------+-------------+------------+----------+-----------+------------+-------------+-------------+-------------
 --  2018 |             |       4.885 |             |      3.269 |    2.909 |     2.297 |      2.123 |       1.676 |       1.632 |
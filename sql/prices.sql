@@ -30,6 +30,9 @@ $$ LANGUAGE SQL;
 
 SET timezone = "Europe/Helsinki";
 
+-- The bills and years are per metering point, meter_id as set by
+-- import-energiatili -meter.
+DROP VIEW IF EXISTS power_bills;
 CREATE VIEW power_bills AS (
     WITH prices AS (
         SELECT DISTINCT ON (elspot.ts)
@@ -42,6 +45,7 @@ CREATE VIEW power_bills AS (
         ORDER BY elspot.ts, power_contracts.valid_from DESC
     )
     SELECT
+        meter_id,
         DATE_TRUNC('month', ts)::date AS month,
         ROUND(SUM(kwh)) AS kwh,
         ROUND(SUM(kwh * is_night(ts)::int)) AS kwh_night,
@@ -51,11 +55,12 @@ CREATE VIEW power_bills AS (
         ROUND(100*(SUM(kwh * is_night(ts)::int * spot_e_kwh)/SUM(kwh * is_night(ts)::int))::numeric, 2) AS energy_c_night_kwh,
         ROUND(100*(SUM(kwh * is_day(ts)::int * spot_e_kwh)/SUM(kwh * is_day(ts)::int))::numeric, 2) AS energy_c_day_kwh
     FROM energiatili INNER JOIN prices USING (ts)
-    GROUP BY 1
-    ORDER BY 1
+    GROUP BY 1, 2
+    ORDER BY 1, 2
 );
 
-CREATE OR REPLACE VIEW power_years AS (
+DROP VIEW IF EXISTS power_years;
+CREATE VIEW power_years AS (
     WITH prices AS (
         SELECT DISTINCT ON (elspot.ts)
             elspot.ts,
@@ -67,6 +72,7 @@ CREATE OR REPLACE VIEW power_years AS (
         ORDER BY elspot.ts, power_contracts.valid_from DESC
     )
     SELECT
+        meter_id,
         DATE_TRUNC('year', ts)::date AS month,
         ROUND(SUM(kwh)) AS kwh,
         ROUND(SUM(kwh * is_night(ts)::int)) AS kwh_night,
@@ -76,6 +82,6 @@ CREATE OR REPLACE VIEW power_years AS (
         ROUND(100*(SUM(kwh * is_night(ts)::int * spot_e_kwh)/SUM(kwh * is_night(ts)::int))::numeric, 2) AS energy_c_night_kwh,
         ROUND(100*(SUM(kwh * is_day(ts)::int * spot_e_kwh)/SUM(kwh * is_day(ts)::int))::numeric, 2) AS energy_c_day_kwh
     FROM energiatili INNER JOIN prices USING (ts)
-    GROUP BY 1
-    ORDER BY 1
+    GROUP BY 1, 2
+    ORDER BY 1, 2
 );
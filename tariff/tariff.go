@@ -0,0 +1,306 @@
+// Package tariff prices electricity use by rules: the spot price with VAT,
+// a retailer's margin, a network operator's time-of-day and seasonal
+// rates, fixed monthly fees and fees by the month's peak power.
+//
+// A rule adds its rate per unit to one item of the bill whenever its
+// conditions hold. A Finnish spot contract with a seasonal transfer tariff
+// could be:
+//
+//	[
+//		{"name": "spot", "item": "energy", "unit": "spot", "rate": 1.255},
+//		{"name": "margin", "item": "energy", "unit": "kwh", "rate": 0.0049},
+//		{"name": "winter weekday", "item": "transfer", "unit": "kwh", "rate": 0.0452,
+//		 "months": [11, 12, 1, 2, 3], "weekdays": ["mon", "tue", "wed", "thu", "fri", "sat"],
+//		 "hours": {"from": 7, "to": 22}},
+//		{"name": "other time", "item": "transfer", "unit": "kwh", "rate": 0.0287,
+//		 "except": "winter weekday"},
+//		{"name": "power", "item": "transfer", "unit": "kw", "rate": 1.9},
+//		{"name": "basic fee", "item": "transfer", "unit": "month", "rate": 9.9},
+//		{"name": "sold", "item": "sold", "unit": "spot", "rate": 1},
+//		{"name": "sale margin", "item": "sold", "unit": "kwh", "rate": -0.003}
+//	]
+//
+// Conditions are evaluated in the location of the times given, so callers
+// pass local times.
+package tariff
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Items of the bill.
+const (
+	Energy   = "energy"
+	Transfer = "transfer"
+
+	// Sold is the credit for energy sold to the grid. Its rules price
+	// the sold kWh, not the consumed.
+	Sold = "sold"
+)
+
+// Units of the rates.
+const (
+	// PerKWh is euros per kWh.
+	PerKWh = "kwh"
+
+	// PerSpot multiplies the hour's spot price, in EUR/kWh. A rate of
+	// 1.24 adds the spot price with 24% VAT.
+	PerSpot = "spot"
+
+	// PerMonth is a fixed fee for each calendar month.
+	PerMonth = "month"
+
+	// PerKW is euros per kW of the month's highest hourly mean power
+	// among the hours matching the rule.
+	PerKW = "kw"
+)
+
+// Rule is one rate of a tariff.
+type Rule struct {
+	// Name identifies the rule in errors and in Except of other rules.
+	Name string `json:"name"`
+
+	Item string  `json:"item"`
+	Unit string  `json:"unit"`
+	Rate float64 `json:"rate"`
+
+	// Months limits the rule to these months, 1 to 12.
+	Months []int `json:"months,omitempty"`
+
+	// Weekdays limits the rule to these days, "mon" to "sun".
+	Weekdays []string `json:"weekdays,omitempty"`
+
+	// Hours limits the rule to a time-of-day band.
+	Hours *Hours `json:"hours,omitempty"`
+
+	// Except names a rule this one applies in the absence of: the
+	// "other time" rate of a band. The named rule must come first.
+	Except string `json:"except,omitempty"`
+}
+
+// Hours is the band of local hours from From up to To, wrapping around
+// midnight if To is less than From, e.g. 22 to 7 for night.
+type Hours struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+func (h Hours) contains(hour int) bool {
+	if h.From <= h.To {
+		return hour >= h.From && hour < h.To
+	}
+	return hour >= h.From || hour < h.To
+}
+
+// Tariff is the rules of a contract, in order.
+type Tariff []Rule
+
+// Usage is the metered use of an hour.
+type Usage struct {
+	Time    time.Time
+	KWh     float64
+	SoldKWh float64
+
+	// Spot is the price in EUR/MWh without VAT.
+	Spot float64
+}
+
+// Cost is an amount in euros by item of the bill.
+type Cost struct {
+	Energy   float64
+	Transfer float64
+
+	// Sold is the credit for sold production, to be subtracted.
+	Sold float64
+}
+
+// Net returns the cost after the credit for sold energy.
+func (c Cost) Net() float64 {
+	return c.Energy + c.Transfer - c.Sold
+}
+
+// Add returns the sum of c and d.
+func (c Cost) Add(d Cost) Cost {
+	return Cost{
+		Energy:   c.Energy + d.Energy,
+		Transfer: c.Transfer + d.Transfer,
+		Sold:     c.Sold + d.Sold,
+	}
+}
+
+func (c *Cost) add(item string, eur float64) {
+	switch item {
+	case Energy:
+		c.Energy += eur
+	case Transfer:
+		c.Transfer += eur
+	case Sold:
+		c.Sold += eur
+	}
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Validate reports the first rule that cannot be evaluated.
+func (t Tariff) Validate() error {
+	seen := make(map[string]bool)
+	for i, r := range t {
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		if err := r.validate(seen); err != nil {
+			return fmt.Errorf("tariff rule %s: %s", name, err)
+		}
+		if r.Name != "" {
+			seen[r.Name] = true
+		}
+	}
+	return nil
+}
+
+func (r Rule) validate(seen map[string]bool) error {
+	switch r.Item {
+	case Energy, Transfer, Sold:
+	default:
+		return fmt.Errorf("unknown item %q, want %s, %s or %s", r.Item, Energy, Transfer, Sold)
+	}
+	switch r.Unit {
+	case PerKWh, PerSpot:
+	case PerMonth, PerKW:
+		if r.Item == Sold {
+			return fmt.Errorf("unit %s cannot price sold energy", r.Unit)
+		}
+	default:
+		return fmt.Errorf("unknown unit %q, want %s, %s, %s or %s", r.Unit, PerKWh, PerSpot, PerMonth, PerKW)
+	}
+	for _, m := range r.Months {
+		if m < 1 || m > 12 {
+			return fmt.Errorf("month %d out of range 1-12", m)
+		}
+	}
+	for _, d := range r.Weekdays {
+		if _, ok := weekdays[strings.ToLower(d)]; !ok {
+			return fmt.Errorf("unknown weekday %q, want mon to sun", d)
+		}
+	}
+	if h := r.Hours; h != nil {
+		if h.From < 0 || h.From > 23 || h.To < 0 || h.To > 24 || h.From == h.To {
+			return fmt.Errorf("hours %d-%d: want distinct hours from 0-23 to 0-24", h.From, h.To)
+		}
+	}
+	if r.Unit == PerMonth && (len(r.Weekdays) > 0 || r.Hours != nil) {
+		return errors.New("a monthly fee can only be limited by months")
+	}
+	if r.Except != "" && !seen[r.Except] {
+		return fmt.Errorf("except %q: no such rule before it", r.Except)
+	}
+	return nil
+}
+
+// matchMonth reports whether the rule's months include t.
+func (r Rule) matchMonth(t time.Time) bool {
+	if len(r.Months) == 0 {
+		return true
+	}
+	for _, m := range r.Months {
+		if time.Month(m) == t.Month() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchOwn reports whether the rule's own conditions hold for the hour
+// starting at t.
+func (r Rule) matchOwn(t time.Time) bool {
+	if !r.matchMonth(t) {
+		return false
+	}
+	if len(r.Weekdays) > 0 {
+		found := false
+		for _, d := range r.Weekdays {
+			if weekdays[strings.ToLower(d)] == t.Weekday() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return r.Hours == nil || r.Hours.contains(t.Hour())
+}
+
+// match reports whether r applies to the hour starting at t, following
+// Except to the rules before it.
+func (t Tariff) match(i int, ts time.Time) bool {
+	r := t[i]
+	if !r.matchOwn(ts) {
+		return false
+	}
+	if r.Except == "" {
+		return true
+	}
+	for j := 0; j < i; j++ {
+		if t[j].Name == r.Except {
+			return !t.match(j, ts)
+		}
+	}
+	return true
+}
+
+// Hour returns the variable cost of u: the rules priced by kWh or by the
+// spot price.
+func (t Tariff) Hour(u Usage) Cost {
+	var c Cost
+	for i, r := range t {
+		if (r.Unit != PerKWh && r.Unit != PerSpot) || !t.match(i, u.Time) {
+			continue
+		}
+		perKWh := r.Rate
+		if r.Unit == PerSpot {
+			perKWh = r.Rate * u.Spot / 1000
+		}
+		kwh := u.KWh
+		if r.Item == Sold {
+			kwh = u.SoldKWh
+		}
+		c.add(r.Item, kwh*perKWh)
+	}
+	return c
+}
+
+// Month returns the fees of the calendar month of month: the fixed
+// monthly fees and the fees by peak power over hours, the month's use.
+func (t Tariff) Month(month time.Time, hours []Usage) Cost {
+	var c Cost
+	for i, r := range t {
+		switch r.Unit {
+		case PerMonth:
+			if r.matchMonth(month) {
+				c.add(r.Item, r.Rate)
+			}
+		case PerKW:
+			var peak float64
+			for _, u := range hours {
+				if u.KWh > peak && t.match(i, u.Time) {
+					peak = u.KWh
+				}
+			}
+			c.add(r.Item, peak*r.Rate)
+		}
+	}
+	return c
+}
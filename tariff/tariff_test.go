@@ -0,0 +1,84 @@
+package tariff_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/joneskoo/etget/tariff"
+)
+
+var seasonal = tariff.Tariff{
+	{Name: "spot", Item: tariff.Energy, Unit: tariff.PerSpot, Rate: 1.24},
+	{Name: "margin", Item: tariff.Energy, Unit: tariff.PerKWh, Rate: 0.002},
+	{Name: "winter weekday", Item: tariff.Transfer, Unit: tariff.PerKWh, Rate: 0.05,
+		Months: []int{11, 12, 1, 2, 3}, Weekdays: []string{"mon", "tue", "wed", "thu", "fri", "sat"},
+		Hours: &tariff.Hours{From: 7, To: 22}},
+	{Name: "other time", Item: tariff.Transfer, Unit: tariff.PerKWh, Rate: 0.03, Except: "winter weekday"},
+	{Name: "power", Item: tariff.Transfer, Unit: tariff.PerKW, Rate: 2, Hours: &tariff.Hours{From: 7, To: 22}},
+	{Name: "basic fee", Item: tariff.Transfer, Unit: tariff.PerMonth, Rate: 10},
+	{Name: "winter fee", Item: tariff.Energy, Unit: tariff.PerMonth, Rate: 1, Months: []int{12, 1, 2}},
+	{Name: "sold", Item: tariff.Sold, Unit: tariff.PerSpot, Rate: 1},
+	{Name: "sale margin", Item: tariff.Sold, Unit: tariff.PerKWh, Rate: -0.003},
+}
+
+func TestHour(t *testing.T) {
+	if err := seasonal.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		time                   string
+		energy, transfer, sold float64
+	}{
+		// 2 kWh at 50 EUR/MWh: 2 * (0.05*1.24 + 0.002); 1 kWh sold: 0.05 - 0.003
+		{"2020-01-08 12:00", 0.128, 0.10, 0.047}, // Wednesday
+		{"2020-01-08 22:00", 0.128, 0.06, 0.047},
+		{"2020-01-08 06:00", 0.128, 0.06, 0.047},
+		{"2020-01-12 12:00", 0.128, 0.06, 0.047}, // Sunday
+		{"2020-06-10 12:00", 0.128, 0.06, 0.047}, // summer
+	}
+	for _, c := range cases {
+		ts, err := time.Parse("2006-01-02 15:04", c.time)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := seasonal.Hour(tariff.Usage{Time: ts, KWh: 2, SoldKWh: 1, Spot: 50})
+		if math.Abs(got.Energy-c.energy) > 1e-9 || math.Abs(got.Transfer-c.transfer) > 1e-9 || math.Abs(got.Sold-c.sold) > 1e-9 {
+			t.Errorf("Hour(%s) = %+v, want energy %v transfer %v sold %v", c.time, got, c.energy, c.transfer, c.sold)
+		}
+	}
+}
+
+func TestMonth(t *testing.T) {
+	hours := []tariff.Usage{
+		{Time: time.Date(2020, 1, 8, 3, 0, 0, 0, time.UTC), KWh: 9}, // outside the power band
+		{Time: time.Date(2020, 1, 8, 8, 0, 0, 0, time.UTC), KWh: 4},
+		{Time: time.Date(2020, 1, 9, 18, 0, 0, 0, time.UTC), KWh: 5.5},
+	}
+	got := seasonal.Month(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), hours)
+	if want := (tariff.Cost{Energy: 1, Transfer: 10 + 2*5.5}); got != want {
+		t.Errorf("Month(January) = %+v, want %+v", got, want)
+	}
+	got = seasonal.Month(time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC), nil)
+	if want := (tariff.Cost{Transfer: 10}); got != want {
+		t.Errorf("Month(June) = %+v, want %+v", got, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cases := []tariff.Tariff{
+		{{Item: "tax", Unit: tariff.PerKWh}},
+		{{Item: tariff.Energy, Unit: "mwh"}},
+		{{Item: tariff.Sold, Unit: tariff.PerMonth}},
+		{{Item: tariff.Energy, Unit: tariff.PerKWh, Months: []int{13}}},
+		{{Item: tariff.Energy, Unit: tariff.PerKWh, Weekdays: []string{"monday"}}},
+		{{Item: tariff.Energy, Unit: tariff.PerKWh, Hours: &tariff.Hours{From: 7, To: 7}}},
+		{{Item: tariff.Energy, Unit: tariff.PerMonth, Hours: &tariff.Hours{From: 7, To: 22}}},
+		{{Item: tariff.Energy, Unit: tariff.PerKWh, Except: "later"}, {Name: "later", Item: tariff.Energy, Unit: tariff.PerKWh}},
+	}
+	for _, c := range cases {
+		if err := c.Validate(); err == nil {
+			t.Errorf("Validate(%+v) = nil, want error", c)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package testserver_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/joneskoo/etget/testserver"
+)
+
+func TestServer(t *testing.T) {
+	srv := testserver.New()
+	defer srv.Close()
+	srv.Handle("/f", testserver.Status(http.StatusServiceUnavailable), testserver.Body("ok"))
+	srv.Handle("/drop", testserver.Response{Drop: true})
+
+	want := []struct {
+		status int
+		body   string
+	}{
+		{http.StatusServiceUnavailable, ""},
+		{http.StatusOK, "ok"},
+		{http.StatusOK, "ok"},
+	}
+	for i, w := range want {
+		resp, err := http.Get(srv.URL + "/f")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != w.status || string(body) != w.body {
+			t.Errorf("request %d: got %d %q, want %d %q", i, resp.StatusCode, body, w.status, w.body)
+		}
+	}
+
+	if n := len(srv.Requests()); n != 3 {
+		t.Errorf("Requests() has %d requests, want 3", n)
+	}
+
+	if resp, err := http.Get(srv.URL + "/drop"); err == nil {
+		resp.Body.Close()
+		t.Error("GET /drop succeeded, want connection error")
+	}
+	if resp, err := http.Get(srv.URL + "/unknown"); err != nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /unknown = %v, %v, want 404", resp, err)
+	}
+}
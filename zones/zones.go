@@ -0,0 +1,215 @@
+// Package zones describes the day-ahead bidding zones of the Nordic and
+// Baltic markets and their changes over time, so that historical prices
+// can be labelled with the zone they were set in.
+//
+// A zone code can have several entries with adjacent validity periods,
+// e.g. when the local currency changed. Historical Nord Pool files name
+// some zones by city, such as "Oslo" for NO1; Code maps those names to
+// the zone codes.
+//
+// A zone split off a larger one, such as SE3 from SE in 2011, names it as
+// its predecessor. Lineage follows the predecessors, so that a long price
+// series of a zone can continue with the prices of the zone it replaced.
+package zones
+
+import (
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joneskoo/etget/internal/zoneinfo"
+)
+
+// Zone is a bidding zone during its validity period.
+type Zone struct {
+	// Code is the zone code, e.g. "SE3".
+	Code string
+
+	// Country is the ISO 3166 code of the country the zone is in.
+	Country string
+
+	// Location is the IANA time zone of the zone.
+	Location string
+
+	// Currency is the ISO 4217 code of the local currency. Day-ahead
+	// prices are published in EUR in every zone.
+	Currency string
+
+	// From and To are the first delivery day and the day after the last,
+	// at midnight in Location. The zero From is a zone older than the
+	// data, and the zero To one still in use.
+	From, To time.Time
+
+	// Names are the column headers of the zone in Nord Pool files, other
+	// than the code.
+	Names []string
+
+	// Predecessor is the code of the zone whose prices stood for this
+	// zone's area before From, if it was split off or merged into it.
+	Predecessor string
+}
+
+// ValidAt reports whether the zone existed at t.
+func (z Zone) ValidAt(t time.Time) bool {
+	return !t.Before(z.From) && (z.To.IsZero() || t.Before(z.To))
+}
+
+// LoadLocation returns the time zone of the zone.
+func (z Zone) LoadLocation() (*time.Location, error) { return zoneinfo.Load(z.Location) }
+
+// dataset lists the zones, one validity period per line: code, country,
+// time zone, currency, first day, day after the last day, the other names
+// in Nord Pool files separated by spaces and the predecessor.
+const dataset = `FI,FI,Europe/Helsinki,EUR,,,,
+SE,SE,Europe/Stockholm,SEK,,2011-11-01,,
+SE1,SE,Europe/Stockholm,SEK,2011-11-01,,,SE
+SE2,SE,Europe/Stockholm,SEK,2011-11-01,,,SE
+SE3,SE,Europe/Stockholm,SEK,2011-11-01,,,SE
+SE4,SE,Europe/Stockholm,SEK,2011-11-01,,,SE
+NO1,NO,Europe/Oslo,NOK,,,Oslo,
+NO2,NO,Europe/Oslo,NOK,,,Kr.sand,
+NO3,NO,Europe/Oslo,NOK,,,Molde Tr.heim,
+NO4,NO,Europe/Oslo,NOK,,,Tromsø,
+NO5,NO,Europe/Oslo,NOK,2010-03-15,,Bergen,
+DK1,DK,Europe/Copenhagen,DKK,1999-07-01,,,
+DK2,DK,Europe/Copenhagen,DKK,2000-10-01,,,
+EE,EE,Europe/Tallinn,EEK,2010-04-01,2011-01-01,,
+EE,EE,Europe/Tallinn,EUR,2011-01-01,,,
+LT,LT,Europe/Vilnius,LTL,2012-06-18,2015-01-01,,
+LT,LT,Europe/Vilnius,EUR,2015-01-01,,,
+LV,LV,Europe/Riga,LVL,2013-06-03,2014-01-01,,
+LV,LV,Europe/Riga,EUR,2014-01-01,,,
+DE-AT-LU,DE,Europe/Berlin,EUR,,2018-10-01,,
+DE-LU,DE,Europe/Berlin,EUR,2018-10-01,,,DE-AT-LU
+AT,AT,Europe/Vienna,EUR,2018-10-01,,,DE-AT-LU
+`
+
+var all []Zone
+
+func init() {
+	var err error
+	if all, err = parse(dataset); err != nil {
+		panic(err) // bundled data
+	}
+}
+
+func parse(data string) ([]Zone, error) {
+	records, err := csv.NewReader(strings.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	zones := make([]Zone, len(records))
+	for i, r := range records {
+		z := Zone{Code: r[0], Country: r[1], Location: r[2], Currency: r[3], Names: strings.Fields(r[6]), Predecessor: r[7]}
+		loc, err := z.LoadLocation()
+		if err != nil {
+			return nil, fmt.Errorf("zone %s: %s", z.Code, err)
+		}
+		for _, d := range []struct {
+			s string
+			t *time.Time
+		}{{r[4], &z.From}, {r[5], &z.To}} {
+			if d.s == "" {
+				continue
+			}
+			if *d.t, err = time.ParseInLocation("2006-01-02", d.s, loc); err != nil {
+				return nil, fmt.Errorf("zone %s: %s", z.Code, err)
+			}
+		}
+		zones[i] = z
+	}
+	return zones, nil
+}
+
+// All returns every validity period of every zone, sorted by code and
+// time.
+func All() []Zone {
+	zones := append([]Zone(nil), all...)
+	sort.SliceStable(zones, func(i, j int) bool {
+		if zones[i].Code != zones[j].Code {
+			return zones[i].Code < zones[j].Code
+		}
+		return zones[i].From.Before(zones[j].From)
+	})
+	return zones
+}
+
+// Code returns the zone code of a Nord Pool column name, e.g. "NO1" for
+// "Oslo". Other names, such as codes and "SYS", are returned unchanged.
+func Code(name string) string {
+	for _, z := range all {
+		for _, n := range z.Names {
+			if strings.EqualFold(n, name) {
+				return z.Code
+			}
+		}
+	}
+	return name
+}
+
+// Known reports whether name is the code or Nord Pool name of a zone.
+func Known(name string) bool {
+	code := Code(name)
+	for _, z := range all {
+		if strings.EqualFold(z.Code, code) {
+			return true
+		}
+	}
+	return false
+}
+
+// Lookup returns the zone of the code or Nord Pool name valid at t. It
+// returns an error if there is no such zone, or it did not exist at t.
+func Lookup(name string, t time.Time) (Zone, error) {
+	code := Code(name)
+	for _, z := range all {
+		if strings.EqualFold(z.Code, code) && z.ValidAt(t) {
+			return z, nil
+		}
+	}
+	if Known(code) {
+		return Zone{}, fmt.Errorf("zone %s did not exist at %s", code, t.Format(time.RFC3339))
+	}
+	return Zone{}, fmt.Errorf("unknown zone %q", name)
+}
+
+// Source is a zone whose prices make up part of a series.
+type Source struct {
+	// Code is the zone the prices are of.
+	Code string
+
+	// From and To bound the part of the series, as in Zone.
+	From, To time.Time
+}
+
+// Lineage returns the sources of a continuous price series of the zone
+// code, oldest first: the zones it replaced, each until its successor
+// began, and last the zone itself. It returns nil for an unknown zone.
+func Lineage(code string) []Source {
+	code = Code(code)
+	var periods []Zone
+	for _, z := range All() {
+		if strings.EqualFold(z.Code, code) {
+			periods = append(periods, z)
+		}
+	}
+	if len(periods) == 0 {
+		return nil
+	}
+	first, last := periods[0], periods[len(periods)-1]
+	var sources []Source
+	if first.Predecessor != "" {
+		for _, s := range Lineage(first.Predecessor) {
+			if !s.From.IsZero() && !s.From.Before(first.From) {
+				continue
+			}
+			if s.To.IsZero() || s.To.After(first.From) {
+				s.To = first.From
+			}
+			sources = append(sources, s)
+		}
+	}
+	return append(sources, Source{Code: first.Code, From: first.From, To: last.To})
+}
@@ -0,0 +1,99 @@
+package zones_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joneskoo/etget/internal/zoneinfo"
+	"github.com/joneskoo/etget/zones"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name     string
+		t        time.Time
+		code     string
+		currency string
+		err      bool
+	}{
+		{"FI", time.Date(2005, 1, 1, 0, 0, 0, 0, time.UTC), "FI", "EUR", false},
+		{"Oslo", time.Date(2005, 1, 1, 0, 0, 0, 0, time.UTC), "NO1", "NOK", false},
+		{"tr.heim", time.Date(2005, 1, 1, 0, 0, 0, 0, time.UTC), "NO3", "NOK", false},
+		{"SE", time.Date(2011, 10, 31, 22, 0, 0, 0, time.UTC), "SE", "SEK", false},
+		{"SE", time.Date(2011, 10, 31, 23, 0, 0, 0, time.UTC), "", "", true},
+		{"SE3", time.Date(2011, 10, 31, 23, 0, 0, 0, time.UTC), "SE3", "SEK", false},
+		{"SE3", time.Date(2011, 10, 31, 22, 0, 0, 0, time.UTC), "", "", true},
+		{"EE", time.Date(2010, 6, 1, 0, 0, 0, 0, time.UTC), "EE", "EEK", false},
+		{"EE", time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC), "EE", "EUR", false},
+		{"EE", time.Date(2009, 6, 1, 0, 0, 0, 0, time.UTC), "", "", true},
+		{"SYS", time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC), "", "", true},
+	}
+	for _, tt := range tests {
+		z, err := zones.Lookup(tt.name, tt.t)
+		if (err != nil) != tt.err || z.Code != tt.code || z.Currency != tt.currency {
+			t.Errorf("Lookup(%s, %s) = %s %s, %v; want %s %s, error %v", tt.name, tt.t, z.Code, z.Currency, err, tt.code, tt.currency, tt.err)
+		}
+	}
+}
+
+func TestAll(t *testing.T) {
+	all := zones.All()
+	for i, z := range all {
+		if _, err := z.LoadLocation(); err != nil {
+			t.Errorf("%s: %s", z.Code, err)
+		}
+		if !z.To.IsZero() && !z.To.After(z.From) {
+			t.Errorf("%s: ends %s before it starts %s", z.Code, z.To, z.From)
+		}
+		// Periods of a code must not overlap.
+		if i > 0 && all[i-1].Code == z.Code && (all[i-1].To.IsZero() || all[i-1].To.After(z.From)) {
+			t.Errorf("%s: period from %s overlaps the previous one", z.Code, z.From)
+		}
+	}
+	if got := zones.Code("Kr.sand"); got != "NO2" {
+		t.Errorf("Code(Kr.sand) = %s, want NO2", got)
+	}
+	if got := zones.Code("SYS"); got != "SYS" {
+		t.Errorf("Code(SYS) = %s, want SYS unchanged", got)
+	}
+}
+
+func TestLineage(t *testing.T) {
+	day := func(s string, loc string) time.Time {
+		l, err := zoneinfo.Load(loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, err := time.ParseInLocation("2006-01-02", s, l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	split := day("2011-11-01", "Europe/Stockholm")
+	tests := []struct {
+		code string
+		want []zones.Source
+	}{
+		{"SE3", []zones.Source{{Code: "SE", To: split}, {Code: "SE3", From: split}}},
+		{"at", []zones.Source{
+			{Code: "DE-AT-LU", To: day("2018-10-01", "Europe/Berlin")},
+			{Code: "AT", From: day("2018-10-01", "Europe/Vienna")},
+		}},
+		{"EE", []zones.Source{{Code: "EE", From: day("2010-04-01", "Europe/Tallinn")}}},
+		{"Oslo", []zones.Source{{Code: "NO1"}}},
+		{"SYS", nil},
+	}
+	for _, tt := range tests {
+		got := zones.Lineage(tt.code)
+		if len(got) != len(tt.want) {
+			t.Errorf("Lineage(%s) = %v, want %v", tt.code, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i].Code != tt.want[i].Code || !got[i].From.Equal(tt.want[i].From) || !got[i].To.Equal(tt.want[i].To) {
+				t.Errorf("Lineage(%s)[%d] = %v, want %v", tt.code, i, got[i], tt.want[i])
+			}
+		}
+	}
+}